@@ -9,6 +9,7 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/redis/go-redis/v9 v9.11.0
 	github.com/valyala/fasthttp v1.64.0
+	golang.org/x/text v0.27.0
 )
 
 require (
@@ -1,79 +1,321 @@
 package main
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"os"
+	"runtime/debug"
 	"strings"
 	"time"
 
+	"github.com/backsoul/quiz/pkg/config"
 	"github.com/backsoul/quiz/pkg/handlers"
 	"github.com/backsoul/quiz/pkg/models"
 	"github.com/backsoul/quiz/pkg/redis"
 	"github.com/backsoul/quiz/pkg/services"
 	hubpkg "github.com/backsoul/quiz/pkg/websocket"
-	ws "github.com/fasthttp/websocket"
+	"github.com/google/uuid"
 	"github.com/valyala/fasthttp"
 )
 
 // Globals
+var cfg *config.Config
 var sessionService *services.SessionService
 var sessionHandler *handlers.SessionHandler
+var questionHandler *handlers.QuestionHandler
 var gameControlHandler *handlers.GameControlHandler
 var hub *hubpkg.Hub
+var fallbackStore *redis.FallbackStore // nil si REDIS_FALLBACK está desactivado
+
+// redisProbeInterval frecuencia con la que se comprueba si Redis volvió
+const redisProbeInterval = 2 * time.Second
 
 func main() {
+	cfg = config.Load()
+	models.CurrencySymbol = cfg.CurrencySymbol
+
 	// Redis setup
-	redisAddr := os.Getenv("REDIS_ADDR")
-	if redisAddr == "" {
-		redisAddr = "localhost:6379"
-	}
-	log.Printf("Connecting to Redis %s", redisAddr)
-	redisClient := redis.NewRedisClient(redisAddr, "", 0)
+	log.Printf("Connecting to Redis %s", cfg.RedisAddr)
+	redisClient := redis.NewRedisClient(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
+	redisClient.SetPrefix(cfg.RedisPrefix)
 	defer redisClient.Close()
 
-	// Load questions from file
-	questions, err := loadQuestions("answers.json")
+	// Con REDIS_FALLBACK, una caída breve de Redis deja el juego en solo lectura
+	var store redis.RedisStore = redisClient
+	if cfg.RedisFallback {
+		fallbackStore = redis.NewFallbackStore(redisClient)
+		go fallbackStore.Watch(redisProbeInterval)
+		store = fallbackStore
+	}
+
+	// Load questions from file(s)
+	questions, err := loadQuestions(cfg.QuestionsFiles)
 	if err != nil {
 		log.Fatalf("Error loading questions: %v", err)
 	}
 	log.Printf("Loaded %d questions", len(questions))
 
 	// Services
-	questionService := services.NewQuestionService(redisClient)
-	sessionService = services.NewSessionService(redisClient)
-	gameStateService := services.NewGameStateService(redisClient)
+	questionService := services.NewQuestionService(store)
+	questionService.SetAnswerMatching(cfg.AnswerMatching)
+	questionService.SetStatsRetention(cfg.StatsRetention)
+	questionService.SetDifficultyRange(cfg.MinDifficulty, cfg.MaxDifficulty, cfg.DifficultyAction == "clamp")
+	sessionService = services.NewSessionService(store)
+	sessionService.SetAutoContinue(cfg.AutoContinueSessions)
+	sessionService.SetMaxPlayers(cfg.MaxPlayers)
+	sessionService.SetMaxPrize(cfg.MaxPrize)
+	sessionService.SetMaxQuestions(cfg.MaxQuestions)
+	sessionService.SetSessionTTL(cfg.SessionTTL)
+	sessionService.SetLives(cfg.PlayerLives)
+	sessionService.SetRejoinWindow(cfg.RejoinWindow)
+	if cfg.LeaderboardDebounce > 0 {
+		go sessionService.RunLeaderboardWorker(cfg.LeaderboardDebounce)
+	}
+	gameStateService := services.NewGameStateService(store)
+	gameStateService.SetMaxQuestions(cfg.MaxQuestions)
+	gameStateService.SetQuestionTimer(services.QuestionTimer{
+		Default:      cfg.QuestionTimeLimit,
+		ByDifficulty: cfg.QuestionTimeByDifficulty,
+	})
+	gameStateService.SetCacheTTL(cfg.GameStateCacheTTL)
+	gameStateService.SetTwoPhaseQuestions(cfg.TwoPhaseQuestions)
 	
 	// Inyectar dependencia para calcular pregunta actual dinámicamente
 	gameStateService.SetSessionService(sessionService)
+	if err := gameStateService.SyncRunningMarker(); err != nil {
+		log.Printf("Warn syncing game state: %v", err)
+	}
 	
 	// Populate Redis
-	if err := questionService.LoadQuestionsFromFile("answers.json"); err != nil {
+	if err := questionService.LoadQuestionsFromFiles(cfg.QuestionsFiles); err != nil {
 		log.Printf("Warn loading to redis: %v", err)
 	}
 
+	auditService := services.NewAuditService(store)
+
 	// WebSocket hub & handlers
 	hub = hubpkg.NewHub()
+	hub.SetReadLimit(int64(cfg.WSMaxMessageSize))
+	hub.SetRateLimit(cfg.WSRateLimit, cfg.WSRateBurst)
 	go hub.Run()
+	gameStateService.SetBroadcaster(hub.BroadcastMessage)
+	gameStateService.SetTickInterval(cfg.TimerTickInterval)
 	sessionHandler = handlers.NewSessionHandler(sessionService, questionService, hub)
+	if cfg.AnswerBatchWindow > 0 {
+		sessionHandler.SetAnswerBatcher(hubpkg.NewEventBatcher(hub, "answersBatch", cfg.AnswerBatchWindow))
+	}
+	sessionHandler.SetAuditService(auditService)
+	sessionHandler.SetStrictFinalAnswer(cfg.StrictFinalAnswer)
+	sessionHandler.SetShowExplanation(cfg.ShowExplanation)
+	sessionHandler.SetMinAnswerTime(cfg.MinAnswerTime, cfg.MinAnswerAction == "reject")
+	sessionHandler.SetGameStateService(gameStateService)
+	questionHandler = handlers.NewQuestionHandler(questionService, sessionService)
+	questionHandler.SetQuestionsFiles(cfg.QuestionsFiles)
+	questionHandler.SetAuditService(auditService)
 	gameControlHandler = handlers.NewGameControlHandler(gameStateService, sessionService, hub)
+	gameControlHandler.SetArchiveService(services.NewArchiveService(store))
+	gameControlHandler.SetQuestionService(questionService)
+	gameControlHandler.SetAuditService(auditService)
+	gameControlHandler.SetDiagnosticsService(services.NewDiagnosticsService(store))
+	gameControlHandler.SetAutoEndAction(cfg.AutoEndAction)
+	gameControlHandler.SetAdminCheck(adminWebSocket)
+	if cfg.AutoEndAction != "off" {
+		sessionHandler.OnSessionOver(gameControlHandler.CheckRoundComplete)
+	}
+
+	// Configuración ajustable en caliente: parte de las variables de entorno,
+	// se sobrescribe con la guardada en Redis y se reaplica en cada cambio
+	settingsService := services.NewSettingsService(store, models.GameSettings{
+		QuestionTimeLimit: int(cfg.QuestionTimeLimit / time.Second),
+		BroadcastInterval: int(cfg.BroadcastInterval / time.Second),
+		TimerTickSeconds:  int(cfg.TimerTickInterval / time.Second),
+		PlayerLives:       cfg.PlayerLives,
+		AnswerMatching:    cfg.AnswerMatching,
+		TwoPhaseQuestions: cfg.TwoPhaseQuestions,
+		StrictFinalAnswer: cfg.StrictFinalAnswer,
+		ShowExplanation:   cfg.ShowExplanation,
+	})
+	settingsService.OnChange(func(settings models.GameSettings) {
+		gameStateService.SetQuestionTimer(services.QuestionTimer{
+			Default:      time.Duration(settings.QuestionTimeLimit) * time.Second,
+			ByDifficulty: cfg.QuestionTimeByDifficulty,
+		})
+		gameStateService.SetTickInterval(time.Duration(settings.TimerTickSeconds) * time.Second)
+		gameStateService.SetTwoPhaseQuestions(settings.TwoPhaseQuestions)
+		sessionService.SetLives(settings.PlayerLives)
+		questionService.SetAnswerMatching(settings.AnswerMatching)
+		sessionHandler.SetStrictFinalAnswer(settings.StrictFinalAnswer)
+		sessionHandler.SetShowExplanation(settings.ShowExplanation)
+	})
+	if _, err := settingsService.Load(); err != nil {
+		log.Printf("Warn loading settings: %v", err)
+	}
+	gameControlHandler.SetSettingsService(settingsService)
 
 	// Broadcaster
 	go func() {
-		ticker := time.NewTicker(5 * time.Second)
+		interval := time.Duration(settingsService.Current().BroadcastInterval) * time.Second
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
+		var failures broadcastFailures
 		for range ticker.C {
+			// El intervalo puede cambiar desde /api/admin/settings
+			if current := time.Duration(settingsService.Current().BroadcastInterval) * time.Second; current != interval {
+				interval = current
+				ticker.Reset(interval)
+			}
 			sessions, err := sessionService.GetActiveSessions()
 			if err != nil {
+				failures.fail(err)
 				continue
 			}
+			failures.succeed()
 			hub.BroadcastMessage("sessions", sessions)
 		}
 	}()
 
 	// Server
-	server := &fasthttp.Server{Handler: requestRouter}
-	log.Fatal(server.ListenAndServe(":8080"))
+	server := newServer(withRecovery(withCompression(withReadOnlyGuard(requestRouter))))
+	log.Fatal(server.ListenAndServe(":" + cfg.Port))
+}
+
+// broadcastEscalation fallos seguidos del broadcaster a partir de los que el problema se considera persistente
+const broadcastEscalation = 5
+
+// broadcastFailures cuenta los fallos seguidos del broadcaster. Registra y
+// difunde connectivityWarning en los fallos 1, 2, 4, 8... para no inundar el
+// log, y connectivityRestored cuando vuelve a funcionar.
+type broadcastFailures struct {
+	consecutive int
+	since       time.Time
+}
+
+func (b *broadcastFailures) fail(err error) {
+	if b.consecutive == 0 {
+		b.since = time.Now()
+	}
+	b.consecutive++
+	if b.consecutive&(b.consecutive-1) != 0 {
+		return
+	}
+
+	if b.consecutive >= broadcastEscalation {
+		log.Printf("🔥 El broadcaster lleva %d fallos seguidos (desde %s) obteniendo sesiones activas: %v", b.consecutive, models.FormatTime(b.since), err)
+	} else {
+		log.Printf("⚠️ Error obteniendo sesiones activas para difundir (%d seguidos): %v", b.consecutive, err)
+	}
+	hub.BroadcastMessage("connectivityWarning", map[string]interface{}{
+		"consecutiveFailures": b.consecutive,
+		"since":               models.FormatTime(b.since),
+		"persistent":          b.consecutive >= broadcastEscalation,
+		"message":             "El servidor tiene problemas para acceder a los datos del juego",
+	})
+}
+
+func (b *broadcastFailures) succeed() {
+	if b.consecutive == 0 {
+		return
+	}
+	log.Printf("✅ El broadcaster se recuperó tras %d fallos seguidos", b.consecutive)
+	hub.BroadcastMessage("connectivityRestored", map[string]interface{}{
+		"consecutiveFailures": b.consecutive,
+		"since":               models.FormatTime(b.since),
+	})
+	b.consecutive = 0
+}
+
+// newServer crea el servidor HTTP con límites de tiempo, tamaño de cuerpo y
+// concurrencia. Las conexiones WebSocket no se ven afectadas: fasthttp quita
+// los plazos de la conexión al secuestrarla en el upgrade de /ws.
+func newServer(handler fasthttp.RequestHandler) *fasthttp.Server {
+	return &fasthttp.Server{
+		Handler:            handler,
+		ReadTimeout:        cfg.ReadTimeout,
+		WriteTimeout:       cfg.WriteTimeout,
+		IdleTimeout:        cfg.IdleTimeout,
+		MaxRequestBodySize: cfg.MaxRequestBodySize,
+		Concurrency:        cfg.Concurrency,
+		ErrorHandler:       serverErrorHandler,
+	}
+}
+
+// serverErrorHandler responde 413 a los cuerpos que superan el límite y
+// conserva las respuestas por defecto de fasthttp para el resto de errores
+func serverErrorHandler(ctx *fasthttp.RequestCtx, err error) {
+	var smallBuffer *fasthttp.ErrSmallBuffer
+	var netErr *net.OpError
+	switch {
+	case errors.Is(err, fasthttp.ErrBodyTooLarge):
+		ctx.Error("Cuerpo de la petición demasiado grande", fasthttp.StatusRequestEntityTooLarge)
+	case errors.As(err, &smallBuffer):
+		ctx.Error("Cabeceras de la petición demasiado grandes", fasthttp.StatusRequestHeaderFieldsTooLarge)
+	case errors.As(err, &netErr) && netErr.Timeout():
+		ctx.Error("Tiempo de lectura agotado", fasthttp.StatusRequestTimeout)
+	default:
+		ctx.Error("Petición inválida", fasthttp.StatusBadRequest)
+	}
+}
+
+// withRecovery captura cualquier panic de un handler, lo registra con su
+// traza e ID de correlación y responde 500 sin tumbar el servidor
+func withRecovery(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		defer func() {
+			if r := recover(); r != nil {
+				correlationID := uuid.New().String()
+				log.Printf("🔥 Panic en %s %s [%s]: %v\n%s", ctx.Method(), ctx.Path(), correlationID, r, debug.Stack())
+
+				data, _ := json.Marshal(models.APIResponse{
+					Success: false,
+					Error:   fmt.Sprintf("Error interno del servidor (ref: %s)", correlationID),
+				})
+				ctx.Response.ResetBody()
+				ctx.Response.Header.Set("X-Correlation-ID", correlationID)
+				ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+				ctx.SetContentType("application/json")
+				ctx.SetBody(data)
+			}
+		}()
+		next(ctx)
+	}
+}
+
+// withReadOnlyGuard rechaza con 503 las peticiones que modifican datos mientras
+// Redis está caído; las lecturas siguen sirviendo el último estado conocido
+func withReadOnlyGuard(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if fallbackStore != nil && fallbackStore.Degraded() && !ctx.IsGet() && !ctx.IsHead() &&
+			strings.HasPrefix(string(ctx.Path()), "/api/") {
+			data, _ := json.Marshal(models.APIResponse{
+				Success: false,
+				Error:   "Servicio temporalmente no disponible: sin conexión con Redis, el juego está en solo lectura",
+			})
+			ctx.Response.Header.Set("Retry-After", "5")
+			ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+			ctx.SetContentType("application/json")
+			ctx.SetBody(data)
+			return
+		}
+		next(ctx)
+	}
+}
+
+// withCompression comprime (gzip/deflate según Accept-Encoding) las respuestas
+// de la API. fasthttp omite los cuerpos menores a 200 bytes; los archivos
+// estáticos y el upgrade de /ws quedan fuera.
+func withCompression(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	compressed := fasthttp.CompressHandlerLevel(next, fasthttp.CompressBestSpeed)
+	return func(ctx *fasthttp.RequestCtx) {
+		if strings.HasPrefix(string(ctx.Path()), "/api/") {
+			compressed(ctx)
+			return
+		}
+		next(ctx)
+	}
 }
 
 func requestRouter(ctx *fasthttp.RequestCtx) {
@@ -94,6 +336,26 @@ func requestRouter(ctx *fasthttp.RequestCtx) {
 			sessionHandler.GetSession(ctx)
 			return
 		}
+		if len(parts) == 5 && parts[4] == "certificate" {
+			ctx.SetUserValue("id", parts[3])
+			sessionHandler.GetCertificate(ctx)
+			return
+		}
+		if len(parts) == 5 && parts[4] == "answered-questions" {
+			ctx.SetUserValue("id", parts[3])
+			sessionHandler.GetAnsweredQuestions(ctx)
+			return
+		}
+		if len(parts) == 5 && parts[4] == "next-prize" {
+			ctx.SetUserValue("id", parts[3])
+			sessionHandler.GetNextPrize(ctx)
+			return
+		}
+		if len(parts) == 5 && parts[4] == "next" {
+			ctx.SetUserValue("id", parts[3])
+			sessionHandler.GetNextQuestion(ctx)
+			return
+		}
 	}
 
 	// Game API: answer/lifeline
@@ -109,6 +371,16 @@ func requestRouter(ctx *fasthttp.RequestCtx) {
 			sessionHandler.UseLifeline(ctx)
 			return
 		}
+		if len(parts) == 5 && parts[4] == "rejoin" {
+			ctx.SetUserValue("id", parts[3])
+			sessionHandler.RejoinSession(ctx)
+			return
+		}
+		if len(parts) == 5 && parts[4] == "select" {
+			ctx.SetUserValue("id", parts[3])
+			sessionHandler.SelectOption(ctx)
+			return
+		}
 	}
 
 	// Game Control API (Admin endpoints)
@@ -120,30 +392,79 @@ func requestRouter(ctx *fasthttp.RequestCtx) {
 		gameControlHandler.EndGame(ctx)
 		return
 	}
+	if method == "POST" && path == "/api/game/finish-all" {
+		if !requireAdmin(ctx) {
+			return
+		}
+		gameControlHandler.FinishAllPlayers(ctx)
+		return
+	}
 	if method == "POST" && path == "/api/game/next-question" {
 		gameControlHandler.NextQuestion(ctx)
 		return
 	}
+	if method == "POST" && path == "/api/game/open-answers" {
+		gameControlHandler.OpenAnswers(ctx)
+		return
+	}
 	if method == "POST" && path == "/api/game/reveal-answer" {
 		gameControlHandler.RevealAnswer(ctx)
 		return
 	}
+	if method == "GET" && path == "/api/admin/live" {
+		if !requireAdmin(ctx) {
+			return
+		}
+		gameControlHandler.GetLive(ctx)
+		return
+	}
+	if method == "GET" && path == "/api/admin/current-question/timing" {
+		if !requireAdmin(ctx) {
+			return
+		}
+		gameControlHandler.GetQuestionTiming(ctx)
+		return
+	}
+	if method == "POST" && path == "/api/game/void-question" {
+		if !requireAdmin(ctx) {
+			return
+		}
+		gameControlHandler.VoidQuestion(ctx)
+		return
+	}
+	if method == "POST" && path == "/api/game/peek-answer" {
+		if !requireAdmin(ctx) {
+			return
+		}
+		gameControlHandler.PeekAnswer(ctx)
+		return
+	}
+	if method == "POST" && path == "/api/game/announce" {
+		if !requireAdmin(ctx) {
+			return
+		}
+		gameControlHandler.Announce(ctx)
+		return
+	}
+	if method == "GET" && strings.HasPrefix(path, "/api/game/question/") {
+		parts := strings.Split(path, "/")
+		if len(parts) == 5 {
+			ctx.SetUserValue("number", parts[4])
+			questionHandler.GetQuestionByNumber(ctx)
+			return
+		}
+	}
 	if method == "GET" && path == "/api/game/state" {
 		gameControlHandler.GetGameState(ctx)
 		return
 	}
+	if method == "GET" && path == "/api/game/clock" {
+		gameControlHandler.GetClock(ctx)
+		return
+	}
 	// WebSocket endpoint
 	if method == "GET" && path == "/ws" {
-		upgrader := ws.FastHTTPUpgrader{CheckOrigin: func(ctx *fasthttp.RequestCtx) bool { return true }}
-		upgrader.Upgrade(ctx, func(conn *ws.Conn) {
-			hub.Register(conn)
-			defer hub.Unregister(conn)
-			for {
-				if _, _, err := conn.ReadMessage(); err != nil {
-					break
-				}
-			}
-		})
+		gameControlHandler.HandleWebSocket(ctx)
 		return
 	}
 	// Static routes
@@ -162,32 +483,282 @@ func requestRouter(ctx *fasthttp.RequestCtx) {
 		return
 	}
 	// Questions API
+	if method == "GET" && path == "/api/questions/random" {
+		questionHandler.GetRandomQuestion(ctx)
+		return
+	}
+	if method == "GET" && path == "/api/questions/random/difficulty" {
+		questionHandler.GetRandomQuestionByDifficulty(ctx)
+		return
+	}
+	if method == "GET" && path == "/api/questions/search" {
+		questionHandler.SearchQuestions(ctx)
+		return
+	}
+	if method == "PATCH" && strings.HasPrefix(path, "/api/questions/") {
+		parts := strings.Split(path, "/")
+		if len(parts) == 4 {
+			if !requireAdmin(ctx) {
+				return
+			}
+			ctx.SetUserValue("id", parts[3])
+			questionHandler.PatchQuestion(ctx)
+			return
+		}
+	}
 	if method == "GET" && path == "/api/questions" {
 		serveQuestionsFromFile(ctx)
 		return
 	}
 	// Admin sessions
+	if path == "/api/admin/settings" && (method == "GET" || method == "PUT") {
+		if !requireAdmin(ctx) {
+			return
+		}
+		if method == "GET" {
+			gameControlHandler.GetSettings(ctx)
+		} else {
+			gameControlHandler.UpdateSettings(ctx)
+		}
+		return
+	}
+	if method == "GET" && path == "/api/admin/spectators" {
+		if !requireAdmin(ctx) {
+			return
+		}
+		sessionHandler.GetSpectators(ctx)
+		return
+	}
 	if method == "GET" && path == "/api/admin/sessions" {
 		sessions, err := sessionService.GetActiveSessions()
 		if err != nil {
 			ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
 			return
 		}
-		data, _ := json.Marshal(sessions)
-		ctx.SetContentType("application/json")
-		ctx.SetBody(data)
+		handlers.StreamJSON(ctx, fasthttp.StatusOK, sessions)
+		return
+	}
+	if method == "POST" && strings.HasPrefix(path, "/api/admin/sessions/") && strings.HasSuffix(path, "/recompute") {
+		parts := strings.Split(path, "/")
+		if len(parts) == 6 {
+			if !requireAdmin(ctx) {
+				return
+			}
+			ctx.SetUserValue("id", parts[4])
+			sessionHandler.RecomputeSession(ctx)
+			return
+		}
+	}
+	if method == "POST" && strings.HasPrefix(path, "/api/admin/archives/") && strings.HasSuffix(path, "/restore") {
+		parts := strings.Split(path, "/")
+		if len(parts) == 6 {
+			if !requireAdmin(ctx) {
+				return
+			}
+			ctx.SetUserValue("id", parts[4])
+			gameControlHandler.RestoreArchive(ctx)
+			return
+		}
+	}
+	if method == "GET" && strings.HasPrefix(path, "/api/admin/archives/") && strings.HasSuffix(path, "/review") {
+		parts := strings.Split(path, "/")
+		if len(parts) == 6 {
+			if !requireAdmin(ctx) {
+				return
+			}
+			ctx.SetUserValue("id", parts[4])
+			gameControlHandler.GetReviewRoom(ctx)
+			return
+		}
+	}
+	if method == "GET" && path == "/api/admin/diagnostics" {
+		if !requireAdmin(ctx) {
+			return
+		}
+		gameControlHandler.GetDiagnostics(ctx)
+		return
+	}
+	if method == "GET" && path == "/api/admin/audit" {
+		if !requireAdmin(ctx) {
+			return
+		}
+		gameControlHandler.GetAuditLog(ctx)
+		return
+	}
+	if method == "GET" && path == "/api/admin/rooms" {
+		if !requireAdmin(ctx) {
+			return
+		}
+		gameControlHandler.ListRooms(ctx)
+		return
+	}
+	if method == "POST" && path == "/api/admin/seed-demo" && cfg.DevMode {
+		if !requireAdmin(ctx) {
+			return
+		}
+		sessionHandler.SeedDemo(ctx)
+		return
+	}
+	if method == "POST" && strings.HasPrefix(path, "/api/admin/players/") && strings.HasSuffix(path, "/adjust-prize") {
+		parts := strings.Split(path, "/")
+		if len(parts) == 6 {
+			if !requireAdmin(ctx) {
+				return
+			}
+			ctx.SetUserValue("id", parts[4])
+			sessionHandler.AdjustPrize(ctx)
+			return
+		}
+	}
+	if method == "GET" && strings.HasPrefix(path, "/api/admin/players/") && strings.HasSuffix(path, "/shuffle") {
+		parts := strings.Split(path, "/")
+		if len(parts) == 6 {
+			if !requireAdmin(ctx) {
+				return
+			}
+			ctx.SetUserValue("id", parts[4])
+			sessionHandler.GetOptionOrder(ctx)
+			return
+		}
+	}
+	if method == "POST" && strings.HasPrefix(path, "/api/admin/players/") && strings.HasSuffix(path, "/message") {
+		parts := strings.Split(path, "/")
+		if len(parts) == 6 {
+			if !requireAdmin(ctx) {
+				return
+			}
+			ctx.SetUserValue("id", parts[4])
+			gameControlHandler.SendPlayerMessage(ctx)
+			return
+		}
+	}
+	if method == "POST" && strings.HasPrefix(path, "/api/admin/players/") && strings.HasSuffix(path, "/restart") {
+		parts := strings.Split(path, "/")
+		if len(parts) == 6 {
+			if !requireAdmin(ctx) {
+				return
+			}
+			ctx.SetUserValue("id", parts[4])
+			sessionHandler.RestartSession(ctx)
+			return
+		}
+	}
+	if method == "POST" && path == "/api/admin/answers/reverse" {
+		if !requireAdmin(ctx) {
+			return
+		}
+		sessionHandler.ReverseAnswer(ctx)
+		return
+	}
+	if method == "POST" && path == "/api/admin/players/preregister" {
+		if !requireAdmin(ctx) {
+			return
+		}
+		sessionHandler.PreregisterPlayers(ctx)
+		return
+	}
+	if method == "POST" && path == "/api/admin/players/status" {
+		if !requireAdmin(ctx) {
+			return
+		}
+		sessionHandler.LookupPlayers(ctx)
+		return
+	}
+	if method == "GET" && path == "/api/admin/answer-key-distribution" {
+		if !requireAdmin(ctx) {
+			return
+		}
+		questionHandler.GetAnswerKeyDistribution(ctx)
+		return
+	}
+	if method == "GET" && path == "/api/admin/questions/unused" {
+		if !requireAdmin(ctx) {
+			return
+		}
+		questionHandler.GetUnusedQuestions(ctx)
+		return
+	}
+	if method == "GET" && strings.HasPrefix(path, "/api/admin/questions/") && strings.HasSuffix(path, "/results") {
+		parts := strings.Split(path, "/")
+		if len(parts) == 6 {
+			if !requireAdmin(ctx) {
+				return
+			}
+			ctx.SetUserValue("id", parts[4])
+			questionHandler.GetQuestionResults(ctx)
+			return
+		}
+	}
+	if method == "GET" && strings.HasPrefix(path, "/api/admin/questions/") && strings.HasSuffix(path, "/answer-timeline") {
+		parts := strings.Split(path, "/")
+		if len(parts) == 6 {
+			if !requireAdmin(ctx) {
+				return
+			}
+			ctx.SetUserValue("id", parts[4])
+			questionHandler.GetAnswerTimeline(ctx)
+			return
+		}
+	}
+	if method == "POST" && path == "/api/admin/questions/reset-stats" {
+		if !requireAdmin(ctx) {
+			return
+		}
+		questionHandler.ResetQuestionStats(ctx)
+		return
+	}
+	if method == "POST" && path == "/api/admin/questions/calibrate" {
+		if !requireAdmin(ctx) {
+			return
+		}
+		questionHandler.CalibrateDifficulty(ctx)
 		return
 	}
 	// Health
-	if method == "GET" && path == "/api/health" {
+	if method == "GET" && (path == "/api/health" || path == "/api/livez") {
 		ctx.SetContentType("application/json")
 		ctx.SetBody([]byte(`{"status":"ok"}`))
 		return
 	}
+	if method == "GET" && path == "/api/readyz" {
+		questionHandler.Readiness(ctx)
+		return
+	}
 	// Fallback
 	ctx.Error("Not found", fasthttp.StatusNotFound)
 }
 
+// requireAdmin valida el token de administración (cabecera X-Admin-Token).
+// Si ADMIN_TOKEN no está configurado, los endpoints de administración quedan abiertos.
+func requireAdmin(ctx *fasthttp.RequestCtx) bool {
+	if validAdminToken(ctx.Request.Header.Peek("X-Admin-Token")) {
+		return true
+	}
+	data, _ := json.Marshal(models.APIResponse{Success: false, Error: "Token de administración inválido"})
+	ctx.SetStatusCode(fasthttp.StatusUnauthorized)
+	ctx.SetContentType("application/json")
+	ctx.SetBody(data)
+	return false
+}
+
+// adminWebSocket valida el token de administración de un WebSocket: el
+// navegador no puede enviar cabeceras al abrirlo, así que también se acepta ?adminToken=
+func adminWebSocket(ctx *fasthttp.RequestCtx) bool {
+	provided := ctx.Request.Header.Peek("X-Admin-Token")
+	if len(provided) == 0 {
+		provided = ctx.QueryArgs().Peek("adminToken")
+	}
+	return validAdminToken(provided)
+}
+
+// validAdminToken compara el token recibido con ADMIN_TOKEN (siempre válido si no está configurado)
+func validAdminToken(provided []byte) bool {
+	if cfg.AdminToken == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare(provided, []byte(cfg.AdminToken)) == 1
+}
+
 func serveFile(ctx *fasthttp.RequestCtx, filename, contentType string) {
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
 		ctx.Error("File not found", fasthttp.StatusNotFound)
@@ -198,7 +769,7 @@ func serveFile(ctx *fasthttp.RequestCtx, filename, contentType string) {
 }
 
 func serveQuestionsFromFile(ctx *fasthttp.RequestCtx) {
-	data, err := os.ReadFile("answers.json")
+	data, err := readQuestionsFiles(cfg.QuestionsFiles)
 	if err != nil {
 		ctx.Error("Error reading questions", fasthttp.StatusInternalServerError)
 		return
@@ -207,8 +778,16 @@ func serveQuestionsFromFile(ctx *fasthttp.RequestCtx) {
 	ctx.SetBody(data)
 }
 
-func loadQuestions(filename string) ([]models.Question, error) {
-	data, err := os.ReadFile(filename)
+// readQuestionsFiles lee el archivo de preguntas o, si hay varios, los combina
+func readQuestionsFiles(filenames []string) ([]byte, error) {
+	if len(filenames) == 1 {
+		return os.ReadFile(filenames[0])
+	}
+	return services.MergeQuestionFiles(filenames)
+}
+
+func loadQuestions(filenames []string) ([]models.Question, error) {
+	data, err := readQuestionsFiles(filenames)
 	if err != nil {
 		return nil, err
 	}
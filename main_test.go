@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/backsoul/quiz/pkg/config"
+	"github.com/backsoul/quiz/pkg/models"
+	hubpkg "github.com/backsoul/quiz/pkg/websocket"
+	"github.com/fasthttp/websocket"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// testClient cliente conectado a un servidor en memoria
+type testClient struct {
+	client *fasthttp.Client
+}
+
+// serveInMemory levanta el servidor en un listener en memoria
+func serveInMemory(t *testing.T, server *fasthttp.Server) *testClient {
+	t.Helper()
+	ln := fasthttputil.NewInmemoryListener()
+	go server.Serve(ln)
+	t.Cleanup(func() { ln.Close() })
+
+	return &testClient{client: &fasthttp.Client{
+		Dial: func(addr string) (net.Conn, error) {
+			return ln.Dial()
+		},
+	}}
+}
+
+// do envía la petición y devuelve la respuesta; prepare permite ajustar cabeceras o cuerpo
+func (c *testClient) do(t *testing.T, method, uri string, prepare func(req *fasthttp.Request)) *fasthttp.Response {
+	t.Helper()
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.Header.SetMethod(method)
+	req.SetRequestURI("http://quiz.test" + uri)
+	if prepare != nil {
+		prepare(req)
+	}
+
+	resp := &fasthttp.Response{}
+	if err := c.client.Do(req, resp); err != nil {
+		t.Fatalf("%s %s: %v", method, uri, err)
+	}
+	return resp
+}
+
+func TestWithRecoveryReturns500AndKeepsServing(t *testing.T) {
+	client := serveInMemory(t, &fasthttp.Server{Handler: withRecovery(func(ctx *fasthttp.RequestCtx) {
+		if string(ctx.Path()) == "/api/boom" {
+			id := ctx.UserValue("id").(string) // sin id: panic
+			ctx.SetBodyString(id)
+			return
+		}
+		ctx.SetBodyString("ok")
+	})})
+
+	resp := client.do(t, "GET", "/api/boom", nil)
+	if resp.StatusCode() != fasthttp.StatusInternalServerError {
+		t.Fatalf("esperaba 500, obtuve %d", resp.StatusCode())
+	}
+	var response models.APIResponse
+	if err := json.Unmarshal(resp.Body(), &response); err != nil {
+		t.Fatalf("la respuesta debe ser un APIResponse JSON: %s", resp.Body())
+	}
+	if response.Success || response.Error == "" {
+		t.Fatalf("respuesta de error inesperada: %+v", response)
+	}
+	if len(resp.Header.Peek("X-Correlation-ID")) == 0 {
+		t.Fatalf("falta el ID de correlación")
+	}
+
+	for i := 0; i < 3; i++ {
+		resp = client.do(t, "GET", "/api/ok", nil)
+		if resp.StatusCode() != fasthttp.StatusOK || string(resp.Body()) != "ok" {
+			t.Fatalf("el servidor debe seguir atendiendo: %d %s", resp.StatusCode(), resp.Body())
+		}
+	}
+}
+
+func TestWithCompressionGzipsAPIResponses(t *testing.T) {
+	payload := strings.Repeat(`{"playerName":"Ana","totalPrize":1000000},`, 200)
+	client := serveInMemory(t, &fasthttp.Server{Handler: withCompression(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("application/json")
+		ctx.SetBodyString(payload)
+	})})
+	acceptGzip := func(req *fasthttp.Request) {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+
+	resp := client.do(t, "GET", "/api/leaderboard", acceptGzip)
+	if string(resp.Header.Peek("Content-Encoding")) != "gzip" {
+		t.Fatalf("esperaba Content-Encoding gzip, obtuve %q", resp.Header.Peek("Content-Encoding"))
+	}
+	if len(resp.Body()) >= len(payload) {
+		t.Fatalf("el cuerpo no se comprimió: %d bytes de %d", len(resp.Body()), len(payload))
+	}
+	body, err := resp.BodyGunzip()
+	if err != nil {
+		t.Fatalf("el cuerpo no se puede descomprimir: %v", err)
+	}
+	if string(body) != payload {
+		t.Fatalf("el cuerpo descomprimido no coincide")
+	}
+
+	// Sin Accept-Encoding, o fuera de /api/, se responde sin comprimir
+	resp = client.do(t, "GET", "/api/leaderboard", nil)
+	if len(resp.Header.Peek("Content-Encoding")) != 0 || string(resp.Body()) != payload {
+		t.Fatalf("sin Accept-Encoding no se debe comprimir")
+	}
+	resp = client.do(t, "GET", "/admin.html", acceptGzip)
+	if len(resp.Header.Peek("Content-Encoding")) != 0 {
+		t.Fatalf("los archivos estáticos no se comprimen")
+	}
+}
+
+func TestNewServerRejectsOversizedBody(t *testing.T) {
+	previous := cfg
+	cfg = config.Default()
+	cfg.MaxRequestBodySize = 1024
+	t.Cleanup(func() { cfg = previous })
+
+	client := serveInMemory(t, newServer(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetBodyString("ok")
+	}))
+
+	resp := client.do(t, fasthttp.MethodPost, "/api/game/announce", func(req *fasthttp.Request) {
+		req.SetBodyString(strings.Repeat("x", 512))
+	})
+	if resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("un cuerpo dentro del límite debe aceptarse, status %d", resp.StatusCode())
+	}
+
+	resp = client.do(t, fasthttp.MethodPost, "/api/game/announce", func(req *fasthttp.Request) {
+		req.SetBodyString(strings.Repeat("x", 4096))
+	})
+	if resp.StatusCode() != fasthttp.StatusRequestEntityTooLarge {
+		t.Fatalf("esperaba 413 para un cuerpo excesivo, status %d", resp.StatusCode())
+	}
+}
+
+func TestSeedDemoRequiresDevMode(t *testing.T) {
+	previous := cfg
+	cfg = config.Default()
+	cfg.AdminToken = "secreto"
+	t.Cleanup(func() { cfg = previous })
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(fasthttp.MethodPost)
+	ctx.Request.SetRequestURI("/api/admin/seed-demo?players=5")
+	ctx.Request.Header.Set("X-Admin-Token", "secreto")
+	requestRouter(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusNotFound {
+		t.Fatalf("sin DEV_MODE la ruta no existe, status %d", ctx.Response.StatusCode())
+	}
+
+	cfg.DevMode = true
+	ctx = &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(fasthttp.MethodPost)
+	ctx.Request.SetRequestURI("/api/admin/seed-demo?players=5")
+	requestRouter(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Fatalf("con DEV_MODE sigue exigiendo el token de administración, status %d", ctx.Response.StatusCode())
+	}
+}
+
+func TestLivezAlwaysOK(t *testing.T) {
+	for _, path := range []string{"/api/livez", "/api/health"} {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.Header.SetMethod(fasthttp.MethodGet)
+		ctx.Request.SetRequestURI(path)
+		requestRouter(ctx)
+		if ctx.Response.StatusCode() != fasthttp.StatusOK || string(ctx.Response.Body()) != `{"status":"ok"}` {
+			t.Fatalf("%s: esperaba 200 sin depender de Redis, obtuve %d %s", path, ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+	}
+}
+
+func TestAdminRoutesRequireToken(t *testing.T) {
+	previous := cfg
+	cfg = config.Default()
+	cfg.AdminToken = "secreto"
+	t.Cleanup(func() { cfg = previous })
+
+	routes := []struct{ method, path string }{
+		{fasthttp.MethodPost, "/api/game/peek-answer"},
+		{fasthttp.MethodPost, "/api/game/announce"},
+		{fasthttp.MethodGet, "/api/admin/audit"},
+	}
+	for _, route := range routes {
+		for _, token := range []string{"", "otro", "secreto2"} {
+			ctx := &fasthttp.RequestCtx{}
+			ctx.Request.Header.SetMethod(route.method)
+			ctx.Request.SetRequestURI(route.path)
+			if token != "" {
+				ctx.Request.Header.Set("X-Admin-Token", token)
+			}
+			requestRouter(ctx)
+			if ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+				t.Fatalf("%s con token %q: esperaba 401, obtuve %d", route.path, token, ctx.Response.StatusCode())
+			}
+		}
+	}
+	if !validAdminToken([]byte("secreto")) || validAdminToken(nil) {
+		t.Fatalf("validAdminToken debe aceptar solo el token configurado")
+	}
+}
+
+// dialHub reemplaza el hub global por uno nuevo y devuelve un cliente ya registrado en él
+func dialHub(t *testing.T) *websocket.Conn {
+	t.Helper()
+	previous := hub
+	hub = hubpkg.NewHub()
+	go hub.Run()
+	t.Cleanup(func() { hub = previous })
+
+	current := hub
+	upgrader := websocket.FastHTTPUpgrader{}
+	ln := fasthttputil.NewInmemoryListener()
+	server := &fasthttp.Server{Handler: func(ctx *fasthttp.RequestCtx) {
+		upgrader.Upgrade(ctx, func(conn *websocket.Conn) {
+			current.ServeConn(conn, func(conn *websocket.Conn, data []byte) {
+				current.SendTo(conn, "pong", nil)
+			})
+		})
+	}}
+	go server.Serve(ln)
+	t.Cleanup(func() { ln.Close() })
+
+	dialer := websocket.Dialer{NetDial: func(network, addr string) (net.Conn, error) { return ln.Dial() }}
+	conn, _, err := dialer.Dial("ws://quiz.test/ws", nil)
+	if err != nil {
+		t.Fatalf("error conectando WebSocket: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	// La respuesta llega desde ServeConn, cuando la conexión ya está registrada
+	if err := conn.WriteJSON(map[string]string{"type": "ping"}); err != nil {
+		t.Fatalf("error enviando ping: %v", err)
+	}
+	readHubMessage(t, conn, "pong")
+	return conn
+}
+
+// readHubMessage lee mensajes hasta encontrar uno del tipo indicado y devuelve sus datos
+func readHubMessage(t *testing.T, conn *websocket.Conn, msgType string) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var message struct {
+			Type string                 `json:"type"`
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("esperando %s: %v", msgType, err)
+		}
+		if message.Type == msgType {
+			return message.Data
+		}
+	}
+}
+
+func TestBroadcastFailuresEmitWarnings(t *testing.T) {
+	conn := dialHub(t)
+	var failures broadcastFailures
+	errRedis := errors.New("dial tcp: connection refused")
+
+	// Se avisa en los fallos 1, 2, 4 y 8; desde broadcastEscalation es persistente
+	for i := 0; i < 8; i++ {
+		failures.fail(errRedis)
+	}
+	failures.succeed()
+	hub.BroadcastMessage("marker", nil)
+
+	var warnings []map[string]interface{}
+	for {
+		var message struct {
+			Type string                 `json:"type"`
+			Data map[string]interface{} `json:"data"`
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("leyendo avisos: %v", err)
+		}
+		if message.Type == "connectivityRestored" {
+			if message.Data["consecutiveFailures"] != float64(8) {
+				t.Fatalf("connectivityRestored inesperado: %v", message.Data)
+			}
+			continue
+		}
+		if message.Type == "marker" {
+			break
+		}
+		if message.Type == "connectivityWarning" {
+			warnings = append(warnings, message.Data)
+		}
+	}
+
+	want := []struct {
+		failures   float64
+		persistent bool
+	}{{1, false}, {2, false}, {4, false}, {8, true}}
+	if len(warnings) != len(want) {
+		t.Fatalf("esperaba %d avisos, hubo %d: %v", len(want), len(warnings), warnings)
+	}
+	for i, w := range want {
+		if warnings[i]["consecutiveFailures"] != w.failures || warnings[i]["persistent"] != w.persistent {
+			t.Fatalf("aviso %d inesperado: %v", i, warnings[i])
+		}
+	}
+
+	// Recuperado, un nuevo fallo vuelve a contar desde 1
+	failures.fail(errRedis)
+	if warning := readHubMessage(t, conn, "connectivityWarning"); warning["consecutiveFailures"] != float64(1) {
+		t.Fatalf("tras recuperarse el conteo reinicia: %v", warning)
+	}
+}
@@ -0,0 +1,301 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/backsoul/quiz/pkg/models"
+)
+
+// Config configuración de la aplicación, cargada una vez al iniciar
+type Config struct {
+	// Redis
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+	RedisPrefix   string
+	RedisFallback bool
+
+	// Servidor
+	Port           string
+	QuestionsFile  string
+	QuestionsFiles []string
+	AdminToken     string
+	DevMode        bool
+
+	// Límites del servidor HTTP
+	ReadTimeout        time.Duration
+	WriteTimeout       time.Duration
+	IdleTimeout        time.Duration
+	MaxRequestBodySize int
+	Concurrency        int
+
+	// Sesiones
+	MaxPlayers           int
+	AutoContinueSessions bool
+	SessionTTL           time.Duration
+	PlayerLives          int
+	RejoinWindow         time.Duration
+
+	// Juego
+	MaxQuestions             int
+	CurrencySymbol           string
+	MaxPrize                 int64
+	QuestionTimeLimit        time.Duration
+	QuestionTimeByDifficulty map[int]time.Duration
+	GameStateCacheTTL        time.Duration
+	AnswerMatching           string
+	MinDifficulty            int
+	MaxDifficulty            int
+	DifficultyAction         string // dificultades fuera de rango al cargar: clamp o reject
+	StatsRetention           time.Duration
+	LeaderboardDebounce      time.Duration // espera tras un cambio antes de recalcular la tabla (0 = sin caché)
+	StrictFinalAnswer        bool
+	TwoPhaseQuestions        bool
+	ShowExplanation          bool
+	MinAnswerTime            time.Duration
+	MinAnswerAction          string
+	AutoEndAction            string // al quedar sin jugadores activos: off, round o end
+
+	// Difusión WebSocket
+	BroadcastInterval time.Duration
+	AnswerBatchWindow time.Duration
+	WSMaxMessageSize  int
+	WSRateLimit       int // mensajes entrantes por segundo y conexión
+	WSRateBurst       int
+	TimerTickInterval time.Duration
+}
+
+// Default devuelve la configuración por defecto
+func Default() *Config {
+	return &Config{
+		RedisAddr:            "localhost:6379",
+		RedisDB:              0,
+		RedisPrefix:          "quiz:",
+		Port:                 "8080",
+		QuestionsFile:        "answers.json",
+		ReadTimeout:          10 * time.Second,
+		WriteTimeout:         10 * time.Second,
+		IdleTimeout:          60 * time.Second,
+		MaxRequestBodySize:   1 << 20,
+		Concurrency:          10000,
+		MaxPlayers:           0,
+		AutoContinueSessions: true,
+		SessionTTL:           24 * time.Hour,
+		PlayerLives:          1,
+		MaxQuestions:         8,
+		CurrencySymbol:       "$",
+		MaxPrize:             models.MaxSafePrize,
+		QuestionTimeLimit:    30 * time.Second,
+		GameStateCacheTTL:    500 * time.Millisecond,
+		AnswerMatching:       "exact",
+		MinDifficulty:        1,
+		MaxDifficulty:        5,
+		DifficultyAction:     "clamp",
+		StatsRetention:       7 * 24 * time.Hour,
+		MinAnswerAction:      "flag",
+		AutoEndAction:        "off",
+		BroadcastInterval:    5 * time.Second,
+		AnswerBatchWindow:    0,
+		LeaderboardDebounce:  500 * time.Millisecond,
+		WSMaxMessageSize:     4096,
+		WSRateLimit:          20,
+		WSRateBurst:          40,
+		TimerTickInterval:    time.Second,
+	}
+}
+
+// Load carga la configuración desde las variables de entorno
+func Load() *Config {
+	return LoadFrom(os.Getenv)
+}
+
+// LoadFrom carga la configuración usando getenv; los valores inválidos se
+// reemplazan por el valor por defecto y se registra una advertencia
+func LoadFrom(getenv func(string) string) *Config {
+	cfg := Default()
+	l := loader{getenv: getenv}
+
+	cfg.RedisAddr = l.str("REDIS_ADDR", cfg.RedisAddr)
+	cfg.RedisPassword = l.str("REDIS_PASSWORD", cfg.RedisPassword)
+	cfg.RedisDB = l.int("REDIS_DB", cfg.RedisDB, 0)
+	cfg.RedisPrefix = l.str("REDIS_PREFIX", cfg.RedisPrefix)
+	cfg.RedisFallback = l.bool("REDIS_FALLBACK", cfg.RedisFallback)
+
+	cfg.Port = l.str("PORT", cfg.Port)
+	cfg.QuestionsFile = l.str("QUESTIONS_FILE", cfg.QuestionsFile)
+	cfg.QuestionsFiles = []string{cfg.QuestionsFile}
+	if files := splitList(getenv("QUESTIONS_FILES")); len(files) > 0 {
+		cfg.QuestionsFiles = files
+	}
+	cfg.AdminToken = l.str("ADMIN_TOKEN", cfg.AdminToken)
+	cfg.DevMode = l.bool("DEV_MODE", cfg.DevMode)
+	cfg.ReadTimeout = l.seconds("SERVER_READ_TIMEOUT", cfg.ReadTimeout, 1)
+	cfg.WriteTimeout = l.seconds("SERVER_WRITE_TIMEOUT", cfg.WriteTimeout, 1)
+	cfg.IdleTimeout = l.seconds("SERVER_IDLE_TIMEOUT", cfg.IdleTimeout, 1)
+	cfg.MaxRequestBodySize = l.int("MAX_REQUEST_BODY_BYTES", cfg.MaxRequestBodySize, 1024)
+	cfg.Concurrency = l.int("SERVER_CONCURRENCY", cfg.Concurrency, 1)
+
+	cfg.MaxPlayers = l.int("MAX_PLAYERS", cfg.MaxPlayers, 0)
+	cfg.AutoContinueSessions = l.bool("AUTO_CONTINUE_SESSIONS", cfg.AutoContinueSessions)
+	cfg.SessionTTL = l.hours("SESSION_TTL_HOURS", cfg.SessionTTL)
+	cfg.PlayerLives = l.int("PLAYER_LIVES", cfg.PlayerLives, 1)
+	cfg.RejoinWindow = l.seconds("REJOIN_WINDOW", cfg.RejoinWindow, 0)
+
+	cfg.MaxQuestions = l.int("MAX_QUESTIONS", cfg.MaxQuestions, 1)
+	cfg.CurrencySymbol = l.str("CURRENCY_SYMBOL", cfg.CurrencySymbol)
+	cfg.MaxPrize = l.int64("MAX_PRIZE", cfg.MaxPrize, 1, models.MaxSafePrize)
+	cfg.QuestionTimeLimit = l.seconds("QUESTION_TIME_LIMIT", cfg.QuestionTimeLimit, 1)
+	if spec := getenv("QUESTION_TIME_BY_DIFFICULTY"); spec != "" {
+		durations, err := ParseDifficultyDurations(spec)
+		if err != nil {
+			log.Printf("⚠️ QUESTION_TIME_BY_DIFFICULTY inválido: %v", err)
+		} else {
+			cfg.QuestionTimeByDifficulty = durations
+		}
+	}
+
+	cfg.GameStateCacheTTL = l.millis("GAME_STATE_CACHE_MS", cfg.GameStateCacheTTL)
+	cfg.StatsRetention = time.Duration(l.int("QUESTION_STATS_RETENTION_HOURS", int(cfg.StatsRetention/time.Hour), 0)) * time.Hour
+	cfg.AnswerMatching = l.oneOf("ANSWER_MATCHING", cfg.AnswerMatching, "exact", "nfc", "fold")
+	cfg.MinDifficulty = l.int("MIN_DIFFICULTY", cfg.MinDifficulty, 0)
+	cfg.MaxDifficulty = l.int("MAX_DIFFICULTY", cfg.MaxDifficulty, cfg.MinDifficulty)
+	cfg.DifficultyAction = l.oneOf("OUT_OF_RANGE_DIFFICULTY", cfg.DifficultyAction, "clamp", "reject")
+	cfg.StrictFinalAnswer = l.bool("STRICT_FINAL_ANSWER", cfg.StrictFinalAnswer)
+	cfg.TwoPhaseQuestions = l.bool("TWO_PHASE_QUESTIONS", cfg.TwoPhaseQuestions)
+	cfg.ShowExplanation = l.bool("SHOW_EXPLANATION_ON_ANSWER", cfg.ShowExplanation)
+	cfg.MinAnswerTime = l.millis("MIN_ANSWER_MS", cfg.MinAnswerTime)
+	cfg.MinAnswerAction = l.oneOf("MIN_ANSWER_ACTION", cfg.MinAnswerAction, "flag", "reject")
+	cfg.AutoEndAction = l.oneOf("AUTO_END_ACTION", cfg.AutoEndAction, "off", "round", "end")
+
+	cfg.BroadcastInterval = l.seconds("BROADCAST_INTERVAL", cfg.BroadcastInterval, 1)
+	cfg.AnswerBatchWindow = l.millis("ANSWER_BATCH_WINDOW_MS", cfg.AnswerBatchWindow)
+	cfg.LeaderboardDebounce = l.millis("LEADERBOARD_DEBOUNCE_MS", cfg.LeaderboardDebounce)
+	cfg.WSMaxMessageSize = l.int("WS_MAX_MESSAGE_BYTES", cfg.WSMaxMessageSize, 1)
+	cfg.WSRateLimit = l.int("WS_RATE_LIMIT", cfg.WSRateLimit, 0)
+	cfg.WSRateBurst = l.int("WS_RATE_BURST", cfg.WSRateBurst, 1)
+	cfg.TimerTickInterval = l.seconds("TIMER_TICK_SECONDS", cfg.TimerTickInterval, 0)
+
+	return cfg
+}
+
+// ParseDifficultyDurations interpreta un mapeo "dificultad:segundos" separado
+// por comas, p. ej. "1:15,2:20,3:30,4:40,5:45"
+func ParseDifficultyDurations(spec string) (map[int]time.Duration, error) {
+	durations := make(map[int]time.Duration)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("entrada inválida %q, se espera dificultad:segundos", pair)
+		}
+		difficulty, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, fmt.Errorf("dificultad inválida en %q", pair)
+		}
+		seconds, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("segundos inválidos en %q", pair)
+		}
+		durations[difficulty] = time.Duration(seconds) * time.Second
+	}
+	return durations, nil
+}
+
+// splitList separa una lista por comas, ignorando entradas vacías
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// loader lee variables de entorno tipadas con valor por defecto
+type loader struct {
+	getenv func(string) string
+}
+
+func (l loader) str(key, def string) string {
+	if value := l.getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+func (l loader) int(key string, def, min int) int {
+	value := l.getenv(key)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min {
+		log.Printf("⚠️ %s inválido (%q), se usa %d", key, value, def)
+		return def
+	}
+	return n
+}
+
+func (l loader) int64(key string, def, min, max int64) int64 {
+	value := l.getenv(key)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < min || n > max {
+		log.Printf("⚠️ %s inválido (%q), se usa %d", key, value, def)
+		return def
+	}
+	return n
+}
+
+func (l loader) oneOf(key, def string, allowed ...string) string {
+	value := strings.ToLower(strings.TrimSpace(l.getenv(key)))
+	if value == "" {
+		return def
+	}
+	for _, option := range allowed {
+		if value == option {
+			return value
+		}
+	}
+	log.Printf("⚠️ %s inválido (%q), se usa %s", key, value, def)
+	return def
+}
+
+func (l loader) bool(key string, def bool) bool {
+	value := l.getenv(key)
+	if value == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("⚠️ %s inválido (%q), se usa %t", key, value, def)
+		return def
+	}
+	return b
+}
+
+func (l loader) seconds(key string, def time.Duration, min int) time.Duration {
+	n := l.int(key, int(def/time.Second), min)
+	return time.Duration(n) * time.Second
+}
+
+func (l loader) millis(key string, def time.Duration) time.Duration {
+	n := l.int(key, int(def/time.Millisecond), 0)
+	return time.Duration(n) * time.Millisecond
+}
+
+func (l loader) hours(key string, def time.Duration) time.Duration {
+	n := l.int(key, int(def/time.Hour), 1)
+	return time.Duration(n) * time.Hour
+}
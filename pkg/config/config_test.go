@@ -0,0 +1,131 @@
+package config
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/backsoul/quiz/pkg/models"
+)
+
+// envFrom devuelve un getenv que lee del mapa
+func envFrom(env map[string]string) func(string) string {
+	return func(key string) string { return env[key] }
+}
+
+// captureLog devuelve lo que se registre en el log mientras corre fn
+func captureLog(t *testing.T, fn func()) string {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	fn()
+	return buf.String()
+}
+
+func TestLoadFromDefaults(t *testing.T) {
+	var cfg *Config
+	output := captureLog(t, func() { cfg = LoadFrom(envFrom(nil)) })
+
+	if !reflect.DeepEqual(cfg.QuestionsFiles, []string{"answers.json"}) {
+		t.Fatalf("archivos por defecto inesperados: %v", cfg.QuestionsFiles)
+	}
+	cfg.QuestionsFiles = nil
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Fatalf("sin variables debe usarse la configuración por defecto:\n%+v", cfg)
+	}
+	if output != "" {
+		t.Fatalf("sin variables no debe haber advertencias: %s", output)
+	}
+}
+
+func TestLoadFromParsesValues(t *testing.T) {
+	cfg := LoadFrom(envFrom(map[string]string{
+		"REDIS_ADDR":                  "redis:6380",
+		"REDIS_PREFIX":                "staging:",
+		"PORT":                        "9090",
+		"QUESTIONS_FILES":             "general.json, , tematica.json",
+		"MAX_QUESTIONS":               "15",
+		"MAX_PLAYERS":                 "50",
+		"AUTO_CONTINUE_SESSIONS":      "false",
+		"SESSION_TTL_HOURS":           "2",
+		"QUESTION_TIME_LIMIT":         "45",
+		"QUESTION_TIME_BY_DIFFICULTY": "1:15, 5:60",
+		"GAME_STATE_CACHE_MS":         "250",
+		"ANSWER_MATCHING":             "FOLD",
+		"MAX_PRIZE":                   "1000000",
+		"AUTO_END_ACTION":             "end",
+	}))
+
+	if cfg.RedisAddr != "redis:6380" || cfg.RedisPrefix != "staging:" || cfg.Port != "9090" {
+		t.Fatalf("Redis o puerto inesperados: %s %s %s", cfg.RedisAddr, cfg.RedisPrefix, cfg.Port)
+	}
+	if !reflect.DeepEqual(cfg.QuestionsFiles, []string{"general.json", "tematica.json"}) {
+		t.Fatalf("archivos inesperados: %v", cfg.QuestionsFiles)
+	}
+	if cfg.MaxQuestions != 15 || cfg.MaxPlayers != 50 || cfg.AutoContinueSessions {
+		t.Fatalf("valores de juego inesperados: %+v", cfg)
+	}
+	if cfg.SessionTTL != 2*time.Hour || cfg.QuestionTimeLimit != 45*time.Second || cfg.GameStateCacheTTL != 250*time.Millisecond {
+		t.Fatalf("duraciones inesperadas: %v %v %v", cfg.SessionTTL, cfg.QuestionTimeLimit, cfg.GameStateCacheTTL)
+	}
+	want := map[int]time.Duration{1: 15 * time.Second, 5: 60 * time.Second}
+	if !reflect.DeepEqual(cfg.QuestionTimeByDifficulty, want) {
+		t.Fatalf("tiempos por dificultad inesperados: %v", cfg.QuestionTimeByDifficulty)
+	}
+	if cfg.AnswerMatching != "fold" || cfg.MaxPrize != 1000000 || cfg.AutoEndAction != "end" {
+		t.Fatalf("opciones inesperadas: %s %d %s", cfg.AnswerMatching, cfg.MaxPrize, cfg.AutoEndAction)
+	}
+}
+
+func TestLoadFromInvalidValuesFallBackWithWarnings(t *testing.T) {
+	env := map[string]string{
+		"MAX_QUESTIONS":               "muchas",
+		"PLAYER_LIVES":                "0",
+		"REDIS_FALLBACK":              "quizás",
+		"ANSWER_MATCHING":             "aproximado",
+		"MAX_PRIZE":                   "9007199254740993", // mayor que MaxSafePrize
+		"QUESTION_TIME_BY_DIFFICULTY": "1:15,2",
+		"BROADCAST_INTERVAL":          "-5",
+	}
+
+	var cfg *Config
+	output := captureLog(t, func() { cfg = LoadFrom(envFrom(env)) })
+	def := Default()
+
+	if cfg.MaxQuestions != def.MaxQuestions || cfg.PlayerLives != def.PlayerLives || cfg.RedisFallback != def.RedisFallback {
+		t.Fatalf("los valores inválidos deben volver al defecto: %d %d %t", cfg.MaxQuestions, cfg.PlayerLives, cfg.RedisFallback)
+	}
+	if cfg.AnswerMatching != def.AnswerMatching || cfg.MaxPrize != models.MaxSafePrize || cfg.BroadcastInterval != def.BroadcastInterval {
+		t.Fatalf("los valores inválidos deben volver al defecto: %s %d %v", cfg.AnswerMatching, cfg.MaxPrize, cfg.BroadcastInterval)
+	}
+	if cfg.QuestionTimeByDifficulty != nil {
+		t.Fatalf("un mapeo inválido se ignora completo: %v", cfg.QuestionTimeByDifficulty)
+	}
+
+	for key := range env {
+		if !strings.Contains(output, key) {
+			t.Errorf("falta la advertencia de %s en:\n%s", key, output)
+		}
+	}
+}
+
+func TestParseDifficultyDurations(t *testing.T) {
+	durations, err := ParseDifficultyDurations("1:15,2:20,,3:30")
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if len(durations) != 3 || durations[2] != 20*time.Second {
+		t.Fatalf("mapeo inesperado: %v", durations)
+	}
+
+	for _, spec := range []string{"1", "x:10", "1:0", "1:-5", "1:diez"} {
+		if _, err := ParseDifficultyDurations(spec); err == nil {
+			t.Errorf("%q debería ser inválido", spec)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"log"
+	"strings"
+
+	"github.com/backsoul/quiz/pkg/services"
+	"github.com/valyala/fasthttp"
+)
+
+// adminIdentity nombre del administrador que realiza la acción, tomado de la
+// cabecera X-Admin-Name ("admin" si no se envía)
+func adminIdentity(ctx *fasthttp.RequestCtx) string {
+	if name := strings.TrimSpace(string(ctx.Request.Header.Peek("X-Admin-Name"))); name != "" {
+		return name
+	}
+	return "admin"
+}
+
+// recordAudit registra una acción de administración; un fallo al auditar se
+// registra en el log pero no interrumpe la acción
+func recordAudit(auditService *services.AuditService, ctx *fasthttp.RequestCtx, action string, params map[string]interface{}) {
+	if auditService == nil {
+		return
+	}
+	if err := auditService.Record(action, adminIdentity(ctx), params); err != nil {
+		log.Printf("⚠️ Error registrando auditoría de %s: %v", action, err)
+	}
+}
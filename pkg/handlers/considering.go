@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+)
+
+// consideringDebounce espera antes de difundir el resumen de opciones
+// consideradas, para agrupar los cambios de varios jugadores en un mensaje
+const consideringDebounce = 500 * time.Millisecond
+
+// consideringTracker lleva qué opción está considerando cada jugador en la
+// pregunta en curso y publica solo el conteo por opción, sin nombres
+type consideringTracker struct {
+	mutex          sync.Mutex
+	questionNumber int
+	leanings       map[string]string // sesión -> opción
+	timer          *time.Timer
+	debounce       time.Duration
+	publish        func(questionNumber int, counts map[string]int, total int)
+}
+
+func newConsideringTracker(debounce time.Duration, publish func(questionNumber int, counts map[string]int, total int)) *consideringTracker {
+	return &consideringTracker{
+		leanings: make(map[string]string),
+		debounce: debounce,
+		publish:  publish,
+	}
+}
+
+// set registra la opción que considera la sesión (vacía = ninguna). Al pasar a
+// otra pregunta se descartan las de la anterior. El resumen se publica una vez
+// cerrada la ventana de debounce.
+func (t *consideringTracker) set(questionNumber int, sessionID, option string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if questionNumber != t.questionNumber {
+		t.questionNumber = questionNumber
+		t.leanings = make(map[string]string)
+	}
+	if option == "" {
+		delete(t.leanings, sessionID)
+	} else {
+		t.leanings[sessionID] = option
+	}
+
+	if t.timer == nil {
+		t.timer = time.AfterFunc(t.debounce, t.flush)
+	}
+}
+
+// flush publica el conteo actual por opción
+func (t *consideringTracker) flush() {
+	t.mutex.Lock()
+	t.timer = nil
+	questionNumber := t.questionNumber
+	counts := make(map[string]int)
+	for _, option := range t.leanings {
+		counts[option]++
+	}
+	total := len(t.leanings)
+	t.mutex.Unlock()
+
+	t.publish(questionNumber, counts, total)
+}
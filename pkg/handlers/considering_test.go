@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// consideringSummary resumen publicado por el tracker
+type consideringSummary struct {
+	questionNumber int
+	counts         map[string]int
+	total          int
+}
+
+// newRecordingTracker crea un tracker que entrega cada resumen publicado por un canal
+func newRecordingTracker(debounce time.Duration) (*consideringTracker, <-chan consideringSummary) {
+	published := make(chan consideringSummary, 10)
+	tracker := newConsideringTracker(debounce, func(questionNumber int, counts map[string]int, total int) {
+		published <- consideringSummary{questionNumber, counts, total}
+	})
+	return tracker, published
+}
+
+// nextSummary espera el siguiente resumen publicado
+func nextSummary(t *testing.T, published <-chan consideringSummary) consideringSummary {
+	t.Helper()
+	select {
+	case summary := <-published:
+		return summary
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no se publicó ningún resumen")
+	}
+	return consideringSummary{}
+}
+
+func TestConsideringTrackerDebouncesAggregate(t *testing.T) {
+	const debounce = 100 * time.Millisecond
+	tracker, published := newRecordingTracker(debounce)
+
+	// Una ráfaga dentro de la ventana se publica como un solo resumen
+	start := time.Now()
+	tracker.set(1, "ana", "B")
+	tracker.set(1, "luis", "B")
+	tracker.set(1, "pedro", "C")
+	tracker.set(1, "ana", "C") // cambia de opinión
+	tracker.set(1, "marta", "D")
+	tracker.set(1, "marta", "") // deja de considerar
+
+	summary := nextSummary(t, published)
+	if elapsed := time.Since(start); elapsed < debounce {
+		t.Fatalf("el resumen se publicó antes de la ventana: %v", elapsed)
+	}
+	if summary.questionNumber != 1 || summary.total != 3 || fmt.Sprint(summary.counts) != "map[B:1 C:2]" {
+		t.Fatalf("resumen inesperado: %+v", summary)
+	}
+	select {
+	case extra := <-published:
+		t.Fatalf("la ráfaga debe publicarse una sola vez, llegó otro resumen: %+v", extra)
+	case <-time.After(2 * debounce):
+	}
+
+	// Al pasar a otra pregunta se descartan las opciones de la anterior
+	tracker.set(2, "luis", "A")
+	summary = nextSummary(t, published)
+	if summary.questionNumber != 2 || summary.total != 1 || fmt.Sprint(summary.counts) != "map[A:1]" {
+		t.Fatalf("resumen de la pregunta 2 inesperado: %+v", summary)
+	}
+}
@@ -2,7 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/backsoul/quiz/pkg/models"
@@ -15,15 +20,69 @@ import (
 type GameControlHandler struct {
 	gameStateService *services.GameStateService
 	sessionService   *services.SessionService
+	archiveService   *services.ArchiveService
+	questionService  *services.QuestionService
+	auditService     *services.AuditService
+	settingsService  *services.SettingsService
+	diagnostics      *services.DiagnosticsService
 	hub              *websocketHub.Hub
+	autoEndAction    string // "off", "round" o "end" al quedar sin jugadores activos
+	adminCheck       func(ctx *fasthttp.RequestCtx) bool
+
+	connMutex      sync.Mutex
+	connStateMutex sync.Mutex                          // ordena los avisos de conexión/desconexión
+	connections    map[string]map[*websocket.Conn]bool // WebSockets abiertos por sesión
+	admins         map[*websocket.Conn]bool            // WebSockets del panel de administración
+	considering    *consideringTracker
 }
 
 func NewGameControlHandler(gameStateService *services.GameStateService, sessionService *services.SessionService, hub *websocketHub.Hub) *GameControlHandler {
-	return &GameControlHandler{
+	gc := &GameControlHandler{
 		gameStateService: gameStateService,
 		sessionService:   sessionService,
 		hub:              hub,
+		connections:      make(map[string]map[*websocket.Conn]bool),
+		admins:           make(map[*websocket.Conn]bool),
 	}
+	gc.considering = newConsideringTracker(consideringDebounce, gc.broadcastConsidering)
+	return gc
+}
+
+// SetArchiveService habilita el archivado de la partida al terminarla
+func (gc *GameControlHandler) SetArchiveService(archiveService *services.ArchiveService) {
+	gc.archiveService = archiveService
+}
+
+// SetQuestionService permite resolver la pregunta en curso (p. ej. su dificultad para el temporizador)
+func (gc *GameControlHandler) SetQuestionService(questionService *services.QuestionService) {
+	gc.questionService = questionService
+}
+
+// SetAuditService habilita el registro de auditoría de las acciones de administración
+func (gc *GameControlHandler) SetAuditService(auditService *services.AuditService) {
+	gc.auditService = auditService
+}
+
+// SetSettingsService habilita la configuración en caliente desde el panel
+func (gc *GameControlHandler) SetSettingsService(settingsService *services.SettingsService) {
+	gc.settingsService = settingsService
+}
+
+// SetDiagnosticsService habilita el diagnóstico de claves de Redis
+func (gc *GameControlHandler) SetDiagnosticsService(diagnostics *services.DiagnosticsService) {
+	gc.diagnostics = diagnostics
+}
+
+// SetAutoEndAction configura qué hacer cuando todos los jugadores quedan
+// eliminados o terminan: "round" solo avisa, "end" además termina la partida
+func (gc *GameControlHandler) SetAutoEndAction(action string) {
+	gc.autoEndAction = action
+}
+
+// SetAdminCheck configura cómo se valida el token de administración de un
+// WebSocket que pide ?role=admin; sin él no se aceptan conexiones de administración
+func (gc *GameControlHandler) SetAdminCheck(check func(ctx *fasthttp.RequestCtx) bool) {
+	gc.adminCheck = check
 }
 
 var upgrader = websocket.FastHTTPUpgrader{
@@ -32,39 +91,369 @@ var upgrader = websocket.FastHTTPUpgrader{
 	},
 }
 
-// HandleWebSocket maneja las conexiones WebSocket
+// HandleWebSocket maneja las conexiones WebSocket. Un jugador puede asociar la
+// conexión a su sesión con ?sessionId=...&token=... para que el panel sepa si
+// sigue conectado. El panel se conecta con ?role=admin (y el token de
+// administración) para recibir los eventos solo para administradores.
 func (gc *GameControlHandler) HandleWebSocket(ctx *fasthttp.RequestCtx) {
+	sessionID := string(ctx.QueryArgs().Peek("sessionId"))
+	if sessionID != "" {
+		token := string(ctx.QueryArgs().Peek("token"))
+		if err := gc.sessionService.VerifySessionToken(sessionID, token); err != nil {
+			log.Printf("⚠️ WebSocket sin asociar a la sesión %s: %v", sessionID, err)
+			sessionID = ""
+		}
+	}
+
+	admin := string(ctx.QueryArgs().Peek("role")) == "admin"
+	if admin && (sessionID != "" || gc.adminCheck == nil || !gc.adminCheck(ctx)) {
+		gc.respondWithError(ctx, fasthttp.StatusUnauthorized, "Token de administración inválido")
+		return
+	}
+
 	err := upgrader.Upgrade(ctx, func(ws *websocket.Conn) {
 		defer ws.Close()
 
-		gc.hub.Register(ws)
-		defer gc.hub.Unregister(ws)
+		// La bienvenida se escribe sin el lock del hub, así que va antes de
+		// registrar el socket de la sesión o del panel: después, sendToSession
+		// o sendToAdmins podrían escribir en él a la vez
+		gc.sendWelcome(ws)
+		if sessionID != "" {
+			gc.trackConnection(sessionID, ws, true)
+			defer gc.trackConnection(sessionID, ws, false)
+		}
+		if admin {
+			gc.trackAdmin(ws, true)
+			defer gc.trackAdmin(ws, false)
+		}
 
-		// Enviar estado actual del juego al conectarse
-		gameState, err := gc.gameStateService.GetGameState()
-		if err == nil {
-			message := websocketHub.Message{
-				Type: "gameState",
-				Data: gameState,
-			}
-			data, _ := json.Marshal(message)
-			ws.WriteMessage(websocket.TextMessage, data)
+		// Escuchar mensajes del cliente hasta que se desconecte
+		gc.hub.ServeConn(ws, func(conn *websocket.Conn, data []byte) {
+			gc.handleCommand(conn, data, sessionID)
+		})
+	})
+
+	if err != nil {
+		log.Printf("Error upgrading to WebSocket: %v", err)
+		ctx.Error("Error upgrading to WebSocket", fasthttp.StatusInternalServerError)
+	}
+}
+
+// sendWelcome envía al socket recién abierto la sala, la hora del servidor y
+// el estado actual del juego
+func (gc *GameControlHandler) sendWelcome(ws *websocket.Conn) {
+	gameState, err := gc.gameStateService.GetGameState()
+	if err != nil {
+		log.Printf("⚠️ Error obteniendo estado del juego para bienvenida: %v", err)
+	}
+	now := time.Now()
+	message := websocketHub.Message{
+		Type: "welcome",
+		Data: map[string]interface{}{
+			"room":            services.DefaultRoom,
+			"serverTime":      models.FormatTime(now),
+			"serverTimeMs":    now.UnixMilli(),
+			"protocolVersion": websocketHub.ProtocolVersion,
+			"gameState":       gameState,
+		},
+	}
+	data, _ := json.Marshal(message)
+	ws.WriteMessage(websocket.TextMessage, data)
+}
+
+// clientCommand comando recibido de un cliente WebSocket
+type clientCommand struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// resyncCommand datos del comando con el que un cliente que se reconecta pide
+// todo el estado de una vez: {"type":"resync","data":{"sessionId":"...","token":"..."}}.
+// Sin sessionId se usa la sesión asociada al abrir el WebSocket, si la hay.
+type resyncCommand struct {
+	SessionID string `json:"sessionId"`
+	Token     string `json:"token"`
+}
+
+// consideringCommand datos del comando con el que el jugador indica la opción
+// sobre la que está dudando: {"type":"considering","data":{"option":"B"}}
+type consideringCommand struct {
+	Option string `json:"option"`
+}
+
+// handleCommand atiende los comandos de un cliente WebSocket; sessionID es la
+// sesión ya verificada al conectar (vacía si no se asoció)
+func (gc *GameControlHandler) handleCommand(conn *websocket.Conn, data []byte, sessionID string) {
+	var cmd clientCommand
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		return
+	}
+
+	switch cmd.Type {
+	case "resync":
+		var resync resyncCommand
+		json.Unmarshal(cmd.Data, &resync)
+		gc.handleResync(conn, sessionID, resync)
+	case "considering":
+		var considering consideringCommand
+		if err := json.Unmarshal(cmd.Data, &considering); err != nil {
+			return
+		}
+		gc.handleConsidering(sessionID, considering)
+	}
+}
+
+// handleResync responde solo a ese cliente con resyncState
+func (gc *GameControlHandler) handleResync(conn *websocket.Conn, sessionID string, cmd resyncCommand) {
+	if cmd.SessionID != "" && cmd.SessionID != sessionID {
+		sessionID = cmd.SessionID
+		if err := gc.sessionService.VerifySessionToken(sessionID, cmd.Token); err != nil {
+			log.Printf("⚠️ Resync sin sesión para %s: %v", sessionID, err)
+			sessionID = ""
 		}
+	}
 
-		// Escuchar mensajes del cliente
-		for {
-			_, _, err := ws.ReadMessage()
-			if err != nil {
-				log.Printf("Error leyendo mensaje WebSocket: %v", err)
-				break
+	if err := gc.hub.SendTo(conn, "resyncState", gc.resyncState(sessionID)); err != nil {
+		log.Printf("⚠️ Error enviando resyncState: %v", err)
+	}
+}
+
+// handleConsidering registra la opción que considera el jugador en la pregunta
+// en curso. Solo cuenta para conexiones asociadas a una sesión, con las
+// respuestas abiertas y una opción de la pregunta; nunca se difunde quién
+// considera qué.
+func (gc *GameControlHandler) handleConsidering(sessionID string, cmd consideringCommand) {
+	if sessionID == "" || gc.questionService == nil {
+		return
+	}
+
+	gameState, err := gc.gameStateService.GetGameState()
+	if err != nil || !gameState.IsActive || !gameState.AnswersOpen || gameState.QuestionNumber == 0 {
+		return
+	}
+
+	option := strings.TrimSpace(cmd.Option)
+	if option != "" {
+		question, err := gc.questionService.GetQuestionByNumber(gameState.QuestionNumber)
+		if err != nil {
+			return
+		}
+		option = gc.questionService.CanonicalOption(question, option)
+		if _, ok := question.Options[option]; !ok {
+			return
+		}
+	}
+	gc.considering.set(gameState.QuestionNumber, sessionID, option)
+}
+
+// broadcastConsidering envía el resumen anónimo de opciones consideradas solo
+// al panel de administración: los jugadores no deben ver hacia dónde se inclina
+// el resto
+func (gc *GameControlHandler) broadcastConsidering(questionNumber int, counts map[string]int, total int) {
+	gc.sendToAdmins("consideringSummary", map[string]interface{}{
+		"questionNumber": questionNumber,
+		"counts":         counts,
+		"total":          total,
+		"timestamp":      models.Timestamp(),
+	})
+}
+
+// resyncState reúne en un solo mensaje el estado del juego, la sesión del
+// jugador, la pregunta en curso con su temporizador y la tabla de posiciones
+func (gc *GameControlHandler) resyncState(sessionID string) map[string]interface{} {
+	now := time.Now()
+	state := map[string]interface{}{
+		"serverTime":   models.FormatTime(now),
+		"serverTimeMs": now.UnixMilli(),
+	}
+
+	gameState, err := gc.gameStateService.GetGameState()
+	if err != nil {
+		log.Printf("⚠️ Error obteniendo estado del juego para resync: %v", err)
+	} else {
+		state["gameState"] = gameState
+		if gameState.IsActive && gameState.QuestionNumber > 0 && gc.questionService != nil {
+			if question, err := gc.questionService.GetQuestionByNumber(gameState.QuestionNumber); err == nil {
+				state["currentQuestion"] = question.Public(gameState.QuestionNumber)
+			}
+			if remaining := remainingSeconds(gameState.QuestionDeadline); remaining != nil {
+				state["remainingSeconds"] = *remaining
+				state["deadline"] = gameState.QuestionDeadline
 			}
 		}
+	}
+
+	if sessionID != "" {
+		if session, err := gc.sessionService.GetSession(sessionID); err == nil {
+			state["session"] = session
+		}
+	}
+
+	if leaderboard, err := gc.sessionService.GetLeaderboard(); err == nil {
+		state["leaderboard"] = leaderboard
+	}
+
+	return state
+}
+
+// remainingSeconds segundos (redondeados hacia arriba, nunca negativos) hasta
+// deadline; nil si la pregunta no tiene temporizador
+func remainingSeconds(deadline *time.Time) *int {
+	if deadline == nil {
+		return nil
+	}
+	remaining := int((time.Until(*deadline) + time.Second - 1) / time.Second)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &remaining
+}
+
+// trackConnection registra o quita un WebSocket de una sesión y, cuando la
+// sesión pasa de 0 a 1 conexiones o de 1 a 0, la marca como
+// conectada/desconectada y avisa a los administradores
+func (gc *GameControlHandler) trackConnection(sessionID string, ws *websocket.Conn, open bool) {
+	gc.connMutex.Lock()
+	sockets := gc.connections[sessionID]
+	before := len(sockets)
+	if open {
+		if sockets == nil {
+			sockets = make(map[*websocket.Conn]bool)
+			gc.connections[sessionID] = sockets
+		}
+		sockets[ws] = true
+	} else {
+		delete(sockets, ws)
+		if len(sockets) == 0 {
+			delete(gc.connections, sessionID)
+		}
+	}
+	after := len(sockets)
+	gc.connMutex.Unlock()
+
+	if (before > 0) != (after > 0) {
+		gc.publishConnection(sessionID)
+	}
+}
+
+// publishConnection guarda si la sesión tiene algún WebSocket abierto y avisa
+// a los administradores. Va fuera de connMutex para que una escritura lenta en
+// Redis no frene los envíos dirigidos; connStateMutex ordena los avisos y el
+// estado se lee al publicar, así dos cambios seguidos no quedan invertidos.
+func (gc *GameControlHandler) publishConnection(sessionID string) {
+	gc.connStateMutex.Lock()
+	defer gc.connStateMutex.Unlock()
+
+	gc.connMutex.Lock()
+	connected := len(gc.connections[sessionID]) > 0
+	gc.connMutex.Unlock()
+
+	session, err := gc.sessionService.SetConnected(sessionID, connected)
+	if err != nil {
+		log.Printf("⚠️ Error actualizando conexión de la sesión %s: %v", sessionID, err)
+		return
+	}
+	gc.sendToAdmins("playerConnection", map[string]interface{}{
+		"sessionId":  session.ID,
+		"playerName": session.PlayerName,
+		"connected":  connected,
+		"timestamp":  models.Timestamp(),
 	})
+}
+
+// trackAdmin registra o quita un WebSocket del panel de administración
+func (gc *GameControlHandler) trackAdmin(ws *websocket.Conn, open bool) {
+	gc.connMutex.Lock()
+	defer gc.connMutex.Unlock()
+
+	if open {
+		gc.admins[ws] = true
+	} else {
+		delete(gc.admins, ws)
+	}
+}
 
+// sendToSession envía un mensaje solo a los WebSockets asociados a una sesión;
+// devuelve a cuántos se entregó
+func (gc *GameControlHandler) sendToSession(sessionID, msgType string, data interface{}) int {
+	gc.connMutex.Lock()
+	sockets := make([]*websocket.Conn, 0, len(gc.connections[sessionID]))
+	for ws := range gc.connections[sessionID] {
+		sockets = append(sockets, ws)
+	}
+	gc.connMutex.Unlock()
+
+	return gc.sendToSockets(sockets, msgType, data)
+}
+
+// sendToAdmins envía un mensaje solo a los WebSockets del panel de
+// administración; devuelve a cuántos se entregó
+func (gc *GameControlHandler) sendToAdmins(msgType string, data interface{}) int {
+	gc.connMutex.Lock()
+	sockets := make([]*websocket.Conn, 0, len(gc.admins))
+	for ws := range gc.admins {
+		sockets = append(sockets, ws)
+	}
+	gc.connMutex.Unlock()
+
+	return gc.sendToSockets(sockets, msgType, data)
+}
+
+// sendToSockets envía un mensaje a cada WebSocket; devuelve a cuántos se entregó
+func (gc *GameControlHandler) sendToSockets(sockets []*websocket.Conn, msgType string, data interface{}) int {
+	delivered := 0
+	for _, ws := range sockets {
+		if err := gc.hub.SendTo(ws, msgType, data); err != nil {
+			log.Printf("⚠️ Error enviando %s: %v", msgType, err)
+			continue
+		}
+		delivered++
+	}
+	return delivered
+}
+
+// SendPlayerMessage maneja POST /api/admin/players/{id}/message: envía un
+// adminMessage solo al jugador (p. ej. "por favor recarga la página")
+func (gc *GameControlHandler) SendPlayerMessage(ctx *fasthttp.RequestCtx) {
+	sessionID, _ := ctx.UserValue("id").(string)
+
+	var request struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(ctx.PostBody(), &request); err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusBadRequest, "JSON inválido")
+		return
+	}
+	request.Text = strings.TrimSpace(request.Text)
+	if request.Text == "" {
+		gc.respondWithError(ctx, fasthttp.StatusBadRequest, "El texto del mensaje es requerido")
+		return
+	}
+
+	session, err := gc.sessionService.GetSession(sessionID)
 	if err != nil {
-		log.Printf("Error upgrading to WebSocket: %v", err)
-		ctx.Error("Error upgrading to WebSocket", fasthttp.StatusInternalServerError)
+		gc.respondWithError(ctx, fasthttp.StatusNotFound, "Sesión no encontrada")
+		return
 	}
+
+	delivered := gc.sendToSession(session.ID, "adminMessage", map[string]interface{}{
+		"sessionId": session.ID,
+		"text":      request.Text,
+		"timestamp": models.Timestamp(),
+	})
+	if delivered == 0 {
+		gc.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("%s no tiene un WebSocket conectado", session.PlayerName))
+		return
+	}
+	recordAudit(gc.auditService, ctx, "player-message", map[string]interface{}{
+		"sessionId": session.ID,
+		"text":      request.Text,
+	})
+
+	gc.respondWithSuccess(ctx, map[string]interface{}{
+		"sessionId": session.ID,
+		"delivered": delivered,
+	}, fmt.Sprintf("Mensaje enviado a %s", session.PlayerName))
 }
 
 // StartGame inicia una nueva partida
@@ -81,15 +470,20 @@ func (gc *GameControlHandler) StartGame(ctx *fasthttp.RequestCtx) {
 	}
 
 	err = gc.gameStateService.StartGame()
+	if errors.Is(err, services.ErrGameAlreadyActive) {
+		gc.respondWithError(ctx, fasthttp.StatusBadRequest, "Ya hay una partida activa")
+		return
+	}
 	if err != nil {
 		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error iniciando partida")
 		return
 	}
 
 	gc.hub.BroadcastGameState(true, "Partida iniciada - Los jugadores pueden ingresar")
+	recordAudit(gc.auditService, ctx, "start", nil)
 
 	gc.respondWithSuccess(ctx, map[string]interface{}{
-		"timestamp": time.Now().Format(time.RFC3339),
+		"timestamp": models.Timestamp(),
 	}, "Partida iniciada exitosamente")
 
 	log.Println("🟢 Partida iniciada desde el panel de administración")
@@ -97,6 +491,28 @@ func (gc *GameControlHandler) StartGame(ctx *fasthttp.RequestCtx) {
 
 // EndGame termina la partida actual
 func (gc *GameControlHandler) EndGame(ctx *fasthttp.RequestCtx) {
+	// Solo una llamada ejecuta la secuencia; un clic duplicado espera y
+	// recibe el mismo resumen sin volver a limpiar
+	locked, err := gc.gameStateService.AcquireEndLock()
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error obteniendo candado de fin de partida")
+		return
+	}
+	if !locked {
+		summary, err := gc.gameStateService.WaitEndSummary(10 * time.Second)
+		if err != nil || summary == nil {
+			gc.respondWithError(ctx, fasthttp.StatusConflict, "La partida se está terminando en otra petición")
+			return
+		}
+		gc.respondWithSuccess(ctx, summary, "La partida ya había sido terminada")
+		return
+	}
+	defer func() {
+		if err := gc.gameStateService.ReleaseEndLock(); err != nil {
+			log.Printf("⚠️ Error liberando candado de fin de partida: %v", err)
+		}
+	}()
+
 	gameState, err := gc.gameStateService.GetGameState()
 	if err != nil {
 		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error obteniendo estado del juego")
@@ -104,49 +520,187 @@ func (gc *GameControlHandler) EndGame(ctx *fasthttp.RequestCtx) {
 	}
 
 	if !gameState.IsActive {
+		if summary, err := gc.gameStateService.GetEndSummary(); err == nil && summary != nil {
+			gc.respondWithSuccess(ctx, summary, "La partida ya había sido terminada")
+			return
+		}
 		gc.respondWithError(ctx, fasthttp.StatusBadRequest, "No hay partida activa para terminar")
 		return
 	}
 
+	summary, err := gc.endGame(gameState)
+	if errors.Is(err, services.ErrGameNotActive) {
+		gc.respondWithError(ctx, fasthttp.StatusBadRequest, "No hay partida activa para terminar")
+		return
+	}
+	if errors.Is(err, errGameDataNotCleared) {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error limpiando datos de la partida")
+		return
+	}
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error terminando partida")
+		return
+	}
+	recordAudit(gc.auditService, ctx, "end", map[string]interface{}{
+		"totalPlayers": summary.TotalPlayers,
+		"archiveId":    summary.ArchiveID,
+	})
+
+	gc.respondWithSuccess(ctx, summary, "Partida terminada exitosamente y datos limpiados")
+
+	log.Printf("🔴 Partida terminada y datos de %d jugadores limpiados desde el panel de administración", summary.TotalPlayers)
+}
+
+// FinishAllPlayers maneja POST /api/game/finish-all: termina a todos los
+// jugadores activos congelando sus premios, sin limpiar los datos de la partida
+func (gc *GameControlHandler) FinishAllPlayers(ctx *fasthttp.RequestCtx) {
+	finished, err := gc.sessionService.FinishAllActive()
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error terminando jugadores activos")
+		return
+	}
+
+	leaderboard, err := gc.sessionService.GetLeaderboard()
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error obteniendo tabla de posiciones")
+		return
+	}
+
+	gc.hub.BroadcastMessage("gameFinished", map[string]interface{}{
+		"timestamp":   models.Timestamp(),
+		"message":     "Se cerraron las puntuaciones de todos los jugadores",
+		"finished":    len(finished),
+		"leaderboard": leaderboard.Leaderboard,
+	})
+	recordAudit(gc.auditService, ctx, "finish-all", map[string]interface{}{
+		"finished": len(finished),
+	})
+
+	gc.respondWithSuccess(ctx, map[string]interface{}{
+		"finished":    len(finished),
+		"leaderboard": leaderboard,
+	}, fmt.Sprintf("%d jugadores terminados", len(finished)))
+
+	log.Printf("🏁 %d jugadores activos terminados desde el panel de administración", len(finished))
+}
+
+// CheckRoundComplete comprueba si ya no quedan jugadores activos y, la primera
+// vez, difunde roundComplete y (con "end") termina la partida
+func (gc *GameControlHandler) CheckRoundComplete() {
+	if gc.autoEndAction != "round" && gc.autoEndAction != "end" {
+		return
+	}
+
+	gameState, err := gc.gameStateService.GetGameState()
+	if err != nil || !gameState.IsActive {
+		return
+	}
+	complete, err := gc.sessionService.MarkRoundComplete()
+	if err != nil {
+		log.Printf("⚠️ Error comprobando fin de la ronda: %v", err)
+		return
+	}
+	if !complete {
+		return
+	}
+
+	sessions, _ := gc.sessionService.GetAllSessions()
+	gc.hub.BroadcastMessage("roundComplete", map[string]interface{}{
+		"action":       gc.autoEndAction,
+		"totalPlayers": len(sessions),
+		"timestamp":    models.Timestamp(),
+		"message":      "Todos los jugadores fueron eliminados o terminaron",
+	})
+	log.Printf("🏁 Ronda completa: no quedan jugadores activos (%s)", gc.autoEndAction)
+
+	if gc.autoEndAction != "end" {
+		return
+	}
+	locked, err := gc.gameStateService.AcquireEndLock()
+	if err != nil || !locked {
+		return
+	}
+	defer func() {
+		if err := gc.gameStateService.ReleaseEndLock(); err != nil {
+			log.Printf("⚠️ Error liberando candado de fin de partida: %v", err)
+		}
+	}()
+
+	// Otra petición pudo terminarla mientras tanto
+	gameState, err = gc.gameStateService.GetGameState()
+	if err != nil || !gameState.IsActive {
+		return
+	}
+	if _, err := gc.endGame(gameState); err != nil {
+		log.Printf("⚠️ Error terminando la partida automáticamente: %v", err)
+		return
+	}
+	log.Printf("🔴 Partida terminada automáticamente: no quedan jugadores activos")
+}
+
+// errGameDataNotCleared la partida se terminó pero no se pudieron limpiar sus datos
+var errGameDataNotCleared = errors.New("error limpiando datos de la partida")
+
+// endGame ejecuta la secuencia de fin de partida: aviso a los jugadores,
+// archivo y limpieza de los datos. Quien la llama debe tener el candado de fin.
+func (gc *GameControlHandler) endGame(gameState *models.GameState) (*models.EndGameSummary, error) {
 	// Obtener estadísticas antes de limpiar para el reporte final
 	activeSessions, _ := gc.sessionService.GetActiveSessions()
 	totalPlayers := len(activeSessions)
 	
 	// Terminar el juego
-	err = gc.gameStateService.EndGame()
+	err := gc.gameStateService.EndGame()
 	if err != nil {
-		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error terminando partida")
-		return
+		return nil, err
 	}
 
-	// Notificar a todos los jugadores que la partida ha terminado ANTES de limpiar datos
-	gc.hub.BroadcastMessage("gameEnded", map[string]interface{}{
-		"timestamp":    time.Now().Format(time.RFC3339),
+	// Notificar a todos los jugadores que la partida ha terminado ANTES de
+	// limpiar datos, esperando a que el aviso se escriba en cada conexión
+	delivery, err := gc.hub.BroadcastAndWait("gameEnded", map[string]interface{}{
+		"timestamp":    models.Timestamp(),
 		"message":      "La partida ha terminado. Todos los datos serán limpiados.",
 		"totalPlayers": totalPlayers,
-	})
+	}, 5*time.Second)
+	if err != nil {
+		log.Printf("⚠️ Aviso de fin de partida sin confirmar: %v", err)
+	} else {
+		log.Printf("📣 Aviso de fin de partida entregado a %d clientes (%d fallidos, %d filtrados)", delivery.Delivered, delivery.Failed, delivery.Filtered)
+	}
 
-	// Esperar un momento para que el mensaje llegue a todos los clientes
-	time.Sleep(1 * time.Second)
+	// Archivar la partida antes de borrar los datos
+	var archiveID string
+	if gc.archiveService != nil {
+		allSessions, err := gc.sessionService.GetAllSessions()
+		if err != nil {
+			log.Printf("⚠️ Error obteniendo sesiones para archivar: %v", err)
+		} else if archive, err := gc.archiveService.CreateArchive(gameState, allSessions); err != nil {
+			log.Printf("⚠️ Error archivando partida: %v", err)
+		} else {
+			archiveID = archive.ID
+		}
+	}
 
 	// Limpiar todas las sesiones y datos de la partida
 	err = gc.sessionService.ClearAllSessions()
 	if err != nil {
 		log.Printf("⚠️ Error limpiando sesiones: %v", err)
-		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error limpiando datos de la partida")
-		return
+		return nil, errGameDataNotCleared
 	}
 
 	// Notificar estado final después de la limpieza
 	gc.hub.BroadcastGameState(false, "Partida terminada - Todos los datos han sido limpiados")
 
-	gc.respondWithSuccess(ctx, map[string]interface{}{
-		"timestamp":    time.Now().Format(time.RFC3339),
-		"totalPlayers": totalPlayers,
-		"dataCleared":  true,
-	}, "Partida terminada exitosamente y datos limpiados")
+	summary := &models.EndGameSummary{
+		Timestamp:    models.Timestamp(),
+		TotalPlayers: totalPlayers,
+		DataCleared:  true,
+		ArchiveID:    archiveID,
+	}
+	if err := gc.gameStateService.SaveEndSummary(summary); err != nil {
+		log.Printf("⚠️ Error guardando resumen de fin de partida: %v", err)
+	}
 
-	log.Printf("🔴 Partida terminada y datos de %d jugadores limpiados desde el panel de administración", totalPlayers)
+	return summary, nil
 }
 
 // GetGameState devuelve el estado actual del juego
@@ -162,6 +716,50 @@ func (gc *GameControlHandler) GetGameState(ctx *fasthttp.RequestCtx) {
 	}, "Estado del juego obtenido exitosamente")
 }
 
+// GetClock maneja GET /api/game/clock: hora del servidor y, si hay pregunta
+// con temporizador, su fecha límite y los milisegundos restantes, para que el
+// cliente corrija el desfase de su reloj
+func (gc *GameControlHandler) GetClock(ctx *fasthttp.RequestCtx) {
+	gameState, err := gc.gameStateService.GetGameState()
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error obteniendo estado del juego")
+		return
+	}
+
+	now := time.Now()
+	clock := map[string]interface{}{
+		"serverTime":   models.FormatTime(now),
+		"serverTimeMs": now.UnixMilli(),
+	}
+	if gameState.IsActive && gameState.QuestionDeadline != nil {
+		// En milisegundos enteros, para que coincida con deadlineMs - serverTimeMs
+		remaining := gameState.QuestionDeadline.UnixMilli() - now.UnixMilli()
+		if remaining < 0 {
+			remaining = 0
+		}
+		clock["questionNumber"] = gameState.QuestionNumber
+		clock["deadline"] = models.FormatTime(*gameState.QuestionDeadline)
+		clock["deadlineMs"] = gameState.QuestionDeadline.UnixMilli()
+		clock["remainingMs"] = remaining
+	}
+
+	gc.respondWithSuccess(ctx, clock, "Hora del servidor")
+}
+
+// ListRooms maneja GET /api/admin/rooms
+func (gc *GameControlHandler) ListRooms(ctx *fasthttp.RequestCtx) {
+	rooms, err := gc.gameStateService.ListRooms()
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error obteniendo partidas: %v", err))
+		return
+	}
+
+	gc.respondWithSuccess(ctx, map[string]interface{}{
+		"rooms": rooms,
+		"total": len(rooms),
+	}, fmt.Sprintf("%d partidas en curso", len(rooms)))
+}
+
 // NextQuestion avanza a la siguiente pregunta para todos los jugadores
 func (gc *GameControlHandler) NextQuestion(ctx *fasthttp.RequestCtx) {
 	gameState, err := gc.gameStateService.GetGameState()
@@ -175,19 +773,104 @@ func (gc *GameControlHandler) NextQuestion(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	// En dos fases la pregunta solo se muestra; OpenAnswers inicia el temporizador
+	if gc.gameStateService.TwoPhaseQuestions() {
+		gameState, err = gc.gameStateService.ShowQuestion(gameState.CurrentQuestion)
+	} else {
+		gameState, err = gc.gameStateService.StartQuestion(gameState.CurrentQuestion, gc.questionDifficulty(gameState.CurrentQuestion))
+	}
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error iniciando temporizador de la pregunta")
+		return
+	}
+
 	// Enviar comando via WebSocket para que todos los jugadores avancen
-	gc.hub.BroadcastMessage("nextQuestion", map[string]interface{}{
-		"timestamp": time.Now().Format(time.RFC3339),
-		"message":   "El administrador ha avanzado a la siguiente pregunta",
+	event := map[string]interface{}{
+		"timestamp":      models.Timestamp(),
+		"message":        "El administrador ha avanzado a la siguiente pregunta",
+		"questionNumber": gameState.QuestionNumber,
+		"duration":       gameState.QuestionDuration,
+		"answersOpen":    gameState.AnswersOpen,
+	}
+	if gameState.QuestionDeadline != nil {
+		event["deadline"] = models.FormatTime(*gameState.QuestionDeadline)
+	}
+	gc.hub.BroadcastMessage("nextQuestion", event)
+	recordAudit(gc.auditService, ctx, "next-question", map[string]interface{}{
+		"questionNumber": gameState.QuestionNumber,
 	})
 
 	gc.respondWithSuccess(ctx, map[string]interface{}{
-		"timestamp": time.Now().Format(time.RFC3339),
+		"timestamp":      models.Timestamp(),
+		"questionNumber": gameState.QuestionNumber,
+		"duration":       gameState.QuestionDuration,
 	}, "Comando enviado para avanzar a la siguiente pregunta")
 
 	log.Println("➡️ Administrador ha forzado el avance a la siguiente pregunta")
 }
 
+// OpenAnswers abre la ventana de respuestas de la pregunta mostrada e inicia
+// su temporizador (modo en dos fases)
+func (gc *GameControlHandler) OpenAnswers(ctx *fasthttp.RequestCtx) {
+	gameState, err := gc.gameStateService.GetGameState()
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error obteniendo estado del juego")
+		return
+	}
+
+	if !gameState.IsActive {
+		gc.respondWithError(ctx, fasthttp.StatusBadRequest, "No hay partida activa")
+		return
+	}
+
+	gameState, err = gc.gameStateService.OpenAnswers(gc.questionDifficulty(gameState.QuestionNumber))
+	if errors.Is(err, services.ErrNoQuestionShown) {
+		gc.respondWithError(ctx, fasthttp.StatusConflict, "Primero muestra una pregunta con next-question")
+		return
+	}
+	if errors.Is(err, services.ErrAnswersAlreadyOpen) {
+		gc.respondWithError(ctx, fasthttp.StatusConflict, "Las respuestas de esta pregunta ya están abiertas")
+		return
+	}
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error abriendo las respuestas")
+		return
+	}
+
+	gc.hub.BroadcastMessage("answersOpened", map[string]interface{}{
+		"timestamp":      models.Timestamp(),
+		"message":        "¡Ya pueden responder!",
+		"questionNumber": gameState.QuestionNumber,
+		"duration":       gameState.QuestionDuration,
+		"deadline":       models.FormatTime(*gameState.QuestionDeadline),
+	})
+	recordAudit(gc.auditService, ctx, "open-answers", map[string]interface{}{
+		"questionNumber": gameState.QuestionNumber,
+	})
+
+	gc.respondWithSuccess(ctx, map[string]interface{}{
+		"questionNumber": gameState.QuestionNumber,
+		"duration":       gameState.QuestionDuration,
+		"deadline":       models.FormatTime(*gameState.QuestionDeadline),
+	}, "Respuestas abiertas")
+
+	log.Printf("🔓 Respuestas abiertas para la pregunta %d", gameState.QuestionNumber)
+}
+
+// questionDifficulty dificultad de la pregunta con ese número en el plan, para
+// elegir la duración del temporizador (0 si no se puede obtener)
+func (gc *GameControlHandler) questionDifficulty(number int) int {
+	if gc.questionService == nil {
+		return 0
+	}
+	question, err := gc.questionService.GetQuestionByNumber(number)
+	if err != nil {
+		log.Printf("⚠️ Error obteniendo pregunta %d para el temporizador: %v", number, err)
+		return 0
+	}
+	return question.Difficulty
+}
+
 // RevealAnswer revela la respuesta correcta a todos los jugadores
 func (gc *GameControlHandler) RevealAnswer(ctx *fasthttp.RequestCtx) {
 	gameState, err := gc.gameStateService.GetGameState()
@@ -201,19 +884,422 @@ func (gc *GameControlHandler) RevealAnswer(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	gc.gameStateService.StopTimerTicks()
+
+	questionNumber := currentQuestionNumber(gameState)
+	reveal := map[string]interface{}{
+		"timestamp":      models.Timestamp(),
+		"message":        "El administrador ha revelado la respuesta correcta",
+		"questionNumber": questionNumber,
+	}
+
+	// Agregar la respuesta correcta y cuántos jugadores eligieron cada opción
+	if gc.questionService != nil {
+		if question, err := gc.questionService.GetQuestionByNumber(questionNumber); err != nil {
+			log.Printf("⚠️ Error obteniendo pregunta %d para revelar: %v", questionNumber, err)
+		} else {
+			reveal["correctOption"] = question.Correct
+			reveal["explanation"] = question.Explanation
+		}
+	}
+	if distribution, total, err := gc.sessionService.GetAnswerDistribution(questionNumber); err != nil {
+		log.Printf("⚠️ Error calculando distribución de respuestas: %v", err)
+	} else {
+		reveal["distribution"] = distribution
+		reveal["totalAnswers"] = total
+	}
+
 	// Enviar comando via WebSocket para revelar la respuesta
-	gc.hub.BroadcastMessage("revealAnswer", map[string]interface{}{
-		"timestamp": time.Now().Format(time.RFC3339),
-		"message":   "El administrador ha revelado la respuesta correcta",
+	gc.hub.BroadcastMessage("revealAnswer", reveal)
+	recordAudit(gc.auditService, ctx, "reveal-answer", map[string]interface{}{
+		"questionNumber": questionNumber,
 	})
 
-	gc.respondWithSuccess(ctx, map[string]interface{}{
-		"timestamp": time.Now().Format(time.RFC3339),
-	}, "Comando enviado para revelar la respuesta correcta")
+	gc.respondWithSuccess(ctx, reveal, "Comando enviado para revelar la respuesta correcta")
 
 	log.Println("💡 Administrador ha revelado la respuesta correcta")
 }
 
+// VoidQuestion anula la pregunta en curso: nadie gana ni pierde por ella y
+// queda fuera de las estadísticas
+func (gc *GameControlHandler) VoidQuestion(ctx *fasthttp.RequestCtx) {
+	gameState, err := gc.gameStateService.GetGameState()
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error obteniendo estado del juego")
+		return
+	}
+
+	if !gameState.IsActive {
+		gc.respondWithError(ctx, fasthttp.StatusBadRequest, "No hay partida activa")
+		return
+	}
+
+	questionNumber := currentQuestionNumber(gameState)
+	gc.gameStateService.StopTimerTicks()
+	affected, err := gc.sessionService.VoidQuestion(questionNumber)
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error anulando la pregunta: %v", err))
+		return
+	}
+	gc.gameStateService.InvalidateCache()
+
+	gc.hub.BroadcastMessage("questionVoided", map[string]interface{}{
+		"timestamp":       models.Timestamp(),
+		"questionNumber":  questionNumber,
+		"affectedPlayers": len(affected),
+		"message":         fmt.Sprintf("La pregunta %d fue anulada: no cuenta para nadie", questionNumber),
+	})
+	recordAudit(gc.auditService, ctx, "void-question", map[string]interface{}{
+		"questionNumber":  questionNumber,
+		"affectedPlayers": len(affected),
+	})
+
+	gc.respondWithSuccess(ctx, map[string]interface{}{
+		"questionNumber":  questionNumber,
+		"affectedPlayers": len(affected),
+		"sessions":        affected,
+	}, fmt.Sprintf("Pregunta %d anulada", questionNumber))
+
+	log.Printf("🚫 Administrador anuló la pregunta %d", questionNumber)
+}
+
+// PeekAnswer muestra la respuesta correcta solo al administrador que la pide,
+// antes de revelarla a los jugadores; no difunde nada por WebSocket
+func (gc *GameControlHandler) PeekAnswer(ctx *fasthttp.RequestCtx) {
+	gameState, err := gc.gameStateService.GetGameState()
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error obteniendo estado del juego")
+		return
+	}
+
+	if !gameState.IsActive {
+		gc.respondWithError(ctx, fasthttp.StatusBadRequest, "No hay partida activa")
+		return
+	}
+
+	if gc.questionService == nil {
+		gc.respondWithError(ctx, fasthttp.StatusServiceUnavailable, "El servicio de preguntas no está disponible")
+		return
+	}
+
+	questionNumber := currentQuestionNumber(gameState)
+	question, err := gc.questionService.GetQuestionByNumber(questionNumber)
+	if errors.Is(err, services.ErrQuestionOutOfPlan) {
+		gc.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("La pregunta %d no está en el plan de la partida", questionNumber))
+		return
+	}
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error obteniendo pregunta: %v", err))
+		return
+	}
+	recordAudit(gc.auditService, ctx, "peek-answer", map[string]interface{}{
+		"questionNumber": questionNumber,
+	})
+
+	gc.respondWithSuccess(ctx, map[string]interface{}{
+		"questionNumber": questionNumber,
+		"questionId":     question.ID,
+		"question":       question.Question,
+		"correctOption":  question.Correct,
+		"explanation":    question.Explanation,
+	}, "Respuesta correcta (solo administrador)")
+}
+
+// GetQuestionTiming devuelve el histograma de tiempos de respuesta de la
+// pregunta en curso y cuántos jugadores siguen pensando
+func (gc *GameControlHandler) GetQuestionTiming(ctx *fasthttp.RequestCtx) {
+	gameState, err := gc.gameStateService.GetGameState()
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error obteniendo estado del juego")
+		return
+	}
+
+	if !gameState.IsActive {
+		gc.respondWithError(ctx, fasthttp.StatusBadRequest, "No hay partida activa")
+		return
+	}
+
+	if gameState.QuestionNumber == 0 || gameState.QuestionStartedAt == nil {
+		gc.respondWithError(ctx, fasthttp.StatusConflict, "No hay una pregunta iniciada con temporizador")
+		return
+	}
+
+	duration := time.Duration(gameState.QuestionDuration) * time.Second
+	timing, err := gc.sessionService.GetAnswerTiming(gameState.QuestionNumber, *gameState.QuestionStartedAt, duration)
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error calculando tiempos: %v", err))
+		return
+	}
+
+	gc.respondWithSuccess(ctx, timing, "Tiempos de respuesta de la pregunta actual")
+}
+
+// GetLive maneja GET /api/admin/live: pregunta en curso, avance de las
+// respuestas y tiempo restante en un solo payload
+func (gc *GameControlHandler) GetLive(ctx *fasthttp.RequestCtx) {
+	gameState, err := gc.gameStateService.GetGameState()
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error obteniendo estado del juego")
+		return
+	}
+
+	if !gameState.IsActive {
+		gc.respondWithError(ctx, fasthttp.StatusBadRequest, "No hay partida activa")
+		return
+	}
+
+	if gameState.QuestionNumber == 0 {
+		gc.respondWithError(ctx, fasthttp.StatusConflict, "No hay una pregunta en curso")
+		return
+	}
+
+	live := &models.LiveQuestion{
+		QuestionNumber: gameState.QuestionNumber,
+		AnswersOpen:    gameState.AnswersOpen,
+		Deadline:       gameState.QuestionDeadline,
+	}
+	if gc.questionService != nil {
+		question, err := gc.questionService.GetQuestionByNumber(gameState.QuestionNumber)
+		if err != nil {
+			gc.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Error obteniendo pregunta %d: %v", gameState.QuestionNumber, err))
+			return
+		}
+		live.Question = question
+	}
+	live.RemainingSeconds = remainingSeconds(gameState.QuestionDeadline)
+
+	progress, err := gc.sessionService.GetAnswerProgress(gameState.QuestionNumber)
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error calculando avance de respuestas: %v", err))
+		return
+	}
+	live.AnswerProgress = *progress
+
+	gc.respondWithSuccess(ctx, live, fmt.Sprintf("Pregunta %d: %d respondieron, %d pendientes", live.QuestionNumber, progress.Answered, progress.Pending))
+}
+
+// GetSettings devuelve la configuración del juego ajustable en caliente
+func (gc *GameControlHandler) GetSettings(ctx *fasthttp.RequestCtx) {
+	if gc.settingsService == nil {
+		gc.respondWithError(ctx, fasthttp.StatusServiceUnavailable, "La configuración en caliente no está disponible")
+		return
+	}
+
+	gc.respondWithSuccess(ctx, gc.settingsService.Current(), "Configuración actual")
+}
+
+// UpdateSettings aplica cambios parciales a la configuración; surten efecto
+// desde la siguiente pregunta, difusión o sesión nueva
+func (gc *GameControlHandler) UpdateSettings(ctx *fasthttp.RequestCtx) {
+	if gc.settingsService == nil {
+		gc.respondWithError(ctx, fasthttp.StatusServiceUnavailable, "La configuración en caliente no está disponible")
+		return
+	}
+
+	var update models.SettingsUpdate
+	if err := json.Unmarshal(ctx.PostBody(), &update); err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusBadRequest, "JSON inválido")
+		return
+	}
+
+	if fieldErr := update.Validate(); fieldErr != nil {
+		gc.respondWithJSON(ctx, fasthttp.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   fieldErr.Message,
+			Data:    fieldErr,
+		})
+		return
+	}
+
+	settings, err := gc.settingsService.Update(update)
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error guardando configuración: %v", err))
+		return
+	}
+
+	var params map[string]interface{}
+	if data, err := json.Marshal(update); err == nil {
+		json.Unmarshal(data, &params)
+	}
+	recordAudit(gc.auditService, ctx, "update-settings", params)
+
+	gc.respondWithSuccess(ctx, settings, "Configuración actualizada")
+}
+
+// currentQuestionNumber pregunta en curso: la iniciada por NextQuestion o, si
+// no hay, la más alta alcanzada por algún jugador
+func currentQuestionNumber(gameState *models.GameState) int {
+	if gameState.QuestionNumber > 0 {
+		return gameState.QuestionNumber
+	}
+	return gameState.CurrentQuestion
+}
+
+// Announce actualiza el mensaje del juego y lo difunde a todos los clientes
+func (gc *GameControlHandler) Announce(ctx *fasthttp.RequestCtx) {
+	var request struct {
+		Message string `json:"message"`
+	}
+
+	if err := json.Unmarshal(ctx.PostBody(), &request); err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusBadRequest, "JSON inválido")
+		return
+	}
+
+	request.Message = strings.TrimSpace(request.Message)
+	if request.Message == "" {
+		gc.respondWithError(ctx, fasthttp.StatusBadRequest, "El mensaje es requerido")
+		return
+	}
+
+	gameState, err := gc.gameStateService.SetMessage(request.Message)
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error actualizando mensaje del juego")
+		return
+	}
+
+	gc.hub.BroadcastMessage("announcement", map[string]interface{}{
+		"message":   request.Message,
+		"isActive":  gameState.IsActive,
+		"timestamp": models.Timestamp(),
+	})
+	recordAudit(gc.auditService, ctx, "announce", map[string]interface{}{
+		"message": request.Message,
+	})
+
+	gc.respondWithSuccess(ctx, map[string]interface{}{
+		"gameState": gameState,
+	}, "Anuncio enviado exitosamente")
+
+	log.Printf("📢 Anuncio del administrador: %s", request.Message)
+}
+
+// RestoreArchive maneja POST /api/admin/archives/{id}/restore
+func (gc *GameControlHandler) RestoreArchive(ctx *fasthttp.RequestCtx) {
+	archiveID, _ := ctx.UserValue("id").(string)
+	if archiveID == "" {
+		gc.respondWithError(ctx, fasthttp.StatusBadRequest, "ID de archivo requerido")
+		return
+	}
+
+	if gc.archiveService == nil {
+		gc.respondWithError(ctx, fasthttp.StatusServiceUnavailable, "El archivado de partidas no está habilitado")
+		return
+	}
+
+	archive, err := gc.archiveService.RestoreArchive(archiveID)
+	if errors.Is(err, services.ErrArchiveNotFound) {
+		gc.respondWithError(ctx, fasthttp.StatusNotFound, "Archivo de partida no encontrado")
+		return
+	}
+	if errors.Is(err, services.ErrArchiveInvalid) {
+		gc.respondWithError(ctx, fasthttp.StatusUnprocessableEntity, fmt.Sprintf("Archivo de partida inválido: %v", err))
+		return
+	}
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error restaurando archivo: %v", err))
+		return
+	}
+	recordAudit(gc.auditService, ctx, "restore-archive", map[string]interface{}{
+		"archiveId": archive.ID,
+	})
+
+	gc.respondWithSuccess(ctx, map[string]interface{}{
+		"archiveId":  archive.ID,
+		"reviewRoom": gc.archiveService.ReviewRoomName(archive.ID),
+		"createdAt":  archive.CreatedAt,
+		"sessions":   archive.Sessions,
+		"gameState":  archive.GameState,
+	}, fmt.Sprintf("Archivo restaurado con %d sesiones", len(archive.Sessions)))
+
+	log.Printf("♻️ Archivo %s restaurado desde el panel de administración", archive.ID)
+}
+
+// GetReviewRoom maneja GET /api/admin/archives/{id}/review: el estado y las
+// sesiones de un archivo restaurado
+func (gc *GameControlHandler) GetReviewRoom(ctx *fasthttp.RequestCtx) {
+	archiveID, _ := ctx.UserValue("id").(string)
+	if archiveID == "" {
+		gc.respondWithError(ctx, fasthttp.StatusBadRequest, "ID de archivo requerido")
+		return
+	}
+
+	if gc.archiveService == nil {
+		gc.respondWithError(ctx, fasthttp.StatusServiceUnavailable, "El archivado de partidas no está habilitado")
+		return
+	}
+
+	room, err := gc.archiveService.GetReviewRoom(archiveID)
+	if errors.Is(err, services.ErrReviewRoomNotFound) {
+		gc.respondWithError(ctx, fasthttp.StatusNotFound, "Sala de revisión no encontrada: restaura el archivo primero")
+		return
+	}
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error obteniendo sala de revisión: %v", err))
+		return
+	}
+
+	gc.respondWithSuccess(ctx, room, fmt.Sprintf("Sala de revisión con %d sesiones", len(room.Sessions)))
+}
+
+// GetDiagnostics maneja GET /api/admin/diagnostics: cantidad de claves de
+// Redis por tipo, para detectar fugas o inconsistencias
+func (gc *GameControlHandler) GetDiagnostics(ctx *fasthttp.RequestCtx) {
+	if gc.diagnostics == nil {
+		gc.respondWithError(ctx, fasthttp.StatusServiceUnavailable, "El diagnóstico no está habilitado")
+		return
+	}
+
+	diagnostics, err := gc.diagnostics.GetDiagnostics()
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error obteniendo diagnóstico: %v", err))
+		return
+	}
+
+	gc.respondWithSuccess(ctx, diagnostics, "Diagnóstico obtenido exitosamente")
+}
+
+// GetAuditLog maneja GET /api/admin/audit?offset=0&limit=50
+func (gc *GameControlHandler) GetAuditLog(ctx *fasthttp.RequestCtx) {
+	if gc.auditService == nil {
+		gc.respondWithError(ctx, fasthttp.StatusServiceUnavailable, "La auditoría no está habilitada")
+		return
+	}
+
+	offset := 0
+	if offsetStr := string(ctx.QueryArgs().Peek("offset")); offsetStr != "" {
+		n, err := strconv.Atoi(offsetStr)
+		if err != nil || n < 0 {
+			gc.respondWithError(ctx, fasthttp.StatusBadRequest, "Parámetro 'offset' debe ser un número no negativo")
+			return
+		}
+		offset = n
+	}
+
+	limit := 50
+	if limitStr := string(ctx.QueryArgs().Peek("limit")); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n < 1 || n > 500 {
+			gc.respondWithError(ctx, fasthttp.StatusBadRequest, "Parámetro 'limit' debe ser un número entre 1 y 500")
+			return
+		}
+		limit = n
+	}
+
+	entries, total, err := gc.auditService.List(offset, limit)
+	if err != nil {
+		gc.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error obteniendo auditoría: %v", err))
+		return
+	}
+
+	gc.respondWithSuccess(ctx, map[string]interface{}{
+		"entries": entries,
+		"total":   total,
+		"offset":  offset,
+		"limit":   limit,
+	}, fmt.Sprintf("%d entradas de auditoría obtenidas", len(entries)))
+}
+
 func (gc *GameControlHandler) respondWithError(ctx *fasthttp.RequestCtx, statusCode int, message string) {
 	response := models.APIResponse{
 		Success: false,
@@ -0,0 +1,1183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/backsoul/quiz/pkg/models"
+	"github.com/backsoul/quiz/pkg/redis"
+	"github.com/backsoul/quiz/pkg/services"
+	websocketHub "github.com/backsoul/quiz/pkg/websocket"
+	"github.com/fasthttp/websocket"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// testEnv servicios sobre un MemoryStore, un hub en marcha y el handler de
+// control del juego, como los arma main
+type testEnv struct {
+	store     *redis.MemoryStore
+	gameState *services.GameStateService
+	sessions  *services.SessionService
+	hub       *websocketHub.Hub
+	gc        *GameControlHandler
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	store := redis.NewMemoryStore()
+	gameState := services.NewGameStateService(store)
+	sessions := services.NewSessionService(store)
+	gameState.SetSessionService(sessions)
+	gameState.SetCacheTTL(0)
+
+	hub := websocketHub.NewHub()
+	go hub.Run()
+
+	return &testEnv{
+		store:     store,
+		gameState: gameState,
+		sessions:  sessions,
+		hub:       hub,
+		gc:        NewGameControlHandler(gameState, sessions, hub),
+	}
+}
+
+// dialRaw abre un WebSocket contra HandleWebSocket por un listener en memoria,
+// sin leer nada. Devuelve también la respuesta del upgrade (o del rechazo).
+func (e *testEnv) dialRaw(t *testing.T, query string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	ln := fasthttputil.NewInmemoryListener()
+	server := &fasthttp.Server{Handler: e.gc.HandleWebSocket}
+	go server.Serve(ln)
+	t.Cleanup(func() { ln.Close() })
+
+	dialer := websocket.Dialer{
+		NetDial: func(network, addr string) (net.Conn, error) {
+			return ln.Dial()
+		},
+	}
+	conn, resp, err := dialer.Dial("ws://quiz.test/ws?"+query, nil)
+	if err == nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, resp, err
+}
+
+// dial abre un WebSocket y lo devuelve ya registrado en el hub, con la
+// bienvenida leída
+func (e *testEnv) dial(t *testing.T, query string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := e.dialRaw(t, query)
+	if err != nil {
+		t.Fatalf("error conectando WebSocket: %v", err)
+	}
+
+	readMessage(t, conn, "welcome")
+
+	// La respuesta a resync llega desde ServeConn, cuando ya está registrada
+	writeCommand(t, conn, "resync", map[string]interface{}{})
+	readMessage(t, conn, "resyncState")
+	return conn
+}
+
+// writeCommand envía un comando {"type":...,"data":...} por el WebSocket
+func writeCommand(t *testing.T, conn *websocket.Conn, msgType string, data interface{}) {
+	t.Helper()
+	if err := conn.WriteJSON(map[string]interface{}{"type": msgType, "data": data}); err != nil {
+		t.Fatalf("error enviando %s: %v", msgType, err)
+	}
+}
+
+// readMessage lee mensajes hasta encontrar uno del tipo indicado y devuelve sus datos
+func readMessage(t *testing.T, conn *websocket.Conn, msgType string) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		var message struct {
+			Type string                 `json:"type"`
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("esperando %s: %v", msgType, err)
+		}
+		if message.Type == msgType {
+			return message.Data
+		}
+	}
+}
+
+// typesUntil lee mensajes hasta uno del tipo stop y devuelve los tipos leídos antes
+func typesUntil(t *testing.T, conn *websocket.Conn, stop string) []string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	var types []string
+	for {
+		var message struct {
+			Type string `json:"type"`
+		}
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("esperando %s: %v", stop, err)
+		}
+		if message.Type == stop {
+			return types
+		}
+		types = append(types, message.Type)
+	}
+}
+
+// expectNoMessage comprueba que no llegue ningún mensaje del tipo indicado en
+// el tiempo dado
+func expectNoMessage(t *testing.T, conn *websocket.Conn, msgType string, wait time.Duration) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(wait))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		var message struct {
+			Type string `json:"type"`
+		}
+		if err := conn.ReadJSON(&message); err != nil {
+			return
+		}
+		if message.Type == msgType {
+			t.Fatalf("no esperaba recibir %s", msgType)
+		}
+	}
+}
+
+// newRequestCtx arma una petición para llamar a un handler directamente
+func newRequestCtx(method, uri string, body string) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(method)
+	ctx.Request.SetRequestURI(uri)
+	if body != "" {
+		ctx.Request.Header.SetContentType("application/json")
+		ctx.Request.SetBodyString(body)
+	}
+	return ctx
+}
+
+// decodeResponse decodifica la respuesta estándar; data se decodifica en out si no es nil
+func decodeResponse(t *testing.T, ctx *fasthttp.RequestCtx, out interface{}) models.APIResponse {
+	t.Helper()
+	var response struct {
+		models.APIResponse
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(ctx.Response.Body(), &response); err != nil {
+		t.Fatalf("respuesta no es JSON (%d): %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	if out != nil && len(response.Data) > 0 {
+		if err := json.Unmarshal(response.Data, out); err != nil {
+			t.Fatalf("error decodificando data: %v", err)
+		}
+	}
+	return response.APIResponse
+}
+
+func TestAnnounceBroadcastsAndPersistsMessage(t *testing.T) {
+	env := newTestEnv(t)
+	if err := env.gameState.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+	conn := env.dial(t, "")
+
+	ctx := newRequestCtx("POST", "/api/game/announce", `{"message":"  Pausa técnica, volvemos en 5 min "}`)
+	env.gc.Announce(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	announcement := readMessage(t, conn, "announcement")
+	if announcement["message"] != "Pausa técnica, volvemos en 5 min" {
+		t.Fatalf("mensaje difundido inesperado: %v", announcement["message"])
+	}
+	if announcement["isActive"] != true {
+		t.Fatalf("el anuncio debería indicar la partida activa: %v", announcement)
+	}
+
+	gameState, err := env.gameState.GetGameState()
+	if err != nil {
+		t.Fatalf("error leyendo estado: %v", err)
+	}
+	if gameState.Message != "Pausa técnica, volvemos en 5 min" {
+		t.Fatalf("el mensaje no quedó guardado: %q", gameState.Message)
+	}
+	if !gameState.IsActive {
+		t.Fatalf("el anuncio no debe alterar el estado de la partida")
+	}
+}
+
+func TestAnnounceRequiresMessage(t *testing.T) {
+	env := newTestEnv(t)
+
+	ctx := newRequestCtx("POST", "/api/game/announce", `{"message":"   "}`)
+	env.gc.Announce(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusBadRequest {
+		t.Fatalf("esperaba 400 con mensaje vacío, obtuve %d", ctx.Response.StatusCode())
+	}
+}
+
+func TestWebSocketWelcomeIsFirstMessage(t *testing.T) {
+	env := newTestEnv(t)
+	if err := env.gameState.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+
+	conn, _, err := env.dialRaw(t, "")
+	if err != nil {
+		t.Fatalf("error conectando WebSocket: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var message struct {
+		Type string `json:"type"`
+		Data struct {
+			Room            string            `json:"room"`
+			ServerTime      string            `json:"serverTime"`
+			ServerTimeMs    int64             `json:"serverTimeMs"`
+			ProtocolVersion int               `json:"protocolVersion"`
+			GameState       *models.GameState `json:"gameState"`
+		} `json:"data"`
+	}
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatalf("error leyendo el primer mensaje: %v", err)
+	}
+
+	if message.Type != "welcome" {
+		t.Fatalf("el primer mensaje debe ser welcome, fue %s", message.Type)
+	}
+	if message.Data.Room != services.DefaultRoom || message.Data.ProtocolVersion != websocketHub.ProtocolVersion {
+		t.Fatalf("sala o versión inesperadas: %+v", message.Data)
+	}
+	if _, err := time.Parse(time.RFC3339, message.Data.ServerTime); err != nil || message.Data.ServerTimeMs == 0 {
+		t.Fatalf("hora del servidor inválida: %q", message.Data.ServerTime)
+	}
+	if message.Data.GameState == nil || !message.Data.GameState.IsActive {
+		t.Fatalf("la bienvenida debe incluir el estado de la partida activa: %+v", message.Data.GameState)
+	}
+}
+
+func TestListRooms(t *testing.T) {
+	env := newTestEnv(t)
+
+	listRooms := func() []models.RoomInfo {
+		t.Helper()
+		ctx := newRequestCtx("GET", "/api/admin/rooms", "")
+		env.gc.ListRooms(ctx)
+		if ctx.Response.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+		var data struct {
+			Rooms []models.RoomInfo `json:"rooms"`
+			Total int               `json:"total"`
+		}
+		decodeResponse(t, ctx, &data)
+		if data.Total != len(data.Rooms) {
+			t.Fatalf("total %d no coincide con %d partidas", data.Total, len(data.Rooms))
+		}
+		return data.Rooms
+	}
+
+	if rooms := listRooms(); len(rooms) != 0 {
+		t.Fatalf("sin partida en curso no debe haber salas: %+v", rooms)
+	}
+
+	if err := env.gameState.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+	for _, name := range []string{"Ana", "Luis"} {
+		if _, _, err := env.sessions.CreateSession(name, models.SessionModeLive, "", ""); err != nil {
+			t.Fatalf("error creando sesión: %v", err)
+		}
+	}
+	// El servidor maneja una sola partida: un registro ajeno se ignora
+	if err := env.store.AddToSet("rooms", "otra"); err != nil {
+		t.Fatalf("error registrando sala: %v", err)
+	}
+
+	rooms := listRooms()
+	if len(rooms) != 1 {
+		t.Fatalf("esperaba solo la partida principal, hay %+v", rooms)
+	}
+	room := rooms[0]
+	if room.ID != services.DefaultRoom || !room.IsActive || room.PlayerCount != 2 || room.CurrentQuestion != 1 {
+		t.Fatalf("sala inesperada: %+v", room)
+	}
+
+	if err := env.gameState.EndGame(); err != nil {
+		t.Fatalf("error terminando partida: %v", err)
+	}
+	if rooms := listRooms(); len(rooms) != 0 {
+		t.Fatalf("al terminar la partida sale del registro: %+v", rooms)
+	}
+}
+
+func TestAdminActionsAreAudited(t *testing.T) {
+	env := newTestEnv(t)
+	env.gc.SetAuditService(services.NewAuditService(env.store))
+
+	perform := func(handler fasthttp.RequestHandler, uri, body string) {
+		t.Helper()
+		ctx := newRequestCtx("POST", uri, body)
+		ctx.Request.Header.Set("X-Admin-Name", "Laura")
+		handler(ctx)
+		if ctx.Response.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("%s: esperaba 200, obtuve %d: %s", uri, ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+	}
+	perform(env.gc.StartGame, "/api/game/start", "")
+	perform(env.gc.Announce, "/api/game/announce", `{"message":"Bienvenidos"}`)
+	perform(env.gc.EndGame, "/api/game/end", "")
+
+	// Una acción rechazada no deja rastro
+	ctx := newRequestCtx("POST", "/api/game/announce", `{"message":""}`)
+	env.gc.Announce(ctx)
+
+	readAudit := func(query string) ([]models.AuditEntry, int) {
+		t.Helper()
+		ctx := newRequestCtx("GET", "/api/admin/audit"+query, "")
+		env.gc.GetAuditLog(ctx)
+		if ctx.Response.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+		var data struct {
+			Entries []models.AuditEntry `json:"entries"`
+			Total   int                 `json:"total"`
+		}
+		decodeResponse(t, ctx, &data)
+		return data.Entries, data.Total
+	}
+
+	entries, total := readAudit("?limit=2")
+	if total != 3 || len(entries) != 2 {
+		t.Fatalf("esperaba 2 de 3 entradas, obtuve %d de %d", len(entries), total)
+	}
+	if entries[0].Action != "end" || entries[1].Action != "announce" {
+		t.Fatalf("las entradas van de la más reciente a la más antigua: %+v", entries)
+	}
+	if entries[1].Params["message"] != "Bienvenidos" {
+		t.Fatalf("la entrada debe incluir los parámetros: %+v", entries[1])
+	}
+
+	entries, _ = readAudit("?offset=2")
+	if len(entries) != 1 || entries[0].Action != "start" {
+		t.Fatalf("la segunda página debe tener el inicio: %+v", entries)
+	}
+	if entries[0].Admin != "Laura" || entries[0].Timestamp.IsZero() {
+		t.Fatalf("la entrada debe registrar administrador y hora: %+v", entries[0])
+	}
+
+	for _, query := range []string{"?offset=-1", "?limit=0", "?limit=abc"} {
+		ctx := newRequestCtx("GET", "/api/admin/audit"+query, "")
+		env.gc.GetAuditLog(ctx)
+		if ctx.Response.StatusCode() != fasthttp.StatusBadRequest {
+			t.Fatalf("%s: esperaba 400, obtuve %d", query, ctx.Response.StatusCode())
+		}
+	}
+}
+
+// withQuestions carga n preguntas (plan en orden inverso) y habilita el servicio de preguntas
+func (e *testEnv) withQuestions(t *testing.T, n int) *services.QuestionService {
+	t.Helper()
+	loadTestQuestions(t, e.store, n)
+	questions := services.NewQuestionService(e.store)
+	e.gc.SetQuestionService(questions)
+	return questions
+}
+
+// answerAs crea la sesión del jugador y registra su respuesta a la pregunta number
+func (e *testEnv) answerAs(t *testing.T, playerName, mode string, number int, option string) *models.GameSession {
+	t.Helper()
+	session, _, err := e.sessions.CreateSession(playerName, mode, "", "")
+	if err != nil {
+		t.Fatalf("error creando sesión de %s: %v", playerName, err)
+	}
+	if option == "" {
+		return session
+	}
+	err = e.sessions.AddAnswer(session.ID, models.PlayerAnswer{
+		QuestionNumber: number,
+		SelectedOption: option,
+		CorrectOption:  "A",
+		IsCorrect:      option == "A",
+		Timestamp:      time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatalf("error registrando respuesta de %s: %v", playerName, err)
+	}
+	return session
+}
+
+func TestRevealIncludesDistribution(t *testing.T) {
+	env := newTestEnv(t)
+	env.withQuestions(t, 8)
+	t.Cleanup(env.gameState.StopTimerTicks)
+	if err := env.gameState.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+	if _, err := env.gameState.StartQuestion(1, 1); err != nil {
+		t.Fatalf("error iniciando pregunta: %v", err)
+	}
+
+	env.answerAs(t, "Ana", models.SessionModeLive, 1, "A")
+	env.answerAs(t, "Luis", models.SessionModeLive, 1, "A")
+	env.answerAs(t, "Marta", models.SessionModeLive, 1, "C")
+	env.answerAs(t, "Pedro", models.SessionModeLive, 1, "")         // aún no responde
+	env.answerAs(t, "Práctica", models.SessionModePractice, 1, "B") // la práctica no cuenta
+	conn := env.dial(t, "")
+
+	ctx := newRequestCtx("POST", "/api/game/reveal", "")
+	env.gc.RevealAnswer(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	reveal := readMessage(t, conn, "revealAnswer")
+	if reveal["correctOption"] != "A" || reveal["explanation"] != "Explicación 8" {
+		t.Fatalf("la revelación debe incluir respuesta y explicación de la pregunta 1 (ID 8): %v", reveal)
+	}
+	distribution, _ := reveal["distribution"].(map[string]interface{})
+	sum := 0.0
+	for _, count := range distribution {
+		sum += count.(float64)
+	}
+	if reveal["totalAnswers"] != float64(3) || sum != 3 {
+		t.Fatalf("la distribución debe sumar los 3 que respondieron: %v", reveal)
+	}
+	if distribution["A"] != float64(2) || distribution["C"] != float64(1) || distribution["B"] != nil {
+		t.Fatalf("distribución inesperada: %v", distribution)
+	}
+}
+
+func TestPeekAnswerIsAdminOnlyAndKeepsAnswersOpen(t *testing.T) {
+	env := newTestEnv(t)
+	env.withQuestions(t, 8)
+	t.Cleanup(env.gameState.StopTimerTicks)
+	if err := env.gameState.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+	if _, err := env.gameState.StartQuestion(1, 1); err != nil {
+		t.Fatalf("error iniciando pregunta: %v", err)
+	}
+	player := env.dial(t, "")
+
+	ctx := newRequestCtx("POST", "/api/game/peek-answer", "")
+	env.gc.PeekAnswer(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	var peek map[string]interface{}
+	decodeResponse(t, ctx, &peek)
+	if peek["correctOption"] != "A" || peek["questionId"] != float64(8) || peek["explanation"] != "Explicación 8" {
+		t.Fatalf("peek inesperado: %v", peek)
+	}
+
+	// Nada llega a los jugadores y la pregunta sigue abierta
+	env.hub.BroadcastMessage("marker", nil)
+	for _, msgType := range typesUntil(t, player, "marker") {
+		if msgType == "revealAnswer" {
+			t.Fatalf("peek no debe difundir la respuesta a los jugadores")
+		}
+	}
+	state, err := env.gameState.GetGameState()
+	if err != nil || !state.AnswersOpen || state.QuestionNumber != 1 {
+		t.Fatalf("peek no debe cerrar la pregunta: %+v (%v)", state, err)
+	}
+
+	// Sin partida activa no hay nada que espiar
+	if err := env.gameState.EndGame(); err != nil {
+		t.Fatalf("error terminando partida: %v", err)
+	}
+	ctx = newRequestCtx("POST", "/api/game/peek-answer", "")
+	env.gc.PeekAnswer(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusBadRequest {
+		t.Fatalf("sin partida esperaba 400, obtuve %d", ctx.Response.StatusCode())
+	}
+}
+
+func TestConcurrentEndGameClearsOnce(t *testing.T) {
+	env := newTestEnv(t)
+	if err := env.gameState.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+	env.answerAs(t, "Ana", models.SessionModeLive, 1, "")
+	env.answerAs(t, "Beto", models.SessionModeLive, 1, "")
+	conn := env.dial(t, "")
+
+	const callers = 2
+	responses := make([]*fasthttp.RequestCtx, callers)
+	var wg sync.WaitGroup
+	for i := range responses {
+		responses[i] = newRequestCtx("POST", "/api/game/end", "")
+		wg.Add(1)
+		go func(ctx *fasthttp.RequestCtx) {
+			defer wg.Done()
+			env.gc.EndGame(ctx)
+		}(responses[i])
+	}
+	wg.Wait()
+
+	var summaries []models.EndGameSummary
+	for _, ctx := range responses {
+		if ctx.Response.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("ambas llamadas deben responder 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+		var summary models.EndGameSummary
+		decodeResponse(t, ctx, &summary)
+		summaries = append(summaries, summary)
+	}
+	if summaries[0] != summaries[1] || summaries[0].TotalPlayers != 2 || !summaries[0].DataCleared {
+		t.Fatalf("ambas llamadas deben devolver el mismo resumen: %+v", summaries)
+	}
+
+	// Un solo aviso de fin: la limpieza se ejecutó una vez
+	env.hub.BroadcastMessage("marker", nil)
+	ended := 0
+	for _, msgType := range typesUntil(t, conn, "marker") {
+		if msgType == "gameEnded" {
+			ended++
+		}
+	}
+	if ended != 1 {
+		t.Fatalf("esperaba un solo gameEnded, hubo %d", ended)
+	}
+
+	// Un clic posterior también recibe el mismo resumen
+	ctx := newRequestCtx("POST", "/api/game/end", "")
+	env.gc.EndGame(ctx)
+	var again models.EndGameSummary
+	decodeResponse(t, ctx, &again)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK || again != summaries[0] {
+		t.Fatalf("un clic repetido debe devolver el resumen guardado: %d %+v", ctx.Response.StatusCode(), again)
+	}
+}
+
+// clearWatchStore avisa antes de borrar claves de sesión, para observar el
+// momento en que EndGame empieza a limpiar
+type clearWatchStore struct {
+	*redis.MemoryStore
+	beforeClear func()
+}
+
+func (s *clearWatchStore) Delete(keys ...string) error {
+	for _, key := range keys {
+		if strings.HasPrefix(key, "session:") && s.beforeClear != nil {
+			s.beforeClear()
+			s.beforeClear = nil
+		}
+	}
+	return s.MemoryStore.Delete(keys...)
+}
+
+func TestEndGameClearsAfterBroadcastIsDelivered(t *testing.T) {
+	store := &clearWatchStore{MemoryStore: redis.NewMemoryStore()}
+	gameState := services.NewGameStateService(store)
+	sessions := services.NewSessionService(store)
+	gameState.SetSessionService(sessions)
+	gameState.SetCacheTTL(0)
+	hub := websocketHub.NewHub()
+	go hub.Run()
+	env := &testEnv{store: store.MemoryStore, gameState: gameState, sessions: sessions, hub: hub, gc: NewGameControlHandler(gameState, sessions, hub)}
+
+	if err := gameState.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+	env.answerAs(t, "Ana", models.SessionModeLive, 1, "")
+	conns := []*websocket.Conn{env.dial(t, ""), env.dial(t, "")}
+
+	// Al empezar la limpieza, gameEnded ya debe estar escrito en cada conexión
+	cleared := false
+	delivered := 0
+	store.beforeClear = func() {
+		cleared = true
+		for _, conn := range conns {
+			conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+			for {
+				var message struct {
+					Type string `json:"type"`
+				}
+				if err := conn.ReadJSON(&message); err != nil {
+					break
+				}
+				if message.Type == "gameEnded" {
+					delivered++
+					break
+				}
+			}
+			conn.SetReadDeadline(time.Time{})
+		}
+	}
+
+	ctx := newRequestCtx("POST", "/api/game/end", "")
+	env.gc.EndGame(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	if !cleared {
+		t.Fatalf("EndGame debe limpiar las sesiones")
+	}
+	if delivered != len(conns) {
+		t.Fatalf("la limpieza empezó con gameEnded entregado a %d de %d clientes", delivered, len(conns))
+	}
+}
+
+func TestPlayerConnectionFlag(t *testing.T) {
+	env := newTestEnv(t)
+	env.gc.SetAdminCheck(func(ctx *fasthttp.RequestCtx) bool {
+		return string(ctx.QueryArgs().Peek("token")) == "secreto"
+	})
+	observer := env.dial(t, "role=admin&token=secreto")
+	session := env.answerAs(t, "Ana", models.SessionModeLive, 1, "")
+	token, err := env.sessions.IssueSessionToken(session.ID)
+	if err != nil {
+		t.Fatalf("error emitiendo token: %v", err)
+	}
+	query := "sessionId=" + session.ID + "&token=" + token
+
+	expectConnection := func(connected bool) {
+		t.Helper()
+		event := readMessage(t, observer, "playerConnection")
+		if event["sessionId"] != session.ID || event["connected"] != connected {
+			t.Fatalf("playerConnection inesperado: %v", event)
+		}
+		if stored, _ := env.sessions.GetSession(session.ID); stored.Connected != connected || stored.GameStatus != "active" {
+			t.Fatalf("la sesión debe quedar connected=%v y seguir activa: %+v", connected, stored)
+		}
+	}
+
+	// Un token inválido no asocia el WebSocket a la sesión
+	unassociated := env.dial(t, "sessionId="+session.ID+"&token=incorrecto")
+	env.hub.BroadcastMessage("marker", nil)
+	for _, msgType := range typesUntil(t, observer, "marker") {
+		if msgType == "playerConnection" {
+			t.Fatalf("un token inválido no debe marcar la sesión como conectada")
+		}
+	}
+	// Se cierra porque nadie la lee y el pipe en memoria tiene un búfer pequeño
+	unassociated.Close()
+
+	first := env.dial(t, query)
+	expectConnection(true)
+
+	// Una segunda pestaña no cambia nada; al cerrarse la primera sigue conectado
+	second := env.dial(t, query)
+	first.Close()
+	env.hub.BroadcastMessage("marker", nil)
+	for _, msgType := range typesUntil(t, observer, "marker") {
+		if msgType == "playerConnection" {
+			t.Fatalf("con otra conexión abierta no debe cambiar el estado")
+		}
+	}
+
+	second.Close()
+	expectConnection(false)
+
+	// El aviso es solo para administradores: otro jugador no lo recibe
+	player := env.dial(t, "")
+	env.dial(t, query)
+	expectConnection(true)
+	env.hub.BroadcastMessage("marker", nil)
+	for _, msgType := range typesUntil(t, player, "marker") {
+		if msgType == "playerConnection" {
+			t.Fatalf("playerConnection no debe llegar a los jugadores")
+		}
+	}
+}
+
+func TestGetLiveCombinesQuestionProgressAndTimer(t *testing.T) {
+	env := newTestEnv(t)
+	env.withQuestions(t, 8)
+	t.Cleanup(env.gameState.StopTimerTicks)
+	get := func() (*fasthttp.RequestCtx, models.LiveQuestion) {
+		t.Helper()
+		ctx := newRequestCtx("GET", "/api/admin/live", "")
+		env.gc.GetLive(ctx)
+		var live models.LiveQuestion
+		if ctx.Response.StatusCode() == fasthttp.StatusOK {
+			decodeResponse(t, ctx, &live)
+		}
+		return ctx, live
+	}
+
+	if ctx, _ := get(); ctx.Response.StatusCode() != fasthttp.StatusBadRequest {
+		t.Fatalf("sin partida: esperaba 400, obtuve %d", ctx.Response.StatusCode())
+	}
+	if err := env.gameState.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+	if ctx, _ := get(); ctx.Response.StatusCode() != fasthttp.StatusConflict {
+		t.Fatalf("sin pregunta en curso: esperaba 409, obtuve %d", ctx.Response.StatusCode())
+	}
+
+	env.gameState.SetQuestionTimer(services.QuestionTimer{Default: 30 * time.Second})
+	if _, err := env.gameState.StartQuestion(1, 1); err != nil {
+		t.Fatalf("error iniciando pregunta: %v", err)
+	}
+	env.answerAs(t, "Ana", models.SessionModeLive, 1, "A")
+	env.answerAs(t, "Luis", models.SessionModeLive, 1, "B")
+	env.answerAs(t, "Marta", models.SessionModeLive, 1, "A")
+	env.answerAs(t, "Pedro", models.SessionModeLive, 1, "")
+	env.answerAs(t, "Práctica", models.SessionModePractice, 1, "C")
+
+	ctx, live := get()
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	if !strings.Contains(string(ctx.Response.Body()), `"correctAnswer":"A"`) {
+		t.Fatalf("la vista de administración incluye la respuesta correcta: %s", ctx.Response.Body())
+	}
+
+	// La pregunta 1 del plan es el ID 8
+	if live.QuestionNumber != 1 || live.Question == nil || live.Question.ID != 8 || !live.AnswersOpen {
+		t.Fatalf("pregunta en curso inesperada: %+v", live)
+	}
+	if live.Deadline == nil || live.RemainingSeconds == nil || *live.RemainingSeconds <= 0 || *live.RemainingSeconds > 30 {
+		t.Fatalf("se esperaban plazo y segundos restantes: %+v", live)
+	}
+	if until := int(time.Until(*live.Deadline).Seconds()); *live.RemainingSeconds < until || *live.RemainingSeconds > until+1 {
+		t.Fatalf("los segundos restantes (%d) no concuerdan con el plazo (%d)", *live.RemainingSeconds, until)
+	}
+	sum := 0
+	for _, count := range live.Distribution {
+		sum += count
+	}
+	if live.Answered != 3 || live.Pending != 1 || sum != live.Answered || live.Distribution["A"] != 2 || live.Distribution["B"] != 1 {
+		t.Fatalf("avance inconsistente: %+v", live.AnswerProgress)
+	}
+}
+
+func TestResyncReturnsGameStateAndSession(t *testing.T) {
+	env := newTestEnv(t)
+	env.withQuestions(t, 8)
+	t.Cleanup(env.gameState.StopTimerTicks)
+	if err := env.gameState.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+	env.gameState.SetQuestionTimer(services.QuestionTimer{Default: 30 * time.Second})
+	if _, err := env.gameState.StartQuestion(1, 1); err != nil {
+		t.Fatalf("error iniciando pregunta: %v", err)
+	}
+	session := env.answerAs(t, "Ana", models.SessionModeLive, 1, "A")
+	token, err := env.sessions.IssueSessionToken(session.ID)
+	if err != nil {
+		t.Fatalf("error emitiendo token: %v", err)
+	}
+
+	// Una conexión nueva, sin sesión asociada, se identifica en el propio resync
+	conn := env.dial(t, "")
+	writeCommand(t, conn, "resync", map[string]interface{}{"sessionId": session.ID, "token": token})
+	state := readMessage(t, conn, "resyncState")
+
+	gameState, _ := state["gameState"].(map[string]interface{})
+	if gameState["isActive"] != true || gameState["questionNumber"] != float64(1) {
+		t.Fatalf("resyncState sin el estado del juego: %v", state)
+	}
+	player, _ := state["session"].(map[string]interface{})
+	if player["id"] != session.ID || player["playerName"] != "Ana" {
+		t.Fatalf("resyncState sin la sesión del jugador: %v", state)
+	}
+	question, _ := state["currentQuestion"].(map[string]interface{})
+	if question["id"] != float64(8) || question["correctAnswer"] != nil {
+		t.Fatalf("la pregunta en curso debe ir sin la respuesta correcta: %v", question)
+	}
+	if remaining, ok := state["remainingSeconds"].(float64); !ok || remaining <= 0 || remaining > 30 {
+		t.Fatalf("resyncState sin temporizador: %v", state)
+	}
+	if _, ok := state["leaderboard"]; !ok {
+		t.Fatalf("resyncState sin tabla de posiciones: %v", state)
+	}
+
+	// Con un token ajeno no se entrega la sesión, pero sí el resto del estado
+	writeCommand(t, conn, "resync", map[string]interface{}{"sessionId": session.ID, "token": "otro"})
+	state = readMessage(t, conn, "resyncState")
+	if _, ok := state["session"]; ok || state["gameState"] == nil {
+		t.Fatalf("con token inválido no debe incluirse la sesión: %v", state)
+	}
+}
+
+func TestLastEliminationTriggersAutoEndAction(t *testing.T) {
+	for _, action := range []string{"off", "round", "end"} {
+		env := newTestEnv(t)
+		questions := env.withQuestions(t, 8)
+		env.gc.SetAutoEndAction(action)
+		t.Cleanup(env.gameState.StopTimerTicks)
+
+		sessionHandler := NewSessionHandler(env.sessions, questions, env.hub)
+		sessionHandler.SetGameStateService(env.gameState)
+		sessionHandler.OnSessionOver(env.gc.CheckRoundComplete)
+		players := &sessionEnv{store: env.store, sessions: env.sessions, questions: questions, gameState: env.gameState, hub: env.hub, h: sessionHandler}
+
+		if err := env.gameState.StartGame(); err != nil {
+			t.Fatalf("%s: error iniciando partida: %v", action, err)
+		}
+		if _, err := env.gameState.StartQuestion(1, 1); err != nil {
+			t.Fatalf("%s: error iniciando pregunta: %v", action, err)
+		}
+		ana, anaToken := players.createSession(t, "Ana")
+		luis, luisToken := players.createSession(t, "Luis")
+		conn := env.dial(t, "")
+
+		// eliminate responde mal y espera a que la sesión quede eliminada
+		eliminate := func(session *models.GameSession, token string) {
+			t.Helper()
+			body := fmt.Sprintf(`{"questionId":%d,"selectedOption":"B"}`, session.CurrentQuestionID)
+			ctx := players.call(sessionHandler.SubmitAnswer, session.ID, token, body)
+			if ctx.Response.StatusCode() != fasthttp.StatusOK {
+				t.Fatalf("%s: esperaba 200, obtuve %d: %s", action, ctx.Response.StatusCode(), ctx.Response.Body())
+			}
+			if stored, _ := env.sessions.GetSession(session.ID); stored.GameStatus == "active" {
+				t.Fatalf("%s: %s debería quedar eliminado", action, session.PlayerName)
+			}
+		}
+
+		// expectNoRoundComplete comprueba, tras una comprobación explícita, que
+		// no se avisó el fin de la ronda
+		expectNoRoundComplete := func() {
+			t.Helper()
+			env.gc.CheckRoundComplete()
+			env.hub.BroadcastMessage("marker", nil)
+			for _, msgType := range typesUntil(t, conn, "marker") {
+				if msgType == "roundComplete" {
+					t.Fatalf("%s: no esperaba roundComplete", action)
+				}
+			}
+		}
+
+		// Mientras quede un jugador activo la ronda sigue
+		eliminate(ana, anaToken)
+		expectNoRoundComplete()
+
+		eliminate(luis, luisToken)
+		if action == "off" {
+			expectNoRoundComplete()
+			if active, _ := env.gameState.IsGameActive(); !active {
+				t.Fatalf("off: la partida debe seguir activa")
+			}
+			continue
+		}
+
+		data := readMessage(t, conn, "roundComplete")
+		if data["action"] != action || data["totalPlayers"] != float64(2) {
+			t.Fatalf("%s: roundComplete inesperado: %v", action, data)
+		}
+
+		if action == "round" {
+			// Comprobarlo de nuevo no repite el aviso ni termina la partida
+			expectNoRoundComplete()
+			if active, _ := env.gameState.IsGameActive(); !active {
+				t.Fatalf("round: la partida debe seguir activa")
+			}
+			if _, err := env.sessions.GetSession(luis.ID); err != nil {
+				t.Fatalf("round: los datos de la partida deben conservarse: %v", err)
+			}
+			continue
+		}
+
+		readMessage(t, conn, "gameEnded")
+		if active, _ := env.gameState.IsGameActive(); active {
+			t.Fatalf("end: la partida debe terminar")
+		}
+	}
+}
+
+func TestSendPlayerMessageReachesOnlyTargetedSocket(t *testing.T) {
+	env := newTestEnv(t)
+	// connect abre un WebSocket asociado a la sesión del jugador
+	connect := func(playerName string) (*models.GameSession, *websocket.Conn) {
+		t.Helper()
+		session := env.answerAs(t, playerName, models.SessionModeLive, 1, "")
+		token, err := env.sessions.IssueSessionToken(session.ID)
+		if err != nil {
+			t.Fatalf("error emitiendo token: %v", err)
+		}
+		return session, env.dial(t, "sessionId="+session.ID+"&token="+token)
+	}
+	send := func(sessionID, body string) *fasthttp.RequestCtx {
+		ctx := newRequestCtx("POST", "/api/admin/players/"+sessionID+"/message", body)
+		ctx.SetUserValue("id", sessionID)
+		env.gc.SendPlayerMessage(ctx)
+		return ctx
+	}
+
+	ana, anaConn := connect("Ana")
+	_, luisConn := connect("Luis")
+	observer := env.dial(t, "")
+
+	ctx := send(ana.ID, `{"text":"  Por favor recarga la página  "}`)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	var result map[string]interface{}
+	decodeResponse(t, ctx, &result)
+	if result["delivered"] != float64(1) {
+		t.Fatalf("esperaba una entrega: %v", result)
+	}
+
+	message := readMessage(t, anaConn, "adminMessage")
+	if message["sessionId"] != ana.ID || message["text"] != "Por favor recarga la página" {
+		t.Fatalf("adminMessage inesperado: %v", message)
+	}
+	env.hub.BroadcastMessage("marker", nil)
+	for _, conn := range []*websocket.Conn{luisConn, observer} {
+		for _, msgType := range typesUntil(t, conn, "marker") {
+			if msgType == "adminMessage" {
+				t.Fatalf("el mensaje solo debe llegar al jugador indicado")
+			}
+		}
+	}
+
+	// Sin texto, sin sesión o sin WebSocket conectado no se envía nada
+	if ctx := send(ana.ID, `{"text":"  "}`); ctx.Response.StatusCode() != fasthttp.StatusBadRequest {
+		t.Fatalf("sin texto: esperaba 400, obtuve %d", ctx.Response.StatusCode())
+	}
+	if ctx := send("6f1c2a9e-0000-4000-8000-000000000000", `{"text":"Hola"}`); ctx.Response.StatusCode() != fasthttp.StatusNotFound {
+		t.Fatalf("sesión inexistente: esperaba 404, obtuve %d", ctx.Response.StatusCode())
+	}
+	pedro := env.answerAs(t, "Pedro", models.SessionModeLive, 1, "")
+	if ctx := send(pedro.ID, `{"text":"Hola"}`); ctx.Response.StatusCode() != fasthttp.StatusNotFound {
+		t.Fatalf("sin WebSocket conectado: esperaba 404, obtuve %d", ctx.Response.StatusCode())
+	}
+}
+
+func TestClockRemainingTimeTracksDeadline(t *testing.T) {
+	env := newTestEnv(t)
+	t.Cleanup(env.gameState.StopTimerTicks)
+	clock := func() map[string]interface{} {
+		t.Helper()
+		ctx := newRequestCtx("GET", "/api/game/clock", "")
+		env.gc.GetClock(ctx)
+		if ctx.Response.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+		var data map[string]interface{}
+		decodeResponse(t, ctx, &data)
+
+		serverTime, err := time.Parse(time.RFC3339, data["serverTime"].(string))
+		if err != nil {
+			t.Fatalf("serverTime no es RFC3339: %v", data["serverTime"])
+		}
+		if diff := data["serverTimeMs"].(float64) - float64(serverTime.UnixMilli()); diff < 0 || diff >= 1000 {
+			t.Fatalf("serverTime y serverTimeMs no concuerdan: %v", data)
+		}
+		return data
+	}
+
+	// Sin pregunta en curso solo se informa la hora del servidor
+	if data := clock(); data["remainingMs"] != nil || data["deadline"] != nil {
+		t.Fatalf("sin partida no debe haber plazo: %v", data)
+	}
+
+	if err := env.gameState.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+	env.gameState.SetQuestionTimer(services.QuestionTimer{Default: 600 * time.Millisecond})
+	if _, err := env.gameState.StartQuestion(1, 1); err != nil {
+		t.Fatalf("error iniciando pregunta: %v", err)
+	}
+
+	first := clock()
+	time.Sleep(150 * time.Millisecond)
+	second := clock()
+
+	for _, data := range []map[string]interface{}{first, second} {
+		if data["questionNumber"] != float64(1) {
+			t.Fatalf("pregunta inesperada: %v", data)
+		}
+		if remaining := data["deadlineMs"].(float64) - data["serverTimeMs"].(float64); data["remainingMs"].(float64) != remaining {
+			t.Fatalf("remainingMs debe ser deadlineMs - serverTimeMs: %v", data)
+		}
+	}
+	if first["deadline"] != second["deadline"] || first["deadlineMs"] != second["deadlineMs"] {
+		t.Fatalf("el plazo no debe moverse: %v / %v", first, second)
+	}
+	elapsed := second["serverTimeMs"].(float64) - first["serverTimeMs"].(float64)
+	if decrease := first["remainingMs"].(float64) - second["remainingMs"].(float64); decrease != elapsed || decrease < 150 {
+		t.Fatalf("lo restante debe bajar lo mismo que avanza el reloj: bajó %v, pasaron %v", decrease, elapsed)
+	}
+
+	// Vencido el plazo lo restante queda en cero, nunca negativo
+	time.Sleep(500 * time.Millisecond)
+	if data := clock(); data["remainingMs"] != float64(0) {
+		t.Fatalf("tras el plazo esperaba 0 ms restantes: %v", data)
+	}
+}
+
+func TestConsideringSummaryIsAnonymousAndAdminOnly(t *testing.T) {
+	env := newTestEnv(t)
+	env.withQuestions(t, 8)
+	env.gc.SetAdminCheck(func(ctx *fasthttp.RequestCtx) bool {
+		return string(ctx.QueryArgs().Peek("token")) == "secreto"
+	})
+	t.Cleanup(env.gameState.StopTimerTicks)
+	if err := env.gameState.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+	if _, err := env.gameState.StartQuestion(1, 1); err != nil {
+		t.Fatalf("error iniciando pregunta: %v", err)
+	}
+
+	admin := env.dial(t, "role=admin&token=secreto")
+	players := make([]*websocket.Conn, 0, 3)
+	for _, playerName := range []string{"Ana", "Luis", "Pedro"} {
+		session := env.answerAs(t, playerName, models.SessionModeLive, 1, "")
+		token, err := env.sessions.IssueSessionToken(session.ID)
+		if err != nil {
+			t.Fatalf("error emitiendo token: %v", err)
+		}
+		players = append(players, env.dial(t, "sessionId="+session.ID+"&token="+token))
+	}
+	anonymous := env.dial(t, "")
+
+	consider := func(conn *websocket.Conn, option string) {
+		writeCommand(t, conn, "considering", map[string]interface{}{"option": option})
+	}
+	consider(players[0], "B")
+	consider(players[1], "B")
+	consider(players[0], "C") // cambia de opinión
+	consider(players[2], "Z") // no es una opción de la pregunta
+	consider(anonymous, "C")  // sin sesión asociada no cuenta
+
+	summary := readMessage(t, admin, "consideringSummary")
+	counts, _ := summary["counts"].(map[string]interface{})
+	if summary["questionNumber"] != float64(1) || summary["total"] != float64(2) || counts["B"] != float64(1) || counts["C"] != float64(1) || len(counts) != 2 {
+		t.Fatalf("resumen inesperado: %v", summary)
+	}
+	for key := range summary {
+		if key != "questionNumber" && key != "counts" && key != "total" && key != "timestamp" {
+			t.Fatalf("el resumen no debe identificar jugadores (%s): %v", key, summary)
+		}
+	}
+
+	// La ráfaga produce un solo resumen y nunca llega a los jugadores
+	time.Sleep(consideringDebounce + 200*time.Millisecond)
+	env.hub.BroadcastMessage("marker", nil)
+	for _, conn := range append(players, anonymous, admin) {
+		for _, msgType := range typesUntil(t, conn, "marker") {
+			if msgType == "consideringSummary" {
+				t.Fatalf("resumen inesperado: debe ser uno solo y solo para administradores")
+			}
+		}
+	}
+}
+
+func TestFinishAllPlayersBroadcastsStandings(t *testing.T) {
+	env := newTestEnv(t)
+	conn := env.dial(t, "")
+	env.answerAs(t, "Ana", models.SessionModeLive, 1, "A")
+	env.answerAs(t, "Luis", models.SessionModeLive, 1, "")
+
+	ctx := newRequestCtx("POST", "/api/game/finish-all", "")
+	env.gc.FinishAllPlayers(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	event := readMessage(t, conn, "gameFinished")
+	standings, _ := event["leaderboard"].([]interface{})
+	if event["finished"] != float64(2) || len(standings) != 2 {
+		t.Fatalf("gameFinished inesperado: %v", event)
+	}
+	for _, row := range standings {
+		if status := row.(map[string]interface{})["status"]; status != "finished" {
+			t.Fatalf("todos deben figurar como terminados: %v", standings)
+		}
+	}
+}
+
+func TestGetDiagnosticsCountsKeysByPrefix(t *testing.T) {
+	env := newTestEnv(t)
+
+	ctx := newRequestCtx("GET", "/api/admin/diagnostics", "")
+	env.gc.GetDiagnostics(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusServiceUnavailable {
+		t.Fatalf("sin servicio de diagnóstico esperaba 503, obtuve %d", ctx.Response.StatusCode())
+	}
+
+	env.gc.SetDiagnosticsService(services.NewDiagnosticsService(env.store))
+	env.withQuestions(t, 8)
+	for i := 1; i <= 3; i++ {
+		id := fmt.Sprintf("s%d", i)
+		env.store.Set("session:"+id, "{}", 0)
+		env.store.Set("session_token:"+id, "token", 0)
+	}
+	env.store.Set("player_sessions:ana", "s1", 0)
+	env.store.Set("player_sessions:luis", "s2", 0)
+	env.store.Set("archive:1", "{}", 0)
+	// Un miembro colgante en active_sessions y una sesión corrupta
+	env.store.AddToSet("active_sessions", "s1")
+	env.store.AddToSet("active_sessions", "colgante")
+	env.store.AddToSet("corrupt_sessions", "s3")
+
+	ctx = newRequestCtx("GET", "/api/admin/diagnostics", "")
+	env.gc.GetDiagnostics(ctx)
+	var got models.StoreDiagnostics
+	decodeResponse(t, ctx, &got)
+
+	want := models.StoreDiagnostics{
+		Sessions:        3,
+		SessionTokens:   3,
+		PlayerSessions:  2,
+		ActiveSessions:  2,
+		CorruptSessions: 1,
+		Questions:       8,
+		Archives:        1,
+		GeneratedAt:     got.GeneratedAt,
+	}
+	if got != want {
+		t.Fatalf("diagnóstico inesperado:\n obtuve %+v\nesperaba %+v", got, want)
+	}
+	if got.GeneratedAt == "" {
+		t.Fatal("el diagnóstico debe indicar cuándo se generó")
+	}
+}
+
+func TestRestoredArchiveCanBeReviewed(t *testing.T) {
+	env := newTestEnv(t)
+	call := func(handler fasthttp.RequestHandler, method, archiveID string) *fasthttp.RequestCtx {
+		ctx := newRequestCtx(method, "/api/admin/archives/"+archiveID, "")
+		ctx.SetUserValue("id", archiveID)
+		handler(ctx)
+		return ctx
+	}
+
+	if ctx := call(env.gc.GetReviewRoom, "GET", "a1"); ctx.Response.StatusCode() != fasthttp.StatusServiceUnavailable {
+		t.Fatalf("sin archivado esperaba 503, obtuve %d", ctx.Response.StatusCode())
+	}
+	archives := services.NewArchiveService(env.store)
+	env.gc.SetArchiveService(archives)
+
+	ana := env.answerAs(t, "Ana", models.SessionModeLive, 1, "A")
+	active, _ := env.sessions.GetActiveSessions()
+	archive, err := archives.CreateArchive(&models.GameState{IsActive: true}, active)
+	if err != nil {
+		t.Fatalf("error archivando: %v", err)
+	}
+
+	if ctx := call(env.gc.GetReviewRoom, "GET", archive.ID); ctx.Response.StatusCode() != fasthttp.StatusNotFound {
+		t.Fatalf("sin restaurar esperaba 404, obtuve %d", ctx.Response.StatusCode())
+	}
+
+	ctx := call(env.gc.RestoreArchive, "POST", archive.ID)
+	var restored struct {
+		ReviewRoom string `json:"reviewRoom"`
+	}
+	decodeResponse(t, ctx, &restored)
+
+	ctx = call(env.gc.GetReviewRoom, "GET", archive.ID)
+	var room models.ReviewRoom
+	decodeResponse(t, ctx, &room)
+	if room.Room != restored.ReviewRoom || room.ArchiveID != archive.ID || room.GameState == nil || room.GameState.IsActive {
+		t.Fatalf("sala de revisión inesperada: %+v (restaurada como %s)", room, restored.ReviewRoom)
+	}
+	if len(room.Sessions) != 1 || room.Sessions[0].ID != ana.ID || room.Sessions[0].PlayerName != "Ana" {
+		t.Fatalf("la sesión archivada debe verse en la sala: %+v", room.Sessions)
+	}
+}
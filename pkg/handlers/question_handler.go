@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 
@@ -14,6 +15,8 @@ import (
 type QuestionHandler struct {
 	questionService *services.QuestionService
 	sessionService  *services.SessionService
+	questionsFiles  []string
+	auditService    *services.AuditService
 }
 
 // NewQuestionHandler crea una nueva instancia del handler
@@ -21,9 +24,20 @@ func NewQuestionHandler(questionService *services.QuestionService, sessionServic
 	return &QuestionHandler{
 		questionService: questionService,
 		sessionService:  sessionService,
+		questionsFiles:  []string{"answers.json"},
 	}
 }
 
+// SetAuditService habilita el registro de auditoría de las acciones de administración
+func (h *QuestionHandler) SetAuditService(auditService *services.AuditService) {
+	h.auditService = auditService
+}
+
+// SetQuestionsFiles configura los archivos desde los que se recargan las preguntas
+func (h *QuestionHandler) SetQuestionsFiles(questionsFiles []string) {
+	h.questionsFiles = questionsFiles
+}
+
 // respondWithJSON envía una respuesta JSON
 func (h *QuestionHandler) respondWithJSON(ctx *fasthttp.RequestCtx, statusCode int, response interface{}) {
 	ctx.Response.Header.Set("Content-Type", "application/json")
@@ -66,18 +80,64 @@ func (h *QuestionHandler) GetAllQuestions(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	count := len(questions)
 	responseData := models.QuestionResponse{
 		Questions: questions,
-		Count:     len(questions),
+		Returned:  &count,
+		Total:     &count,
 	}
 
 	h.respondWithSuccess(ctx, responseData, "Preguntas obtenidas exitosamente")
 }
 
+// SearchQuestions maneja GET /api/questions/search?difficulty=3&category=history&q=war&limit=10&offset=0
+func (h *QuestionHandler) SearchQuestions(ctx *fasthttp.RequestCtx) {
+	args := ctx.QueryArgs()
+	criteria := models.QuestionSearchCriteria{
+		Category: string(args.Peek("category")),
+		Text:     string(args.Peek("q")),
+	}
+
+	intParams := []struct {
+		name   string
+		target *int
+	}{
+		{"difficulty", &criteria.Difficulty},
+		{"limit", &criteria.Limit},
+		{"offset", &criteria.Offset},
+	}
+	for _, param := range intParams {
+		value := string(args.Peek(param.name))
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			h.respondWithError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("Parámetro '%s' debe ser un número no negativo", param.name))
+			return
+		}
+		*param.target = n
+	}
+
+	questions, total, err := h.questionService.SearchQuestions(criteria)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error buscando preguntas: %v", err))
+		return
+	}
+
+	h.respondWithSuccess(ctx, map[string]interface{}{
+		"questions": questions,
+		"total":     total,
+		"returned":  len(questions),
+		"limit":     criteria.Limit,
+		"offset":    criteria.Offset,
+	}, fmt.Sprintf("%d de %d preguntas encontradas", len(questions), total))
+}
+
 // GetQuestion maneja GET /api/questions/{id}
 func (h *QuestionHandler) GetQuestion(ctx *fasthttp.RequestCtx) {
 	// Obtener el ID de los parámetros de la URL
-	idStr := ctx.UserValue("id").(string)
+	idStr, _ := ctx.UserValue("id").(string)
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		h.respondWithError(ctx, fasthttp.StatusBadRequest, "ID de pregunta inválido")
@@ -97,9 +157,74 @@ func (h *QuestionHandler) GetQuestion(ctx *fasthttp.RequestCtx) {
 	h.respondWithSuccess(ctx, responseData, "Pregunta obtenida exitosamente")
 }
 
+// PatchQuestion maneja PATCH /api/questions/{id}: aplica solo los campos
+// enviados (p. ej. corregir una opción o la dificultad) y guarda el resultado
+func (h *QuestionHandler) PatchQuestion(ctx *fasthttp.RequestCtx) {
+	idStr, _ := ctx.UserValue("id").(string)
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, "ID de pregunta inválido")
+		return
+	}
+
+	var patch models.QuestionPatch
+	if err := json.Unmarshal(ctx.PostBody(), &patch); err != nil {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, "JSON inválido")
+		return
+	}
+
+	if _, err := h.questionService.GetQuestion(id); err != nil {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Pregunta no encontrada: %v", err))
+		return
+	}
+
+	question, err := h.questionService.PatchQuestion(id, patch)
+	if errors.Is(err, services.ErrInvalidQuestion) || errors.Is(err, services.ErrDifficultyOutOfRange) {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error actualizando pregunta: %v", err))
+		return
+	}
+	recordAudit(h.auditService, ctx, "patch-question", map[string]interface{}{
+		"questionId": id,
+	})
+
+	h.respondWithSuccess(ctx, models.QuestionResponse{
+		Question: question,
+	}, "Pregunta actualizada exitosamente")
+}
+
+// GetQuestionByNumber maneja GET /api/game/question/{number}
+func (h *QuestionHandler) GetQuestionByNumber(ctx *fasthttp.RequestCtx) {
+	numberStr, _ := ctx.UserValue("number").(string)
+	number, err := strconv.Atoi(numberStr)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, "Número de pregunta inválido")
+		return
+	}
+
+	question, err := h.questionService.GetQuestionByNumber(number)
+	if errors.Is(err, services.ErrQuestionOutOfPlan) {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("La pregunta %d no está en el plan de la partida", number))
+		return
+	}
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error obteniendo pregunta: %v", err))
+		return
+	}
+
+	h.respondWithSuccess(ctx, question.Public(number), fmt.Sprintf("Pregunta %d obtenida exitosamente", number))
+}
+
 // GetRandomQuestion maneja GET /api/questions/random
 func (h *QuestionHandler) GetRandomQuestion(ctx *fasthttp.RequestCtx) {
 	question, err := h.questionService.GetRandomQuestion()
+	if errors.Is(err, services.ErrNoUnseenQuestions) {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, "Ya se mostraron todas las preguntas en esta partida")
+		return
+	}
 	if err != nil {
 		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error obteniendo pregunta aleatoria: %v", err))
 		return
@@ -141,9 +266,11 @@ func (h *QuestionHandler) GetQuestionsByDifficulty(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	count := len(questions)
 	responseData := models.QuestionResponse{
 		Questions: questions,
-		Count:     len(questions),
+		Returned:  &count,
+		Total:     &count,
 	}
 
 	h.respondWithSuccess(ctx, responseData, fmt.Sprintf("Preguntas de dificultad %d-%d obtenidas exitosamente", min, max))
@@ -173,6 +300,10 @@ func (h *QuestionHandler) GetRandomQuestionByDifficulty(ctx *fasthttp.RequestCtx
 	}
 
 	question, err := h.questionService.GetRandomQuestionByDifficulty(min, max)
+	if errors.Is(err, services.ErrNoUnseenQuestions) {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Ya se mostraron todas las preguntas de dificultad %d-%d en esta partida", min, max))
+		return
+	}
 	if err != nil {
 		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Error obteniendo pregunta aleatoria por dificultad: %v", err))
 		return
@@ -201,7 +332,7 @@ func (h *QuestionHandler) GetQuestionMetadata(ctx *fasthttp.RequestCtx) {
 
 	responseData := models.QuestionResponse{
 		Metadata: metadata,
-		Count:    count,
+		Total:    &count,
 	}
 
 	h.respondWithSuccess(ctx, responseData, "Metadatos obtenidos exitosamente")
@@ -209,7 +340,7 @@ func (h *QuestionHandler) GetQuestionMetadata(ctx *fasthttp.RequestCtx) {
 
 // ReloadQuestions maneja POST /api/questions/reload
 func (h *QuestionHandler) ReloadQuestions(ctx *fasthttp.RequestCtx) {
-	err := h.questionService.ReloadQuestions("answers.json")
+	err := h.questionService.ReloadQuestions(h.questionsFiles...)
 	if err != nil {
 		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error recargando preguntas: %v", err))
 		return
@@ -240,12 +371,23 @@ func (h *QuestionHandler) GetCurrentQuestionInfo(ctx *fasthttp.RequestCtx) {
 	questionIDStats := make(map[int][]models.GameSession)
 	activePlayers := []models.GameSession{}
 
+	// Sesiones antiguas pueden no tener CurrentQuestionID; se resuelve con el plan
+	plan, planErr := h.questionService.GetQuestionPlan()
+	if planErr != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error obteniendo plan de preguntas: %v", planErr))
+		return
+	}
+
 	for _, session := range sessions {
 		if session.GameStatus == "active" {
 			activePlayers = append(activePlayers, session)
 			questionStats[session.CurrentQuestion] = append(questionStats[session.CurrentQuestion], session)
-			if session.CurrentQuestionID > 0 {
-				questionIDStats[session.CurrentQuestionID] = append(questionIDStats[session.CurrentQuestionID], session)
+			questionID := session.CurrentQuestionID
+			if questionID == 0 && session.CurrentQuestion >= 1 && session.CurrentQuestion <= len(plan) {
+				questionID = plan[session.CurrentQuestion-1]
+			}
+			if questionID > 0 {
+				questionIDStats[questionID] = append(questionIDStats[questionID], session)
 			}
 		}
 	}
@@ -275,24 +417,16 @@ func (h *QuestionHandler) GetCurrentQuestionInfo(ctx *fasthttp.RequestCtx) {
 		}
 	}
 
-	// Si no hay questionID válido, usar una pregunta por defecto
-	var question *models.Question
-	if mostCommonQuestionID > 0 {
-		questionResult, questionErr := h.questionService.GetQuestion(mostCommonQuestionID)
-		if questionErr != nil {
-			h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Error obteniendo pregunta %d: %v", mostCommonQuestionID, questionErr))
-			return
-		}
-		question = questionResult
-	} else {
-		// Fallback: obtener la primera pregunta disponible
-		questions, questionsErr := h.questionService.GetAllQuestions()
-		if questionsErr != nil || len(questions) == 0 {
-			h.respondWithError(ctx, fasthttp.StatusInternalServerError, "No hay preguntas disponibles")
-			return
-		}
-		question = &questions[0]
-		mostCommonQuestionNumber = 1
+	// Todos los jugadores activos están fuera del plan (juego completado)
+	if mostCommonQuestionID == 0 {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, "No hay pregunta actual en el plan de la partida")
+		return
+	}
+
+	question, questionErr := h.questionService.GetQuestion(mostCommonQuestionID)
+	if questionErr != nil {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Error obteniendo pregunta %d: %v", mostCommonQuestionID, questionErr))
+		return
 	}
 
 	// Preparar respuesta
@@ -312,6 +446,164 @@ func (h *QuestionHandler) GetCurrentQuestionInfo(ctx *fasthttp.RequestCtx) {
 	h.respondWithSuccess(ctx, responseData, fmt.Sprintf("Pregunta %d activa para %d jugadores", mostCommonQuestionNumber, maxCount))
 }
 
+// CalibrateDifficulty maneja POST /api/admin/questions/calibrate?apply=true&minAttempts=5
+func (h *QuestionHandler) CalibrateDifficulty(ctx *fasthttp.RequestCtx) {
+	apply := string(ctx.QueryArgs().Peek("apply")) == "true"
+
+	minAttempts := 5
+	if minStr := string(ctx.QueryArgs().Peek("minAttempts")); minStr != "" {
+		n, err := strconv.Atoi(minStr)
+		if err != nil || n < 1 {
+			h.respondWithError(ctx, fasthttp.StatusBadRequest, "Parámetro 'minAttempts' debe ser un número positivo")
+			return
+		}
+		minAttempts = n
+	}
+
+	stats, err := h.sessionService.GetQuestionStats()
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error obteniendo estadísticas: %v", err))
+		return
+	}
+
+	suggestions, err := h.questionService.CalibrateDifficulties(stats, minAttempts, apply)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error calibrando dificultades: %v", err))
+		return
+	}
+	if apply {
+		recordAudit(h.auditService, ctx, "calibrate-difficulty", map[string]interface{}{
+			"minAttempts": minAttempts,
+			"updated":     len(suggestions),
+		})
+	}
+
+	h.respondWithSuccess(ctx, map[string]interface{}{
+		"suggestions": suggestions,
+		"applied":     apply,
+		"minAttempts": minAttempts,
+	}, fmt.Sprintf("%d preguntas calibradas", len(suggestions)))
+}
+
+// ResetQuestionStats maneja POST /api/admin/questions/reset-stats
+func (h *QuestionHandler) ResetQuestionStats(ctx *fasthttp.RequestCtx) {
+	if err := h.questionService.ResetPlayCounts(); err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error reiniciando conteo de jugadas: %v", err))
+		return
+	}
+	recordAudit(h.auditService, ctx, "reset-question-stats", nil)
+
+	h.respondWithSuccess(ctx, map[string]interface{}{
+		"timestamp": models.Timestamp(),
+	}, "Conteo de jugadas por pregunta reiniciado")
+}
+
+// GetUnusedQuestions maneja GET /api/admin/questions/unused?room=main
+func (h *QuestionHandler) GetUnusedQuestions(ctx *fasthttp.RequestCtx) {
+	room := string(ctx.QueryArgs().Peek("room"))
+	if room == "" {
+		room = services.DefaultRoom
+	}
+
+	questions, err := h.questionService.GetUnusedQuestions(room)
+	if errors.Is(err, services.ErrUnknownRoom) {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Partida no encontrada: %s", room))
+		return
+	}
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error obteniendo preguntas sin usar: %v", err))
+		return
+	}
+
+	count := len(questions)
+	h.respondWithSuccess(ctx, models.QuestionResponse{
+		Questions: questions,
+		Returned:  &count,
+		Total:     &count,
+	}, fmt.Sprintf("%d preguntas sin usar en la partida %s", count, room))
+}
+
+// GetQuestionResults maneja GET /api/admin/questions/{id}/results
+func (h *QuestionHandler) GetQuestionResults(ctx *fasthttp.RequestCtx) {
+	idStr, _ := ctx.UserValue("id").(string)
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, "ID de pregunta inválido")
+		return
+	}
+
+	if _, err := h.questionService.GetQuestion(id); err != nil {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Pregunta no encontrada: %v", err))
+		return
+	}
+
+	result, err := h.sessionService.GetQuestionResult(id)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error obteniendo resultados: %v", err))
+		return
+	}
+
+	h.respondWithSuccess(ctx, result, fmt.Sprintf("Pregunta %d: %d de %d respuestas correctas", id, result.Correct, result.Attempts))
+}
+
+// GetAnswerTimeline maneja GET /api/admin/questions/{id}/answer-timeline
+func (h *QuestionHandler) GetAnswerTimeline(ctx *fasthttp.RequestCtx) {
+	idStr, _ := ctx.UserValue("id").(string)
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, "ID de pregunta inválido")
+		return
+	}
+
+	if _, err := h.questionService.GetQuestion(id); err != nil {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Pregunta no encontrada: %v", err))
+		return
+	}
+
+	timeline, err := h.sessionService.GetAnswerTimeline(id)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error obteniendo línea de tiempo: %v", err))
+		return
+	}
+
+	h.respondWithSuccess(ctx, timeline, fmt.Sprintf("Pregunta %d: %d respuestas en orden de llegada", id, len(timeline.Answers)))
+}
+
+// GetAnswerKeyDistribution maneja GET /api/admin/answer-key-distribution
+func (h *QuestionHandler) GetAnswerKeyDistribution(ctx *fasthttp.RequestCtx) {
+	distribution, err := h.questionService.GetAnswerKeyDistribution()
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error calculando distribución de respuestas correctas: %v", err))
+		return
+	}
+
+	h.respondWithSuccess(ctx, distribution, fmt.Sprintf("Distribución de %d preguntas", distribution.Overall.Total))
+}
+
+// Readiness maneja GET /api/readyz: listo para recibir tráfico solo si Redis
+// responde y hay preguntas cargadas
+func (h *QuestionHandler) Readiness(ctx *fasthttp.RequestCtx) {
+	if err := h.questionService.HealthCheck(); err != nil {
+		h.respondWithError(ctx, fasthttp.StatusServiceUnavailable, fmt.Sprintf("No listo: %v", err))
+		return
+	}
+
+	count, err := h.questionService.GetQuestionCount()
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusServiceUnavailable, fmt.Sprintf("No listo: %v", err))
+		return
+	}
+	if count == 0 {
+		h.respondWithError(ctx, fasthttp.StatusServiceUnavailable, "No listo: no hay preguntas cargadas")
+		return
+	}
+
+	h.respondWithSuccess(ctx, map[string]interface{}{
+		"status":    "ready",
+		"questions": count,
+	}, "Servicio listo")
+}
+
 // HealthCheck maneja GET /api/health
 func (h *QuestionHandler) HealthCheck(ctx *fasthttp.RequestCtx) {
 	err := h.questionService.HealthCheck()
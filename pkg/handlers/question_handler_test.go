@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/backsoul/quiz/pkg/models"
+	"github.com/backsoul/quiz/pkg/redis"
+	"github.com/backsoul/quiz/pkg/services"
+	"github.com/valyala/fasthttp"
+)
+
+// loadTestQuestions carga n preguntas (IDs 1..n, plan en orden inverso) con
+// "A" como respuesta correcta
+func loadTestQuestions(t *testing.T, store redis.RedisStore, n int) {
+	t.Helper()
+	questions := make([]models.Question, 0, n)
+	for i := n; i >= 1; i-- {
+		questions = append(questions, models.Question{
+			ID:          i,
+			Question:    fmt.Sprintf("Pregunta %d", i),
+			Options:     map[string]string{"A": "Uno", "B": "Dos", "C": "Tres", "D": "Cuatro"},
+			Correct:     "A",
+			Explanation: fmt.Sprintf("Explicación %d", i),
+			Difficulty:  (i-1)%5 + 1,
+		})
+	}
+	data, err := json.Marshal(map[string]interface{}{"questions": questions})
+	if err != nil {
+		t.Fatalf("error serializando preguntas: %v", err)
+	}
+	if err := store.LoadQuestionsFromJSON(data); err != nil {
+		t.Fatalf("error cargando preguntas: %v", err)
+	}
+}
+
+// newTestQuestionHandler crea el handler de preguntas sobre un MemoryStore con n preguntas
+func newTestQuestionHandler(t *testing.T, n int) (*QuestionHandler, *redis.MemoryStore) {
+	t.Helper()
+	store := redis.NewMemoryStore()
+	loadTestQuestions(t, store, n)
+	questions := services.NewQuestionService(store)
+	sessions := services.NewSessionService(store)
+	return NewQuestionHandler(questions, sessions), store
+}
+
+func TestGetQuestionByNumber(t *testing.T) {
+	h, _ := newTestQuestionHandler(t, 5)
+
+	ctx := newRequestCtx("GET", "/api/game/question/1", "")
+	ctx.SetUserValue("number", "1")
+	h.GetQuestionByNumber(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	var question models.PublicQuestion
+	decodeResponse(t, ctx, &question)
+	// El plan va en orden inverso: la pregunta 1 de la partida es la de ID 5
+	if question.Number != 1 || question.ID != 5 {
+		t.Fatalf("esperaba la pregunta número 1 con ID 5, obtuve número %d ID %d", question.Number, question.ID)
+	}
+	if strings.Contains(string(ctx.Response.Body()), "correctAnswer") {
+		t.Fatalf("la respuesta no debe incluir la respuesta correcta: %s", ctx.Response.Body())
+	}
+
+	for _, number := range []string{"0", "6", "-1"} {
+		ctx := newRequestCtx("GET", "/api/game/question/"+number, "")
+		ctx.SetUserValue("number", number)
+		h.GetQuestionByNumber(ctx)
+		if ctx.Response.StatusCode() != fasthttp.StatusNotFound {
+			t.Fatalf("número %s: esperaba 404, obtuve %d", number, ctx.Response.StatusCode())
+		}
+	}
+
+	ctx = newRequestCtx("GET", "/api/game/question/abc", "")
+	ctx.SetUserValue("number", "abc")
+	h.GetQuestionByNumber(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusBadRequest {
+		t.Fatalf("número inválido: esperaba 400, obtuve %d", ctx.Response.StatusCode())
+	}
+}
+
+// unreachableStore MemoryStore que simula Redis caído en el health check
+type unreachableStore struct {
+	*redis.MemoryStore
+}
+
+func (s unreachableStore) HealthCheck() error {
+	return errors.New("dial tcp: connection refused")
+}
+
+func TestReadiness(t *testing.T) {
+	readiness := func(store redis.RedisStore) (int, models.APIResponse) {
+		t.Helper()
+		h := NewQuestionHandler(services.NewQuestionService(store), services.NewSessionService(store))
+		ctx := newRequestCtx("GET", "/api/readyz", "")
+		h.Readiness(ctx)
+		return ctx.Response.StatusCode(), decodeResponse(t, ctx, nil)
+	}
+
+	ready := redis.NewMemoryStore()
+	loadTestQuestions(t, ready, 5)
+	if status, response := readiness(ready); status != fasthttp.StatusOK || !response.Success {
+		t.Fatalf("con Redis y preguntas esperaba 200, obtuve %d: %+v", status, response)
+	}
+
+	if status, response := readiness(redis.NewMemoryStore()); status != fasthttp.StatusServiceUnavailable || !strings.Contains(response.Error, "preguntas") {
+		t.Fatalf("sin preguntas esperaba 503, obtuve %d: %+v", status, response)
+	}
+
+	down := unreachableStore{redis.NewMemoryStore()}
+	loadTestQuestions(t, down, 5)
+	if status, response := readiness(down); status != fasthttp.StatusServiceUnavailable || !strings.Contains(response.Error, "connection refused") {
+		t.Fatalf("con Redis caído esperaba 503, obtuve %d: %+v", status, response)
+	}
+}
+
+func TestQuestionResponseCounts(t *testing.T) {
+	for _, n := range []int{0, 8} {
+		h, _ := newTestQuestionHandler(t, n)
+
+		// La dificultad 1 reúne los IDs 1 y 6 cuando hay 8 preguntas
+		inRange := 0
+		if n > 0 {
+			inRange = 2
+		}
+		cases := []struct {
+			name     string
+			handler  fasthttp.RequestHandler
+			uri      string
+			returned *int
+			total    int
+		}{
+			{"all", h.GetAllQuestions, "/api/questions", &n, n},
+			{"difficulty", h.GetQuestionsByDifficulty, "/api/questions/difficulty?min=1&max=1", &inRange, inRange},
+			{"metadata", h.GetQuestionMetadata, "/api/questions/metadata", nil, n},
+		}
+		for _, c := range cases {
+			ctx := newRequestCtx("GET", c.uri, "")
+			c.handler(ctx)
+			if ctx.Response.StatusCode() != fasthttp.StatusOK {
+				t.Fatalf("%d preguntas, %s: esperaba 200, obtuve %d: %s", n, c.name, ctx.Response.StatusCode(), ctx.Response.Body())
+			}
+			var response models.QuestionResponse
+			decodeResponse(t, ctx, &response)
+
+			// Un total de cero se incluye: no debe confundirse con un campo ausente
+			if response.Total == nil || *response.Total != c.total {
+				t.Fatalf("%d preguntas, %s: total inesperado en %s", n, c.name, ctx.Response.Body())
+			}
+			switch {
+			case c.returned == nil && response.Returned != nil:
+				t.Fatalf("%d preguntas, %s: returned no aplica: %s", n, c.name, ctx.Response.Body())
+			case c.returned != nil && (response.Returned == nil || *response.Returned != *c.returned || len(response.Questions) != *c.returned):
+				t.Fatalf("%d preguntas, %s: returned inesperado en %s", n, c.name, ctx.Response.Body())
+			}
+		}
+	}
+}
+
+func TestGetUnusedQuestions(t *testing.T) {
+	h, store := newTestQuestionHandler(t, 8)
+	unused := func(room string) *fasthttp.RequestCtx {
+		ctx := newRequestCtx("GET", "/api/admin/questions/unused?room="+room, "")
+		h.GetUnusedQuestions(ctx)
+		return ctx
+	}
+
+	// Se sirven las preguntas 1 y 3 del plan (IDs 8 y 6) y una al azar
+	gameState := services.NewGameStateService(store)
+	t.Cleanup(gameState.StopTimerTicks)
+	if err := gameState.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+	for _, number := range []int{1, 3} {
+		if _, err := gameState.ShowQuestion(number); err != nil {
+			t.Fatalf("error mostrando pregunta %d: %v", number, err)
+		}
+	}
+	served := map[int]bool{8: true, 6: true}
+	random, err := h.questionService.GetRandomUnseenQuestion(services.DefaultRoom)
+	if err != nil {
+		t.Fatalf("error sirviendo pregunta al azar: %v", err)
+	}
+	served[random.ID] = true
+
+	ctx := unused("")
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	var response models.QuestionResponse
+	decodeResponse(t, ctx, &response)
+
+	// Dificultades de la prueba: ID i tiene (i-1)%5+1
+	var want []string
+	for _, id := range []int{1, 6, 2, 7, 3, 8, 4, 5} {
+		if !served[id] {
+			want = append(want, fmt.Sprint(id))
+		}
+	}
+	var got []string
+	for i, question := range response.Questions {
+		got = append(got, fmt.Sprint(question.ID))
+		if i > 0 && question.Difficulty < response.Questions[i-1].Difficulty {
+			t.Fatalf("las preguntas deben ir por dificultad: %+v", response.Questions)
+		}
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") || *response.Returned != len(want) {
+		t.Fatalf("esperaba las no servidas %v, obtuve %v", want, got)
+	}
+
+	// Otra partida no existe
+	if ctx := unused("otra"); ctx.Response.StatusCode() != fasthttp.StatusNotFound {
+		t.Fatalf("partida desconocida: esperaba 404, obtuve %d", ctx.Response.StatusCode())
+	}
+}
+
+func TestPatchQuestionUpdatesOnlyGivenFields(t *testing.T) {
+	patch := func(h *QuestionHandler, id, body string) *fasthttp.RequestCtx {
+		ctx := newRequestCtx("PATCH", "/api/questions/"+id, body)
+		ctx.SetUserValue("id", id)
+		h.PatchQuestion(ctx)
+		return ctx
+	}
+
+	cases := []struct {
+		name   string
+		body   string
+		change func(q *redis.Question)
+	}{
+		{"una opción", `{"options":{"B":"Opción corregida"}}`, func(q *redis.Question) { q.Options["B"] = "Opción corregida" }},
+		{"dificultad", `{"difficulty":5}`, func(q *redis.Question) { q.Difficulty = 5 }},
+		{"respuesta correcta", `{"correctAnswer":"C"}`, func(q *redis.Question) { q.Correct = "C" }},
+		{"explicación", `{"explanation":"Nueva explicación"}`, func(q *redis.Question) { q.Explanation = "Nueva explicación" }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, store := newTestQuestionHandler(t, 3)
+			before, err := store.GetQuestion(2)
+			if err != nil {
+				t.Fatalf("error obteniendo pregunta: %v", err)
+			}
+			other, _ := store.GetQuestion(1)
+			want := *before
+			want.Options = make(map[string]string, len(before.Options))
+			for key, text := range before.Options {
+				want.Options[key] = text
+			}
+			tc.change(&want)
+
+			ctx := patch(h, "2", tc.body)
+			if ctx.Response.StatusCode() != fasthttp.StatusOK {
+				t.Fatalf("esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+			}
+			after, _ := store.GetQuestion(2)
+			if !reflect.DeepEqual(*after, want) {
+				t.Fatalf("el resto de la pregunta no debe cambiar:\n obtuve %+v\nesperaba %+v", *after, want)
+			}
+			// Las demás preguntas quedan intactas
+			if after, _ := store.GetQuestion(1); !reflect.DeepEqual(after, other) {
+				t.Fatalf("el parche no debe tocar otras preguntas: %+v", after)
+			}
+		})
+	}
+
+	t.Run("inválidos", func(t *testing.T) {
+		h, store := newTestQuestionHandler(t, 3)
+		before, _ := store.GetQuestion(2)
+		for body, want := range map[string]int{
+			`{"correctAnswer":"Z"}`:  fasthttp.StatusBadRequest,
+			`{"options":{"A":"  "}}`: fasthttp.StatusBadRequest,
+			`{"question":""}`:        fasthttp.StatusBadRequest,
+			`{"difficulty":99}`:      fasthttp.StatusBadRequest,
+			`{"difficulty":`:         fasthttp.StatusBadRequest,
+		} {
+			if ctx := patch(h, "2", body); ctx.Response.StatusCode() != want {
+				t.Fatalf("%s: esperaba %d, obtuve %d", body, want, ctx.Response.StatusCode())
+			}
+		}
+		if after, _ := store.GetQuestion(2); !reflect.DeepEqual(after, before) {
+			t.Fatalf("un parche rechazado no debe guardar nada: %+v", after)
+		}
+		if ctx := patch(h, "99", `{"difficulty":2}`); ctx.Response.StatusCode() != fasthttp.StatusNotFound {
+			t.Fatalf("pregunta inexistente: esperaba 404, obtuve %d", ctx.Response.StatusCode())
+		}
+		if ctx := patch(h, "abc", `{"difficulty":2}`); ctx.Response.StatusCode() != fasthttp.StatusBadRequest {
+			t.Fatalf("ID inválido: esperaba 400, obtuve %d", ctx.Response.StatusCode())
+		}
+	})
+}
@@ -1,22 +1,38 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/backsoul/quiz/pkg/models"
 	"github.com/backsoul/quiz/pkg/services"
 	websocketHub "github.com/backsoul/quiz/pkg/websocket"
+	"github.com/google/uuid"
 	"github.com/valyala/fasthttp"
 )
 
 // SessionHandler maneja las peticiones HTTP para sesiones
 type SessionHandler struct {
-	sessionService  *services.SessionService
-	questionService *services.QuestionService
-	hub             *websocketHub.Hub
+	sessionService   *services.SessionService
+	questionService  *services.QuestionService
+	gameStateService *services.GameStateService
+	hub              *websocketHub.Hub
+	auditService     *services.AuditService
+	answerBatcher    *websocketHub.EventBatcher
+	minAnswerTime    time.Duration
+	rejectFast       bool // rechaza (en lugar de marcar) las respuestas antes de minAnswerTime
+	sessionOver      func()
+
+	// Ajustables en caliente desde /api/admin/settings
+	settingsMutex   sync.RWMutex
+	strictFinal     bool // rechaza confirmar una opción distinta a la seleccionada
+	showExplanation bool // incluye la explicación en la respuesta a SubmitAnswer
 }
 
 // NewSessionHandler crea una nueva instancia del handler de sesiones
@@ -28,10 +44,69 @@ func NewSessionHandler(sessionService *services.SessionService, questionService
 	}
 }
 
+// SetAnswerBatcher agrupa los eventos answerSubmitted en lotes; sin batcher
+// cada respuesta se difunde individualmente
+func (h *SessionHandler) SetAnswerBatcher(batcher *websocketHub.EventBatcher) {
+	h.answerBatcher = batcher
+}
+
+// SetGameStateService permite rechazar respuestas mientras la ventana de
+// respuestas de la pregunta en curso está cerrada
+func (h *SessionHandler) SetGameStateService(gameStateService *services.GameStateService) {
+	h.gameStateService = gameStateService
+}
+
+// SetStrictFinalAnswer exige que la respuesta final coincida con la opción
+// seleccionada antes (si la hubo)
+func (h *SessionHandler) SetStrictFinalAnswer(strict bool) {
+	h.settingsMutex.Lock()
+	h.strictFinal = strict
+	h.settingsMutex.Unlock()
+}
+
+// SetShowExplanation envía al jugador la explicación de la pregunta que acaba de
+// responder; desactivado, la explicación se conoce recién al revelar la respuesta
+func (h *SessionHandler) SetShowExplanation(show bool) {
+	h.settingsMutex.Lock()
+	h.showExplanation = show
+	h.settingsMutex.Unlock()
+}
+
+// answerSettings devuelve los valores vigentes de strictFinal y showExplanation
+func (h *SessionHandler) answerSettings() (strictFinal, showExplanation bool) {
+	h.settingsMutex.RLock()
+	defer h.settingsMutex.RUnlock()
+	return h.strictFinal, h.showExplanation
+}
+
+// SetMinAnswerTime fija el tiempo mínimo, medido en el servidor desde que se
+// abrió la pregunta, para responder; las respuestas más rápidas se rechazan con
+// reject o, si no, se aceptan marcadas como sospechosas (0 = sin mínimo)
+func (h *SessionHandler) SetMinAnswerTime(min time.Duration, reject bool) {
+	h.minAnswerTime = min
+	h.rejectFast = reject
+}
+
+// SetAuditService habilita el registro de auditoría de las acciones de administración
+func (h *SessionHandler) SetAuditService(auditService *services.AuditService) {
+	h.auditService = auditService
+}
+
+// OnSessionOver registra una función que se llama, en segundo plano, cada vez
+// que un jugador en vivo queda eliminado o termina su sesión
+func (h *SessionHandler) OnSessionOver(fn func()) {
+	h.sessionOver = fn
+}
+
 // CreateSession maneja POST /api/sessions
 func (h *SessionHandler) CreateSession(ctx *fasthttp.RequestCtx) {
 	var request models.SessionCreateRequest
-	if err := json.Unmarshal(ctx.PostBody(), &request); err != nil {
+	if isFormRequest(ctx) {
+		args := ctx.PostArgs()
+		request.PlayerName = string(args.Peek("playerName"))
+		request.SessionID = string(args.Peek("sessionId"))
+		request.ClaimCode = string(args.Peek("claimCode"))
+	} else if err := json.Unmarshal(ctx.PostBody(), &request); err != nil {
 		h.respondWithError(ctx, fasthttp.StatusBadRequest, "JSON inválido")
 		return
 	}
@@ -41,25 +116,315 @@ func (h *SessionHandler) CreateSession(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	session, err := h.sessionService.CreateSession(request.PlayerName)
+	mode := string(ctx.QueryArgs().Peek("mode"))
+	if mode == "" {
+		mode = models.SessionModeLive
+	}
+	if mode != models.SessionModeLive && mode != models.SessionModePractice && mode != models.SessionModeWager {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, "Modo inválido: use 'live', 'practice' o 'wager'")
+		return
+	}
+
+	session, created, err := h.sessionService.CreateSession(request.PlayerName, mode, request.SessionID, request.ClaimCode)
+	if errors.Is(err, services.ErrNameReserved) {
+		h.respondWithError(ctx, fasthttp.StatusForbidden, fmt.Sprintf("El nombre %s está reservado, ingresa tu código de registro", request.PlayerName))
+		return
+	}
+	if errors.Is(err, services.ErrPlayerNameTaken) {
+		h.respondWithError(ctx, fasthttp.StatusConflict, fmt.Sprintf("El nombre %s ya está en uso, elige otro", request.PlayerName))
+		return
+	}
+	if errors.Is(err, services.ErrGameFull) {
+		h.hub.BroadcastMessage("gameFull", map[string]interface{}{
+			"message":   "La partida está llena, espera a que se libere un cupo",
+			"timestamp": models.Timestamp(),
+		})
+		h.respondWithError(ctx, fasthttp.StatusServiceUnavailable, "La partida está llena")
+		return
+	}
 	if err != nil {
 		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error creando sesión: %v", err))
 		return
 	}
 
-	log.Printf("👤 Nuevo jugador: %s (ID: %s)", request.PlayerName, session.ID)
+	// Una sesión nueva recibe su token; continuar una existente exige el token
+	// actual, que no se renueva: el nombre o el ID de sesión por sí solos no
+	// bastan para tomar la sesión de otro jugador
+	token := string(ctx.Request.Header.Peek("X-Session-Token"))
+	if created {
+		token, err = h.sessionService.IssueSessionToken(session.ID)
+		if err != nil {
+			h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error creando sesión: %v", err))
+			return
+		}
+	} else {
+		err := h.sessionService.VerifySessionToken(session.ID, token)
+		if errors.Is(err, services.ErrInvalidSessionToken) {
+			h.respondWithError(ctx, fasthttp.StatusConflict, fmt.Sprintf("El nombre %s ya está en uso, elige otro", request.PlayerName))
+			return
+		}
+		if err != nil {
+			h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error verificando sesión: %v", err))
+			return
+		}
+	}
+
+	// Avisar al admin solo de sesiones nuevas; recargar o reconectarse no es un nuevo ingreso
+	if created {
+		log.Printf("👤 Nuevo jugador: %s (ID: %s, modo: %s)", request.PlayerName, session.ID, session.Mode)
+		if !session.IsPractice() {
+			h.hub.BroadcastMessage("playerJoined", map[string]interface{}{
+				"playerName": session.PlayerName,
+				"sessionId":  session.ID,
+				"mode":       session.Mode,
+				"timestamp":  models.Timestamp(),
+			})
+		}
+	}
 
 	responseData := models.SessionResponse{
 		Session: session,
+		Token:   token,
 		Message: "Sesión creada exitosamente",
 	}
 
 	h.respondWithSuccess(ctx, responseData, "Sesión creada exitosamente")
 }
 
+// PreregisterPlayers maneja POST /api/admin/players/preregister
+func (h *SessionHandler) PreregisterPlayers(ctx *fasthttp.RequestCtx) {
+	var request models.PreregisterRequest
+	if err := json.Unmarshal(ctx.PostBody(), &request); err != nil {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, "JSON inválido")
+		return
+	}
+
+	if len(request.Names) == 0 {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, "La lista de nombres es requerida")
+		return
+	}
+
+	reservations, err := h.sessionService.PreregisterPlayers(request.Names)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error pre-registrando jugadores: %v", err))
+		return
+	}
+	recordAudit(h.auditService, ctx, "preregister", map[string]interface{}{
+		"names": request.Names,
+	})
+
+	h.respondWithSuccess(ctx, map[string]interface{}{
+		"reservations": reservations,
+		"count":        len(reservations),
+	}, fmt.Sprintf("%d jugadores pre-registrados", len(reservations)))
+}
+
+// LookupPlayers maneja POST /api/admin/players/status
+func (h *SessionHandler) LookupPlayers(ctx *fasthttp.RequestCtx) {
+	var request models.PlayersLookupRequest
+	if err := json.Unmarshal(ctx.PostBody(), &request); err != nil {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, "JSON inválido")
+		return
+	}
+
+	if len(request.Names) == 0 {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, "La lista de nombres es requerida")
+		return
+	}
+
+	statuses, err := h.sessionService.LookupPlayers(request.Names)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error consultando jugadores: %v", err))
+		return
+	}
+
+	h.respondWithSuccess(ctx, statuses, fmt.Sprintf("Estado de %d jugadores obtenido", len(statuses)))
+}
+
+// AdjustPrize maneja POST /api/admin/players/{sessionId}/adjust-prize
+func (h *SessionHandler) AdjustPrize(ctx *fasthttp.RequestCtx) {
+	sessionID, ok := h.sessionIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	var request models.AdjustPrizeRequest
+	if err := json.Unmarshal(ctx.PostBody(), &request); err != nil {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, "JSON inválido")
+		return
+	}
+
+	admin := adminIdentity(ctx)
+	session, err := h.sessionService.AdjustPrize(sessionID, request, admin)
+	if errors.Is(err, services.ErrInvalidAdjustment) || errors.Is(err, services.ErrNegativePrize) {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Sesión no encontrada: %v", err))
+		return
+	}
+
+	adjustment := session.PrizeAdjustments[len(session.PrizeAdjustments)-1]
+	h.hub.BroadcastMessage("prizeAdjusted", map[string]interface{}{
+		"sessionId":     session.ID,
+		"playerName":    session.PlayerName,
+		"previousPrize": adjustment.PreviousPrize,
+		"newPrize":      adjustment.NewPrize,
+		"reason":        adjustment.Reason,
+		"timestamp":     models.FormatTime(adjustment.Timestamp),
+	})
+	recordAudit(h.auditService, ctx, "adjust-prize", map[string]interface{}{
+		"sessionId":     session.ID,
+		"playerName":    session.PlayerName,
+		"previousPrize": adjustment.PreviousPrize,
+		"newPrize":      adjustment.NewPrize,
+		"reason":        adjustment.Reason,
+	})
+
+	h.respondWithSuccess(ctx, models.SessionResponse{Session: session}, fmt.Sprintf("Premio de %s corregido a $%d", session.PlayerName, session.TotalPrize))
+}
+
+// ReverseAnswer maneja POST /api/admin/answers/reverse
+func (h *SessionHandler) ReverseAnswer(ctx *fasthttp.RequestCtx) {
+	var request struct {
+		SessionID      string `json:"sessionId"`
+		QuestionNumber int    `json:"questionNumber"`
+	}
+
+	if err := json.Unmarshal(ctx.PostBody(), &request); err != nil {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, "JSON inválido")
+		return
+	}
+	if request.SessionID == "" || request.QuestionNumber <= 0 {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, "sessionId y questionNumber son requeridos")
+		return
+	}
+
+	session, reversed, err := h.sessionService.ReverseAnswer(request.SessionID, request.QuestionNumber)
+	if errors.Is(err, services.ErrAnswerNotFound) {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("La sesión no tiene respuesta para la pregunta %d", request.QuestionNumber))
+		return
+	}
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Sesión no encontrada: %v", err))
+		return
+	}
+
+	h.hub.BroadcastMessage("answerReversed", map[string]interface{}{
+		"sessionId":       session.ID,
+		"playerName":      session.PlayerName,
+		"questionNumber":  request.QuestionNumber,
+		"totalPrize":      session.TotalPrize,
+		"currentQuestion": session.CurrentQuestion,
+		"gameStatus":      session.GameStatus,
+		"timestamp":       models.Timestamp(),
+	})
+	recordAudit(h.auditService, ctx, "reverse-answer", map[string]interface{}{
+		"sessionId":      session.ID,
+		"playerName":     session.PlayerName,
+		"questionNumber": request.QuestionNumber,
+		"wasCorrect":     reversed.IsCorrect,
+	})
+
+	h.respondWithSuccess(ctx, models.SessionResponse{Session: session}, fmt.Sprintf("Respuesta de %s anulada, premio recalculado a $%d", session.PlayerName, session.TotalPrize))
+}
+
+// RecomputeSession maneja POST /api/admin/sessions/{id}/recompute
+func (h *SessionHandler) RecomputeSession(ctx *fasthttp.RequestCtx) {
+	sessionID, ok := h.sessionIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	session, changed, err := h.sessionService.RecomputeSession(sessionID)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Sesión no encontrada: %v", err))
+		return
+	}
+	recordAudit(h.auditService, ctx, "recompute-session", map[string]interface{}{
+		"sessionId": session.ID,
+		"changed":   changed,
+	})
+
+	message := "La sesión ya era consistente con sus respuestas"
+	if changed {
+		message = "Sesión recalculada a partir de sus respuestas"
+	}
+	h.respondWithSuccess(ctx, map[string]interface{}{
+		"session": session,
+		"changed": changed,
+	}, message)
+}
+
+// RestartSession maneja POST /api/admin/players/{sessionId}/restart
+func (h *SessionHandler) RestartSession(ctx *fasthttp.RequestCtx) {
+	sessionID, ok := h.sessionIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	session, err := h.sessionService.RestartSession(sessionID)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Sesión no encontrada: %v", err))
+		return
+	}
+
+	h.hub.BroadcastMessage("playerRestarted", map[string]interface{}{
+		"sessionId":  session.ID,
+		"playerName": session.PlayerName,
+		"timestamp":  models.Timestamp(),
+		"message":    fmt.Sprintf("%s vuelve a empezar desde la pregunta 1", session.PlayerName),
+	})
+	recordAudit(h.auditService, ctx, "restart-session", map[string]interface{}{
+		"sessionId":  session.ID,
+		"playerName": session.PlayerName,
+	})
+
+	h.respondWithSuccess(ctx, models.SessionResponse{Session: session}, fmt.Sprintf("Sesión de %s reiniciada", session.PlayerName))
+}
+
+// SeedDemo maneja POST /api/admin/seed-demo?players=20&seed=1
+func (h *SessionHandler) SeedDemo(ctx *fasthttp.RequestCtx) {
+	players := 20
+	if value := string(ctx.QueryArgs().Peek("players")); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 500 {
+			h.respondWithError(ctx, fasthttp.StatusBadRequest, "players debe ser un número entre 1 y 500")
+			return
+		}
+		players = n
+	}
+
+	seed := int64(1)
+	if value := string(ctx.QueryArgs().Peek("seed")); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			h.respondWithError(ctx, fasthttp.StatusBadRequest, "seed inválido")
+			return
+		}
+		seed = n
+	}
+
+	sessions, err := h.sessionService.SeedDemoSessions(players, seed)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error creando datos de demostración: %v", err))
+		return
+	}
+	recordAudit(h.auditService, ctx, "seed-demo", map[string]interface{}{
+		"players": players,
+		"seed":    seed,
+	})
+
+	h.respondWithSuccess(ctx, models.SessionResponse{Sessions: sessions}, fmt.Sprintf("%d sesiones de demostración creadas", len(sessions)))
+}
+
 // GetSession maneja GET /api/sessions/{id}
 func (h *SessionHandler) GetSession(ctx *fasthttp.RequestCtx) {
-	sessionID := ctx.UserValue("id").(string)
+	sessionID, ok := h.sessionIDParam(ctx)
+	if !ok {
+		return
+	}
 
 	session, err := h.sessionService.GetSession(sessionID)
 	if err != nil {
@@ -74,9 +439,115 @@ func (h *SessionHandler) GetSession(ctx *fasthttp.RequestCtx) {
 	h.respondWithSuccess(ctx, responseData, "Sesión obtenida exitosamente")
 }
 
+// GetCertificate maneja GET /api/sessions/{id}/certificate
+func (h *SessionHandler) GetCertificate(ctx *fasthttp.RequestCtx) {
+	sessionID, ok := h.sessionIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	certificate, err := h.sessionService.GetCertificate(sessionID)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Sesión no encontrada: %v", err))
+		return
+	}
+
+	h.respondWithSuccess(ctx, certificate, "Certificado obtenido exitosamente")
+}
+
+// GetAnsweredQuestions maneja GET /api/sessions/{id}/answered-questions. Con la
+// sesión aún en juego exige el token, para no filtrar respuestas a otros jugadores.
+func (h *SessionHandler) GetAnsweredQuestions(ctx *fasthttp.RequestCtx) {
+	sessionID, ok := h.sessionIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	session, err := h.sessionService.GetSession(sessionID)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Sesión no encontrada: %v", err))
+		return
+	}
+	if session.GameStatus == "active" && !h.authorizeSession(ctx, sessionID) {
+		return
+	}
+
+	answered := make([]models.AnsweredQuestion, 0, len(session.AnswersGiven))
+	for _, answer := range session.AnswersGiven {
+		question, err := h.questionService.GetQuestion(answer.QuestionID)
+		if err != nil {
+			h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error obteniendo pregunta %d: %v", answer.QuestionID, err))
+			return
+		}
+		answered = append(answered, models.AnsweredQuestion{
+			QuestionNumber: answer.QuestionNumber,
+			Question:       question,
+			SelectedOption: answer.SelectedOption,
+			IsCorrect:      answer.IsCorrect,
+			PrizeWon:       answer.PrizeWon,
+			Voided:         answer.Voided,
+		})
+	}
+
+	h.respondWithSuccess(ctx, answered, fmt.Sprintf("%d preguntas respondidas", len(answered)))
+}
+
+// GetOptionOrder maneja GET /api/admin/players/{id}/shuffle: orden de las
+// opciones servido al jugador en cada pregunta respondida y en la actual
+func (h *SessionHandler) GetOptionOrder(ctx *fasthttp.RequestCtx) {
+	sessionID, ok := h.sessionIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	session, err := h.sessionService.GetSession(sessionID)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Sesión no encontrada: %v", err))
+		return
+	}
+
+	audit := &models.OptionOrderAudit{
+		SessionID:  session.ID,
+		PlayerName: session.PlayerName,
+		Questions:  make([]models.ServedOptionOrder, 0, len(session.AnswersGiven)+1),
+	}
+	served := func(questionNumber, questionID int, selected string) bool {
+		question, err := h.questionService.GetQuestion(questionID)
+		if err != nil {
+			h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error obteniendo pregunta %d: %v", questionID, err))
+			return false
+		}
+		audit.Questions = append(audit.Questions, models.ServedOptionOrder{
+			QuestionNumber: questionNumber,
+			QuestionID:     questionID,
+			OptionOrder:    question.OptionOrder,
+			SelectedOption: selected,
+		})
+		return true
+	}
+
+	answeredCurrent := false
+	for _, answer := range session.AnswersGiven {
+		if !served(answer.QuestionNumber, answer.QuestionID, answer.SelectedOption) {
+			return
+		}
+		answeredCurrent = answeredCurrent || answer.QuestionID == session.CurrentQuestionID
+	}
+	if session.CurrentQuestionID > 0 && !answeredCurrent {
+		if !served(session.CurrentQuestion, session.CurrentQuestionID, "") {
+			return
+		}
+	}
+
+	h.respondWithSuccess(ctx, audit, fmt.Sprintf("Orden de opciones de %d preguntas", len(audit.Questions)))
+}
+
 // GetPlayerSession maneja GET /api/sessions/player/{playerName}
 func (h *SessionHandler) GetPlayerSession(ctx *fasthttp.RequestCtx) {
-	playerName := ctx.UserValue("playerName").(string)
+	playerName, ok := h.pathParam(ctx, "playerName")
+	if !ok {
+		return
+	}
 
 	session, err := h.sessionService.GetActiveSessionByPlayer(playerName)
 	if err != nil {
@@ -93,7 +564,10 @@ func (h *SessionHandler) GetPlayerSession(ctx *fasthttp.RequestCtx) {
 
 // GetPlayerHistory maneja GET /api/sessions/player/{playerName}/history
 func (h *SessionHandler) GetPlayerHistory(ctx *fasthttp.RequestCtx) {
-	playerName := ctx.UserValue("playerName").(string)
+	playerName, ok := h.pathParam(ctx, "playerName")
+	if !ok {
+		return
+	}
 
 	sessions, err := h.sessionService.GetPlayerHistory(playerName)
 	if err != nil {
@@ -101,7 +575,7 @@ func (h *SessionHandler) GetPlayerHistory(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	responseData := models.SessionResponse{
+	responseData := models.SessionListResponse{
 		Sessions: sessions,
 	}
 
@@ -116,33 +590,218 @@ func (h *SessionHandler) GetActiveSessions(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	responseData := models.SessionResponse{
-		Sessions: sessions,
+	StreamJSON(ctx, fasthttp.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("%d sesiones activas obtenidas", len(sessions)),
+		Data:    models.SessionListResponse{Sessions: sessions},
+	})
+}
+
+// GetNextPrize maneja GET /api/sessions/{id}/next-prize
+func (h *SessionHandler) GetNextPrize(ctx *fasthttp.RequestCtx) {
+	sessionID, ok := h.sessionIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	nextPrize, err := h.sessionService.GetNextPrize(sessionID)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Sesión no encontrada: %v", err))
+		return
+	}
+
+	h.respondWithSuccess(ctx, nextPrize, "Premio de la pregunta actual obtenido exitosamente")
+}
+
+// GetNextQuestion maneja GET /api/sessions/{id}/next para el juego a ritmo
+// propio: devuelve la siguiente pregunta de la sesión según el plan (sin la
+// respuesta correcta) y la fija como la pregunta a responder
+func (h *SessionHandler) GetNextQuestion(ctx *fasthttp.RequestCtx) {
+	sessionID, ok := h.sessionIDParam(ctx)
+	if !ok {
+		return
+	}
+	if !h.authorizeSession(ctx, sessionID) {
+		return
+	}
+
+	session, err := h.sessionService.GetSession(sessionID)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, "Sesión no encontrada")
+		return
+	}
+
+	if session.GameStatus == "finished" {
+		h.respondWithSuccess(ctx, map[string]interface{}{
+			"complete": true,
+		}, "Juego completado")
+		return
+	}
+	if session.GameStatus != "active" {
+		h.respondWithError(ctx, fasthttp.StatusConflict, "La sesión no está activa")
+		return
+	}
+
+	number := session.CurrentQuestion
+	question, err := h.questionService.GetQuestionByNumber(number)
+	if errors.Is(err, services.ErrQuestionOutOfPlan) {
+		h.respondWithSuccess(ctx, map[string]interface{}{
+			"complete": true,
+		}, "Juego completado")
+		return
+	}
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error obteniendo pregunta: %v", err))
+		return
+	}
+
+	if _, err := h.sessionService.SetCurrentQuestionID(sessionID, question.ID); err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error actualizando sesión: %v", err))
+		return
+	}
+
+	h.respondWithSuccess(ctx, map[string]interface{}{
+		"complete": false,
+		"question": question.Public(number),
+	}, fmt.Sprintf("Pregunta %d obtenida exitosamente", number))
+}
+
+// SelectOption maneja POST /api/sessions/{id}/select: marca la opción que el
+// jugador está considerando sin puntuarla; SubmitAnswer la confirma
+func (h *SessionHandler) SelectOption(ctx *fasthttp.RequestCtx) {
+	sessionID, ok := h.sessionIDParam(ctx)
+	if !ok {
+		return
+	}
+	if !h.authorizeSession(ctx, sessionID) {
+		return
+	}
+
+	var request models.SelectRequest
+	if err := json.Unmarshal(ctx.PostBody(), &request); err != nil {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, "JSON inválido")
+		return
+	}
+
+	session, err := h.sessionService.GetSession(sessionID)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, "Sesión no encontrada")
+		return
+	}
+
+	if session.CurrentQuestionID > 0 && request.QuestionID != session.CurrentQuestionID {
+		h.respondWithError(ctx, fasthttp.StatusConflict, fmt.Sprintf("La pregunta %d no es la pregunta actual de la sesión", request.QuestionID))
+		return
+	}
+
+	question, err := h.questionService.GetQuestion(request.QuestionID)
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Pregunta no encontrada (ID: %d)", request.QuestionID))
+		return
+	}
+
+	option := h.questionService.CanonicalOption(question, request.SelectedOption)
+	if _, ok := question.Options[option]; !ok {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("La opción %q no existe en la pregunta", request.SelectedOption))
+		return
+	}
+
+	session, err = h.sessionService.SelectOption(sessionID, request.QuestionID, option)
+	if errors.Is(err, services.ErrSessionNotPlaying) {
+		h.respondWithError(ctx, fasthttp.StatusConflict, "La sesión ya no está en juego")
+		return
+	}
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error guardando selección: %v", err))
+		return
+	}
+
+	h.hub.BroadcastMessage("answerSelected", map[string]interface{}{
+		"playerName":     session.PlayerName,
+		"sessionId":      session.ID,
+		"questionNumber": session.TentativeSelection.QuestionNumber,
+		"selectedOption": option,
+		"timestamp":      models.FormatTime(session.TentativeSelection.SelectedAt),
+		"message":        fmt.Sprintf("%s está considerando la %s", session.PlayerName, option),
+	})
+
+	h.respondWithSuccess(ctx, session.TentativeSelection, "Selección registrada; confírmala como respuesta final")
+}
+
+// RejoinSession maneja POST /api/sessions/{id}/rejoin: un jugador eliminado
+// vuelve a la pregunta en curso si todavía está dentro de REJOIN_WINDOW
+func (h *SessionHandler) RejoinSession(ctx *fasthttp.RequestCtx) {
+	sessionID, ok := h.sessionIDParam(ctx)
+	if !ok {
+		return
+	}
+	if !h.authorizeSession(ctx, sessionID) {
+		return
+	}
+
+	currentQuestion := 0
+	if h.gameStateService != nil {
+		gameState, err := h.gameStateService.GetGameState()
+		if err != nil {
+			h.respondWithError(ctx, fasthttp.StatusInternalServerError, "Error obteniendo estado del juego")
+			return
+		}
+		currentQuestion = gameState.QuestionNumber
+	}
+
+	session, err := h.sessionService.RejoinSession(sessionID, currentQuestion)
+	if errors.Is(err, services.ErrRejoinNotAllowed) || errors.Is(err, services.ErrRejoinWindowExpired) {
+		h.respondWithError(ctx, fasthttp.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("Sesión no encontrada: %v", err))
+		return
 	}
 
-	h.respondWithSuccess(ctx, responseData, fmt.Sprintf("%d sesiones activas obtenidas", len(sessions)))
+	h.hub.BroadcastMessage("playerRejoined", map[string]interface{}{
+		"sessionId":      session.ID,
+		"playerName":     session.PlayerName,
+		"questionNumber": session.CurrentQuestion,
+		"timestamp":      models.Timestamp(),
+		"message":        fmt.Sprintf("%s volvió a la partida", session.PlayerName),
+	})
+
+	h.respondWithSuccess(ctx, models.SessionResponse{Session: session}, "Volviste a la partida con una vida")
 }
 
 // SubmitAnswer maneja POST /api/sessions/{id}/answer
 func (h *SessionHandler) SubmitAnswer(ctx *fasthttp.RequestCtx) {
-	sessionID := ctx.UserValue("id").(string)
+	// Hora de llegada según el servidor, antes de cualquier lectura de Redis,
+	// para ordenar las respuestas en la línea de tiempo de cada pregunta
+	receivedAt := time.Now().UTC()
 
-	// Estructura para recibir la respuesta
-	var answerRequest struct {
-		QuestionID     int    `json:"questionId"`
-		SelectedOption string `json:"selectedOption"`
-		TimeToAnswer   int    `json:"timeToAnswer"`
+	sessionID, ok := h.sessionIDParam(ctx)
+	if !ok {
+		return
+	}
+	if !h.authorizeSession(ctx, sessionID) {
+		return
 	}
 
-	if err := json.Unmarshal(ctx.PostBody(), &answerRequest); err != nil {
+	var answerRequest models.AnswerRequest
+	if isFormRequest(ctx) {
+		if err := parseAnswerForm(ctx.PostArgs(), &answerRequest); err != nil {
+			h.respondWithError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("Formulario inválido: %v", err))
+			return
+		}
+	} else if err := json.Unmarshal(ctx.PostBody(), &answerRequest); err != nil {
 		h.respondWithError(ctx, fasthttp.StatusBadRequest, "JSON inválido")
 		return
 	}
 
-	// Validar que el ID de pregunta sea válido
-	if answerRequest.QuestionID <= 0 {
-		log.Printf("❌ ID de pregunta inválido recibido: %d", answerRequest.QuestionID)
-		h.respondWithError(ctx, fasthttp.StatusBadRequest, "ID de pregunta inválido")
+	if fieldErr := answerRequest.Validate(); fieldErr != nil {
+		log.Printf("❌ Respuesta inválida recibida: %v", fieldErr)
+		h.respondWithJSON(ctx, fasthttp.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   fieldErr.Message,
+			Data:    fieldErr,
+		})
 		return
 	}
 
@@ -153,6 +812,33 @@ func (h *SessionHandler) SubmitAnswer(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	// La respuesta debe corresponder a la pregunta servida a la sesión
+	if session.CurrentQuestionID > 0 && answerRequest.QuestionID != session.CurrentQuestionID {
+		h.respondWithError(ctx, fasthttp.StatusConflict, fmt.Sprintf("La pregunta %d no es la pregunta actual de la sesión", answerRequest.QuestionID))
+		return
+	}
+
+	// Mientras la pregunta se lee en voz alta no se aceptan respuestas
+	if h.gameStateService != nil && h.gameStateService.TwoPhaseQuestions() {
+		gameState, err := h.gameStateService.GetGameState()
+		if err == nil && gameState.QuestionNumber == session.CurrentQuestion && !gameState.AnswersOpen {
+			h.respondWithError(ctx, fasthttp.StatusConflict, "Las respuestas aún no están abiertas")
+			return
+		}
+	}
+
+	// Una respuesta antes del tiempo mínimo (medido en el servidor) es sospechosa
+	suspicious := false
+	if elapsed, ok := h.serverAnswerTime(session.CurrentQuestion); ok && elapsed < h.minAnswerTime {
+		if h.rejectFast {
+			log.Printf("🚫 Respuesta de %s a la pregunta %d rechazada: %v desde que se abrió", session.PlayerName, session.CurrentQuestion, elapsed)
+			h.respondWithError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("Respuesta demasiado rápida; espera al menos %v", h.minAnswerTime))
+			return
+		}
+		log.Printf("⚠️ Respuesta sospechosa de %s a la pregunta %d: %v desde que se abrió", session.PlayerName, session.CurrentQuestion, elapsed)
+		suspicious = true
+	}
+
 	// Obtener la pregunta para verificar la respuesta
 	log.Printf("🔍 Buscando pregunta con ID: %d", answerRequest.QuestionID)
 	question, err := h.questionService.GetQuestion(answerRequest.QuestionID)
@@ -162,9 +848,19 @@ func (h *SessionHandler) SubmitAnswer(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	// Crear la respuesta del jugador
+	// Crear la respuesta del jugador (con la clave de la opción según el modo de comparación)
+	answerRequest.SelectedOption = h.questionService.CanonicalOption(question, answerRequest.SelectedOption)
+
+	// En modo estricto la respuesta final debe ser la opción seleccionada
+	strictFinal, showExplanation := h.answerSettings()
+	if selection := session.TentativeSelection; strictFinal && selection != nil &&
+		selection.QuestionID == answerRequest.QuestionID && selection.Option != answerRequest.SelectedOption {
+		h.respondWithError(ctx, fasthttp.StatusConflict, fmt.Sprintf("La respuesta final debe ser la opción seleccionada (%s); selecciona otra antes de confirmar", selection.Option))
+		return
+	}
+
 	isCorrect := answerRequest.SelectedOption == question.Correct
-	prizeWon := 0
+	prizeWon := int64(0)
 	if isCorrect && session.CurrentQuestion <= len(models.PrizeLevels) {
 		prizeWon = models.PrizeLevels[session.CurrentQuestion-1]
 	}
@@ -176,8 +872,10 @@ func (h *SessionHandler) SubmitAnswer(ctx *fasthttp.RequestCtx) {
 		CorrectOption:  question.Correct,
 		IsCorrect:      isCorrect,
 		TimeToAnswer:   answerRequest.TimeToAnswer,
-		Timestamp:      time.Now(),
+		Timestamp:      receivedAt,
 		PrizeWon:       prizeWon,
+		Wager:          answerRequest.Wager,
+		Suspicious:     suspicious,
 	}
 
 	// Agregar la respuesta a la sesión
@@ -197,7 +895,7 @@ func (h *SessionHandler) SubmitAnswer(ctx *fasthttp.RequestCtx) {
 		resultText = "Incorrecto"
 	}
 
-	h.hub.BroadcastMessage("answerSubmitted", map[string]interface{}{
+	answerEvent := map[string]interface{}{
 		"playerName":     session.PlayerName,
 		"sessionId":      sessionID,
 		"questionNumber": session.CurrentQuestion,
@@ -206,20 +904,54 @@ func (h *SessionHandler) SubmitAnswer(ctx *fasthttp.RequestCtx) {
 		"isCorrect":      isCorrect,
 		"prizeWon":       prizeWon,
 		"timeToAnswer":   answerRequest.TimeToAnswer,
-		"timestamp":      time.Now().Format(time.RFC3339),
+		"suspicious":     suspicious,
+		"timestamp":      models.Timestamp(),
 		"message":        fmt.Sprintf("%s respondió %s - %s", session.PlayerName, answerRequest.SelectedOption, resultText),
 		"icon":           resultIcon,
-	})
+	}
+	if h.answerBatcher != nil {
+		h.answerBatcher.Add(answerEvent)
+	} else {
+		h.hub.BroadcastMessage("answerSubmitted", answerEvent)
+	}
+
+	// Con la última respuesta pendiente el presentador puede avanzar
+	if !session.IsPractice() {
+		allAnswered, err := h.sessionService.MarkAllAnswered(session.CurrentQuestion)
+		if err != nil {
+			log.Printf("⚠️ Error comprobando si todos respondieron la pregunta %d: %v", session.CurrentQuestion, err)
+		} else if allAnswered {
+			h.hub.BroadcastMessage("allAnswered", map[string]interface{}{
+				"questionNumber": session.CurrentQuestion,
+				"timestamp":      models.Timestamp(),
+				"message":        fmt.Sprintf("Todos los jugadores respondieron la pregunta %d", session.CurrentQuestion),
+			})
+		}
+	}
 
 	log.Printf("📝 %s respondió %s en pregunta %d: %s", session.PlayerName, answerRequest.SelectedOption, session.CurrentQuestion, resultText)
+	if h.sessionOver != nil && !session.IsPractice() && updatedSession != nil && updatedSession.GameStatus != "active" {
+		go h.sessionOver()
+	}
 
 	responseData := models.SessionResponse{
 		Session: updatedSession,
 	}
+	if showExplanation {
+		responseData.Explanation = question.Explanation
+	}
 
 	message := "Respuesta guardada"
-	if isCorrect {
+	if session.IsWager() && updatedSession != nil {
+		if isCorrect {
+			message = fmt.Sprintf("¡Correcto! Tu premio acumulado es $%d", updatedSession.TotalPrize)
+		} else {
+			message = fmt.Sprintf("Respuesta incorrecta. Tu premio acumulado es $%d", updatedSession.TotalPrize)
+		}
+	} else if isCorrect {
 		message = fmt.Sprintf("¡Correcto! Has ganado $%d", prizeWon)
+	} else if updatedSession != nil && updatedSession.GameStatus == "active" {
+		message = fmt.Sprintf("Respuesta incorrecta. Te quedan %d vidas.", updatedSession.LivesRemaining)
 	} else {
 		message = "Respuesta incorrecta. Ahora estás en modo espectador."
 	}
@@ -229,13 +961,21 @@ func (h *SessionHandler) SubmitAnswer(ctx *fasthttp.RequestCtx) {
 
 // UseLifeline maneja POST /api/sessions/{id}/lifeline
 func (h *SessionHandler) UseLifeline(ctx *fasthttp.RequestCtx) {
-	sessionID := ctx.UserValue("id").(string)
+	sessionID, ok := h.sessionIDParam(ctx)
+	if !ok {
+		return
+	}
+	if !h.authorizeSession(ctx, sessionID) {
+		return
+	}
 
 	var lifelineRequest struct {
 		Type string `json:"type"`
 	}
 
-	if err := json.Unmarshal(ctx.PostBody(), &lifelineRequest); err != nil {
+	if isFormRequest(ctx) {
+		lifelineRequest.Type = string(ctx.PostArgs().Peek("type"))
+	} else if err := json.Unmarshal(ctx.PostBody(), &lifelineRequest); err != nil {
 		h.respondWithError(ctx, fasthttp.StatusBadRequest, "JSON inválido")
 		return
 	}
@@ -254,7 +994,7 @@ func (h *SessionHandler) UseLifeline(ctx *fasthttp.RequestCtx) {
 		"sessionId":       sessionID,
 		"lifelineType":    lifelineRequest.Type,
 		"currentQuestion": session.CurrentQuestion,
-		"timestamp":       time.Now().Format(time.RFC3339),
+		"timestamp":       models.Timestamp(),
 		"message":         fmt.Sprintf("%s usó el comodín: %s", session.PlayerName, lifelineRequest.Type),
 	})
 
@@ -269,12 +1009,21 @@ func (h *SessionHandler) UseLifeline(ctx *fasthttp.RequestCtx) {
 
 // FinishSession maneja POST /api/sessions/{id}/finish
 func (h *SessionHandler) FinishSession(ctx *fasthttp.RequestCtx) {
-	sessionID := ctx.UserValue("id").(string)
+	sessionID, ok := h.sessionIDParam(ctx)
+	if !ok {
+		return
+	}
+	if !h.authorizeSession(ctx, sessionID) {
+		return
+	}
 
 	if err := h.sessionService.FinishSession(sessionID); err != nil {
 		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error terminando sesión: %v", err))
 		return
 	}
+	if h.sessionOver != nil {
+		go h.sessionOver()
+	}
 
 	h.respondWithSuccess(ctx, nil, "Sesión terminada exitosamente")
 }
@@ -315,6 +1064,111 @@ func (h *SessionHandler) GetPlayersStatus(ctx *fasthttp.RequestCtx) {
 	h.respondWithSuccess(ctx, status, "Estado de jugadores obtenido exitosamente")
 }
 
+// GetSpectators maneja GET /api/admin/spectators
+func (h *SessionHandler) GetSpectators(ctx *fasthttp.RequestCtx) {
+	spectators, err := h.sessionService.GetSpectators()
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error obteniendo espectadores: %v", err))
+		return
+	}
+
+	h.respondWithSuccess(ctx, spectators, fmt.Sprintf("%d jugadores eliminados", len(spectators)))
+}
+
+// serverAnswerTime tiempo transcurrido desde que se abrió la pregunta número
+// questionNumber; ok es false sin tiempo mínimo o si no es la pregunta en curso
+func (h *SessionHandler) serverAnswerTime(questionNumber int) (time.Duration, bool) {
+	if h.minAnswerTime <= 0 || h.gameStateService == nil {
+		return 0, false
+	}
+	gameState, err := h.gameStateService.GetGameState()
+	if err != nil || gameState.QuestionNumber != questionNumber || gameState.QuestionStartedAt == nil {
+		return 0, false
+	}
+	return time.Since(*gameState.QuestionStartedAt), true
+}
+
+// isFormRequest indica si el cuerpo llega como application/x-www-form-urlencoded
+// en lugar de JSON (clientes mínimos, curl -d)
+func isFormRequest(ctx *fasthttp.RequestCtx) bool {
+	contentType := ctx.Request.Header.ContentType()
+	if i := bytes.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return bytes.EqualFold(bytes.TrimSpace(contentType), []byte("application/x-www-form-urlencoded"))
+}
+
+// parseAnswerForm llena una respuesta desde un formulario; los campos numéricos
+// vacíos quedan en cero
+func parseAnswerForm(args *fasthttp.Args, request *models.AnswerRequest) error {
+	request.SelectedOption = string(args.Peek("selectedOption"))
+	fields := []struct {
+		name string
+		set  func(int64)
+	}{
+		{"questionId", func(v int64) { request.QuestionID = int(v) }},
+		{"timeToAnswer", func(v int64) { request.TimeToAnswer = int(v) }},
+		{"wager", func(v int64) { request.Wager = v }},
+	}
+	for _, field := range fields {
+		raw := args.Peek(field.name)
+		if len(raw) == 0 {
+			continue
+		}
+		value, err := strconv.ParseInt(string(raw), 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s debe ser un número", field.name)
+		}
+		field.set(value)
+	}
+	return nil
+}
+
+// authorizeSession exige el token de la sesión en la cabecera X-Session-Token.
+// Una sesión que no existe se responde como 404, igual que en GetSession.
+func (h *SessionHandler) authorizeSession(ctx *fasthttp.RequestCtx, sessionID string) bool {
+	token := string(ctx.Request.Header.Peek("X-Session-Token"))
+	err := h.sessionService.VerifySessionToken(sessionID, token)
+	if errors.Is(err, services.ErrInvalidSessionToken) {
+		if _, err := h.sessionService.GetSession(sessionID); err != nil && !errors.Is(err, services.ErrSessionCorrupt) {
+			h.respondWithError(ctx, fasthttp.StatusNotFound, "Sesión no encontrada")
+			return false
+		}
+		h.respondWithError(ctx, fasthttp.StatusUnauthorized, "Token de sesión inválido")
+		return false
+	}
+	if err != nil {
+		h.respondWithError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("Error verificando sesión: %v", err))
+		return false
+	}
+	return true
+}
+
+// pathParam obtiene un parámetro de ruta; si falta o no es texto responde 400
+func (h *SessionHandler) pathParam(ctx *fasthttp.RequestCtx, name string) (string, bool) {
+	value, ok := ctx.UserValue(name).(string)
+	if !ok || value == "" {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("Parámetro '%s' requerido en la ruta", name))
+		return "", false
+	}
+	return value, true
+}
+
+// sessionIDParam obtiene el ID de sesión de la ruta y verifica que sea un UUID
+// antes de consultar Redis: un ID mal formado es 400, uno válido que no existe
+// lo resuelve cada handler como 404
+func (h *SessionHandler) sessionIDParam(ctx *fasthttp.RequestCtx) (string, bool) {
+	sessionID, ok := h.pathParam(ctx, "id")
+	if !ok {
+		return "", false
+	}
+	if _, err := uuid.Parse(sessionID); err != nil {
+		h.respondWithError(ctx, fasthttp.StatusBadRequest, "ID de sesión inválido")
+		return "", false
+	}
+	return sessionID, true
+}
+
 // Métodos auxiliares para respuestas HTTP
 func (h *SessionHandler) respondWithJSON(ctx *fasthttp.RequestCtx, statusCode int, response interface{}) {
 	ctx.Response.Header.Set("Content-Type", "application/json")
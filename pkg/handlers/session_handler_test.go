@@ -0,0 +1,904 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/backsoul/quiz/pkg/models"
+	"github.com/backsoul/quiz/pkg/redis"
+	"github.com/backsoul/quiz/pkg/services"
+	websocketHub "github.com/backsoul/quiz/pkg/websocket"
+	"github.com/fasthttp/websocket"
+	"github.com/valyala/fasthttp"
+)
+
+// sessionEnv handler de sesiones armado como en main, sobre un MemoryStore con 8 preguntas
+type sessionEnv struct {
+	store     *redis.MemoryStore
+	sessions  *services.SessionService
+	questions *services.QuestionService
+	gameState *services.GameStateService
+	hub       *websocketHub.Hub
+	h         *SessionHandler
+}
+
+func newSessionEnv(t *testing.T) *sessionEnv {
+	t.Helper()
+	store := redis.NewMemoryStore()
+	loadTestQuestions(t, store, 8)
+
+	sessions := services.NewSessionService(store)
+	questions := services.NewQuestionService(store)
+	gameState := services.NewGameStateService(store)
+	gameState.SetSessionService(sessions)
+	gameState.SetCacheTTL(0)
+
+	hub := websocketHub.NewHub()
+	go hub.Run()
+
+	h := NewSessionHandler(sessions, questions, hub)
+	h.SetGameStateService(gameState)
+	return &sessionEnv{
+		store:     store,
+		sessions:  sessions,
+		questions: questions,
+		gameState: gameState,
+		hub:       hub,
+		h:         h,
+	}
+}
+
+// createSession crea la sesión por el handler y devuelve la sesión y su token
+func (e *sessionEnv) createSession(t *testing.T, playerName string) (*models.GameSession, string) {
+	t.Helper()
+	ctx := newRequestCtx("POST", "/api/sessions", fmt.Sprintf(`{"playerName":%q}`, playerName))
+	e.h.CreateSession(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("error creando sesión de %s: %d %s", playerName, ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	var response models.SessionResponse
+	decodeResponse(t, ctx, &response)
+	if response.Session == nil || response.Token == "" {
+		t.Fatalf("la respuesta debe incluir sesión y token: %s", ctx.Response.Body())
+	}
+	return response.Session, response.Token
+}
+
+// call invoca un handler de sesión con el id en la ruta y, si no está vacío,
+// el token en X-Session-Token
+func (e *sessionEnv) call(handler fasthttp.RequestHandler, sessionID, token, body string) *fasthttp.RequestCtx {
+	ctx := newRequestCtx("POST", "/api/sessions/"+sessionID, body)
+	ctx.SetUserValue("id", sessionID)
+	if token != "" {
+		ctx.Request.Header.Set("X-Session-Token", token)
+	}
+	handler(ctx)
+	return ctx
+}
+
+func TestSessionHandlersRejectMissingID(t *testing.T) {
+	env := newSessionEnv(t)
+
+	routes := map[string]fasthttp.RequestHandler{
+		"GetSession":           env.h.GetSession,
+		"GetCertificate":       env.h.GetCertificate,
+		"GetAnsweredQuestions": env.h.GetAnsweredQuestions,
+		"GetNextPrize":         env.h.GetNextPrize,
+		"GetNextQuestion":      env.h.GetNextQuestion,
+		"SelectOption":         env.h.SelectOption,
+		"SubmitAnswer":         env.h.SubmitAnswer,
+		"UseLifeline":          env.h.UseLifeline,
+		"FinishSession":        env.h.FinishSession,
+	}
+
+	for name, handler := range routes {
+		// Sin valor en la ruta
+		ctx := newRequestCtx("POST", "/api/sessions/", `{"questionId":1,"selectedOption":"A"}`)
+		handler(ctx)
+		if ctx.Response.StatusCode() != fasthttp.StatusBadRequest {
+			t.Errorf("%s sin id: esperaba 400, obtuve %d", name, ctx.Response.StatusCode())
+		}
+
+		// Con un valor que no es texto
+		ctx = newRequestCtx("POST", "/api/sessions/", `{"questionId":1,"selectedOption":"A"}`)
+		ctx.SetUserValue("id", 42)
+		handler(ctx)
+		if ctx.Response.StatusCode() != fasthttp.StatusBadRequest {
+			t.Errorf("%s con id no textual: esperaba 400, obtuve %d", name, ctx.Response.StatusCode())
+		}
+		if response := decodeResponse(t, ctx, nil); response.Success || response.Error == "" {
+			t.Errorf("%s: esperaba un error explicado, obtuve %+v", name, response)
+		}
+	}
+}
+
+func TestSessionTokenRequired(t *testing.T) {
+	env := newSessionEnv(t)
+	session, token := env.createSession(t, "Ana")
+	answer := fmt.Sprintf(`{"questionId":%d,"selectedOption":"A"}`, session.CurrentQuestionID)
+
+	routes := []struct {
+		name    string
+		handler fasthttp.RequestHandler
+		body    string
+	}{
+		{"SubmitAnswer", env.h.SubmitAnswer, answer},
+		{"UseLifeline", env.h.UseLifeline, `{"type":"fiftyFifty"}`},
+		{"FinishSession", env.h.FinishSession, ""},
+	}
+
+	for _, route := range routes {
+		for _, bad := range []string{"", "token-incorrecto", token + "x"} {
+			ctx := env.call(route.handler, session.ID, bad, route.body)
+			if ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+				t.Fatalf("%s con token %q: esperaba 401, obtuve %d", route.name, bad, ctx.Response.StatusCode())
+			}
+		}
+	}
+	if stored, _ := env.sessions.GetSession(session.ID); len(stored.AnswersGiven) != 0 || stored.GameStatus != "active" {
+		t.Fatalf("las peticiones rechazadas no deben modificar la sesión: %+v", stored)
+	}
+
+	// El token de otra sesión tampoco sirve
+	_, otherToken := env.createSession(t, "Beto")
+	if ctx := env.call(env.h.SubmitAnswer, session.ID, otherToken, answer); ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Fatalf("el token de otra sesión debe rechazarse, obtuve %d", ctx.Response.StatusCode())
+	}
+
+	for _, route := range routes {
+		ctx := env.call(route.handler, session.ID, token, route.body)
+		if ctx.Response.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("%s con el token correcto: esperaba 200, obtuve %d: %s", route.name, ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+	}
+}
+
+func TestSeedDemo(t *testing.T) {
+	env := newSessionEnv(t)
+
+	ctx := newRequestCtx("POST", "/api/admin/seed-demo?players=5&seed=3", "")
+	env.h.SeedDemo(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	var response models.SessionResponse
+	decodeResponse(t, ctx, &response)
+	if len(response.Sessions) != 5 {
+		t.Fatalf("esperaba 5 sesiones, hay %d", len(response.Sessions))
+	}
+
+	for _, players := range []string{"0", "501", "muchos"} {
+		ctx := newRequestCtx("POST", "/api/admin/seed-demo?players="+players, "")
+		env.h.SeedDemo(ctx)
+		if ctx.Response.StatusCode() != fasthttp.StatusBadRequest {
+			t.Fatalf("players=%s: esperaba 400, obtuve %d", players, ctx.Response.StatusCode())
+		}
+	}
+}
+
+func TestNextQuestionAnswerNext(t *testing.T) {
+	env := newSessionEnv(t)
+	env.sessions.SetMaxQuestions(2)
+	session, token := env.createSession(t, "Ana")
+
+	type nextData struct {
+		Complete bool                   `json:"complete"`
+		Question *models.PublicQuestion `json:"question"`
+	}
+	next := func() nextData {
+		t.Helper()
+		ctx := env.call(env.h.GetNextQuestion, session.ID, token, "")
+		if ctx.Response.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("next: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+		if strings.Contains(string(ctx.Response.Body()), "correct") {
+			t.Fatalf("next no debe revelar la respuesta correcta: %s", ctx.Response.Body())
+		}
+		var data nextData
+		decodeResponse(t, ctx, &data)
+		return data
+	}
+	answer := func(questionID int) *fasthttp.RequestCtx {
+		return env.call(env.h.SubmitAnswer, session.ID, token, fmt.Sprintf(`{"questionId":%d,"selectedOption":"A"}`, questionID))
+	}
+
+	// El plan está en orden inverso: la pregunta 1 es el ID 8
+	first := next()
+	if first.Complete || first.Question == nil || first.Question.Number != 1 || first.Question.ID != 8 {
+		t.Fatalf("primera pregunta inesperada: %+v", first)
+	}
+	if ctx := answer(8); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("answer: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	second := next()
+	if second.Complete || second.Question.Number != 2 || second.Question.ID != 7 {
+		t.Fatalf("segunda pregunta inesperada: %+v", second.Question)
+	}
+	if stored, _ := env.sessions.GetSession(session.ID); stored.CurrentQuestionID != 7 {
+		t.Fatalf("next debe fijar la pregunta a responder, quedó %d", stored.CurrentQuestionID)
+	}
+	// Responder otra pregunta que la servida se rechaza
+	if ctx := answer(8); ctx.Response.StatusCode() != fasthttp.StatusConflict {
+		t.Fatalf("respuesta a otra pregunta: esperaba 409, obtuve %d", ctx.Response.StatusCode())
+	}
+	if ctx := answer(7); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("answer: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	if done := next(); !done.Complete || done.Question != nil {
+		t.Fatalf("tras la última pregunta esperaba el juego completado: %+v", done)
+	}
+}
+
+// dial abre un WebSocket registrado en el hub del entorno
+func (e *sessionEnv) dial(t *testing.T) *websocket.Conn {
+	t.Helper()
+	env := &testEnv{
+		store:     e.store,
+		gameState: e.gameState,
+		sessions:  e.sessions,
+		hub:       e.hub,
+		gc:        NewGameControlHandler(e.gameState, e.sessions, e.hub),
+	}
+	return env.dial(t, "")
+}
+
+func TestReconnectDoesNotRebroadcastJoin(t *testing.T) {
+	env := newSessionEnv(t)
+	conn := env.dial(t)
+
+	session, token := env.createSession(t, "Ana")
+	if joined := readMessage(t, conn, "playerJoined"); joined["sessionId"] != session.ID {
+		t.Fatalf("playerJoined inesperado: %v", joined)
+	}
+
+	// Recargar con el mismo nombre o reconectarse con el ID (y el token) devuelve la misma sesión
+	for _, body := range []string{`{"playerName":"Ana"}`, fmt.Sprintf(`{"playerName":"Ana","sessionId":%q}`, session.ID)} {
+		ctx := newRequestCtx("POST", "/api/sessions", body)
+		ctx.Request.Header.Set("X-Session-Token", token)
+		env.h.CreateSession(ctx)
+		var reconnected models.SessionResponse
+		decodeResponse(t, ctx, &reconnected)
+		if reconnected.Session == nil || reconnected.Session.ID != session.ID {
+			t.Fatalf("la reconexión debe devolver la misma sesión")
+		}
+	}
+
+	env.hub.BroadcastMessage("marker", nil)
+	for _, msgType := range typesUntil(t, conn, "marker") {
+		if msgType == "playerJoined" {
+			t.Fatalf("una reconexión no debe difundir playerJoined")
+		}
+	}
+
+	// Un jugador nuevo sí se anuncia
+	other, _ := env.createSession(t, "Luis")
+	if joined := readMessage(t, conn, "playerJoined"); joined["sessionId"] != other.ID {
+		t.Fatalf("playerJoined inesperado: %v", joined)
+	}
+}
+
+func TestSubmitAnswerFieldErrors(t *testing.T) {
+	env := newSessionEnv(t)
+	session, token := env.createSession(t, "Ana")
+	id := session.CurrentQuestionID
+
+	cases := []struct {
+		body  string
+		field string
+	}{
+		{`{"selectedOption":"A"}`, "questionId"},
+		{fmt.Sprintf(`{"questionId":%d}`, id), "selectedOption"},
+		{fmt.Sprintf(`{"questionId":%d,"selectedOption":" "}`, id), "selectedOption"},
+		{fmt.Sprintf(`{"questionId":%d,"selectedOption":"A","timeToAnswer":-3}`, id), "timeToAnswer"},
+		{fmt.Sprintf(`{"questionId":%d,"selectedOption":"A","wager":-1}`, id), "wager"},
+	}
+	for _, c := range cases {
+		ctx := env.call(env.h.SubmitAnswer, session.ID, token, c.body)
+		if ctx.Response.StatusCode() != fasthttp.StatusBadRequest {
+			t.Fatalf("%s: esperaba 400, obtuve %d", c.body, ctx.Response.StatusCode())
+		}
+		var fieldErr models.FieldError
+		response := decodeResponse(t, ctx, &fieldErr)
+		if response.Success || fieldErr.Field != c.field || response.Error != fieldErr.Message {
+			t.Fatalf("%s: esperaba error en %s, obtuve %+v (%+v)", c.body, c.field, fieldErr, response)
+		}
+	}
+	if stored, _ := env.sessions.GetSession(session.ID); len(stored.AnswersGiven) != 0 {
+		t.Fatalf("una respuesta inválida no debe guardarse")
+	}
+}
+
+func TestSelectThenConfirm(t *testing.T) {
+	env := newSessionEnv(t)
+	env.h.SetStrictFinalAnswer(true)
+	conn := env.dial(t)
+	session, token := env.createSession(t, "Ana")
+	id := session.CurrentQuestionID
+
+	ctx := env.call(env.h.SelectOption, session.ID, token, fmt.Sprintf(`{"questionId":%d,"selectedOption":"A"}`, id))
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("select: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	selected := readMessage(t, conn, "answerSelected")
+	if selected["selectedOption"] != "A" || !strings.Contains(selected["message"].(string), "Ana está considerando la A") {
+		t.Fatalf("answerSelected inesperado: %v", selected)
+	}
+
+	// Seleccionar no puntúa
+	stored, _ := env.sessions.GetSession(session.ID)
+	if len(stored.AnswersGiven) != 0 || stored.TotalPrize != 0 || stored.TentativeSelection == nil || stored.TentativeSelection.Option != "A" {
+		t.Fatalf("la selección debe guardarse sin puntuar: %+v", stored)
+	}
+
+	ctx = env.call(env.h.SubmitAnswer, session.ID, token, fmt.Sprintf(`{"questionId":%d,"selectedOption":"A"}`, id))
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("confirmar: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	stored, _ = env.sessions.GetSession(session.ID)
+	if len(stored.AnswersGiven) != 1 || !stored.AnswersGiven[0].IsCorrect || stored.TotalPrize == 0 || stored.TentativeSelection != nil {
+		t.Fatalf("confirmar debe puntuar y limpiar la selección: %+v", stored)
+	}
+}
+
+func TestSelectThenChange(t *testing.T) {
+	env := newSessionEnv(t)
+	env.h.SetStrictFinalAnswer(true)
+	session, token := env.createSession(t, "Ana")
+	id := session.CurrentQuestionID
+	body := func(option string) string {
+		return fmt.Sprintf(`{"questionId":%d,"selectedOption":%q}`, id, option)
+	}
+
+	for _, option := range []string{"B", "A"} {
+		if ctx := env.call(env.h.SelectOption, session.ID, token, body(option)); ctx.Response.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("select %s: esperaba 200, obtuve %d", option, ctx.Response.StatusCode())
+		}
+	}
+	if stored, _ := env.sessions.GetSession(session.ID); stored.TentativeSelection.Option != "A" {
+		t.Fatalf("cambiar de opción reemplaza la selección: %+v", stored.TentativeSelection)
+	}
+
+	// En modo estricto confirmar otra opción se rechaza sin puntuar
+	if ctx := env.call(env.h.SubmitAnswer, session.ID, token, body("B")); ctx.Response.StatusCode() != fasthttp.StatusConflict {
+		t.Fatalf("confirmar otra opción: esperaba 409, obtuve %d", ctx.Response.StatusCode())
+	}
+	if stored, _ := env.sessions.GetSession(session.ID); len(stored.AnswersGiven) != 0 {
+		t.Fatalf("una confirmación rechazada no debe guardarse")
+	}
+	if ctx := env.call(env.h.SubmitAnswer, session.ID, token, body("A")); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("confirmar la selección: esperaba 200, obtuve %d", ctx.Response.StatusCode())
+	}
+
+	// Sin modo estricto la respuesta final puede diferir de la selección
+	env.h.SetStrictFinalAnswer(false)
+	other, otherToken := env.createSession(t, "Beto")
+	id = other.CurrentQuestionID
+	env.call(env.h.SelectOption, other.ID, otherToken, body("A"))
+	if ctx := env.call(env.h.SubmitAnswer, other.ID, otherToken, body("B")); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("sin modo estricto: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	if stored, _ := env.sessions.GetSession(other.ID); len(stored.AnswersGiven) != 1 || stored.AnswersGiven[0].SelectedOption != "B" {
+		t.Fatalf("la respuesta final manda sobre la selección: %+v", stored.AnswersGiven)
+	}
+}
+
+func TestTwoPhaseAnswersRejectedUntilOpened(t *testing.T) {
+	env := newSessionEnv(t)
+	env.gameState.SetTwoPhaseQuestions(true)
+	gc := NewGameControlHandler(env.gameState, env.sessions, env.hub)
+	if err := env.gameState.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+	session, token := env.createSession(t, "Ana")
+	answer := fmt.Sprintf(`{"questionId":%d,"selectedOption":"A"}`, session.CurrentQuestionID)
+
+	// Abrir antes de mostrar una pregunta no tiene sentido
+	ctx := newRequestCtx("POST", "/api/game/open-answers", "")
+	gc.OpenAnswers(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusConflict {
+		t.Fatalf("abrir sin pregunta mostrada: esperaba 409, obtuve %d", ctx.Response.StatusCode())
+	}
+
+	ctx = newRequestCtx("POST", "/api/game/next-question", "")
+	gc.NextQuestion(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("next-question: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	if state, _ := env.gameState.GetGameState(); state.AnswersOpen || state.QuestionDeadline != nil {
+		t.Fatalf("mostrar la pregunta no abre respuestas ni inicia el temporizador: %+v", state)
+	}
+
+	if ctx := env.call(env.h.SubmitAnswer, session.ID, token, answer); ctx.Response.StatusCode() != fasthttp.StatusConflict {
+		t.Fatalf("antes de abrir: esperaba 409, obtuve %d", ctx.Response.StatusCode())
+	}
+	if stored, _ := env.sessions.GetSession(session.ID); len(stored.AnswersGiven) != 0 {
+		t.Fatalf("una respuesta antes de abrir no debe guardarse")
+	}
+
+	ctx = newRequestCtx("POST", "/api/game/open-answers", "")
+	gc.OpenAnswers(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("open-answers: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	if state, _ := env.gameState.GetGameState(); !state.AnswersOpen || state.QuestionDeadline == nil {
+		t.Fatalf("abrir las respuestas inicia el temporizador: %+v", state)
+	}
+	ctx = newRequestCtx("POST", "/api/game/open-answers", "")
+	gc.OpenAnswers(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusConflict {
+		t.Fatalf("abrir dos veces: esperaba 409, obtuve %d", ctx.Response.StatusCode())
+	}
+
+	if ctx := env.call(env.h.SubmitAnswer, session.ID, token, answer); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("después de abrir: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+}
+
+func TestAnsweredQuestions(t *testing.T) {
+	env := newSessionEnv(t)
+	session, token := env.createSession(t, "Ana")
+	answered := func(token string) (*fasthttp.RequestCtx, []models.AnsweredQuestion) {
+		t.Helper()
+		ctx := env.call(env.h.GetAnsweredQuestions, session.ID, token, "")
+		var data []models.AnsweredQuestion
+		if ctx.Response.StatusCode() == fasthttp.StatusOK {
+			decodeResponse(t, ctx, &data)
+		}
+		return ctx, data
+	}
+
+	// El plan está en orden inverso: la pregunta 1 es el ID 8 y la 2 el ID 7
+	if ctx := env.call(env.h.SubmitAnswer, session.ID, token, `{"questionId":8,"selectedOption":"A"}`); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("answer: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	// A mitad de partida solo el propio jugador ve sus respuestas
+	if ctx, _ := answered(""); ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Fatalf("sesión activa sin token: esperaba 401, obtuve %d", ctx.Response.StatusCode())
+	}
+	if ctx, data := answered(token); ctx.Response.StatusCode() != fasthttp.StatusOK || len(data) != 1 {
+		t.Fatalf("sesión activa con token: esperaba 200 y una pregunta, obtuve %d %+v", ctx.Response.StatusCode(), data)
+	}
+
+	if ctx := env.call(env.h.SubmitAnswer, session.ID, token, `{"questionId":7,"selectedOption":"B"}`); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("answer: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	// Eliminado el jugador, la revisión es pública y trae las preguntas completas
+	ctx, data := answered("")
+	if ctx.Response.StatusCode() != fasthttp.StatusOK || len(data) != 2 {
+		t.Fatalf("sesión eliminada: esperaba 200 y dos preguntas, obtuve %d %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	first, second := data[0], data[1]
+	if first.QuestionNumber != 1 || first.Question == nil || first.Question.ID != 8 || first.Question.Correct != "A" ||
+		first.Question.Explanation != "Explicación 8" || first.SelectedOption != "A" || !first.IsCorrect || first.PrizeWon != models.PrizeLevels[0] {
+		t.Fatalf("primera pregunta inesperada: %+v (%+v)", first, first.Question)
+	}
+	if second.QuestionNumber != 2 || second.Question.ID != 7 || second.SelectedOption != "B" || second.IsCorrect || second.PrizeWon != 0 {
+		t.Fatalf("segunda pregunta inesperada: %+v (%+v)", second, second.Question)
+	}
+
+	if ctx := env.call(env.h.GetAnsweredQuestions, "6f1c2a9e-0000-4000-8000-000000000000", "", ""); ctx.Response.StatusCode() != fasthttp.StatusNotFound {
+		t.Fatalf("sesión inexistente: esperaba 404, obtuve %d", ctx.Response.StatusCode())
+	}
+}
+
+func TestSubmitAnswerExplanation(t *testing.T) {
+	for _, show := range []bool{false, true} {
+		env := newSessionEnv(t)
+		env.h.SetShowExplanation(show)
+		session, token := env.createSession(t, "Ana")
+
+		// Se incluye también al fallar: el jugador ya se comprometió con su respuesta
+		ctx := env.call(env.h.SubmitAnswer, session.ID, token, fmt.Sprintf(`{"questionId":%d,"selectedOption":"B"}`, session.CurrentQuestionID))
+		if ctx.Response.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("answer: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+		var response models.SessionResponse
+		decodeResponse(t, ctx, &response)
+
+		want := ""
+		if show {
+			want = fmt.Sprintf("Explicación %d", session.CurrentQuestionID)
+		}
+		if response.Explanation != want {
+			t.Fatalf("showExplanation=%v: explicación %q, esperaba %q", show, response.Explanation, want)
+		}
+		if !show && strings.Contains(string(ctx.Response.Body()), "explanation") {
+			t.Fatalf("sin la opción la respuesta no debe traer el campo: %s", ctx.Response.Body())
+		}
+	}
+}
+
+func TestLastAnswerTriggersOneAllAnswered(t *testing.T) {
+	env := newSessionEnv(t)
+	observer := env.dial(t)
+
+	type player struct {
+		session *models.GameSession
+		token   string
+	}
+	var players []player
+	for _, name := range []string{"Ana", "Beto", "Carla"} {
+		session, token := env.createSession(t, name)
+		players = append(players, player{session, token})
+	}
+	answer := func(p player, option string) {
+		ctx := env.call(env.h.SubmitAnswer, p.session.ID, p.token, fmt.Sprintf(`{"questionId":%d,"selectedOption":%q}`, p.session.CurrentQuestionID, option))
+		if ctx.Response.StatusCode() != fasthttp.StatusOK {
+			t.Errorf("answer de %s: esperaba 200, obtuve %d: %s", p.session.PlayerName, ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+	}
+	countAllAnswered := func() int {
+		t.Helper()
+		env.hub.BroadcastMessage("marker", nil)
+		count := 0
+		for _, msgType := range typesUntil(t, observer, "marker") {
+			if msgType == "allAnswered" {
+				count++
+			}
+		}
+		return count
+	}
+
+	answer(players[0], "A")
+	if count := countAllAnswered(); count != 0 {
+		t.Fatalf("con jugadores pendientes no debe avisarse, hubo %d", count)
+	}
+
+	// Las dos últimas respuestas llegan a la vez: el aviso sale una sola vez
+	var wg sync.WaitGroup
+	for _, p := range players[1:] {
+		wg.Add(1)
+		go func(p player, option string) {
+			defer wg.Done()
+			answer(p, option)
+		}(p, map[string]string{"Beto": "B", "Carla": "A"}[p.session.PlayerName])
+	}
+	wg.Wait()
+
+	if count := countAllAnswered(); count != 1 {
+		t.Fatalf("esperaba exactamente un allAnswered, hubo %d", count)
+	}
+}
+
+func TestMinimumAnswerTime(t *testing.T) {
+	env := newSessionEnv(t)
+	if err := env.gameState.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+	if _, err := env.gameState.StartQuestion(1, 1); err != nil {
+		t.Fatalf("error iniciando pregunta: %v", err)
+	}
+	submit := func(name string) (*fasthttp.RequestCtx, *models.GameSession) {
+		t.Helper()
+		session, token := env.createSession(t, name)
+		ctx := env.call(env.h.SubmitAnswer, session.ID, token, fmt.Sprintf(`{"questionId":%d,"selectedOption":"A"}`, session.CurrentQuestionID))
+		stored, _ := env.sessions.GetSession(session.ID)
+		return ctx, stored
+	}
+
+	// Antes del mínimo se acepta marcada como sospechosa
+	env.h.SetMinAnswerTime(time.Minute, false)
+	ctx, stored := submit("Ana")
+	if ctx.Response.StatusCode() != fasthttp.StatusOK || len(stored.AnswersGiven) != 1 || !stored.AnswersGiven[0].Suspicious {
+		t.Fatalf("esperaba la respuesta guardada como sospechosa: %d %+v", ctx.Response.StatusCode(), stored.AnswersGiven)
+	}
+
+	// O se rechaza si así se configura
+	env.h.SetMinAnswerTime(time.Minute, true)
+	ctx, stored = submit("Beto")
+	if ctx.Response.StatusCode() != fasthttp.StatusBadRequest || len(stored.AnswersGiven) != 0 {
+		t.Fatalf("esperaba 400 sin guardar la respuesta: %d %+v", ctx.Response.StatusCode(), stored.AnswersGiven)
+	}
+
+	// Pasado el mínimo la respuesta es normal
+	env.h.SetMinAnswerTime(20*time.Millisecond, true)
+	time.Sleep(40 * time.Millisecond)
+	ctx, stored = submit("Carla")
+	if ctx.Response.StatusCode() != fasthttp.StatusOK || len(stored.AnswersGiven) != 1 || stored.AnswersGiven[0].Suspicious {
+		t.Fatalf("una respuesta a tiempo no es sospechosa: %d %+v", ctx.Response.StatusCode(), stored.AnswersGiven)
+	}
+}
+
+// formCall invoca un handler de sesión con el cuerpo codificado como formulario
+func (e *sessionEnv) formCall(handler fasthttp.RequestHandler, sessionID, token, form string) *fasthttp.RequestCtx {
+	ctx := newRequestCtx("POST", "/api/sessions/"+sessionID, "")
+	ctx.Request.Header.SetContentType("application/x-www-form-urlencoded; charset=UTF-8")
+	ctx.Request.SetBodyString(form)
+	ctx.SetUserValue("id", sessionID)
+	ctx.Request.Header.Set("X-Session-Token", token)
+	handler(ctx)
+	return ctx
+}
+
+func TestFormEncodedBodies(t *testing.T) {
+	env := newSessionEnv(t)
+
+	// CreateSession
+	ctx := newRequestCtx("POST", "/api/sessions", "")
+	ctx.Request.Header.SetContentType("application/x-www-form-urlencoded")
+	ctx.Request.SetBodyString("playerName=Ana+Mar%C3%ADa")
+	env.h.CreateSession(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("create: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	var created models.SessionResponse
+	decodeResponse(t, ctx, &created)
+	if created.Session == nil || created.Session.PlayerName != "Ana María" || created.Token == "" {
+		t.Fatalf("sesión creada por formulario inesperada: %s", ctx.Response.Body())
+	}
+	session, token := created.Session, created.Token
+
+	// UseLifeline
+	ctx = env.formCall(env.h.UseLifeline, session.ID, token, "type=fiftyFifty")
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("lifeline: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	if stored, _ := env.sessions.GetSession(session.ID); !stored.LifelinesUsed.FiftyFifty {
+		t.Fatalf("el comodín enviado por formulario debe quedar usado")
+	}
+
+	// SubmitAnswer: un número mal formado es 400
+	ctx = env.formCall(env.h.SubmitAnswer, session.ID, token, "questionId=uno&selectedOption=A")
+	if ctx.Response.StatusCode() != fasthttp.StatusBadRequest {
+		t.Fatalf("answer con questionId inválido: esperaba 400, obtuve %d", ctx.Response.StatusCode())
+	}
+	form := fmt.Sprintf("questionId=%d&selectedOption=A&timeToAnswer=4", session.CurrentQuestionID)
+	ctx = env.formCall(env.h.SubmitAnswer, session.ID, token, form)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("answer: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	stored, _ := env.sessions.GetSession(session.ID)
+	if len(stored.AnswersGiven) != 1 {
+		t.Fatalf("esperaba una respuesta guardada, hay %d", len(stored.AnswersGiven))
+	}
+	answer := stored.AnswersGiven[0]
+	if answer.QuestionID != session.CurrentQuestionID || answer.SelectedOption != "A" || !answer.IsCorrect || answer.TimeToAnswer != 4 {
+		t.Fatalf("respuesta por formulario inesperada: %+v", answer)
+	}
+}
+
+func TestEmptyListsSerializeAsArrays(t *testing.T) {
+	env := newSessionEnv(t)
+
+	history := newRequestCtx("GET", "/api/players/Nadie/history", "")
+	history.SetUserValue("playerName", "Nadie")
+
+	cases := []struct {
+		name    string
+		handler fasthttp.RequestHandler
+		ctx     *fasthttp.RequestCtx
+		want    []string
+	}{
+		{"leaderboard", env.h.GetLeaderboard, newRequestCtx("GET", "/api/leaderboard", ""),
+			[]string{`"leaderboard":[]`, `"totalPlayers":0`, `"activePlayers":0`}},
+		{"history", env.h.GetPlayerHistory, history, []string{`"sessions":[]`}},
+		{"active", env.h.GetActiveSessions, newRequestCtx("GET", "/api/sessions/active", ""), []string{`"sessions":[]`}},
+	}
+	for _, c := range cases {
+		c.handler(c.ctx)
+		if c.ctx.Response.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("%s: esperaba 200, obtuve %d: %s", c.name, c.ctx.Response.StatusCode(), c.ctx.Response.Body())
+		}
+		body := string(c.ctx.Response.Body())
+		if strings.Contains(body, "null") {
+			t.Fatalf("%s: un resultado vacío no debe serializarse como null: %s", c.name, body)
+		}
+		for _, want := range c.want {
+			if !strings.Contains(body, want) {
+				t.Fatalf("%s: esperaba %s en %s", c.name, want, body)
+			}
+		}
+	}
+}
+
+func TestMalformedVersusMissingSessionID(t *testing.T) {
+	env := newSessionEnv(t)
+	const missing = "6f1c2a9e-0000-4000-8000-000000000000"
+
+	routes := []struct {
+		name    string
+		handler fasthttp.RequestHandler
+		body    string
+	}{
+		{"GetSession", env.h.GetSession, ""},
+		{"SubmitAnswer", env.h.SubmitAnswer, `{"questionId":1,"selectedOption":"A"}`},
+		{"UseLifeline", env.h.UseLifeline, `{"type":"fiftyFifty"}`},
+	}
+	for _, route := range routes {
+		for _, garbage := range []string{"abc", "no-existe", missing + "x", "../session"} {
+			ctx := env.call(route.handler, garbage, "token", route.body)
+			if ctx.Response.StatusCode() != fasthttp.StatusBadRequest {
+				t.Fatalf("%s con id %q: esperaba 400, obtuve %d", route.name, garbage, ctx.Response.StatusCode())
+			}
+		}
+
+		// Un UUID bien formado que no existe es 404, con o sin token
+		for _, token := range []string{"", "token"} {
+			ctx := env.call(route.handler, missing, token, route.body)
+			if ctx.Response.StatusCode() != fasthttp.StatusNotFound {
+				t.Fatalf("%s con un UUID inexistente: esperaba 404, obtuve %d: %s", route.name, ctx.Response.StatusCode(), ctx.Response.Body())
+			}
+		}
+	}
+
+	// Para una sesión existente el token sigue siendo obligatorio
+	session, _ := env.createSession(t, "Ana")
+	if ctx := env.call(env.h.UseLifeline, session.ID, "", `{"type":"fiftyFifty"}`); ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Fatalf("sesión existente sin token: esperaba 401, obtuve %d", ctx.Response.StatusCode())
+	}
+}
+
+func TestOptionOrderAuditMatchesServedQuestions(t *testing.T) {
+	env := newSessionEnv(t)
+	env.sessions.SetMaxQuestions(2)
+	// Cada pregunta trae sus opciones en un orden propio del archivo; el plan es 2, 1
+	bank := `{"questions":[
+		{"id":2,"question":"Dos","options":{"D":"Cuatro","C":"Tres","B":"Dos","A":"Uno"},"correctAnswer":"A","difficulty":1},
+		{"id":1,"question":"Uno","options":{"B":"Dos","D":"Cuatro","A":"Uno","C":"Tres"},"correctAnswer":"A","difficulty":1}
+	]}`
+	if err := env.store.LoadQuestionsFromJSON([]byte(bank)); err != nil {
+		t.Fatalf("error cargando preguntas: %v", err)
+	}
+	session, token := env.createSession(t, "Ana")
+
+	// served pide la siguiente pregunta y devuelve el orden en que se le mostró
+	served := func() (int, []string) {
+		t.Helper()
+		ctx := env.call(env.h.GetNextQuestion, session.ID, token, "")
+		var data struct {
+			Question *models.PublicQuestion `json:"question"`
+		}
+		decodeResponse(t, ctx, &data)
+		if data.Question == nil {
+			t.Fatalf("next: se esperaba una pregunta: %s", ctx.Response.Body())
+		}
+		return data.Question.ID, data.Question.OptionOrder
+	}
+	firstID, firstOrder := served()
+	if ctx := env.call(env.h.SubmitAnswer, session.ID, token, fmt.Sprintf(`{"questionId":%d,"selectedOption":"A"}`, firstID)); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("answer: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	secondID, secondOrder := served()
+
+	ctx := newRequestCtx("GET", "/api/admin/players/"+session.ID+"/shuffle", "")
+	ctx.SetUserValue("id", session.ID)
+	env.h.GetOptionOrder(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	var audit models.OptionOrderAudit
+	decodeResponse(t, ctx, &audit)
+
+	want := []models.ServedOptionOrder{
+		{QuestionNumber: 1, QuestionID: firstID, OptionOrder: firstOrder, SelectedOption: "A"},
+		{QuestionNumber: 2, QuestionID: secondID, OptionOrder: secondOrder},
+	}
+	if audit.SessionID != session.ID || audit.PlayerName != "Ana" || len(audit.Questions) != len(want) {
+		t.Fatalf("auditoría inesperada: %+v", audit)
+	}
+	if strings.Join(firstOrder, ",") != "D,C,B,A" || strings.Join(secondOrder, ",") != "B,D,A,C" {
+		t.Fatalf("el jugador debe ver el orden del archivo: %v / %v", firstOrder, secondOrder)
+	}
+	for i, w := range want {
+		got := audit.Questions[i]
+		if got.QuestionNumber != w.QuestionNumber || got.QuestionID != w.QuestionID || got.SelectedOption != w.SelectedOption ||
+			strings.Join(got.OptionOrder, ",") != strings.Join(w.OptionOrder, ",") {
+			t.Fatalf("pregunta %d: esperaba %+v, obtuve %+v", i+1, w, got)
+		}
+	}
+}
+
+// collectTimestamps recorre un JSON decodificado y devuelve, por ruta, cada
+// texto que parece una marca de tiempo
+func collectTimestamps(path string, value interface{}, found map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			collectTimestamps(path+"."+key, child, found)
+		}
+	case []interface{}:
+		for i, child := range v {
+			collectTimestamps(fmt.Sprintf("%s[%d]", path, i), child, found)
+		}
+	case string:
+		if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			found[path] = v
+		}
+	}
+}
+
+// TestMain fija una zona local distinta de UTC para todo el paquete: así se
+// nota cualquier hora sin convertir. Se cambia antes de correr las pruebas:
+// cambiarla dentro de una prueba compite con las goroutines de las anteriores.
+func TestMain(m *testing.M) {
+	time.Local = time.FixedZone("COT", -5*60*60)
+	os.Exit(m.Run())
+}
+
+func TestSessionResponseTimestampsAreUTC(t *testing.T) {
+	env := newSessionEnv(t)
+	session, token := env.createSession(t, "Ana")
+	if ctx := env.call(env.h.SelectOption, session.ID, token, fmt.Sprintf(`{"questionId":%d,"selectedOption":"B"}`, session.CurrentQuestionID)); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("select: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	if ctx := env.call(env.h.SubmitAnswer, session.ID, token, fmt.Sprintf(`{"questionId":%d,"selectedOption":"A"}`, session.CurrentQuestionID)); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("answer: esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	ctx := env.call(env.h.GetSession, session.ID, "", "")
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("esperaba 200, obtuve %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	var body interface{}
+	if err := json.Unmarshal(ctx.Response.Body(), &body); err != nil {
+		t.Fatalf("respuesta no es JSON: %s", ctx.Response.Body())
+	}
+	found := make(map[string]string)
+	collectTimestamps("", body, found)
+
+	for _, required := range []string{".data.session.startTime", ".data.session.lastActivity", ".data.session.answersGiven[0].timestamp"} {
+		if _, ok := found[required]; !ok {
+			t.Fatalf("falta la marca de tiempo %s: %v", required, found)
+		}
+	}
+	for path, value := range found {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil || !strings.HasSuffix(value, "Z") || parsed.Location() != time.UTC {
+			t.Fatalf("%s no es RFC3339 en UTC: %q", path, value)
+		}
+	}
+}
+
+func TestResumingSessionRequiresCurrentToken(t *testing.T) {
+	env := newSessionEnv(t)
+	session, token := env.createSession(t, "Ana")
+
+	resume := func(body, token string) *fasthttp.RequestCtx {
+		ctx := newRequestCtx("POST", "/api/sessions", body)
+		if token != "" {
+			ctx.Request.Header.Set("X-Session-Token", token)
+		}
+		env.h.CreateSession(ctx)
+		return ctx
+	}
+
+	// Conocer el nombre o el ID de la sesión no basta para tomarla
+	byID := fmt.Sprintf(`{"playerName":"Ana","sessionId":%q}`, session.ID)
+	for _, attempt := range []struct{ body, token string }{
+		{`{"playerName":"Ana"}`, ""},
+		{`{"playerName":"Ana"}`, "otro-token"},
+		{byID, ""},
+	} {
+		ctx := resume(attempt.body, attempt.token)
+		if ctx.Response.StatusCode() != fasthttp.StatusConflict {
+			t.Fatalf("%s sin token válido: esperaba 409, obtuve %d: %s", attempt.body, ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+		if strings.Contains(string(ctx.Response.Body()), `"token"`) {
+			t.Fatalf("un intento rechazado no debe recibir token: %s", ctx.Response.Body())
+		}
+	}
+	body := fmt.Sprintf(`{"questionId":%d,"selectedOption":"A"}`, session.CurrentQuestionID)
+	if ctx := env.call(env.h.SubmitAnswer, session.ID, "", body); ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Fatalf("responder sin token: esperaba 401, obtuve %d", ctx.Response.StatusCode())
+	}
+
+	// Con el token actual se continúa la sesión y el token no cambia
+	ctx := resume(byID, token)
+	var resumed models.SessionResponse
+	decodeResponse(t, ctx, &resumed)
+	if resumed.Session == nil || resumed.Session.ID != session.ID || resumed.Token != token {
+		t.Fatalf("la reanudación debe devolver la misma sesión y el mismo token: %s", ctx.Response.Body())
+	}
+	if ctx := env.call(env.h.SubmitAnswer, session.ID, token, body); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("el token original debe seguir valiendo: %d %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+}
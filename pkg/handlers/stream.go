@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+
+	"github.com/valyala/fasthttp"
+)
+
+// StreamJSON escribe una respuesta JSON grande codificándola directamente
+// sobre la conexión, sin armar todo el cuerpo en memoria. El status y el
+// Content-Type se fijan antes de escribir el primer byte; un error de
+// codificación a mitad de camino solo puede registrarse.
+func StreamJSON(ctx *fasthttp.RequestCtx, statusCode int, data interface{}) {
+	ctx.SetStatusCode(statusCode)
+	ctx.SetContentType("application/json")
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			log.Printf("❌ Error serializando respuesta en streaming: %v", err)
+			return
+		}
+		if err := w.Flush(); err != nil {
+			log.Printf("⚠️ Error enviando respuesta en streaming: %v", err)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// serveInMemory levanta un servidor fasthttp en memoria y devuelve un cliente conectado a él
+func serveInMemory(t *testing.T, handler fasthttp.RequestHandler) *fasthttp.Client {
+	t.Helper()
+	ln := fasthttputil.NewInmemoryListener()
+	server := &fasthttp.Server{Handler: handler}
+	go server.Serve(ln)
+	t.Cleanup(func() { ln.Close() })
+
+	return &fasthttp.Client{
+		Dial: func(addr string) (net.Conn, error) {
+			return ln.Dial()
+		},
+	}
+}
+
+func TestStreamJSONLargeResponse(t *testing.T) {
+	type item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	items := make([]item, 50000)
+	for i := range items {
+		items[i] = item{ID: i, Name: fmt.Sprintf("Jugador %05d con un nombre algo largo", i)}
+	}
+
+	client := serveInMemory(t, func(ctx *fasthttp.RequestCtx) {
+		StreamJSON(ctx, fasthttp.StatusOK, items)
+	})
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI("http://quiz.test/api/sessions")
+
+	if err := client.Do(req, resp); err != nil {
+		t.Fatalf("error en la petición: %v", err)
+	}
+	if resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("esperaba 200, obtuve %d", resp.StatusCode())
+	}
+	if string(resp.Header.ContentType()) != "application/json" {
+		t.Fatalf("Content-Type inesperado: %s", resp.Header.ContentType())
+	}
+
+	var decoded []item
+	if err := json.Unmarshal(resp.Body(), &decoded); err != nil {
+		t.Fatalf("el cuerpo no es JSON completo (%d bytes): %v", len(resp.Body()), err)
+	}
+	if len(decoded) != len(items) {
+		t.Fatalf("esperaba %d elementos, llegaron %d", len(items), len(decoded))
+	}
+	if decoded[len(decoded)-1] != items[len(items)-1] {
+		t.Fatalf("el último elemento llegó alterado: %+v", decoded[len(decoded)-1])
+	}
+}
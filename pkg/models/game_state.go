@@ -10,9 +10,96 @@ type GameState struct {
 	PlayerCount     int        `json:"playerCount"`
 	CurrentQuestion int        `json:"currentQuestion"` // Pregunta más alta alcanzada por algún jugador
 	MaxQuestions    int        `json:"maxQuestions"`    // Total de preguntas disponibles
+
+	// Temporizador de la pregunta en curso (fijado por NextQuestion)
+	QuestionNumber    int        `json:"questionNumber,omitempty"`
+	QuestionDuration  int        `json:"questionDuration,omitempty"` // en segundos
+	QuestionStartedAt *time.Time `json:"questionStartedAt,omitempty"`
+	QuestionDeadline  *time.Time `json:"questionDeadline,omitempty"`
+	AnswersOpen       bool       `json:"answersOpen"` // false mientras la pregunta se lee en voz alta
 }
 
 type GameControl struct {
 	Action  string `json:"action"` // "start" o "end"
 	AdminID string `json:"adminId,omitempty"`
 }
+
+// GameArchive instantánea de una partida terminada
+type GameArchive struct {
+	ID        string        `json:"id"`
+	CreatedAt time.Time     `json:"createdAt"`
+	GameState *GameState    `json:"gameState"`
+	Sessions  []GameSession `json:"sessions"`
+}
+
+// ReviewRoom partida restaurada desde un archivo para revisarla, sin tocar la
+// partida en curso
+type ReviewRoom struct {
+	ArchiveID string        `json:"archiveId"`
+	Room      string        `json:"room"`
+	GameState *GameState    `json:"gameState"`
+	Sessions  []GameSession `json:"sessions"`
+}
+
+// EndGameSummary resultado de terminar una partida; se reutiliza para
+// responder igual a clics duplicados en "terminar partida"
+type EndGameSummary struct {
+	Timestamp    string `json:"timestamp"`
+	TotalPlayers int    `json:"totalPlayers"`
+	DataCleared  bool   `json:"dataCleared"`
+	ArchiveID    string `json:"archiveId"`
+}
+
+// AnswerProgress avance de las respuestas a una pregunta entre los jugadores en vivo
+type AnswerProgress struct {
+	Answered     int            `json:"answered"`
+	Pending      int            `json:"pending"` // activos en esa pregunta que aún no responden
+	Distribution map[string]int `json:"distribution"`
+}
+
+// LiveQuestion pregunta en curso (con la respuesta correcta), avance de las
+// respuestas y temporizador, en una sola consulta para el panel
+type LiveQuestion struct {
+	QuestionNumber   int        `json:"questionNumber"`
+	Question         *Question  `json:"question"`
+	AnswersOpen      bool       `json:"answersOpen"`
+	Deadline         *time.Time `json:"deadline,omitempty"`
+	RemainingSeconds *int       `json:"remainingSeconds,omitempty"` // sin temporizador se omite
+	AnswerProgress
+}
+
+// RoomInfo resumen de una partida en curso para el listado de administración
+type RoomInfo struct {
+	ID              string `json:"id"`
+	IsActive        bool   `json:"isActive"`
+	Message         string `json:"message"`
+	PlayerCount     int    `json:"playerCount"`
+	CurrentQuestion int    `json:"currentQuestion"`
+}
+
+// AuditEntry registro de una acción de administración
+type AuditEntry struct {
+	Action    string                 `json:"action"`
+	Admin     string                 `json:"admin"`
+	Timestamp time.Time              `json:"timestamp"`
+	Params    map[string]interface{} `json:"params,omitempty"`
+}
+
+// TimingBucket rango de tiempo de respuesta y cuántos jugadores cayeron en él
+type TimingBucket struct {
+	FromSeconds int `json:"fromSeconds"`
+	ToSeconds   int `json:"toSeconds,omitempty"` // 0 en el último rango (sin límite)
+	Count       int `json:"count"`
+}
+
+// QuestionTiming distribución de tiempos de respuesta de la pregunta en curso,
+// medidos en el servidor desde que se inició la pregunta
+type QuestionTiming struct {
+	QuestionNumber int            `json:"questionNumber"`
+	StartedAt      time.Time      `json:"startedAt"`
+	ElapsedSeconds int            `json:"elapsedSeconds"` // tiempo transcurrido desde el inicio
+	Answered       int            `json:"answered"`
+	Pending        int            `json:"pending"` // jugadores aún pensando
+	AverageSeconds float64        `json:"averageSeconds"`
+	Buckets        []TimingBucket `json:"buckets"`
+}
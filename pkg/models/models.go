@@ -8,6 +8,50 @@ type Question struct {
 	Correct     string            `json:"correctAnswer"`
 	Explanation string            `json:"explanation"`
 	Difficulty  int               `json:"difficulty"`
+	Category    string            `json:"category,omitempty"`
+	OptionOrder []string          `json:"optionOrder,omitempty"` // orden de presentación de las opciones
+}
+
+// PublicQuestion pregunta sin la respuesta correcta ni la explicación, apta para jugadores
+type PublicQuestion struct {
+	ID          int               `json:"id"`
+	Number      int               `json:"number"`
+	Question    string            `json:"question"`
+	Options     map[string]string `json:"options"`
+	OptionOrder []string          `json:"optionOrder,omitempty"`
+	Difficulty  int               `json:"difficulty"`
+}
+
+// Public devuelve la versión sanitizada de la pregunta para el número indicado
+func (q *Question) Public(number int) *PublicQuestion {
+	return &PublicQuestion{
+		ID:          q.ID,
+		Number:      number,
+		Question:    q.Question,
+		Options:     q.Options,
+		OptionOrder: q.OptionOrder,
+		Difficulty:  q.Difficulty,
+	}
+}
+
+// QuestionSearchCriteria filtros combinables para buscar preguntas
+type QuestionSearchCriteria struct {
+	Difficulty int    // 0 = cualquier dificultad
+	Category   string // vacío = cualquier categoría
+	Text       string // subcadena en la pregunta o la explicación
+	Limit      int    // 0 = sin límite
+	Offset     int
+}
+
+// QuestionPatch cambios parciales a una pregunta: los campos ausentes no se
+// tocan y Options se fusiona opción por opción
+type QuestionPatch struct {
+	Question    *string           `json:"question"`
+	Options     map[string]string `json:"options"`
+	Correct     *string           `json:"correctAnswer"`
+	Explanation *string           `json:"explanation"`
+	Difficulty  *int              `json:"difficulty"`
+	Category    *string           `json:"category"`
 }
 
 // QuestionsData estructura para el JSON completo
@@ -29,10 +73,54 @@ type APIResponse struct {
 	Error   string      `json:"error,omitempty"`
 }
 
-// QuestionResponse respuesta específica para preguntas
+// QuestionResponse respuesta específica para preguntas; Returned es cuántas
+// trae la respuesta y Total cuántas hay. Se omiten si no aplican, pero un cero
+// real se incluye
 type QuestionResponse struct {
 	Question  *Question   `json:"question,omitempty"`
 	Questions []Question  `json:"questions,omitempty"`
-	Count     int         `json:"count,omitempty"`
+	Returned  *int        `json:"returned,omitempty"`
+	Total     *int        `json:"total,omitempty"`
 	Metadata  interface{} `json:"metadata,omitempty"`
 }
+
+// QuestionStats estadísticas de respuestas de una pregunta
+type QuestionStats struct {
+	QuestionID  int     `json:"questionId"`
+	Attempts    int     `json:"attempts"`
+	Correct     int     `json:"correct"`
+	Incorrect   int     `json:"incorrect"`
+	CorrectRate float64 `json:"correctRate"`
+	AvgTime     float64 `json:"avgTime"` // en segundos
+}
+
+// AnswerKeyCount veces que una opción es la respuesta correcta en un grupo de preguntas
+type AnswerKeyCount struct {
+	Option  string  `json:"option"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"` // sobre el total del grupo (0-100)
+}
+
+// AnswerKeyGroup reparto de la respuesta correcta entre las opciones de un
+// grupo de preguntas (todo el banco o una dificultad)
+type AnswerKeyGroup struct {
+	Difficulty int              `json:"difficulty,omitempty"`
+	Total      int              `json:"total"`
+	Options    []AnswerKeyCount `json:"options"`
+}
+
+// AnswerKeyDistribution reparto de la respuesta correcta en el banco de preguntas
+type AnswerKeyDistribution struct {
+	Overall      AnswerKeyGroup   `json:"overall"`
+	ByDifficulty []AnswerKeyGroup `json:"byDifficulty"`
+}
+
+// DifficultySuggestion dificultad sugerida para una pregunta según resultados reales
+type DifficultySuggestion struct {
+	QuestionID          int     `json:"questionId"`
+	CurrentDifficulty   int     `json:"currentDifficulty"`
+	SuggestedDifficulty int     `json:"suggestedDifficulty"`
+	Attempts            int     `json:"attempts"`
+	CorrectRate         float64 `json:"correctRate"`
+	Applied             bool    `json:"applied"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// CurrencySymbol símbolo con el que se formatean los premios (configurable con CURRENCY_SYMBOL)
+var CurrencySymbol = "$"
+
+// MaxSafePrize mayor monto que un cliente JavaScript representa sin perder precisión (2^53 - 1)
+const MaxSafePrize int64 = 1<<53 - 1
+
+// ClampPrize limita un monto calculado al tope maxPrize
+func ClampPrize(amount, maxPrize int64) int64 {
+	if amount > maxPrize {
+		return maxPrize
+	}
+	return amount
+}
+
+// FormatPrize formatea un monto con el símbolo de moneda y separador de miles, p. ej. "$1,000,000"
+func FormatPrize(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := strconv.FormatInt(amount, 10)
+	formatted := make([]byte, 0, len(digits)+len(digits)/3)
+	for i := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			formatted = append(formatted, ',')
+		}
+		formatted = append(formatted, digits[i])
+	}
+
+	return sign + CurrencySymbol + string(formatted)
+}
+
+// MarshalJSON agrega formattedPrize (TotalPrize formateado) a la sesión en las
+// respuestas de la API; para guardarla se usa StorageJSON
+func (s GameSession) MarshalJSON() ([]byte, error) {
+	type session GameSession
+	return json.Marshal(struct {
+		session
+		FormattedPrize string `json:"formattedPrize"`
+	}{
+		session:        session(s),
+		FormattedPrize: FormatPrize(s.TotalPrize),
+	})
+}
+
+// storedSession GameSession sin MarshalJSON, tal como se guarda en Redis
+type storedSession GameSession
+
+// StorageJSON serializa la sesión para guardarla, sin formattedPrize: es un
+// valor calculado que quedaría desactualizado al cambiar el premio
+func (s *GameSession) StorageJSON() ([]byte, error) {
+	return json.Marshal((*storedSession)(s))
+}
+
+// StorageJSON serializa el archivo para guardarlo, con sus sesiones sin formattedPrize
+func (a *GameArchive) StorageJSON() ([]byte, error) {
+	sessions := make([]storedSession, len(a.Sessions))
+	for i, session := range a.Sessions {
+		sessions[i] = storedSession(session)
+	}
+
+	type archive GameArchive
+	return json.Marshal(struct {
+		*archive
+		Sessions []storedSession `json:"sessions"`
+	}{
+		archive:  (*archive)(a),
+		Sessions: sessions,
+	})
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFormatPrize(t *testing.T) {
+	cases := []struct {
+		amount int64
+		want   string
+	}{
+		{0, "$0"},
+		{5, "$5"},
+		{999, "$999"},
+		{1000, "$1,000"},
+		{64000, "$64,000"},
+		{125000, "$125,000"},
+		{1000000, "$1,000,000"},
+		{-2500, "-$2,500"},
+		{MaxSafePrize, "$9,007,199,254,740,991"},
+	}
+	for _, c := range cases {
+		if got := FormatPrize(c.amount); got != c.want {
+			t.Fatalf("FormatPrize(%d) = %q, esperaba %q", c.amount, got, c.want)
+		}
+	}
+}
+
+func TestFormatPrizeCurrencySymbol(t *testing.T) {
+	previous := CurrencySymbol
+	CurrencySymbol = "COP "
+	t.Cleanup(func() { CurrencySymbol = previous })
+
+	if got := FormatPrize(1500000); got != "COP 1,500,000" {
+		t.Fatalf("símbolo configurado no aplicado: %q", got)
+	}
+}
+
+func TestSessionJSONIncludesFormattedPrize(t *testing.T) {
+	session := GameSession{ID: "s1", PlayerName: "Ana", TotalPrize: 32000}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		t.Fatalf("error serializando sesión: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("error decodificando sesión: %v", err)
+	}
+	if decoded["formattedPrize"] != "$32,000" || decoded["totalPrize"] != float64(32000) {
+		t.Fatalf("se esperaban ambos campos del premio: %s", data)
+	}
+
+	// Lo que se guarda no incluye el valor calculado
+	stored, err := session.StorageJSON()
+	if err != nil {
+		t.Fatalf("error serializando para guardar: %v", err)
+	}
+	if strings.Contains(string(stored), "formattedPrize") {
+		t.Fatalf("formattedPrize no debe guardarse: %s", stored)
+	}
+}
+
+func TestClampPrize(t *testing.T) {
+	for _, c := range []struct{ amount, want int64 }{
+		{0, 0},
+		{999999, 999999},
+		{1000000, 1000000},
+		{1000001, 1000000},
+		{MaxSafePrize, 1000000},
+		{-500, -500},
+	} {
+		if got := ClampPrize(c.amount, 1000000); got != c.want {
+			t.Fatalf("ClampPrize(%d, 1000000) = %d, esperaba %d", c.amount, got, c.want)
+		}
+	}
+}
+
+func TestMaxSafePrizeKeepsJSONPrecision(t *testing.T) {
+	data, err := json.Marshal(GameSession{ID: "s1", TotalPrize: MaxSafePrize})
+	if err != nil {
+		t.Fatalf("error serializando sesión: %v", err)
+	}
+	if !strings.Contains(string(data), `"totalPrize":9007199254740991`) {
+		t.Fatalf("el premio debe serializarse como entero exacto: %s", data)
+	}
+
+	// Un cliente que decodifica con float64 (como JavaScript) recupera el mismo valor
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("error decodificando sesión: %v", err)
+	}
+	if int64(decoded["totalPrize"].(float64)) != MaxSafePrize {
+		t.Fatalf("se perdió precisión: %v", decoded["totalPrize"])
+	}
+}
@@ -1,19 +1,46 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
+
+// Modos de sesión
+const (
+	SessionModeLive     = "live"     // cuenta para la tabla de posiciones
+	SessionModePractice = "practice" // calentamiento, no afecta resultados
+	SessionModeWager    = "wager"    // apuesta: el premio se acumula y se arriesga en cada pregunta
+)
 
 // GameSession representa la sesión de un jugador
 type GameSession struct {
 	ID                string         `json:"id"`
 	PlayerName        string         `json:"playerName"`
 	CurrentQuestion   int            `json:"currentQuestion"`
-	TotalPrize        int            `json:"totalPrize"`
+	TotalPrize        int64          `json:"totalPrize"`
 	LifelinesUsed     LifelinesState `json:"lifelinesUsed"`
 	AnswersGiven      []PlayerAnswer `json:"answersGiven"`
 	GameStatus        string         `json:"gameStatus"` // "active", "finished", "paused"
 	StartTime         time.Time      `json:"startTime"`
 	LastActivity      time.Time      `json:"lastActivity"`
 	CurrentQuestionID int            `json:"currentQuestionId"`
+	Mode              string         `json:"mode"`           // "live", "practice" o "wager"
+	LivesRemaining    int            `json:"livesRemaining"` // errores que aún puede cometer antes de quedar eliminado
+	Connected         bool           `json:"connected"`      // tiene al menos un WebSocket abierto asociado a la sesión
+
+	PrizeAdjustments   []PrizeAdjustment   `json:"prizeAdjustments,omitempty"`   // correcciones manuales del presentador
+	TentativeSelection *TentativeSelection `json:"tentativeSelection,omitempty"` // opción elegida sin confirmar como respuesta final
+	Rejoins            []Rejoin            `json:"rejoins,omitempty"`            // reingresos tras quedar eliminado
+}
+
+// IsPractice indica si la sesión es de práctica (las sesiones antiguas sin modo son "live")
+func (s *GameSession) IsPractice() bool {
+	return s.Mode == SessionModePractice
+}
+
+// IsWager indica si la sesión juega con apuestas
+func (s *GameSession) IsWager() bool {
+	return s.Mode == SessionModeWager
 }
 
 // LifelinesState estado de los comodines
@@ -33,35 +60,149 @@ type PlayerAnswer struct {
 	TimeToAnswer     int       `json:"timeToAnswer"`     // en segundos
 	LifelinesUsedFor []string  `json:"lifelinesUsedFor"` // comodines usados para esta pregunta
 	Timestamp        time.Time `json:"timestamp"`
-	PrizeWon         int       `json:"prizeWon"`
+	PrizeWon         int64     `json:"prizeWon"`
+	Wager            int64     `json:"wager,omitempty"`      // monto apostado (solo en modo apuesta)
+	Voided           bool      `json:"voided,omitempty"`     // pregunta anulada: no suma premio ni elimina
+	Suspicious       bool      `json:"suspicious,omitempty"` // llegó antes del tiempo mínimo de respuesta
+}
+
+// TentativeSelection opción que el jugador está considerando; no puntúa hasta
+// que la confirma como respuesta final
+type TentativeSelection struct {
+	QuestionID     int       `json:"questionId"`
+	QuestionNumber int       `json:"questionNumber"`
+	Option         string    `json:"option"`
+	SelectedAt     time.Time `json:"selectedAt"`
+}
+
+// Rejoin reingreso de un jugador eliminado, con una vida, en la pregunta indicada
+type Rejoin struct {
+	QuestionNumber int       `json:"questionNumber"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// SelectRequest request para marcar una opción sin confirmarla
+type SelectRequest struct {
+	QuestionID     int    `json:"questionId"`
+	SelectedOption string `json:"selectedOption"`
+}
+
+// PrizeAdjustment registro de una corrección manual del premio
+type PrizeAdjustment struct {
+	PreviousPrize int64     `json:"previousPrize"`
+	NewPrize      int64     `json:"newPrize"`
+	Reason        string    `json:"reason"`
+	Admin         string    `json:"admin"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// AdjustPrizeRequest request para corregir el premio de un jugador; se usa
+// Delta (relativo) o NewValue (absoluto), no ambos
+type AdjustPrizeRequest struct {
+	Delta    *int64 `json:"delta,omitempty"`
+	NewValue *int64 `json:"newValue,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+// AnswerRequest request para enviar una respuesta
+type AnswerRequest struct {
+	QuestionID     int    `json:"questionId"`
+	SelectedOption string `json:"selectedOption"`
+	TimeToAnswer   int    `json:"timeToAnswer"`
+	Wager          int64  `json:"wager"` // solo en sesiones de modo apuesta
+}
+
+// FieldError error de validación de un campo de la petición
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Validate verifica los campos de la respuesta; devuelve el primer campo inválido
+func (r *AnswerRequest) Validate() *FieldError {
+	if r.QuestionID <= 0 {
+		return &FieldError{Field: "questionId", Message: "ID de pregunta inválido"}
+	}
+	if strings.TrimSpace(r.SelectedOption) == "" {
+		return &FieldError{Field: "selectedOption", Message: "La opción seleccionada es requerida"}
+	}
+	if r.TimeToAnswer < 0 {
+		return &FieldError{Field: "timeToAnswer", Message: "El tiempo de respuesta no puede ser negativo"}
+	}
+	if r.Wager < 0 {
+		return &FieldError{Field: "wager", Message: "La apuesta no puede ser negativa"}
+	}
+	if r.Wager > MaxSafePrize {
+		return &FieldError{Field: "wager", Message: "La apuesta supera el premio máximo"}
+	}
+	return nil
 }
 
 // SessionCreateRequest request para crear sesión
 type SessionCreateRequest struct {
 	PlayerName string `json:"playerName"`
+	SessionID  string `json:"sessionId,omitempty"` // sesión previa del cliente, para reconectarse
+	ClaimCode  string `json:"claimCode,omitempty"` // código de un nombre pre-registrado
+}
+
+// SessionListResponse listado de sesiones; a diferencia de SessionResponse,
+// una lista vacía se serializa como [] en lugar de omitirse
+type SessionListResponse struct {
+	Sessions []GameSession `json:"sessions"`
+}
+
+// PreregisterRequest request para reservar nombres de jugadores
+type PreregisterRequest struct {
+	Names []string `json:"names"`
+}
+
+// PlayerReservation nombre reservado y el código para reclamarlo
+type PlayerReservation struct {
+	PlayerName string `json:"playerName"`
+	ClaimCode  string `json:"claimCode,omitempty"`
+	Created    bool   `json:"created"` // false si el nombre ya estaba reservado
 }
 
 // SessionResponse respuesta de sesión
 type SessionResponse struct {
 	Session  *GameSession  `json:"session,omitempty"`
+	Token    string        `json:"token,omitempty"` // solo al crear/continuar la sesión
 	Sessions []GameSession `json:"sessions,omitempty"`
 	Message  string        `json:"message,omitempty"`
+
+	Explanation string `json:"explanation,omitempty"` // al responder, si la configuración lo permite
 }
 
 // PrizeLevel niveles de premios
-var PrizeLevels = []int{
+var PrizeLevels = []int64{
 	100, 200, 300, 500, 1000, 2000, 4000, 8000, 16000, 32000,
 	64000, 125000, 250000, 500000, 1000000,
 }
 
+// NextPrize premio en juego en la pregunta actual de una sesión
+type NextPrize struct {
+	SessionID             string `json:"sessionId"`
+	QuestionNumber        int    `json:"questionNumber"`
+	Prize                 int64  `json:"prize"` // premio al acertar
+	FormattedPrize        string `json:"formattedPrize"`
+	PrizeIfWrong          int64  `json:"prizeIfWrong"` // premio que conserva si falla
+	FormattedPrizeIfWrong string `json:"formattedPrizeIfWrong"`
+	Complete              bool   `json:"complete"` // true si ya no quedan preguntas en la escalera
+}
+
 // LeaderboardEntry entrada en la tabla de posiciones
 type LeaderboardEntry struct {
-	Position     int    `json:"position"`
-	PlayerName   string `json:"playerName"`
-	CurrentPrize int    `json:"currentPrize"`
-	Status       string `json:"status"` // "playing", "eliminated", "finished"
-	Avatar       string `json:"avatar"`
-	Question     int    `json:"question"`
+	Position       int    `json:"position"`
+	PlayerName     string `json:"playerName"`
+	CurrentPrize   int64  `json:"currentPrize"`
+	FormattedPrize string `json:"formattedPrize"`
+	Status         string `json:"status"` // "playing", "eliminated", "finished"
+	Avatar         string `json:"avatar"`
+	Question       int    `json:"question"`
 }
 
 // LeaderboardResponse respuesta de la tabla de posiciones
@@ -71,6 +212,78 @@ type LeaderboardResponse struct {
 	ActivePlayers int                `json:"activePlayers"`
 }
 
+// Certificate datos para el certificado imprimible de un jugador
+type Certificate struct {
+	SessionID          string    `json:"sessionId"`
+	PlayerName         string    `json:"playerName"`
+	FinalPrize         int64     `json:"finalPrize"`
+	QuestionsConquered int       `json:"questionsConquered"`
+	Status             string    `json:"status"`
+	Rank               int       `json:"rank,omitempty"`        // 0 en sesiones de práctica
+	RankedAmong        string    `json:"rankedAmong,omitempty"` // "finishers" o "allPlayers"
+	TotalRanked        int       `json:"totalRanked"`
+	Date               time.Time `json:"date"`
+}
+
+// Spectator jugador eliminado que sigue la partida como espectador
+type Spectator struct {
+	SessionID      string    `json:"sessionId"`
+	PlayerName     string    `json:"playerName"`
+	EliminatedAt   int       `json:"eliminatedAt"` // número de la pregunta en la que fue eliminado
+	FinalPrize     int64     `json:"finalPrize"`
+	FormattedPrize string    `json:"formattedPrize"`
+	EliminatedTime time.Time `json:"eliminatedTime"`
+}
+
+// AnsweredQuestion pregunta ya respondida por el jugador, completa (con la
+// respuesta correcta y la explicación) junto con lo que eligió
+type AnsweredQuestion struct {
+	QuestionNumber int       `json:"questionNumber"`
+	Question       *Question `json:"question"`
+	SelectedOption string    `json:"selectedOption"`
+	IsCorrect      bool      `json:"isCorrect"`
+	PrizeWon       int64     `json:"prizeWon"`
+	Voided         bool      `json:"voided,omitempty"`
+}
+
+// AnswerTimelineEntry respuesta a una pregunta en el orden en que llegó al servidor
+type AnswerTimelineEntry struct {
+	Position        int       `json:"position"`
+	SessionID       string    `json:"sessionId"`
+	PlayerName      string    `json:"playerName"`
+	SelectedOption  string    `json:"selectedOption"`
+	IsCorrect       bool      `json:"isCorrect"`
+	Suspicious      bool      `json:"suspicious,omitempty"`
+	ReceivedAt      time.Time `json:"receivedAt"`
+	SinceFirstMs    int64     `json:"sinceFirstMs"`    // desde la primera respuesta
+	SincePreviousMs int64     `json:"sincePreviousMs"` // desde la respuesta anterior
+}
+
+// AnswerTimeline orden de llegada de las respuestas a una pregunta, para
+// detectar respuestas sospechosamente sincronizadas
+type AnswerTimeline struct {
+	QuestionID int                   `json:"questionId"`
+	Answers    []AnswerTimelineEntry `json:"answers"`
+}
+
+// ServedOptionOrder orden en que se le presentaron las opciones de una pregunta
+type ServedOptionOrder struct {
+	QuestionNumber int      `json:"questionNumber"`
+	QuestionID     int      `json:"questionId"`
+	OptionOrder    []string `json:"optionOrder"`
+	SelectedOption string   `json:"selectedOption,omitempty"` // vacío en la pregunta aún sin responder
+}
+
+// OptionOrderAudit opciones tal como las vio un jugador, para resolver reclamos
+// de "las opciones cambiaron de lugar". Shuffled es false mientras no exista
+// barajado por sesión: todos ven el orden del archivo de preguntas.
+type OptionOrderAudit struct {
+	SessionID  string              `json:"sessionId"`
+	PlayerName string              `json:"playerName"`
+	Shuffled   bool                `json:"shuffled"`
+	Questions  []ServedOptionOrder `json:"questions"`
+}
+
 // PlayerStatus estado individual de un jugador
 type PlayerStatus struct {
 	PlayerName      string    `json:"playerName"`
@@ -80,6 +293,24 @@ type PlayerStatus struct {
 	LastActivity    time.Time `json:"lastActivity"`
 }
 
+// PlayerLookupStatus estado de un jugador buscado por nombre; GameStatus es
+// "active", "finished", "eliminated" o "not_found"
+type PlayerLookupStatus struct {
+	PlayerName      string     `json:"playerName"`
+	GameStatus      string     `json:"gameStatus"`
+	SessionID       string     `json:"sessionId,omitempty"`
+	CurrentQuestion int        `json:"currentQuestion,omitempty"`
+	TotalPrize      int64      `json:"totalPrize,omitempty"`
+	LivesRemaining  int        `json:"livesRemaining,omitempty"`
+	Connected       bool       `json:"connected"`
+	LastActivity    *time.Time `json:"lastActivity,omitempty"`
+}
+
+// PlayersLookupRequest request para consultar el estado de varios jugadores
+type PlayersLookupRequest struct {
+	Names []string `json:"names"`
+}
+
 // PlayersStatusResponse respuesta del estado de todos los jugadores
 type PlayersStatusResponse struct {
 	TotalPlayers    int            `json:"totalPlayers"`
@@ -88,3 +319,16 @@ type PlayersStatusResponse struct {
 	CurrentQuestion int            `json:"currentQuestion"`
 	Players         []PlayerStatus `json:"players"`
 }
+
+// StoreDiagnostics cantidad de claves de Redis por tipo, para detectar fugas
+// (miembros colgantes en active_sessions, sesiones huérfanas, etc.)
+type StoreDiagnostics struct {
+	Sessions        int64  `json:"sessions"`
+	SessionTokens   int64  `json:"sessionTokens"`
+	PlayerSessions  int64  `json:"playerSessions"`
+	ActiveSessions  int64  `json:"activeSessions"`
+	CorruptSessions int64  `json:"corruptSessions"`
+	Questions       int64  `json:"questions"`
+	Archives        int64  `json:"archives"`
+	GeneratedAt     string `json:"generatedAt"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "testing"
+
+func TestAnswerRequestValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		request AnswerRequest
+		field   string
+	}{
+		{"válida", AnswerRequest{QuestionID: 3, SelectedOption: "B", TimeToAnswer: 12}, ""},
+		{"apuesta válida", AnswerRequest{QuestionID: 3, SelectedOption: "B", Wager: 500}, ""},
+		{"sin pregunta", AnswerRequest{SelectedOption: "B"}, "questionId"},
+		{"pregunta negativa", AnswerRequest{QuestionID: -1, SelectedOption: "B"}, "questionId"},
+		{"sin opción", AnswerRequest{QuestionID: 3}, "selectedOption"},
+		{"opción en blanco", AnswerRequest{QuestionID: 3, SelectedOption: "  "}, "selectedOption"},
+		{"tiempo negativo", AnswerRequest{QuestionID: 3, SelectedOption: "B", TimeToAnswer: -1}, "timeToAnswer"},
+		{"apuesta negativa", AnswerRequest{QuestionID: 3, SelectedOption: "B", Wager: -5}, "wager"},
+		{"apuesta excesiva", AnswerRequest{QuestionID: 3, SelectedOption: "B", Wager: MaxSafePrize + 1}, "wager"},
+	}
+	for _, c := range cases {
+		err := c.request.Validate()
+		if c.field == "" {
+			if err != nil {
+				t.Fatalf("%s: no esperaba error, obtuve %v", c.name, err)
+			}
+			continue
+		}
+		if err == nil || err.Field != c.field || err.Message == "" {
+			t.Fatalf("%s: esperaba error en %s, obtuve %v", c.name, c.field, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package models
+
+// GameSettings configuración del juego ajustable en caliente desde el panel
+type GameSettings struct {
+	QuestionTimeLimit int    `json:"questionTimeLimit"` // segundos por pregunta (preguntas sin tiempo por dificultad)
+	BroadcastInterval int    `json:"broadcastInterval"` // segundos entre difusiones del listado de sesiones
+	TimerTickSeconds  int    `json:"timerTickSeconds"`  // segundos entre eventos timerTick (0 = desactivado)
+	PlayerLives       int    `json:"playerLives"`       // vidas de las sesiones nuevas (1 = eliminación directa)
+	AnswerMatching    string `json:"answerMatching"`    // "exact", "nfc" o "fold"
+	TwoPhaseQuestions bool   `json:"twoPhaseQuestions"`
+	StrictFinalAnswer bool   `json:"strictFinalAnswer"`
+	ShowExplanation   bool   `json:"showExplanation"` // explicación en la respuesta del jugador al contestar
+}
+
+// SettingsUpdate cambios parciales a la configuración; los campos omitidos no cambian
+type SettingsUpdate struct {
+	QuestionTimeLimit *int    `json:"questionTimeLimit,omitempty"`
+	BroadcastInterval *int    `json:"broadcastInterval,omitempty"`
+	TimerTickSeconds  *int    `json:"timerTickSeconds,omitempty"`
+	PlayerLives       *int    `json:"playerLives,omitempty"`
+	AnswerMatching    *string `json:"answerMatching,omitempty"`
+	TwoPhaseQuestions *bool   `json:"twoPhaseQuestions,omitempty"`
+	StrictFinalAnswer *bool   `json:"strictFinalAnswer,omitempty"`
+	ShowExplanation   *bool   `json:"showExplanation,omitempty"`
+}
+
+// Validate verifica los rangos de los campos presentes; devuelve el primer campo inválido
+func (u *SettingsUpdate) Validate() *FieldError {
+	if u.QuestionTimeLimit != nil && (*u.QuestionTimeLimit < 5 || *u.QuestionTimeLimit > 600) {
+		return &FieldError{Field: "questionTimeLimit", Message: "Debe estar entre 5 y 600 segundos"}
+	}
+	if u.BroadcastInterval != nil && (*u.BroadcastInterval < 1 || *u.BroadcastInterval > 300) {
+		return &FieldError{Field: "broadcastInterval", Message: "Debe estar entre 1 y 300 segundos"}
+	}
+	if u.TimerTickSeconds != nil && (*u.TimerTickSeconds < 0 || *u.TimerTickSeconds > 60) {
+		return &FieldError{Field: "timerTickSeconds", Message: "Debe estar entre 0 y 60 segundos"}
+	}
+	if u.PlayerLives != nil && (*u.PlayerLives < 1 || *u.PlayerLives > 10) {
+		return &FieldError{Field: "playerLives", Message: "Debe estar entre 1 y 10"}
+	}
+	if u.AnswerMatching != nil {
+		switch *u.AnswerMatching {
+		case "exact", "nfc", "fold":
+		default:
+			return &FieldError{Field: "answerMatching", Message: "Debe ser exact, nfc o fold"}
+		}
+	}
+	return nil
+}
+
+// Apply aplica los cambios presentes sobre la configuración
+func (u *SettingsUpdate) Apply(settings *GameSettings) {
+	if u.QuestionTimeLimit != nil {
+		settings.QuestionTimeLimit = *u.QuestionTimeLimit
+	}
+	if u.BroadcastInterval != nil {
+		settings.BroadcastInterval = *u.BroadcastInterval
+	}
+	if u.TimerTickSeconds != nil {
+		settings.TimerTickSeconds = *u.TimerTickSeconds
+	}
+	if u.PlayerLives != nil {
+		settings.PlayerLives = *u.PlayerLives
+	}
+	if u.AnswerMatching != nil {
+		settings.AnswerMatching = *u.AnswerMatching
+	}
+	if u.TwoPhaseQuestions != nil {
+		settings.TwoPhaseQuestions = *u.TwoPhaseQuestions
+	}
+	if u.StrictFinalAnswer != nil {
+		settings.StrictFinalAnswer = *u.StrictFinalAnswer
+	}
+	if u.ShowExplanation != nil {
+		settings.ShowExplanation = *u.ShowExplanation
+	}
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Timestamp hora actual en UTC y RFC3339, el formato de las marcas de tiempo
+// que devuelven la API y los eventos WebSocket
+func Timestamp() string {
+	return FormatTime(time.Now())
+}
+
+// FormatTime da formato RFC3339 en UTC a una marca de tiempo
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
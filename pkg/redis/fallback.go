@@ -0,0 +1,640 @@
+package redis
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrStoreUnavailable indica que Redis no responde y la operación no puede
+// atenderse con el último estado conocido (escrituras o lecturas sin copia)
+var ErrStoreUnavailable = errors.New("almacenamiento temporalmente no disponible")
+
+// FallbackStore envuelve un RedisStore y guarda en memoria una copia de lo
+// último leído o escrito de las claves críticas (ver cacheable) y del banco de
+// preguntas. Si Redis se cae, esas lecturas se sirven desde la copia (solo
+// lectura) y las escrituras se rechazan con ErrStoreUnavailable hasta que
+// Watch detecta que Redis volvió.
+type FallbackStore struct {
+	primary RedisStore
+	cache   *MemoryStore
+
+	mutex           sync.RWMutex
+	degraded        bool
+	known           map[string]bool // claves cuya copia refleja Redis (incluida su ausencia)
+	patterns        map[string]bool // patrones consultados con éxito
+	questionsCached bool
+}
+
+// NewFallbackStore crea la capa de respaldo sobre primary
+func NewFallbackStore(primary RedisStore) *FallbackStore {
+	return &FallbackStore{
+		primary:  primary,
+		cache:    NewMemoryStore(),
+		known:    make(map[string]bool),
+		patterns: make(map[string]bool),
+	}
+}
+
+var _ RedisStore = (*FallbackStore)(nil)
+
+// Claves críticas que se copian en memoria: el estado del juego y las sesiones
+// activas (el set y cada sesión). El resto no se copia: la copia crecería sin
+// límite y las claves con TTL en Redis (tokens, locks) se seguirían sirviendo
+// vencidas durante una caída.
+var (
+	cachedKeys     = map[string]bool{"game_state": true, "active_sessions": true}
+	cachedPrefixes = []string{"session:"}
+)
+
+// cacheable indica si la clave (o el patrón, sin su '*' final) es crítica
+func cacheable(key string) bool {
+	key = strings.TrimSuffix(key, "*")
+	if cachedKeys[key] {
+		return true
+	}
+	for _, prefix := range cachedPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// FullKey devuelve la clave tal como queda en el almacén principal
+func (f *FallbackStore) FullKey(key string) string {
+	return FullKey(f.primary, key)
+}
+
+// Degraded indica si Redis está caído y se sirve el último estado conocido
+func (f *FallbackStore) Degraded() bool {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.degraded
+}
+
+// Watch comprueba cada interval si Redis volvió mientras esté degradado. No
+// hay nada que fusionar: las escrituras se rechazaron durante la caída, así que
+// Redis sigue siendo la fuente de verdad y la copia se refresca con las lecturas.
+func (f *FallbackStore) Watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if f.Degraded() {
+			f.HealthCheck()
+		}
+	}
+}
+
+// failed indica si err es un fallo de conexión (no redis.Nil ni un error de
+// respuesta de Redis) y, en ese caso, pasa a modo degradado
+func (f *FallbackStore) failed(err error) bool {
+	if err == nil || err == redis.Nil {
+		return false
+	}
+	var replyErr redis.Error
+	if errors.As(err, &replyErr) {
+		return false
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if !f.degraded {
+		f.degraded = true
+		log.Printf("⚠️ Redis no disponible (%v): sirviendo el último estado conocido en solo lectura", err)
+	}
+	return true
+}
+
+// recovered sale del modo degradado tras una operación exitosa contra Redis
+func (f *FallbackStore) recovered() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.degraded {
+		f.degraded = false
+		log.Println("✅ Redis disponible de nuevo: se reanudan las escrituras")
+	}
+}
+
+// remember marca claves críticas cuya copia en memoria refleja Redis
+func (f *FallbackStore) remember(keys ...string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for _, key := range keys {
+		if cacheable(key) {
+			f.known[key] = true
+		}
+	}
+}
+
+// cached devuelve ErrStoreUnavailable si la clave nunca se leyó ni escribió
+func (f *FallbackStore) cached(key string) error {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	if !f.known[key] {
+		return ErrStoreUnavailable
+	}
+	return nil
+}
+
+// write ejecuta una escritura en Redis; en modo degradado la rechaza
+func (f *FallbackStore) write(op func() error) error {
+	if f.Degraded() {
+		return ErrStoreUnavailable
+	}
+	if err := op(); err != nil {
+		if f.failed(err) {
+			return ErrStoreUnavailable
+		}
+		return err
+	}
+	return nil
+}
+
+// LoadQuestionsFromJSON carga las preguntas en Redis y en la copia local
+func (f *FallbackStore) LoadQuestionsFromJSON(jsonData []byte) error {
+	if err := f.write(func() error { return f.primary.LoadQuestionsFromJSON(jsonData) }); err != nil {
+		return err
+	}
+	if err := f.cache.LoadQuestionsFromJSON(jsonData); err != nil {
+		return err
+	}
+	f.mutex.Lock()
+	f.questionsCached = true
+	f.mutex.Unlock()
+	return nil
+}
+
+// SaveQuestion guarda una pregunta en Redis y en la copia local
+func (f *FallbackStore) SaveQuestion(question Question) error {
+	if err := f.write(func() error { return f.primary.SaveQuestion(question) }); err != nil {
+		return err
+	}
+	return f.cache.SaveQuestion(question)
+}
+
+// questionsFallback devuelve la copia local de preguntas si existe
+func (f *FallbackStore) questionsFallback() (*MemoryStore, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	if !f.questionsCached {
+		return nil, ErrStoreUnavailable
+	}
+	return f.cache, nil
+}
+
+// GetQuestion obtiene una pregunta
+func (f *FallbackStore) GetQuestion(id int) (*Question, error) {
+	if !f.Degraded() {
+		question, err := f.primary.GetQuestion(id)
+		if !f.failed(err) {
+			return question, err
+		}
+	}
+	cache, err := f.questionsFallback()
+	if err != nil {
+		return nil, err
+	}
+	return cache.GetQuestion(id)
+}
+
+// GetAllQuestions obtiene todas las preguntas
+func (f *FallbackStore) GetAllQuestions() ([]Question, error) {
+	if !f.Degraded() {
+		questions, err := f.primary.GetAllQuestions()
+		if !f.failed(err) {
+			return questions, err
+		}
+	}
+	cache, err := f.questionsFallback()
+	if err != nil {
+		return nil, err
+	}
+	return cache.GetAllQuestions()
+}
+
+// GetQuestionsByDifficulty obtiene preguntas por rango de dificultad
+func (f *FallbackStore) GetQuestionsByDifficulty(minDifficulty, maxDifficulty int) ([]Question, error) {
+	if !f.Degraded() {
+		questions, err := f.primary.GetQuestionsByDifficulty(minDifficulty, maxDifficulty)
+		if !f.failed(err) {
+			return questions, err
+		}
+	}
+	cache, err := f.questionsFallback()
+	if err != nil {
+		return nil, err
+	}
+	return cache.GetQuestionsByDifficulty(minDifficulty, maxDifficulty)
+}
+
+// GetRandomQuestion obtiene una pregunta aleatoria
+func (f *FallbackStore) GetRandomQuestion() (*Question, error) {
+	if !f.Degraded() {
+		question, err := f.primary.GetRandomQuestion()
+		if !f.failed(err) {
+			return question, err
+		}
+	}
+	cache, err := f.questionsFallback()
+	if err != nil {
+		return nil, err
+	}
+	return cache.GetRandomQuestion()
+}
+
+// GetQuestionPlan obtiene el orden de las preguntas
+func (f *FallbackStore) GetQuestionPlan() ([]int, error) {
+	if !f.Degraded() {
+		plan, err := f.primary.GetQuestionPlan()
+		if !f.failed(err) {
+			return plan, err
+		}
+	}
+	cache, err := f.questionsFallback()
+	if err != nil {
+		return nil, err
+	}
+	return cache.GetQuestionPlan()
+}
+
+// GetMetadata obtiene la metadata de las preguntas
+func (f *FallbackStore) GetMetadata() (map[string]interface{}, error) {
+	if !f.Degraded() {
+		metadata, err := f.primary.GetMetadata()
+		if !f.failed(err) {
+			return metadata, err
+		}
+	}
+	cache, err := f.questionsFallback()
+	if err != nil {
+		return nil, err
+	}
+	return cache.GetMetadata()
+}
+
+// GetQuestionCount obtiene el número de preguntas
+func (f *FallbackStore) GetQuestionCount() (int, error) {
+	if !f.Degraded() {
+		count, err := f.primary.GetQuestionCount()
+		if !f.failed(err) {
+			return count, err
+		}
+	}
+	cache, err := f.questionsFallback()
+	if err != nil {
+		return 0, err
+	}
+	return cache.GetQuestionCount()
+}
+
+// HealthCheck verifica Redis; un fallo activa el modo degradado y un éxito lo termina
+func (f *FallbackStore) HealthCheck() error {
+	err := f.primary.HealthCheck()
+	if err != nil {
+		f.failed(err)
+		return err
+	}
+	f.recovered()
+	return nil
+}
+
+// Set guarda un valor
+func (f *FallbackStore) Set(key, value string, ttl time.Duration) error {
+	if err := f.write(func() error { return f.primary.Set(key, value, ttl) }); err != nil {
+		return err
+	}
+	if !cacheable(key) {
+		return nil
+	}
+	f.remember(key)
+	return f.cache.Set(key, value, ttl)
+}
+
+// Get obtiene un valor; en modo degradado devuelve la última copia conocida
+func (f *FallbackStore) Get(key string) (string, error) {
+	if !f.Degraded() {
+		value, err := f.primary.Get(key)
+		if !f.failed(err) {
+			if !cacheable(key) {
+				return value, err
+			}
+			if err == nil {
+				f.cache.Set(key, value, 0)
+				f.remember(key)
+			} else if err == redis.Nil {
+				f.cache.Delete(key)
+				f.remember(key)
+			}
+			return value, err
+		}
+	}
+	if err := f.cached(key); err != nil {
+		return "", err
+	}
+	return f.cache.Get(key)
+}
+
+// SetIfAbsent guarda un valor solo si la clave no existe
+func (f *FallbackStore) SetIfAbsent(key, value string, ttl time.Duration) (bool, error) {
+	var created bool
+	err := f.write(func() error {
+		var err error
+		created, err = f.primary.SetIfAbsent(key, value, ttl)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	if created && cacheable(key) {
+		f.cache.Set(key, value, ttl)
+		f.remember(key)
+	}
+	return created, nil
+}
+
+// Delete elimina una o varias claves
+func (f *FallbackStore) Delete(keys ...string) error {
+	if err := f.write(func() error { return f.primary.Delete(keys...) }); err != nil {
+		return err
+	}
+	f.remember(keys...)
+	return f.cache.Delete(keys...)
+}
+
+// DeleteIfExists elimina una clave y devuelve si existía
+func (f *FallbackStore) DeleteIfExists(key string) (bool, error) {
+	var existed bool
+	err := f.write(func() error {
+		var err error
+		existed, err = f.primary.DeleteIfExists(key)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	f.remember(key)
+	f.cache.Delete(key)
+	return existed, nil
+}
+
+// GetKeysByPattern obtiene claves que coinciden con un patrón. Al responder
+// Redis, se descartan de la copia las claves del patrón que ya no existen.
+func (f *FallbackStore) GetKeysByPattern(pattern string) ([]string, error) {
+	if !f.Degraded() {
+		keys, err := f.primary.GetKeysByPattern(pattern)
+		if !f.failed(err) {
+			if err == nil {
+				f.pruneCache(pattern, keys)
+			}
+			return keys, err
+		}
+	}
+
+	f.mutex.RLock()
+	known := f.patterns[pattern]
+	f.mutex.RUnlock()
+	if !known {
+		return nil, ErrStoreUnavailable
+	}
+	return f.cache.GetKeysByPattern(pattern)
+}
+
+// CountKeysByPattern cuenta las claves que coinciden con un patrón; en modo
+// degradado solo si el patrón ya se había consultado
+func (f *FallbackStore) CountKeysByPattern(pattern string) (int64, error) {
+	if !f.Degraded() {
+		count, err := f.primary.CountKeysByPattern(pattern)
+		if !f.failed(err) {
+			return count, err
+		}
+	}
+
+	f.mutex.RLock()
+	known := f.patterns[pattern]
+	f.mutex.RUnlock()
+	if !known {
+		return 0, ErrStoreUnavailable
+	}
+	return f.cache.CountKeysByPattern(pattern)
+}
+
+// pruneCache elimina de la copia las claves del patrón ausentes en Redis
+func (f *FallbackStore) pruneCache(pattern string, keys []string) {
+	present := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		present[key] = true
+	}
+	cachedKeys, _ := f.cache.GetKeysByPattern(pattern)
+	for _, key := range cachedKeys {
+		if !present[key] {
+			f.cache.Delete(key)
+		}
+	}
+
+	if !cacheable(pattern) {
+		return
+	}
+	f.mutex.Lock()
+	f.patterns[pattern] = true
+	f.mutex.Unlock()
+}
+
+// AddToSet agrega un elemento a un conjunto
+func (f *FallbackStore) AddToSet(key, value string) error {
+	if err := f.write(func() error { return f.primary.AddToSet(key, value) }); err != nil {
+		return err
+	}
+	if !cacheable(key) {
+		return nil
+	}
+	return f.cache.AddToSet(key, value)
+}
+
+// AddToSetIfAbsent agrega un elemento a un conjunto; devuelve false si ya estaba
+func (f *FallbackStore) AddToSetIfAbsent(key, value string) (bool, error) {
+	var added bool
+	err := f.write(func() error {
+		var err error
+		added, err = f.primary.AddToSetIfAbsent(key, value)
+		return err
+	})
+	if err != nil || !cacheable(key) {
+		return added, err
+	}
+	return added, f.cache.AddToSet(key, value)
+}
+
+// AddToSetIfBelow agrega un elemento a un conjunto si tiene menos de limit elementos
+func (f *FallbackStore) AddToSetIfBelow(key, value string, limit int64) (bool, error) {
+	var added bool
+	err := f.write(func() error {
+		var err error
+		added, err = f.primary.AddToSetIfBelow(key, value, limit)
+		return err
+	})
+	if err != nil || !added || !cacheable(key) {
+		return added, err
+	}
+	return added, f.cache.AddToSet(key, value)
+}
+
+// RemoveFromSet remueve un elemento de un conjunto
+func (f *FallbackStore) RemoveFromSet(key, value string) error {
+	if err := f.write(func() error { return f.primary.RemoveFromSet(key, value) }); err != nil {
+		return err
+	}
+	return f.cache.RemoveFromSet(key, value)
+}
+
+// GetSetMembers obtiene los miembros de un conjunto; en modo degradado
+// devuelve la última copia conocida
+func (f *FallbackStore) GetSetMembers(key string) ([]string, error) {
+	if !f.Degraded() {
+		members, err := f.primary.GetSetMembers(key)
+		if !f.failed(err) {
+			if err == nil && cacheable(key) {
+				f.cache.Delete(key)
+				for _, member := range members {
+					f.cache.AddToSet(key, member)
+				}
+				f.remember(key)
+			}
+			return members, err
+		}
+	}
+	if err := f.cached(key); err != nil {
+		return nil, err
+	}
+	return f.cache.GetSetMembers(key)
+}
+
+// GetSetSize obtiene la cantidad de miembros de un conjunto
+func (f *FallbackStore) GetSetSize(key string) (int64, error) {
+	if !f.Degraded() {
+		size, err := f.primary.GetSetSize(key)
+		if !f.failed(err) {
+			return size, err
+		}
+	}
+	if err := f.cached(key); err != nil {
+		return 0, err
+	}
+	return f.cache.GetSetSize(key)
+}
+
+// PushToList agrega un elemento al inicio de una lista
+func (f *FallbackStore) PushToList(key, value string) error {
+	return f.write(func() error { return f.primary.PushToList(key, value) })
+}
+
+// GetListRange obtiene elementos de una lista (sin copia local)
+func (f *FallbackStore) GetListRange(key string, start, stop int64) ([]string, error) {
+	if f.Degraded() {
+		return nil, ErrStoreUnavailable
+	}
+	values, err := f.primary.GetListRange(key, start, stop)
+	if f.failed(err) {
+		return nil, ErrStoreUnavailable
+	}
+	return values, err
+}
+
+// GetListLength obtiene la cantidad de elementos de una lista (sin copia local)
+func (f *FallbackStore) GetListLength(key string) (int64, error) {
+	if f.Degraded() {
+		return 0, ErrStoreUnavailable
+	}
+	length, err := f.primary.GetListLength(key)
+	if f.failed(err) {
+		return 0, ErrStoreUnavailable
+	}
+	return length, err
+}
+
+// SetHashField guarda un campo en un hash
+func (f *FallbackStore) SetHashField(key, field, value string) error {
+	if err := f.write(func() error { return f.primary.SetHashField(key, field, value) }); err != nil {
+		return err
+	}
+	if !cacheable(key) {
+		return nil
+	}
+	return f.cache.SetHashField(key, field, value)
+}
+
+// SetHashFieldIfAbsent guarda un campo en un hash solo si no existe
+func (f *FallbackStore) SetHashFieldIfAbsent(key, field, value string) (bool, error) {
+	var created bool
+	err := f.write(func() error {
+		var err error
+		created, err = f.primary.SetHashFieldIfAbsent(key, field, value)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	if created && cacheable(key) {
+		f.cache.SetHashField(key, field, value)
+	}
+	return created, nil
+}
+
+// GetHashField obtiene un campo de un hash; en modo degradado devuelve la
+// última copia conocida del hash
+func (f *FallbackStore) GetHashField(key, field string) (string, error) {
+	if !f.Degraded() {
+		value, err := f.primary.GetHashField(key, field)
+		if !f.failed(err) {
+			return value, err
+		}
+	}
+	if err := f.cached(key); err != nil {
+		return "", err
+	}
+	return f.cache.GetHashField(key, field)
+}
+
+// IncrementHashField incrementa un campo numérico de un hash
+func (f *FallbackStore) IncrementHashField(key, field string, delta int64) (int64, error) {
+	var value int64
+	err := f.write(func() error {
+		var err error
+		value, err = f.primary.IncrementHashField(key, field, delta)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if cacheable(key) {
+		f.cache.SetHashField(key, field, strconv.FormatInt(value, 10))
+	}
+	return value, nil
+}
+
+// GetHashAll obtiene todos los campos de un hash; en modo degradado devuelve
+// la última copia conocida
+func (f *FallbackStore) GetHashAll(key string) (map[string]string, error) {
+	if !f.Degraded() {
+		fields, err := f.primary.GetHashAll(key)
+		if !f.failed(err) {
+			if err == nil && cacheable(key) {
+				f.cache.Delete(key)
+				for field, value := range fields {
+					f.cache.SetHashField(key, field, value)
+				}
+				f.remember(key)
+			}
+			return fields, err
+		}
+	}
+	if err := f.cached(key); err != nil {
+		return nil, err
+	}
+	return f.cache.GetHashAll(key)
+}
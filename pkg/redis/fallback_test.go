@@ -0,0 +1,159 @@
+package redis
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// flakyStore simula un Redis que puede caerse: con down activo las operaciones
+// usadas por la prueba fallan con un error de conexión
+type flakyStore struct {
+	*MemoryStore
+	down bool
+}
+
+var errConnRefused = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+func (s *flakyStore) HealthCheck() error {
+	if s.down {
+		return errConnRefused
+	}
+	return nil
+}
+
+func (s *flakyStore) Set(key, value string, ttl time.Duration) error {
+	if s.down {
+		return errConnRefused
+	}
+	return s.MemoryStore.Set(key, value, ttl)
+}
+
+func (s *flakyStore) Get(key string) (string, error) {
+	if s.down {
+		return "", errConnRefused
+	}
+	return s.MemoryStore.Get(key)
+}
+
+func (s *flakyStore) AddToSet(key, value string) error {
+	if s.down {
+		return errConnRefused
+	}
+	return s.MemoryStore.AddToSet(key, value)
+}
+
+func (s *flakyStore) GetSetMembers(key string) ([]string, error) {
+	if s.down {
+		return nil, errConnRefused
+	}
+	return s.MemoryStore.GetSetMembers(key)
+}
+
+func TestFallbackStoreServesLastKnownStateDuringOutage(t *testing.T) {
+	primary := &flakyStore{MemoryStore: NewMemoryStore()}
+	f := NewFallbackStore(primary)
+
+	if err := f.Set("game_state", `{"isActive":true}`, 0); err != nil {
+		t.Fatalf("error guardando estado: %v", err)
+	}
+	if err := f.AddToSet("active_sessions", "s1"); err != nil {
+		t.Fatalf("error agregando sesión: %v", err)
+	}
+	if _, err := f.GetSetMembers("active_sessions"); err != nil {
+		t.Fatalf("error leyendo sesiones: %v", err)
+	}
+
+	// Redis se cae: la primera operación fallida activa el modo degradado
+	primary.down = true
+	value, err := f.Get("game_state")
+	if err != nil || value != `{"isActive":true}` {
+		t.Fatalf("se esperaba el último estado conocido: %q (%v)", value, err)
+	}
+	if !f.Degraded() {
+		t.Fatalf("tras el fallo debe quedar en modo degradado")
+	}
+	members, err := f.GetSetMembers("active_sessions")
+	if err != nil || len(members) != 1 || members[0] != "s1" {
+		t.Fatalf("se esperaban las sesiones conocidas: %v (%v)", members, err)
+	}
+
+	// Las escrituras y las lecturas sin copia se rechazan con un error claro
+	if err := f.Set("game_state", `{"isActive":false}`, 0); !errors.Is(err, ErrStoreUnavailable) {
+		t.Fatalf("en modo degradado se esperaba ErrStoreUnavailable al escribir, obtuve %v", err)
+	}
+	if _, err := f.Get("nunca_leida"); !errors.Is(err, ErrStoreUnavailable) {
+		t.Fatalf("sin copia se esperaba ErrStoreUnavailable, obtuve %v", err)
+	}
+	if err := f.HealthCheck(); err == nil || !f.Degraded() {
+		t.Fatalf("con Redis caído el chequeo debe fallar y seguir degradado")
+	}
+
+	// Redis vuelve: el chequeo sale del modo degradado y Redis manda de nuevo
+	primary.down = false
+	primary.MemoryStore.Set("game_state", `{"isActive":false}`, 0)
+	if err := f.HealthCheck(); err != nil || f.Degraded() {
+		t.Fatalf("al recuperarse Redis debe salir del modo degradado (%v)", err)
+	}
+	if err := f.Set("game_state", `{"isActive":true,"message":"de vuelta"}`, 0); err != nil {
+		t.Fatalf("tras recuperarse se aceptan escrituras: %v", err)
+	}
+	if value, _ := primary.MemoryStore.Get("game_state"); value != `{"isActive":true,"message":"de vuelta"}` {
+		t.Fatalf("la escritura debe llegar a Redis: %q", value)
+	}
+}
+
+func TestFallbackStoreIgnoresMissingKeys(t *testing.T) {
+	f := NewFallbackStore(&flakyStore{MemoryStore: NewMemoryStore()})
+
+	// Una clave inexistente no es una caída de Redis
+	if _, err := f.Get("game_state"); err == nil || errors.Is(err, ErrStoreUnavailable) {
+		t.Fatalf("se esperaba redis.Nil, obtuve %v", err)
+	}
+	if f.Degraded() {
+		t.Fatalf("una clave ausente no debe activar el modo degradado")
+	}
+}
+
+func TestFallbackStoreCopiesOnlyCriticalKeys(t *testing.T) {
+	primary := &flakyStore{MemoryStore: NewMemoryStore()}
+	f := NewFallbackStore(primary)
+
+	f.Set("game_state", `{"isActive":true}`, 0)
+	f.Set("session:s1", `{"id":"s1"}`, 0)
+	f.Set("session:s2", `{"id":"s2"}`, 30*time.Millisecond)
+	f.Set("session_token:s1", "hash", time.Hour)
+	primary.MemoryStore.Set("end_lock", "1", time.Hour)
+	for _, key := range []string{"game_state", "session:s1", "session_token:s1", "end_lock"} {
+		if _, err := f.Get(key); err != nil {
+			t.Fatalf("error leyendo %s: %v", key, err)
+		}
+	}
+
+	// Solo el estado del juego y las sesiones quedan en la copia
+	for _, key := range []string{"session_token:s1", "end_lock"} {
+		if _, err := f.cache.Get(key); err == nil {
+			t.Fatalf("%s no debe copiarse en memoria", key)
+		}
+	}
+
+	primary.down = true
+	time.Sleep(50 * time.Millisecond)
+	if value, err := f.Get("session:s1"); err != nil || value != `{"id":"s1"}` {
+		t.Fatalf("se esperaba la sesión conocida: %q (%v)", value, err)
+	}
+	if !f.Degraded() {
+		t.Fatalf("tras el fallo debe quedar en modo degradado")
+	}
+	// Lo que no se copia no se sirve vencido: se rechaza con un error claro
+	for _, key := range []string{"session_token:s1", "end_lock"} {
+		if _, err := f.Get(key); !errors.Is(err, ErrStoreUnavailable) {
+			t.Fatalf("%s: se esperaba ErrStoreUnavailable, obtuve %v", key, err)
+		}
+	}
+	// La copia conserva el TTL con el que se escribió la clave
+	if _, err := f.Get("session:s2"); err == nil || errors.Is(err, ErrStoreUnavailable) {
+		t.Fatalf("la sesión vencida no debe servirse: %v", err)
+	}
+}
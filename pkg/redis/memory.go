@@ -0,0 +1,517 @@
+package redis
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MemoryStore implementación en memoria de RedisStore, pensada para pruebas
+// rápidas y deterministas sin un servidor Redis. Usa las mismas claves que
+// RedisClient (sin prefijo, al ser local al proceso) y devuelve redis.Nil
+// cuando una clave no existe.
+type MemoryStore struct {
+	mutex   sync.RWMutex
+	strings map[string]string
+	sets    map[string]map[string]bool
+	hashes  map[string]map[string]string
+	lists   map[string][]string
+	expires map[string]time.Time
+}
+
+// NewMemoryStore crea un almacén en memoria vacío
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		strings: make(map[string]string),
+		sets:    make(map[string]map[string]bool),
+		hashes:  make(map[string]map[string]string),
+		lists:   make(map[string][]string),
+		expires: make(map[string]time.Time),
+	}
+}
+
+// LoadQuestionsFromJSON carga las preguntas igual que RedisClient
+func (m *MemoryStore) LoadQuestionsFromJSON(jsonData []byte) error {
+	var questionsData QuestionsData
+	if err := json.Unmarshal(jsonData, &questionsData); err != nil {
+		return fmt.Errorf("error parsing JSON: %v", err)
+	}
+
+	ids, _ := m.GetSetMembers("question_ids")
+	for _, id := range ids {
+		m.Delete("question:" + id)
+	}
+	m.Delete("question_ids", "question_plan")
+
+	plan := make([]string, 0, len(questionsData.Questions))
+	for _, question := range questionsData.Questions {
+		if err := m.SaveQuestion(question); err != nil {
+			log.Printf("❌ Error guardando pregunta %d: %v", question.ID, err)
+			continue
+		}
+		idStr := strconv.Itoa(question.ID)
+		m.AddToSet("question_ids", idStr)
+		plan = append(plan, idStr)
+	}
+
+	metadataJSON, _ := json.Marshal(questionsData.Metadata)
+	m.Set("metadata", string(metadataJSON), 0)
+
+	m.mutex.Lock()
+	m.lists["question_plan"] = plan
+	m.mutex.Unlock()
+
+	return nil
+}
+
+// SaveQuestion guarda una pregunta individual
+func (m *MemoryStore) SaveQuestion(question Question) error {
+	questionJSON, err := json.Marshal(question)
+	if err != nil {
+		return fmt.Errorf("error serializing question: %v", err)
+	}
+	return m.Set(fmt.Sprintf("question:%d", question.ID), string(questionJSON), 0)
+}
+
+// GetQuestion obtiene una pregunta por ID
+func (m *MemoryStore) GetQuestion(id int) (*Question, error) {
+	questionJSON, err := m.Get(fmt.Sprintf("question:%d", id))
+	if err != nil {
+		return nil, fmt.Errorf("question %d not found", id)
+	}
+
+	var question Question
+	if err := json.Unmarshal([]byte(questionJSON), &question); err != nil {
+		return nil, fmt.Errorf("error parsing question: %v", err)
+	}
+	return &question, nil
+}
+
+// GetAllQuestions obtiene todas las preguntas
+func (m *MemoryStore) GetAllQuestions() ([]Question, error) {
+	ids, _ := m.GetSetMembers("question_ids")
+
+	var questions []Question
+	for _, idStr := range ids {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			continue
+		}
+		question, err := m.GetQuestion(id)
+		if err != nil {
+			continue
+		}
+		questions = append(questions, *question)
+	}
+	return questions, nil
+}
+
+// GetQuestionsByDifficulty obtiene preguntas filtradas por dificultad
+func (m *MemoryStore) GetQuestionsByDifficulty(minDifficulty, maxDifficulty int) ([]Question, error) {
+	allQuestions, _ := m.GetAllQuestions()
+
+	var filtered []Question
+	for _, question := range allQuestions {
+		if question.Difficulty >= minDifficulty && question.Difficulty <= maxDifficulty {
+			filtered = append(filtered, question)
+		}
+	}
+	return filtered, nil
+}
+
+// GetRandomQuestion obtiene una pregunta aleatoria
+func (m *MemoryStore) GetRandomQuestion() (*Question, error) {
+	ids, _ := m.GetSetMembers("question_ids")
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("error getting random question ID: %v", redis.Nil)
+	}
+	id, err := strconv.Atoi(ids[rand.Intn(len(ids))])
+	if err != nil {
+		return nil, err
+	}
+	return m.GetQuestion(id)
+}
+
+// GetQuestionPlan obtiene los IDs en orden de juego
+func (m *MemoryStore) GetQuestionPlan() ([]int, error) {
+	m.mutex.RLock()
+	idStrs := append([]string(nil), m.lists["question_plan"]...)
+	m.mutex.RUnlock()
+
+	plan := make([]int, 0, len(idStrs))
+	for _, idStr := range idStrs {
+		if id, err := strconv.Atoi(idStr); err == nil {
+			plan = append(plan, id)
+		}
+	}
+	return plan, nil
+}
+
+// GetMetadata obtiene los metadatos del quiz
+func (m *MemoryStore) GetMetadata() (map[string]interface{}, error) {
+	metadataJSON, err := m.Get("metadata")
+	if err != nil {
+		return nil, fmt.Errorf("metadata not found")
+	}
+
+	var metadata map[string]interface{}
+	if err := json.Unmarshal([]byte(metadataJSON), &metadata); err != nil {
+		return nil, fmt.Errorf("error parsing metadata: %v", err)
+	}
+	return metadata, nil
+}
+
+// GetQuestionCount obtiene el número total de preguntas
+func (m *MemoryStore) GetQuestionCount() (int, error) {
+	count, _ := m.GetSetSize("question_ids")
+	return int(count), nil
+}
+
+// HealthCheck siempre responde bien en memoria
+func (m *MemoryStore) HealthCheck() error {
+	return nil
+}
+
+// Set guarda un valor con TTL opcional
+func (m *MemoryStore) Set(key, value string, ttl time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.strings[key] = value
+	if ttl > 0 {
+		m.expires[key] = time.Now().Add(ttl)
+	} else {
+		delete(m.expires, key)
+	}
+	return nil
+}
+
+// SetIfAbsent guarda un valor solo si la clave no existe
+func (m *MemoryStore) SetIfAbsent(key, value string, ttl time.Duration) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.expireLocked(key)
+	if m.existsLocked(key) {
+		return false, nil
+	}
+	m.strings[key] = value
+	if ttl > 0 {
+		m.expires[key] = time.Now().Add(ttl)
+	}
+	return true, nil
+}
+
+// Get obtiene un valor por clave
+func (m *MemoryStore) Get(key string) (string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.expireLocked(key)
+	value, ok := m.strings[key]
+	if !ok {
+		return "", redis.Nil
+	}
+	return value, nil
+}
+
+// Delete elimina una o varias claves de cualquier tipo
+func (m *MemoryStore) Delete(keys ...string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, key := range keys {
+		m.deleteLocked(key)
+	}
+	return nil
+}
+
+// DeleteIfExists elimina una clave y devuelve si existía
+func (m *MemoryStore) DeleteIfExists(key string) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.expireLocked(key)
+	existed := m.existsLocked(key)
+	m.deleteLocked(key)
+	return existed, nil
+}
+
+// GetKeysByPattern obtiene claves que coinciden con un patrón glob
+func (m *MemoryStore) GetKeysByPattern(pattern string) ([]string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var keys []string
+	for _, key := range m.allKeysLocked() {
+		m.expireLocked(key)
+		if !m.existsLocked(key) {
+			continue
+		}
+		if ok, _ := path.Match(pattern, key); ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// CountKeysByPattern cuenta las claves que coinciden con un patrón glob
+func (m *MemoryStore) CountKeysByPattern(pattern string) (int64, error) {
+	keys, err := m.GetKeysByPattern(pattern)
+	return int64(len(keys)), err
+}
+
+// AddToSet agrega un elemento a un conjunto
+func (m *MemoryStore) AddToSet(key, value string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.sets[key] == nil {
+		m.sets[key] = make(map[string]bool)
+	}
+	m.sets[key][value] = true
+	return nil
+}
+
+// AddToSetIfAbsent agrega un elemento a un conjunto; devuelve false si ya estaba
+func (m *MemoryStore) AddToSetIfAbsent(key, value string) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.sets[key] == nil {
+		m.sets[key] = make(map[string]bool)
+	}
+	if m.sets[key][value] {
+		return false, nil
+	}
+	m.sets[key][value] = true
+	return true, nil
+}
+
+// AddToSetIfBelow agrega un elemento a un conjunto solo si tiene menos de
+// limit elementos (o si ya estaba); devuelve si quedó en el set
+func (m *MemoryStore) AddToSetIfBelow(key, value string, limit int64) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.sets[key][value] {
+		return true, nil
+	}
+	if int64(len(m.sets[key])) >= limit {
+		return false, nil
+	}
+	if m.sets[key] == nil {
+		m.sets[key] = make(map[string]bool)
+	}
+	m.sets[key][value] = true
+	return true, nil
+}
+
+// RemoveFromSet remueve un elemento de un conjunto
+func (m *MemoryStore) RemoveFromSet(key, value string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.sets[key], value)
+	if len(m.sets[key]) == 0 {
+		delete(m.sets, key)
+	}
+	return nil
+}
+
+// GetSetMembers obtiene los miembros de un conjunto, ordenados para ser deterministas
+func (m *MemoryStore) GetSetMembers(key string) ([]string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	members := make([]string, 0, len(m.sets[key]))
+	for member := range m.sets[key] {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// GetSetSize obtiene la cantidad de miembros de un conjunto
+func (m *MemoryStore) GetSetSize(key string) (int64, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return int64(len(m.sets[key])), nil
+}
+
+// PushToList agrega un elemento al inicio de una lista
+func (m *MemoryStore) PushToList(key, value string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.lists[key] = append([]string{value}, m.lists[key]...)
+	return nil
+}
+
+// GetListRange obtiene los elementos de una lista entre start y stop
+// (inclusive); los índices negativos cuentan desde el final, como en LRANGE
+func (m *MemoryStore) GetListRange(key string, start, stop int64) ([]string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	list := m.lists[key]
+	length := int64(len(list))
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	if start > stop {
+		return []string{}, nil
+	}
+	return append([]string(nil), list[start:stop+1]...), nil
+}
+
+// GetListLength obtiene la cantidad de elementos de una lista
+func (m *MemoryStore) GetListLength(key string) (int64, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return int64(len(m.lists[key])), nil
+}
+
+// SetHashField guarda un campo en un hash
+func (m *MemoryStore) SetHashField(key, field, value string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.hashLocked(key)[field] = value
+	return nil
+}
+
+// SetHashFieldIfAbsent guarda un campo en un hash solo si no existe
+func (m *MemoryStore) SetHashFieldIfAbsent(key, field, value string) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	hash := m.hashLocked(key)
+	if _, ok := hash[field]; ok {
+		return false, nil
+	}
+	hash[field] = value
+	return true, nil
+}
+
+// GetHashField obtiene un campo de un hash
+func (m *MemoryStore) GetHashField(key, field string) (string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	value, ok := m.hashes[key][field]
+	if !ok {
+		return "", redis.Nil
+	}
+	return value, nil
+}
+
+// IncrementHashField incrementa un campo numérico de un hash
+func (m *MemoryStore) IncrementHashField(key, field string, delta int64) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	hash := m.hashLocked(key)
+	current := int64(0)
+	if value, ok := hash[field]; ok {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("hash value is not an integer")
+		}
+		current = n
+	}
+	current += delta
+	hash[field] = strconv.FormatInt(current, 10)
+	return current, nil
+}
+
+// GetHashAll obtiene una copia de todos los campos de un hash
+func (m *MemoryStore) GetHashAll(key string) (map[string]string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	result := make(map[string]string, len(m.hashes[key]))
+	for field, value := range m.hashes[key] {
+		result[field] = value
+	}
+	return result, nil
+}
+
+func (m *MemoryStore) hashLocked(key string) map[string]string {
+	if m.hashes[key] == nil {
+		m.hashes[key] = make(map[string]string)
+	}
+	return m.hashes[key]
+}
+
+func (m *MemoryStore) expireLocked(key string) {
+	if expiresAt, ok := m.expires[key]; ok && time.Now().After(expiresAt) {
+		m.deleteLocked(key)
+	}
+}
+
+func (m *MemoryStore) deleteLocked(key string) {
+	delete(m.strings, key)
+	delete(m.sets, key)
+	delete(m.hashes, key)
+	delete(m.lists, key)
+	delete(m.expires, key)
+}
+
+func (m *MemoryStore) existsLocked(key string) bool {
+	if _, ok := m.strings[key]; ok {
+		return true
+	}
+	if _, ok := m.sets[key]; ok {
+		return true
+	}
+	if _, ok := m.hashes[key]; ok {
+		return true
+	}
+	_, ok := m.lists[key]
+	return ok
+}
+
+func (m *MemoryStore) allKeysLocked() []string {
+	seen := make(map[string]bool)
+	var keys []string
+	add := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	for key := range m.strings {
+		add(key)
+	}
+	for key := range m.sets {
+		add(key)
+	}
+	for key := range m.hashes {
+		add(key)
+	}
+	for key := range m.lists {
+		add(key)
+	}
+	return keys
+}
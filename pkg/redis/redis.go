@@ -1,11 +1,14 @@
 package redis
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -15,8 +18,12 @@ import (
 type RedisClient struct {
 	client *redis.Client
 	ctx    context.Context
+	prefix string // espacio de nombres antepuesto a todas las claves
 }
 
+// DefaultKeyPrefix prefijo de claves por defecto
+const DefaultKeyPrefix = "quiz:"
+
 // Question estructura para representar una pregunta
 type Question struct {
 	ID          int               `json:"id"`
@@ -25,6 +32,84 @@ type Question struct {
 	Correct     string            `json:"correctAnswer"`
 	Explanation string            `json:"explanation"`
 	Difficulty  int               `json:"difficulty"`
+	Category    string            `json:"category,omitempty"`
+	OptionOrder []string          `json:"optionOrder,omitempty"` // orden de presentación de las opciones
+}
+
+// UnmarshalJSON toma el orden de las opciones del propio JSON cuando la
+// pregunta no trae optionOrder, ya que el mapa de opciones no lo conserva
+func (q *Question) UnmarshalJSON(data []byte) error {
+	type plainQuestion Question
+	aux := struct {
+		*plainQuestion
+		Options json.RawMessage `json:"options"`
+	}{plainQuestion: (*plainQuestion)(q)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	q.Options = nil
+	if len(aux.Options) == 0 || string(aux.Options) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(aux.Options, &q.Options); err != nil {
+		return err
+	}
+	if len(q.OptionOrder) == 0 {
+		order, err := jsonObjectKeys(aux.Options)
+		if err != nil {
+			return err
+		}
+		q.OptionOrder = order
+	}
+	q.OptionOrder = normalizeOptionOrder(q.OptionOrder, q.Options)
+	return nil
+}
+
+// jsonObjectKeys devuelve las claves de un objeto JSON en el orden en que aparecen
+func jsonObjectKeys(data []byte) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var keys []string
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("clave de opción inválida: %v", token)
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// normalizeOptionOrder descarta del orden claves repetidas o que no son
+// opciones y agrega al final, ordenadas, las opciones que falten
+func normalizeOptionOrder(order []string, options map[string]string) []string {
+	normalized := make([]string, 0, len(options))
+	seen := make(map[string]bool, len(options))
+	for _, key := range order {
+		if _, ok := options[key]; ok && !seen[key] {
+			seen[key] = true
+			normalized = append(normalized, key)
+		}
+	}
+	var missing []string
+	for key := range options {
+		if !seen[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return append(normalized, missing...)
 }
 
 // QuestionsData estructura para el JSON completo
@@ -59,9 +144,26 @@ func NewRedisClient(addr, password string, db int) *RedisClient {
 	return &RedisClient{
 		client: rdb,
 		ctx:    ctx,
+		prefix: DefaultKeyPrefix,
 	}
 }
 
+// SetPrefix cambia el espacio de nombres de las claves, para que varios
+// despliegues puedan compartir la misma instancia de Redis
+func (r *RedisClient) SetPrefix(prefix string) {
+	r.prefix = prefix
+}
+
+// key antepone el prefijo configurado a una clave
+func (r *RedisClient) key(k string) string {
+	return r.prefix + k
+}
+
+// FullKey devuelve la clave con el prefijo configurado, tal como queda en Redis
+func (r *RedisClient) FullKey(k string) string {
+	return r.key(k)
+}
+
 // LoadQuestionsFromJSON carga las preguntas desde un archivo JSON a Redis
 func (r *RedisClient) LoadQuestionsFromJSON(jsonData []byte) error {
 	var questionsData QuestionsData
@@ -77,37 +179,46 @@ func (r *RedisClient) LoadQuestionsFromJSON(jsonData []byte) error {
 		log.Printf("⚠️ Error limpiando preguntas existentes: %v", err)
 	}
 
-	// Cargar cada pregunta individualmente
+	// Cargar cada pregunta individualmente; solo las guardadas entran en los
+	// índices, para que el conteo refleje una carga parcial
+	questionIDs := make([]interface{}, 0, len(questionsData.Questions))
 	for _, question := range questionsData.Questions {
 		if err := r.SaveQuestion(question); err != nil {
 			log.Printf("❌ Error guardando pregunta %d: %v", question.ID, err)
 			continue
 		}
+		questionIDs = append(questionIDs, question.ID)
 	}
 
 	// Guardar metadatos
 	metadataJSON, _ := json.Marshal(questionsData.Metadata)
-	if err := r.client.Set(r.ctx, "quiz:metadata", metadataJSON, 0).Err(); err != nil {
+	if err := r.client.Set(r.ctx, r.key("metadata"), metadataJSON, 0).Err(); err != nil {
 		log.Printf("⚠️ Error guardando metadatos: %v", err)
 	}
 
 	// Guardar lista de IDs de preguntas
-	questionIDs := make([]interface{}, len(questionsData.Questions))
-	for i, q := range questionsData.Questions {
-		questionIDs[i] = q.ID
-	}
-
-	if err := r.client.Del(r.ctx, "quiz:question_ids").Err(); err != nil {
+	if err := r.client.Del(r.ctx, r.key("question_ids")).Err(); err != nil {
 		log.Printf("⚠️ Error limpiando lista de IDs: %v", err)
 	}
 
 	if len(questionIDs) > 0 {
-		if err := r.client.SAdd(r.ctx, "quiz:question_ids", questionIDs...).Err(); err != nil {
+		if err := r.client.SAdd(r.ctx, r.key("question_ids"), questionIDs...).Err(); err != nil {
 			log.Printf("⚠️ Error guardando lista de IDs: %v", err)
 		}
 	}
 
-	log.Printf("✅ %d preguntas cargadas exitosamente en Redis", len(questionsData.Questions))
+	// Guardar el plan de preguntas en el orden del archivo
+	if err := r.client.Del(r.ctx, r.key("question_plan")).Err(); err != nil {
+		log.Printf("⚠️ Error limpiando plan de preguntas: %v", err)
+	}
+
+	if len(questionIDs) > 0 {
+		if err := r.client.RPush(r.ctx, r.key("question_plan"), questionIDs...).Err(); err != nil {
+			log.Printf("⚠️ Error guardando plan de preguntas: %v", err)
+		}
+	}
+
+	log.Printf("✅ %d preguntas cargadas exitosamente en Redis", len(questionIDs))
 	return nil
 }
 
@@ -118,13 +229,13 @@ func (r *RedisClient) SaveQuestion(question Question) error {
 		return fmt.Errorf("error serializing question: %v", err)
 	}
 
-	key := fmt.Sprintf("quiz:question:%d", question.ID)
+	key := r.key(fmt.Sprintf("question:%d", question.ID))
 	return r.client.Set(r.ctx, key, questionJSON, 0).Err()
 }
 
 // GetQuestion obtiene una pregunta específica por ID
 func (r *RedisClient) GetQuestion(id int) (*Question, error) {
-	key := fmt.Sprintf("quiz:question:%d", id)
+	key := r.key(fmt.Sprintf("question:%d", id))
 
 	questionJSON, err := r.client.Get(r.ctx, key).Result()
 	if err != nil {
@@ -145,7 +256,7 @@ func (r *RedisClient) GetQuestion(id int) (*Question, error) {
 // GetAllQuestions obtiene todas las preguntas
 func (r *RedisClient) GetAllQuestions() ([]Question, error) {
 	// Obtener todos los IDs de preguntas
-	questionIDs, err := r.client.SMembers(r.ctx, "quiz:question_ids").Result()
+	questionIDs, err := r.client.SMembers(r.ctx, r.key("question_ids")).Result()
 	if err != nil {
 		return nil, fmt.Errorf("error getting question IDs: %v", err)
 	}
@@ -190,7 +301,7 @@ func (r *RedisClient) GetQuestionsByDifficulty(minDifficulty, maxDifficulty int)
 // GetRandomQuestion obtiene una pregunta aleatoria
 func (r *RedisClient) GetRandomQuestion() (*Question, error) {
 	// Obtener un ID aleatorio de la lista
-	idStr, err := r.client.SRandMember(r.ctx, "quiz:question_ids").Result()
+	idStr, err := r.client.SRandMember(r.ctx, r.key("question_ids")).Result()
 	if err != nil {
 		return nil, fmt.Errorf("error getting random question ID: %v", err)
 	}
@@ -203,9 +314,29 @@ func (r *RedisClient) GetRandomQuestion() (*Question, error) {
 	return r.GetQuestion(id)
 }
 
+// GetQuestionPlan obtiene los IDs de preguntas en el orden en que se juegan
+func (r *RedisClient) GetQuestionPlan() ([]int, error) {
+	idStrs, err := r.client.LRange(r.ctx, r.key("question_plan"), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error getting question plan: %v", err)
+	}
+
+	plan := make([]int, 0, len(idStrs))
+	for _, idStr := range idStrs {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			log.Printf("⚠️ ID de pregunta inválido en el plan: %s", idStr)
+			continue
+		}
+		plan = append(plan, id)
+	}
+
+	return plan, nil
+}
+
 // GetMetadata obtiene los metadatos del quiz
 func (r *RedisClient) GetMetadata() (map[string]interface{}, error) {
-	metadataJSON, err := r.client.Get(r.ctx, "quiz:metadata").Result()
+	metadataJSON, err := r.client.Get(r.ctx, r.key("metadata")).Result()
 	if err != nil {
 		if err == redis.Nil {
 			return nil, fmt.Errorf("metadata not found")
@@ -223,7 +354,7 @@ func (r *RedisClient) GetMetadata() (map[string]interface{}, error) {
 
 // GetQuestionCount obtiene el número total de preguntas en Redis
 func (r *RedisClient) GetQuestionCount() (int, error) {
-	count, err := r.client.SCard(r.ctx, "quiz:question_ids").Result()
+	count, err := r.client.SCard(r.ctx, r.key("question_ids")).Result()
 	if err != nil {
 		return 0, fmt.Errorf("error getting question count: %v", err)
 	}
@@ -233,16 +364,16 @@ func (r *RedisClient) GetQuestionCount() (int, error) {
 // ClearAllQuestions elimina todas las preguntas de Redis
 func (r *RedisClient) ClearAllQuestions() error {
 	// Obtener todos los IDs para eliminar las preguntas individuales
-	questionIDs, err := r.client.SMembers(r.ctx, "quiz:question_ids").Result()
+	questionIDs, err := r.client.SMembers(r.ctx, r.key("question_ids")).Result()
 	if err == nil {
 		for _, idStr := range questionIDs {
-			key := fmt.Sprintf("quiz:question:%s", idStr)
+			key := r.key("question:" + idStr)
 			r.client.Del(r.ctx, key)
 		}
 	}
 
 	// Limpiar la lista de IDs
-	return r.client.Del(r.ctx, "quiz:question_ids").Err()
+	return r.client.Del(r.ctx, r.key("question_ids")).Err()
 }
 
 // Close cierra la conexión con Redis
@@ -261,12 +392,17 @@ func (r *RedisClient) HealthCheck() error {
 
 // Set guarda un valor con TTL opcional
 func (r *RedisClient) Set(key, value string, ttl time.Duration) error {
-	return r.client.Set(r.ctx, key, value, ttl).Err()
+	return r.client.Set(r.ctx, r.key(key), value, ttl).Err()
+}
+
+// SetIfAbsent guarda un valor solo si la clave no existe (SETNX); devuelve si se guardó
+func (r *RedisClient) SetIfAbsent(key, value string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(r.ctx, r.key(key), value, ttl).Result()
 }
 
 // Get obtiene un valor por clave
 func (r *RedisClient) Get(key string) (string, error) {
-	result, err := r.client.Get(r.ctx, key).Result()
+	result, err := r.client.Get(r.ctx, r.key(key)).Result()
 	if err != nil {
 		return "", err
 	}
@@ -275,25 +411,127 @@ func (r *RedisClient) Get(key string) (string, error) {
 
 // AddToSet agrega un elemento a un conjunto
 func (r *RedisClient) AddToSet(key, value string) error {
-	return r.client.SAdd(r.ctx, key, value).Err()
+	return r.client.SAdd(r.ctx, r.key(key), value).Err()
+}
+
+// AddToSetIfAbsent agrega un elemento a un conjunto; devuelve false si ya estaba
+func (r *RedisClient) AddToSetIfAbsent(key, value string) (bool, error) {
+	added, err := r.client.SAdd(r.ctx, r.key(key), value).Result()
+	return added == 1, err
+}
+
+// addToSetIfBelowScript agrega ARGV[1] al set KEYS[1] si ya es miembro o si
+// el set tiene menos de ARGV[2] elementos; devuelve 1 si quedó en el set
+var addToSetIfBelowScript = redis.NewScript(`
+if redis.call('SISMEMBER', KEYS[1], ARGV[1]) == 1 then
+	return 1
+end
+if redis.call('SCARD', KEYS[1]) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('SADD', KEYS[1], ARGV[1])
+return 1
+`)
+
+// AddToSetIfBelow agrega un elemento a un conjunto solo si tiene menos de
+// limit elementos (o si ya estaba), de forma atómica; devuelve si quedó en el set
+func (r *RedisClient) AddToSetIfBelow(key, value string, limit int64) (bool, error) {
+	added, err := addToSetIfBelowScript.Run(r.ctx, r.client, []string{r.key(key)}, value, limit).Int()
+	return added == 1, err
 }
 
 // RemoveFromSet remueve un elemento de un conjunto
 func (r *RedisClient) RemoveFromSet(key, value string) error {
-	return r.client.SRem(r.ctx, key, value).Err()
+	return r.client.SRem(r.ctx, r.key(key), value).Err()
 }
 
 // GetSetMembers obtiene todos los miembros de un conjunto
 func (r *RedisClient) GetSetMembers(key string) ([]string, error) {
-	return r.client.SMembers(r.ctx, key).Result()
+	return r.client.SMembers(r.ctx, r.key(key)).Result()
+}
+
+// GetSetSize obtiene la cantidad de miembros de un conjunto
+func (r *RedisClient) GetSetSize(key string) (int64, error) {
+	return r.client.SCard(r.ctx, r.key(key)).Result()
+}
+
+// SetHashField guarda un campo en un hash
+func (r *RedisClient) SetHashField(key, field, value string) error {
+	return r.client.HSet(r.ctx, r.key(key), field, value).Err()
+}
+
+// SetHashFieldIfAbsent guarda un campo en un hash solo si no existe
+func (r *RedisClient) SetHashFieldIfAbsent(key, field, value string) (bool, error) {
+	return r.client.HSetNX(r.ctx, r.key(key), field, value).Result()
+}
+
+// GetHashField obtiene un campo de un hash
+func (r *RedisClient) GetHashField(key, field string) (string, error) {
+	return r.client.HGet(r.ctx, r.key(key), field).Result()
 }
 
-// GetKeysByPattern obtiene claves que coinciden con un patrón
+// IncrementHashField incrementa un campo numérico de un hash
+func (r *RedisClient) IncrementHashField(key, field string, delta int64) (int64, error) {
+	return r.client.HIncrBy(r.ctx, r.key(key), field, delta).Result()
+}
+
+// GetHashAll obtiene todos los campos de un hash
+func (r *RedisClient) GetHashAll(key string) (map[string]string, error) {
+	return r.client.HGetAll(r.ctx, r.key(key)).Result()
+}
+
+// GetKeysByPattern obtiene claves que coinciden con un patrón (sin el prefijo)
 func (r *RedisClient) GetKeysByPattern(pattern string) ([]string, error) {
-	return r.client.Keys(r.ctx, pattern).Result()
+	keys, err := r.client.Keys(r.ctx, r.key(pattern)).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, k := range keys {
+		keys[i] = strings.TrimPrefix(k, r.prefix)
+	}
+	return keys, nil
+}
+
+// CountKeysByPattern cuenta las claves que coinciden con un patrón usando SCAN,
+// sin bloquear Redis como KEYS
+func (r *RedisClient) CountKeysByPattern(pattern string) (int64, error) {
+	var count int64
+	iter := r.client.Scan(r.ctx, 0, r.key(pattern), 1000).Iterator()
+	for iter.Next(r.ctx) {
+		count++
+	}
+	if err := iter.Err(); err != nil {
+		return 0, err
+	}
+	return count, nil
 }
 
 // Delete elimina una o varias claves
 func (r *RedisClient) Delete(keys ...string) error {
-	return r.client.Del(r.ctx, keys...).Err()
+	prefixed := make([]string, len(keys))
+	for i, k := range keys {
+		prefixed[i] = r.key(k)
+	}
+	return r.client.Del(r.ctx, prefixed...).Err()
+}
+
+// PushToList agrega un elemento al inicio de una lista (LPUSH)
+func (r *RedisClient) PushToList(key, value string) error {
+	return r.client.LPush(r.ctx, r.key(key), value).Err()
+}
+
+// GetListRange obtiene los elementos de una lista entre start y stop (inclusive)
+func (r *RedisClient) GetListRange(key string, start, stop int64) ([]string, error) {
+	return r.client.LRange(r.ctx, r.key(key), start, stop).Result()
+}
+
+// GetListLength obtiene la cantidad de elementos de una lista
+func (r *RedisClient) GetListLength(key string) (int64, error) {
+	return r.client.LLen(r.ctx, r.key(key)).Result()
+}
+
+// DeleteIfExists elimina una clave y devuelve si existía
+func (r *RedisClient) DeleteIfExists(key string) (bool, error) {
+	deleted, err := r.client.Del(r.ctx, r.key(key)).Result()
+	return deleted > 0, err
 }
@@ -0,0 +1,153 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// recordingHook registra los comandos enviados a Redis sin conectarse; a KEYS
+// responde con las claves indicadas
+type recordingHook struct {
+	commands [][]string
+	keys     []string
+}
+
+func (h *recordingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, net.ErrClosed
+	}
+}
+
+func (h *recordingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		args := make([]string, 0, len(cmd.Args()))
+		for _, arg := range cmd.Args() {
+			if s, ok := arg.(string); ok {
+				args = append(args, s)
+			}
+		}
+		h.commands = append(h.commands, args)
+		if keysCmd, ok := cmd.(*redis.StringSliceCmd); ok && cmd.Name() == "keys" {
+			keysCmd.SetVal(h.keys)
+		}
+		return nil
+	}
+}
+
+func (h *recordingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+// newRecordingClient crea un RedisClient que registra sus comandos en lugar de enviarlos
+func newRecordingClient(prefix string) (*RedisClient, *recordingHook) {
+	hook := &recordingHook{}
+	client := redis.NewClient(&redis.Options{Addr: "redis.test:6379"})
+	client.AddHook(hook)
+	r := &RedisClient{client: client, ctx: context.Background(), prefix: DefaultKeyPrefix}
+	if prefix != "" {
+		r.SetPrefix(prefix)
+	}
+	return r, hook
+}
+
+func TestKeysUseConfiguredPrefix(t *testing.T) {
+	r, hook := newRecordingClient("staging:")
+	r.Set("game_state", "{}", time.Minute)
+	r.Get("session:abc")
+	r.AddToSet("active_sessions", "abc")
+	r.PushToList("audit", "{}")
+	r.Delete("game_state", "end_game_summary")
+
+	want := [][]string{
+		{"set", "staging:game_state"},
+		{"get", "staging:session:abc"},
+		{"sadd", "staging:active_sessions"},
+		{"lpush", "staging:audit"},
+		{"del", "staging:game_state", "staging:end_game_summary"},
+	}
+	if len(hook.commands) != len(want) {
+		t.Fatalf("esperaba %d comandos, hubo %v", len(want), hook.commands)
+	}
+	for i, expected := range want {
+		got := hook.commands[i]
+		if len(got) < len(expected) || strings.Join(got[:len(expected)], " ") != strings.Join(expected, " ") {
+			t.Fatalf("comando %d: esperaba %v, obtuve %v", i, expected, got)
+		}
+	}
+}
+
+func TestGetKeysByPatternStripsPrefix(t *testing.T) {
+	r, hook := newRecordingClient("staging:")
+	hook.keys = []string{"staging:session:a", "staging:session:b"}
+
+	keys, err := r.GetKeysByPattern("session:*")
+	if err != nil {
+		t.Fatalf("error obteniendo claves: %v", err)
+	}
+	if got := hook.commands[0]; strings.Join(got, " ") != "keys staging:session:*" {
+		t.Fatalf("el patrón debe llevar el prefijo: %v", got)
+	}
+	if strings.Join(keys, ",") != "session:a,session:b" {
+		t.Fatalf("las claves se devuelven sin prefijo: %v", keys)
+	}
+}
+
+func TestDefaultKeyPrefix(t *testing.T) {
+	r, hook := newRecordingClient("")
+	r.Get("game_state")
+	if got := hook.commands[0]; strings.Join(got, " ") != "get quiz:game_state" {
+		t.Fatalf("el prefijo por defecto es quiz:, obtuve %v", got)
+	}
+}
+
+func TestQuestionOptionOrderFromFile(t *testing.T) {
+	data := []byte(`{"questions":[{"id":1,"question":"¿Capital de Francia?","options":{"D":"Roma","B":"París","A":"Madrid","C":"Lisboa"},"correctAnswer":"B"}]}`)
+	store := NewMemoryStore()
+	if err := store.LoadQuestionsFromJSON(data); err != nil {
+		t.Fatalf("error cargando preguntas: %v", err)
+	}
+	question, err := store.GetQuestion(1)
+	if err != nil {
+		t.Fatalf("error obteniendo pregunta: %v", err)
+	}
+	if got := strings.Join(question.OptionOrder, ","); got != "D,B,A,C" {
+		t.Fatalf("el orden debe ser el del archivo, obtuve %s", got)
+	}
+
+	// Serializar varias veces produce siempre lo mismo y conserva el orden
+	first, err := json.Marshal(question)
+	if err != nil {
+		t.Fatalf("error serializando pregunta: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		again, _ := json.Marshal(question)
+		if string(again) != string(first) {
+			t.Fatalf("serialización inestable:\n%s\n%s", first, again)
+		}
+	}
+	var decoded Question
+	if err := json.Unmarshal(first, &decoded); err != nil {
+		t.Fatalf("error decodificando pregunta: %v", err)
+	}
+	if got := strings.Join(decoded.OptionOrder, ","); got != "D,B,A,C" {
+		t.Fatalf("el orden debe sobrevivir a la ida y vuelta, obtuve %s", got)
+	}
+}
+
+func TestQuestionOptionOrderNormalized(t *testing.T) {
+	var question Question
+	data := `{"id":1,"options":{"A":"Uno","B":"Dos","C":"Tres","D":"Cuatro"},"optionOrder":["C","X","C","A"]}`
+	if err := json.Unmarshal([]byte(data), &question); err != nil {
+		t.Fatalf("error decodificando pregunta: %v", err)
+	}
+	// Se descartan claves desconocidas y repetidas; las que faltan van al final, ordenadas
+	if got := strings.Join(question.OptionOrder, ","); got != "C,A,B,D" {
+		t.Fatalf("orden normalizado inesperado: %s", got)
+	}
+}
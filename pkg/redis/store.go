@@ -0,0 +1,65 @@
+package redis
+
+import "time"
+
+// RedisStore operaciones de almacenamiento que usan los servicios. Lo
+// implementan RedisClient (Redis real) y MemoryStore (en memoria, para pruebas).
+type RedisStore interface {
+	// Preguntas
+	LoadQuestionsFromJSON(jsonData []byte) error
+	SaveQuestion(question Question) error
+	GetQuestion(id int) (*Question, error)
+	GetAllQuestions() ([]Question, error)
+	GetQuestionsByDifficulty(minDifficulty, maxDifficulty int) ([]Question, error)
+	GetRandomQuestion() (*Question, error)
+	GetQuestionPlan() ([]int, error)
+	GetMetadata() (map[string]interface{}, error)
+	GetQuestionCount() (int, error)
+	HealthCheck() error
+
+	// Claves
+	Set(key, value string, ttl time.Duration) error
+	Get(key string) (string, error)
+	SetIfAbsent(key, value string, ttl time.Duration) (bool, error)
+	Delete(keys ...string) error
+	DeleteIfExists(key string) (bool, error)
+	GetKeysByPattern(pattern string) ([]string, error)
+	CountKeysByPattern(pattern string) (int64, error)
+
+	// Conjuntos
+	AddToSet(key, value string) error
+	AddToSetIfAbsent(key, value string) (bool, error)
+	AddToSetIfBelow(key, value string, limit int64) (bool, error)
+	RemoveFromSet(key, value string) error
+	GetSetMembers(key string) ([]string, error)
+	GetSetSize(key string) (int64, error)
+
+	// Listas
+	PushToList(key, value string) error
+	GetListRange(key string, start, stop int64) ([]string, error)
+	GetListLength(key string) (int64, error)
+
+	// Hashes
+	SetHashField(key, field, value string) error
+	SetHashFieldIfAbsent(key, field, value string) (bool, error)
+	GetHashField(key, field string) (string, error)
+	IncrementHashField(key, field string, delta int64) (int64, error)
+	GetHashAll(key string) (map[string]string, error)
+}
+
+var _ RedisStore = (*RedisClient)(nil)
+var _ RedisStore = (*MemoryStore)(nil)
+
+// keyNamer lo implementan los almacenes que anteponen un prefijo a las claves
+type keyNamer interface {
+	FullKey(key string) string
+}
+
+// FullKey devuelve el nombre con el que key queda guardada en el almacén,
+// con el prefijo de claves (REDIS_PREFIX) si el almacén lo aplica
+func FullKey(store RedisStore, key string) string {
+	if namer, ok := store.(keyNamer); ok {
+		return namer.FullKey(key)
+	}
+	return key
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"unicode"
+
+	"github.com/backsoul/quiz/pkg/models"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Modos de comparación de opciones
+const (
+	AnswerMatchExact = "exact" // comparación literal
+	AnswerMatchNFC   = "nfc"   // normalización Unicode NFC ("Perú" compuesto o descompuesto)
+	AnswerMatchFold  = "fold"  // NFC y sin tildes ni diacríticos ("Peru" == "Perú")
+)
+
+// SetAnswerMatching configura cómo se compara la opción elegida con las de la pregunta
+func (s *QuestionService) SetAnswerMatching(mode string) {
+	s.matchingMutex.Lock()
+	s.answerMatching = mode
+	s.matchingMutex.Unlock()
+}
+
+// matchingMode devuelve el modo de comparación vigente
+func (s *QuestionService) matchingMode() string {
+	s.matchingMutex.RLock()
+	defer s.matchingMutex.RUnlock()
+	return s.answerMatching
+}
+
+// normalizeOption aplica la normalización del modo indicado
+func normalizeOption(mode, option string) string {
+	switch mode {
+	case AnswerMatchNFC:
+		return norm.NFC.String(option)
+	case AnswerMatchFold:
+		folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), option)
+		if err != nil {
+			return norm.NFC.String(option)
+		}
+		return folded
+	default:
+		return option
+	}
+}
+
+// CanonicalOption devuelve la clave de la pregunta que coincide con la opción
+// elegida según el modo configurado, o la opción tal cual si ninguna coincide.
+// La normalización se aplica por igual a la elección, a las claves y a la
+// respuesta correcta, así que basta comparar el resultado con question.Correct.
+func (s *QuestionService) CanonicalOption(question *models.Question, selected string) string {
+	mode := s.matchingMode()
+	if mode == "" || mode == AnswerMatchExact {
+		return selected
+	}
+
+	normalized := normalizeOption(mode, selected)
+	if normalized == normalizeOption(mode, question.Correct) {
+		return question.Correct
+	}
+	for key := range question.Options {
+		if normalized == normalizeOption(mode, key) {
+			return key
+		}
+	}
+	return selected
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/backsoul/quiz/pkg/models"
+)
+
+func TestCanonicalOptionAccentedKeys(t *testing.T) {
+	s, _ := newTestQuestionService(t, testQuestions(1))
+	question := &models.Question{
+		ID:       1,
+		Question: "¿Dónde está Machu Picchu?",
+		Options:  map[string]string{"Perú": "Perú", "Bolivia": "Bolivia", "Ecuador": "Ecuador"},
+		Correct:  "Perú",
+	}
+	const decomposed = "Peru\u0301" // "Perú" con la tilde como carácter combinante
+
+	cases := []struct {
+		mode     string
+		selected string
+		want     string
+	}{
+		{AnswerMatchExact, "Perú", "Perú"},
+		{AnswerMatchExact, decomposed, decomposed},
+		{AnswerMatchExact, "Peru", "Peru"},
+		{AnswerMatchNFC, decomposed, "Perú"},
+		{AnswerMatchNFC, "Peru", "Peru"},
+		{AnswerMatchFold, "Peru", "Perú"},
+		{AnswerMatchFold, decomposed, "Perú"},
+		{AnswerMatchFold, "Bolívia", "Bolivia"},
+		{AnswerMatchFold, "Chile", "Chile"},
+	}
+	for _, c := range cases {
+		s.SetAnswerMatching(c.mode)
+		if got := s.CanonicalOption(question, c.selected); got != c.want {
+			t.Fatalf("modo %s, opción %q: obtuve %q, esperaba %q", c.mode, c.selected, got, c.want)
+		}
+	}
+}
+
+func TestCanonicalOptionNormalizesStoredCorrectKey(t *testing.T) {
+	s, _ := newTestQuestionService(t, testQuestions(1))
+	s.SetAnswerMatching(AnswerMatchNFC)
+
+	// La clave guardada viene descompuesta y la elección compuesta: también coinciden
+	question := &models.Question{
+		Options: map[string]string{"Peru\u0301": "Perú", "Chile": "Chile"},
+		Correct: "Peru\u0301",
+	}
+	if got := s.CanonicalOption(question, "Perú"); got != question.Correct {
+		t.Fatalf("se esperaba la clave correcta guardada, obtuve %q", got)
+	}
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/backsoul/quiz/pkg/models"
+	"github.com/backsoul/quiz/pkg/redis"
+	"github.com/google/uuid"
+)
+
+// ErrArchiveNotFound indica que no existe el archivo solicitado
+var ErrArchiveNotFound = errors.New("archivo de partida no encontrado")
+
+// ErrArchiveInvalid indica que el archivo existe pero no tiene un formato válido
+var ErrArchiveInvalid = errors.New("archivo de partida inválido")
+
+// ErrReviewRoomNotFound indica que el archivo no se restauró o su sala de
+// revisión ya venció
+var ErrReviewRoomNotFound = errors.New("sala de revisión no encontrada")
+
+// ArchiveService guarda instantáneas de partidas y las restaura para revisión
+type ArchiveService struct {
+	redisClient redis.RedisStore
+}
+
+// NewArchiveService crea una nueva instancia del servicio de archivos
+func NewArchiveService(redisClient redis.RedisStore) *ArchiveService {
+	return &ArchiveService{
+		redisClient: redisClient,
+	}
+}
+
+// CreateArchive guarda una instantánea del estado y las sesiones de la partida
+func (s *ArchiveService) CreateArchive(gameState *models.GameState, sessions []models.GameSession) (*models.GameArchive, error) {
+	archive := &models.GameArchive{
+		ID:        uuid.New().String(),
+		CreatedAt: time.Now().UTC(),
+		GameState: gameState,
+		Sessions:  sessions,
+	}
+
+	data, err := archive.StorageJSON()
+	if err != nil {
+		return nil, fmt.Errorf("error serializando archivo: %v", err)
+	}
+
+	if err := s.redisClient.Set(archiveKey(archive.ID), string(data), 0); err != nil {
+		return nil, fmt.Errorf("error guardando archivo: %v", err)
+	}
+
+	log.Printf("🗄️ Partida archivada (ID: %s, %d sesiones)", archive.ID, len(sessions))
+	return archive, nil
+}
+
+// GetArchive obtiene y valida un archivo de partida
+func (s *ArchiveService) GetArchive(archiveID string) (*models.GameArchive, error) {
+	data, err := s.redisClient.Get(archiveKey(archiveID))
+	if err != nil {
+		if err.Error() == "redis: nil" {
+			return nil, ErrArchiveNotFound
+		}
+		return nil, fmt.Errorf("error obteniendo archivo: %v", err)
+	}
+
+	var archive models.GameArchive
+	if err := json.Unmarshal([]byte(data), &archive); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrArchiveInvalid, err)
+	}
+
+	if archive.ID != archiveID || archive.GameState == nil {
+		return nil, fmt.Errorf("%w: faltan datos de la partida", ErrArchiveInvalid)
+	}
+	for _, session := range archive.Sessions {
+		if session.ID == "" {
+			return nil, fmt.Errorf("%w: sesión sin ID", ErrArchiveInvalid)
+		}
+	}
+
+	return &archive, nil
+}
+
+// RestoreArchive repuebla las sesiones y el estado de un archivo en una sala
+// de revisión (review:{id}:*) para no chocar con la partida en curso.
+// Restaurar dos veces el mismo archivo produce el mismo resultado.
+func (s *ArchiveService) RestoreArchive(archiveID string) (*models.GameArchive, error) {
+	archive, err := s.GetArchive(archiveID)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := reviewRoomPrefix(archiveID)
+	if err := s.redisClient.Delete(prefix + "sessions"); err != nil {
+		return nil, fmt.Errorf("error limpiando sala de revisión: %v", err)
+	}
+
+	for _, session := range archive.Sessions {
+		sessionJSON, err := session.StorageJSON()
+		if err != nil {
+			return nil, fmt.Errorf("error serializando sesión %s: %v", session.ID, err)
+		}
+		if err := s.redisClient.Set(prefix+"session:"+session.ID, string(sessionJSON), 24*time.Hour); err != nil {
+			return nil, fmt.Errorf("error restaurando sesión %s: %v", session.ID, err)
+		}
+		if err := s.redisClient.AddToSet(prefix+"sessions", session.ID); err != nil {
+			return nil, fmt.Errorf("error registrando sesión %s: %v", session.ID, err)
+		}
+	}
+
+	// La sala de revisión nunca queda activa
+	reviewState := *archive.GameState
+	reviewState.IsActive = false
+	stateJSON, err := json.Marshal(reviewState)
+	if err != nil {
+		return nil, fmt.Errorf("error serializando estado: %v", err)
+	}
+	if err := s.redisClient.Set(prefix+"game_state", string(stateJSON), 24*time.Hour); err != nil {
+		return nil, fmt.Errorf("error restaurando estado: %v", err)
+	}
+
+	log.Printf("♻️ Archivo %s restaurado en sala de revisión (%d sesiones)", archiveID, len(archive.Sessions))
+	return archive, nil
+}
+
+// GetReviewRoom lee el estado y las sesiones restaurados de un archivo, con
+// las sesiones en el orden de la tabla de posiciones
+func (s *ArchiveService) GetReviewRoom(archiveID string) (*models.ReviewRoom, error) {
+	prefix := reviewRoomPrefix(archiveID)
+	stateJSON, err := s.redisClient.Get(prefix + "game_state")
+	if err != nil {
+		if err.Error() == "redis: nil" {
+			return nil, ErrReviewRoomNotFound
+		}
+		return nil, fmt.Errorf("error obteniendo sala de revisión: %v", err)
+	}
+
+	var gameState models.GameState
+	if err := json.Unmarshal([]byte(stateJSON), &gameState); err != nil {
+		return nil, fmt.Errorf("error leyendo estado de revisión: %v", err)
+	}
+
+	sessionIDs, err := s.redisClient.GetSetMembers(prefix + "sessions")
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo sesiones de revisión: %v", err)
+	}
+	sessions := make([]models.GameSession, 0, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		data, err := s.redisClient.Get(prefix + "session:" + sessionID)
+		if err != nil {
+			log.Printf("⚠️ Sesión %s de la sala de revisión %s no disponible: %v", sessionID, archiveID, err)
+			continue
+		}
+		var session models.GameSession
+		if err := json.Unmarshal([]byte(data), &session); err != nil {
+			log.Printf("⚠️ Sesión %s de la sala de revisión %s ilegible: %v", sessionID, archiveID, err)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return rankBefore(&sessions[i], &sessions[j])
+	})
+
+	return &models.ReviewRoom{
+		ArchiveID: archiveID,
+		Room:      s.ReviewRoomName(archiveID),
+		GameState: &gameState,
+		Sessions:  sessions,
+	}, nil
+}
+
+// ReviewRoomName nombre de la sala de revisión de un archivo, tal como queda
+// en Redis (con el prefijo de claves configurado)
+func (s *ArchiveService) ReviewRoomName(archiveID string) string {
+	return redis.FullKey(s.redisClient, strings.TrimSuffix(reviewRoomPrefix(archiveID), ":"))
+}
+
+func archiveKey(archiveID string) string {
+	return fmt.Sprintf("archive:%s", archiveID)
+}
+
+func reviewRoomPrefix(archiveID string) string {
+	return fmt.Sprintf("review:%s:", archiveID)
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/backsoul/quiz/pkg/models"
+	"github.com/backsoul/quiz/pkg/redis"
+)
+
+func TestArchiveSnapshotAndRestore(t *testing.T) {
+	sessions, store := newTestSessionService(t)
+	gameState := NewGameStateService(store)
+	if err := gameState.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+
+	ana := createTestSession(t, sessions, "Ana")
+	addTestAnswer(t, sessions, ana.ID, testAnswer(1, true, 500))
+	beto := createTestSession(t, sessions, "Beto")
+
+	state, _ := gameState.GetGameState()
+	active, _ := sessions.GetActiveSessions()
+	archives := NewArchiveService(store)
+	archive, err := archives.CreateArchive(state, active)
+	if err != nil {
+		t.Fatalf("error archivando: %v", err)
+	}
+
+	// La partida en vivo se limpia: las sesiones ya no existen
+	if err := sessions.ClearAllSessions(); err != nil {
+		t.Fatalf("error limpiando sesiones: %v", err)
+	}
+
+	for i := 0; i < 2; i++ { // restaurar dos veces da el mismo resultado
+		restored, err := archives.RestoreArchive(archive.ID)
+		if err != nil {
+			t.Fatalf("error restaurando: %v", err)
+		}
+		if len(restored.Sessions) != 2 {
+			t.Fatalf("esperaba 2 sesiones archivadas, hay %d", len(restored.Sessions))
+		}
+	}
+
+	prefix := reviewRoomPrefix(archive.ID)
+	members := setMembers(t, store, prefix+"sessions")
+	if len(members) != 2 || !members[ana.ID] || !members[beto.ID] {
+		t.Fatalf("las sesiones no reaparecieron en la sala de revisión: %v", members)
+	}
+
+	data, err := store.Get(prefix + "session:" + ana.ID)
+	if err != nil {
+		t.Fatalf("falta la sesión restaurada de Ana: %v", err)
+	}
+	var restoredAna models.GameSession
+	if err := json.Unmarshal([]byte(data), &restoredAna); err != nil {
+		t.Fatalf("sesión restaurada ilegible: %v", err)
+	}
+	if restoredAna.PlayerName != "Ana" || restoredAna.TotalPrize != 500 || len(restoredAna.AnswersGiven) != 1 {
+		t.Fatalf("la sesión restaurada no coincide: %+v", restoredAna)
+	}
+
+	stateData, err := store.Get(prefix + "game_state")
+	if err != nil {
+		t.Fatalf("falta el estado restaurado: %v", err)
+	}
+	var reviewState models.GameState
+	json.Unmarshal([]byte(stateData), &reviewState)
+	if reviewState.IsActive {
+		t.Fatalf("la sala de revisión no debe quedar activa")
+	}
+
+	// La partida en vivo no se toca
+	if active, _ := sessions.GetActiveSessions(); len(active) != 0 {
+		t.Fatalf("restaurar no debe crear sesiones en vivo, hay %d", len(active))
+	}
+}
+
+func TestRestoreArchiveValidation(t *testing.T) {
+	_, store := newTestSessionService(t)
+	archives := NewArchiveService(store)
+
+	if _, err := archives.RestoreArchive("no-existe"); !errors.Is(err, ErrArchiveNotFound) {
+		t.Fatalf("esperaba ErrArchiveNotFound, obtuve %v", err)
+	}
+
+	store.Set(archiveKey("roto"), `{"id":"roto"`, 0)
+	if _, err := archives.RestoreArchive("roto"); !errors.Is(err, ErrArchiveInvalid) {
+		t.Fatalf("JSON inválido: esperaba ErrArchiveInvalid, obtuve %v", err)
+	}
+
+	store.Set(archiveKey("sin-estado"), `{"id":"sin-estado","sessions":[]}`, 0)
+	if _, err := archives.RestoreArchive("sin-estado"); !errors.Is(err, ErrArchiveInvalid) {
+		t.Fatalf("sin estado: esperaba ErrArchiveInvalid, obtuve %v", err)
+	}
+}
+
+// prefixedStore MemoryStore que reporta sus claves con un prefijo, como
+// RedisClient con REDIS_PREFIX
+type prefixedStore struct {
+	*redis.MemoryStore
+}
+
+func (s prefixedStore) FullKey(key string) string {
+	return "staging:" + key
+}
+
+func TestGetReviewRoomAfterRestore(t *testing.T) {
+	sessions, store := newTestSessionService(t)
+	ana := createTestSession(t, sessions, "Ana")
+	beto := createTestSession(t, sessions, "Beto")
+	addTestAnswer(t, sessions, beto.ID, testAnswer(1, true, 500))
+
+	active, _ := sessions.GetActiveSessions()
+	archives := NewArchiveService(store)
+	archive, err := archives.CreateArchive(&models.GameState{IsActive: true, CurrentQuestion: 2}, active)
+	if err != nil {
+		t.Fatalf("error archivando: %v", err)
+	}
+
+	if _, err := archives.GetReviewRoom(archive.ID); !errors.Is(err, ErrReviewRoomNotFound) {
+		t.Fatalf("sin restaurar: esperaba ErrReviewRoomNotFound, obtuve %v", err)
+	}
+
+	if _, err := archives.RestoreArchive(archive.ID); err != nil {
+		t.Fatalf("error restaurando: %v", err)
+	}
+	room, err := archives.GetReviewRoom(archive.ID)
+	if err != nil {
+		t.Fatalf("error leyendo sala de revisión: %v", err)
+	}
+	if room.Room != "review:"+archive.ID || room.GameState.IsActive || room.GameState.CurrentQuestion != 2 {
+		t.Fatalf("sala de revisión inesperada: %+v", room)
+	}
+	if len(room.Sessions) != 2 || room.Sessions[0].ID != beto.ID || room.Sessions[1].ID != ana.ID {
+		t.Fatalf("esperaba a Beto y luego a Ana: %+v", room.Sessions)
+	}
+	if room.Sessions[0].TotalPrize != 500 || len(room.Sessions[0].AnswersGiven) != 1 {
+		t.Fatalf("la sesión restaurada no coincide: %+v", room.Sessions[0])
+	}
+
+	// El nombre reportado lleva el prefijo de claves del almacén
+	prefixed := NewArchiveService(prefixedStore{store})
+	if name := prefixed.ReviewRoomName(archive.ID); name != "staging:review:"+archive.ID {
+		t.Fatalf("nombre de sala sin prefijo: %s", name)
+	}
+}
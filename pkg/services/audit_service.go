@@ -0,0 +1,75 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/backsoul/quiz/pkg/models"
+	"github.com/backsoul/quiz/pkg/redis"
+)
+
+// auditKey lista de acciones de administración, de la más reciente a la más antigua
+const auditKey = "audit"
+
+// AuditService registra las acciones de administración en un log de solo escritura
+type AuditService struct {
+	redisClient redis.RedisStore
+}
+
+// NewAuditService crea una nueva instancia del servicio de auditoría
+func NewAuditService(redisClient redis.RedisStore) *AuditService {
+	return &AuditService{
+		redisClient: redisClient,
+	}
+}
+
+// Record agrega una entrada al log de auditoría
+func (s *AuditService) Record(action, admin string, params map[string]interface{}) error {
+	entry := models.AuditEntry{
+		Action:    action,
+		Admin:     admin,
+		Timestamp: time.Now().UTC(),
+		Params:    params,
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error serializando entrada de auditoría: %v", err)
+	}
+
+	if err := s.redisClient.PushToList(auditKey, string(data)); err != nil {
+		return fmt.Errorf("error guardando entrada de auditoría: %v", err)
+	}
+	return nil
+}
+
+// List devuelve una página del log (más recientes primero) y el total de entradas
+func (s *AuditService) List(offset, limit int) ([]models.AuditEntry, int, error) {
+	total, err := s.redisClient.GetListLength(auditKey)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error contando entradas de auditoría: %v", err)
+	}
+
+	entries := []models.AuditEntry{}
+	if limit <= 0 || int64(offset) >= total {
+		return entries, int(total), nil
+	}
+
+	items, err := s.redisClient.GetListRange(auditKey, int64(offset), int64(offset+limit-1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error leyendo entradas de auditoría: %v", err)
+	}
+
+	for _, item := range items {
+		var entry models.AuditEntry
+		if err := json.Unmarshal([]byte(item), &entry); err != nil {
+			log.Printf("⚠️ Entrada de auditoría inválida: %v", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, int(total), nil
+}
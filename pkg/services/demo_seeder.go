@@ -0,0 +1,117 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/backsoul/quiz/pkg/models"
+	"github.com/google/uuid"
+)
+
+// demoOptions opciones posibles de respuesta para las sesiones de demostración
+var demoOptions = []string{"A", "B", "C", "D"}
+
+// SeedDemoSessions crea count sesiones de demostración con progreso, premios y
+// estados plausibles, para poblar la tabla de posiciones y el panel de
+// administración durante el desarrollo. La misma semilla produce los mismos datos.
+func (s *SessionService) SeedDemoSessions(count int, seed int64) ([]models.GameSession, error) {
+	rng := rand.New(rand.NewSource(seed))
+	ids := rand.New(rand.NewSource(seed))
+	now := time.Now().UTC()
+
+	sessions := make([]models.GameSession, 0, count)
+	for i := 1; i <= count; i++ {
+		session := s.demoSession(fmt.Sprintf("Demo %02d", i), rng, now)
+		session.ID = uuid.Must(uuid.NewRandomFromReader(ids)).String()
+
+		if err := s.saveSession(&session); err != nil {
+			return nil, fmt.Errorf("error guardando sesión de demostración: %v", err)
+		}
+		if session.GameStatus == "active" {
+			if err := s.addToActiveSessions(session.ID); err != nil {
+				log.Printf("⚠️ Error agregando a sesiones activas: %v", err)
+			}
+		}
+		if err := s.addToPlayerSessions(session.PlayerName, session.ID); err != nil {
+			log.Printf("⚠️ Error agregando a sesiones del jugador: %v", err)
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	log.Printf("🧪 %d sesiones de demostración creadas (semilla %d)", count, seed)
+	return sessions, nil
+}
+
+// demoSession genera una sesión con un número aleatorio de respuestas correctas,
+// terminada en error (eliminado), en curso o con el juego completado
+func (s *SessionService) demoSession(playerName string, rng *rand.Rand, now time.Time) models.GameSession {
+	correct := rng.Intn(len(models.PrizeLevels) + 1)
+	status := "finished"
+	if correct < len(models.PrizeLevels) {
+		status = "active"
+		if rng.Intn(2) == 0 {
+			status = "eliminated"
+		}
+	}
+
+	startTime := now.Add(-time.Duration(5+rng.Intn(25)) * time.Minute)
+	timestamp := startTime
+	answers := []models.PlayerAnswer{}
+	totalPrize := int64(0)
+
+	for number := 1; number <= correct; number++ {
+		option := demoOptions[rng.Intn(len(demoOptions))]
+		timeToAnswer := 3 + rng.Intn(25)
+		timestamp = timestamp.Add(time.Duration(timeToAnswer) * time.Second)
+		totalPrize = models.PrizeLevels[number-1]
+		answers = append(answers, models.PlayerAnswer{
+			QuestionID:     s.questionIDForNumber(number),
+			QuestionNumber: number,
+			SelectedOption: option,
+			CorrectOption:  option,
+			IsCorrect:      true,
+			TimeToAnswer:   timeToAnswer,
+			Timestamp:      timestamp,
+			PrizeWon:       totalPrize,
+		})
+	}
+
+	currentQuestion := correct + 1
+	if status == "eliminated" {
+		correctIndex := rng.Intn(len(demoOptions))
+		wrongIndex := (correctIndex + 1 + rng.Intn(len(demoOptions)-1)) % len(demoOptions)
+		timeToAnswer := 3 + rng.Intn(25)
+		timestamp = timestamp.Add(time.Duration(timeToAnswer) * time.Second)
+		answers = append(answers, models.PlayerAnswer{
+			QuestionID:     s.questionIDForNumber(currentQuestion),
+			QuestionNumber: currentQuestion,
+			SelectedOption: demoOptions[wrongIndex],
+			CorrectOption:  demoOptions[correctIndex],
+			IsCorrect:      false,
+			TimeToAnswer:   timeToAnswer,
+			Timestamp:      timestamp,
+		})
+	}
+
+	lives := s.startingLives()
+	if status == "eliminated" {
+		lives = 0
+	}
+
+	return models.GameSession{
+		PlayerName:        playerName,
+		CurrentQuestion:   currentQuestion,
+		TotalPrize:        totalPrize,
+		LifelinesUsed:     models.LifelinesState{FiftyFifty: rng.Intn(3) == 0, Audience: rng.Intn(3) == 0, Phone: rng.Intn(3) == 0},
+		AnswersGiven:      answers,
+		GameStatus:        status,
+		StartTime:         startTime,
+		LastActivity:      timestamp,
+		CurrentQuestionID: s.questionIDForNumber(currentQuestion),
+		Mode:              models.SessionModeLive,
+		LivesRemaining:    lives,
+	}
+}
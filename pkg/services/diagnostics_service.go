@@ -0,0 +1,66 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/backsoul/quiz/pkg/models"
+	"github.com/backsoul/quiz/pkg/redis"
+)
+
+// DiagnosticsService cuenta las claves guardadas en Redis para depurar fugas
+type DiagnosticsService struct {
+	redisClient redis.RedisStore
+}
+
+// NewDiagnosticsService crea una nueva instancia del servicio de diagnóstico
+func NewDiagnosticsService(redisClient redis.RedisStore) *DiagnosticsService {
+	return &DiagnosticsService{
+		redisClient: redisClient,
+	}
+}
+
+// GetDiagnostics cuenta las claves por prefijo (SCAN) y el tamaño de los sets
+// de sesiones (SCARD)
+func (s *DiagnosticsService) GetDiagnostics() (*models.StoreDiagnostics, error) {
+	diagnostics := &models.StoreDiagnostics{GeneratedAt: models.Timestamp()}
+
+	patterns := []struct {
+		pattern string
+		count   *int64
+	}{
+		{"session:*", &diagnostics.Sessions},
+		{"session_token:*", &diagnostics.SessionTokens},
+		{"player_sessions:*", &diagnostics.PlayerSessions},
+		{"archive:*", &diagnostics.Archives},
+	}
+	for _, p := range patterns {
+		count, err := s.redisClient.CountKeysByPattern(p.pattern)
+		if err != nil {
+			return nil, fmt.Errorf("error contando claves %s: %v", p.pattern, err)
+		}
+		*p.count = count
+	}
+
+	sets := []struct {
+		key   string
+		count *int64
+	}{
+		{"active_sessions", &diagnostics.ActiveSessions},
+		{"corrupt_sessions", &diagnostics.CorruptSessions},
+	}
+	for _, set := range sets {
+		size, err := s.redisClient.GetSetSize(set.key)
+		if err != nil {
+			return nil, fmt.Errorf("error contando %s: %v", set.key, err)
+		}
+		*set.count = size
+	}
+
+	questions, err := s.redisClient.GetQuestionCount()
+	if err != nil {
+		return nil, err
+	}
+	diagnostics.Questions = int64(questions)
+
+	return diagnostics, nil
+}
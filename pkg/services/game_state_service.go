@@ -2,32 +2,174 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/backsoul/quiz/pkg/models"
 	"github.com/backsoul/quiz/pkg/redis"
 )
 
+// ErrGameAlreadyActive indica que otra llamada ya inició la partida
+var ErrGameAlreadyActive = errors.New("ya hay una partida activa")
+
+// ErrGameNotActive indica que no hay partida activa que terminar
+var ErrGameNotActive = errors.New("no hay partida activa")
+
+// ErrNoQuestionShown indica que no hay pregunta mostrada cuyas respuestas abrir
+var ErrNoQuestionShown = errors.New("no hay una pregunta mostrada")
+
+// ErrAnswersAlreadyOpen indica que la ventana de respuestas ya está abierta
+var ErrAnswersAlreadyOpen = errors.New("las respuestas ya están abiertas")
+
 type GameStateService struct {
-	redisClient    *redis.RedisClient
+	redisClient    redis.RedisStore
 	sessionService *SessionService
+	maxQuestions   int
+
+	// Ajustables en caliente desde /api/admin/settings
+	settingsMutex sync.RWMutex
+	timer         QuestionTimer
+	twoPhase      bool // NextQuestion solo muestra la pregunta; OpenAnswers abre las respuestas
+	tickInterval  time.Duration
+
+	// Caché del estado calculado, para no consultar Redis en cada petición
+	cacheTTL   time.Duration
+	cacheMutex sync.Mutex
+	cached     *models.GameState
+	cachedAt   time.Time
+	cacheGen   uint64 // aumenta al invalidar; descarta cargas que empezaron antes
+
+	// Cuenta regresiva difundida por el servidor (timerTick)
+	broadcast func(msgType string, data interface{})
+	tickMutex sync.Mutex
+	stopTick  chan struct{}
 }
 
-func NewGameStateService(redisClient *redis.RedisClient) *GameStateService {
+func NewGameStateService(redisClient redis.RedisStore) *GameStateService {
 	return &GameStateService{
-		redisClient: redisClient,
+		redisClient:  redisClient,
+		timer:        DefaultQuestionTimer(),
+		maxQuestions: 8,
+		cacheTTL:     500 * time.Millisecond,
+		tickInterval: time.Second,
 	}
 }
 
+// SetBroadcaster configura cómo se difunden los eventos del temporizador
+// (normalmente hub.BroadcastMessage); sin él no se envían ticks
+func (gs *GameStateService) SetBroadcaster(broadcast func(msgType string, data interface{})) {
+	gs.broadcast = broadcast
+}
+
+// SetTickInterval configura cada cuánto se difunde timerTick (0 = desactivado)
+func (gs *GameStateService) SetTickInterval(interval time.Duration) {
+	gs.settingsMutex.Lock()
+	gs.tickInterval = interval
+	gs.settingsMutex.Unlock()
+}
+
+// SetCacheTTL configura cuánto tiempo se reutiliza el estado calculado (0 = sin caché)
+func (gs *GameStateService) SetCacheTTL(ttl time.Duration) {
+	gs.cacheMutex.Lock()
+	gs.cacheTTL = ttl
+	gs.cacheMutex.Unlock()
+	gs.InvalidateCache()
+}
+
+// InvalidateCache descarta el estado en caché; se llama en cada transición del juego
+func (gs *GameStateService) InvalidateCache() {
+	gs.cacheMutex.Lock()
+	gs.cached = nil
+	gs.cacheGen++
+	gs.cacheMutex.Unlock()
+}
+
+// SetMaxQuestions configura el total de preguntas del quiz
+func (gs *GameStateService) SetMaxQuestions(maxQuestions int) {
+	gs.maxQuestions = maxQuestions
+}
+
+// SetQuestionTimer configura el tiempo de respuesta por pregunta
+func (gs *GameStateService) SetQuestionTimer(timer QuestionTimer) {
+	gs.settingsMutex.Lock()
+	gs.timer = timer
+	gs.settingsMutex.Unlock()
+}
+
+// SetTwoPhaseQuestions separa mostrar la pregunta (lectura en voz alta) de
+// abrir las respuestas
+func (gs *GameStateService) SetTwoPhaseQuestions(twoPhase bool) {
+	gs.settingsMutex.Lock()
+	gs.twoPhase = twoPhase
+	gs.settingsMutex.Unlock()
+}
+
+// TwoPhaseQuestions indica si las preguntas se muestran antes de abrir las respuestas
+func (gs *GameStateService) TwoPhaseQuestions() bool {
+	gs.settingsMutex.RLock()
+	defer gs.settingsMutex.RUnlock()
+	return gs.twoPhase
+}
+
 // SetSessionService permite inyectar el servicio de sesiones para calcular la pregunta actual
 func (gs *GameStateService) SetSessionService(sessionService *SessionService) {
 	gs.sessionService = sessionService
 }
 
-const gameStateKey = "quiz:game_state"
+const gameStateKey = "game_state"
+
+// gameRunningKey marca la partida en curso; se toma con SETNX para que solo una
+// llamada concurrente a StartGame (o EndGame) gane
+const gameRunningKey = "game_running"
+
+// roomsKey registro de las partidas en curso
+const roomsKey = "rooms"
 
+// endLockKey candado que protege la secuencia completa de terminar la partida
+// (aviso, archivo y limpieza); endSummaryKey guarda su resultado
+const (
+	endLockKey    = "end_game_lock"
+	endSummaryKey = "end_game_summary"
+	endLockTTL    = 30 * time.Second
+	endSummaryTTL = 10 * time.Minute
+)
+
+// GetGameState devuelve el estado del juego, usando la caché si sigue vigente.
+// Cada llamada recibe su propia copia, que puede modificar libremente. La carga
+// desde Redis se hace sin el candado, para no frenar a quien lee la caché.
 func (gs *GameStateService) GetGameState() (*models.GameState, error) {
+	gs.cacheMutex.Lock()
+	if gs.cached != nil && time.Since(gs.cachedAt) < gs.cacheTTL {
+		state := *gs.cached
+		gs.cacheMutex.Unlock()
+		return &state, nil
+	}
+	gen := gs.cacheGen
+	gs.cacheMutex.Unlock()
+
+	gameState, err := gs.loadGameState()
+	if err != nil {
+		return nil, err
+	}
+
+	gs.cacheMutex.Lock()
+	// Una transición durante la carga la deja obsoleta: no se guarda
+	if gs.cacheTTL > 0 && gen == gs.cacheGen {
+		cached := *gameState
+		gs.cached = &cached
+		gs.cachedAt = time.Now()
+	}
+	gs.cacheMutex.Unlock()
+
+	return gameState, nil
+}
+
+// loadGameState lee el estado desde Redis y calcula la pregunta actual
+func (gs *GameStateService) loadGameState() (*models.GameState, error) {
 	data, err := gs.redisClient.Get(gameStateKey)
 	if err != nil && err.Error() == "redis: nil" {
 		// Estado inicial del juego
@@ -35,7 +177,7 @@ func (gs *GameStateService) GetGameState() (*models.GameState, error) {
 			IsActive:        false,
 			Message:         "Partida detenida - Los jugadores no pueden ingresar",
 			CurrentQuestion: 1,
-			MaxQuestions:    8, // Número total de preguntas del quiz
+			MaxQuestions:    gs.maxQuestions, // Número total de preguntas del quiz
 		}, nil
 	}
 	if err != nil {
@@ -55,7 +197,7 @@ func (gs *GameStateService) GetGameState() (*models.GameState, error) {
 
 	// Asegurar que MaxQuestions esté establecido
 	if gameState.MaxQuestions == 0 {
-		gameState.MaxQuestions = 8
+		gameState.MaxQuestions = gs.maxQuestions
 	}
 
 	return &gameState, nil
@@ -88,15 +230,49 @@ func (gs *GameStateService) calculateCurrentQuestion() int {
 	return maxQuestion
 }
 
+// StartGame inicia la partida; devuelve ErrGameAlreadyActive si ya está en curso
 func (gs *GameStateService) StartGame() error {
-	now := time.Now()
+	acquired, err := gs.redisClient.SetIfAbsent(gameRunningKey, models.Timestamp(), 0)
+	if err != nil {
+		return fmt.Errorf("error marcando partida en curso: %w", err)
+	}
+	if !acquired {
+		return ErrGameAlreadyActive
+	}
+
+	if err := gs.startGame(); err != nil {
+		if delErr := gs.redisClient.Delete(gameRunningKey); delErr != nil {
+			log.Printf("⚠️ Error liberando marca de partida en curso: %v", delErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// SyncRunningMarker crea la marca de partida en curso si el estado guardado
+// indica una partida activa sin marca (p. ej. iniciada por una versión anterior).
+// Se llama una vez al arrancar el servidor.
+func (gs *GameStateService) SyncRunningMarker() error {
+	gameState, err := gs.loadGameState()
+	if err != nil {
+		return err
+	}
+	if !gameState.IsActive {
+		return nil
+	}
+	_, err = gs.redisClient.SetIfAbsent(gameRunningKey, models.Timestamp(), 0)
+	return err
+}
+
+func (gs *GameStateService) startGame() error {
+	now := time.Now().UTC()
 	gameState := &models.GameState{
 		IsActive:        true,
 		StartTime:       &now,
 		EndTime:         nil,
 		Message:         "Partida activa - Los jugadores pueden ingresar",
 		CurrentQuestion: 1,
-		MaxQuestions:    8,
+		MaxQuestions:    gs.maxQuestions,
 	}
 
 	data, err := json.Marshal(gameState)
@@ -104,28 +280,340 @@ func (gs *GameStateService) StartGame() error {
 		return fmt.Errorf("error serializando estado del juego: %w", err)
 	}
 
-	return gs.redisClient.Set(gameStateKey, string(data), 0)
+	// Reiniciar el registro de preguntas servidas y el resumen de la partida anterior
+	if err := gs.redisClient.Delete(servedQuestionsKey(DefaultRoom), endSummaryKey); err != nil {
+		return fmt.Errorf("error reiniciando preguntas servidas: %w", err)
+	}
+
+	if err := gs.saveGameState(string(data)); err != nil {
+		return err
+	}
+
+	return gs.redisClient.AddToSet(roomsKey, DefaultRoom)
 }
 
+// EndGame termina la partida; devuelve ErrGameNotActive si no hay partida en
+// curso o si otra llamada concurrente ya la terminó
 func (gs *GameStateService) EndGame() error {
+	released, err := gs.redisClient.DeleteIfExists(gameRunningKey)
+	if err != nil {
+		return fmt.Errorf("error liberando marca de partida en curso: %w", err)
+	}
+
+	if !released {
+		return ErrGameNotActive
+	}
+	gs.StopTimerTicks()
+
 	currentState, err := gs.GetGameState()
 	if err != nil {
 		return err
 	}
 
-	now := time.Now()
+	now := time.Now().UTC()
 	currentState.IsActive = false
 	currentState.EndTime = &now
 	currentState.Message = "Partida terminada - Los jugadores no pueden ingresar"
 	currentState.CurrentQuestion = 1 // Reset pregunta al terminar
-	currentState.MaxQuestions = 8
+	currentState.MaxQuestions = gs.maxQuestions
 
 	data, err := json.Marshal(currentState)
 	if err != nil {
 		return fmt.Errorf("error serializando estado del juego: %w", err)
 	}
 
-	return gs.redisClient.Set(gameStateKey, string(data), 0)
+	if err := gs.saveGameState(string(data)); err != nil {
+		return err
+	}
+
+	return gs.redisClient.RemoveFromSet(roomsKey, DefaultRoom)
+}
+
+// AcquireEndLock toma el candado de fin de partida; devuelve false si otra
+// llamada ya lo tiene. Expira solo por si el proceso muere a mitad de camino.
+func (gs *GameStateService) AcquireEndLock() (bool, error) {
+	return gs.redisClient.SetIfAbsent(endLockKey, models.Timestamp(), endLockTTL)
+}
+
+// ReleaseEndLock libera el candado de fin de partida
+func (gs *GameStateService) ReleaseEndLock() error {
+	return gs.redisClient.Delete(endLockKey)
+}
+
+// SaveEndSummary guarda el resultado de la última partida terminada
+func (gs *GameStateService) SaveEndSummary(summary *models.EndGameSummary) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("error serializando resumen de fin de partida: %w", err)
+	}
+	return gs.redisClient.Set(endSummaryKey, string(data), endSummaryTTL)
+}
+
+// GetEndSummary devuelve el resultado de la última partida terminada, o nil
+// si no hay uno reciente
+func (gs *GameStateService) GetEndSummary() (*models.EndGameSummary, error) {
+	data, err := gs.redisClient.Get(endSummaryKey)
+	if err != nil {
+		if err.Error() == "redis: nil" {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var summary models.EndGameSummary
+	if err := json.Unmarshal([]byte(data), &summary); err != nil {
+		return nil, fmt.Errorf("error deserializando resumen de fin de partida: %w", err)
+	}
+	return &summary, nil
+}
+
+// WaitEndSummary espera a que la llamada que tiene el candado termine la
+// partida y devuelve su resumen (nil si no termina antes de timeout)
+func (gs *GameStateService) WaitEndSummary(timeout time.Duration) (*models.EndGameSummary, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := gs.redisClient.Get(endLockKey)
+		if err != nil && err.Error() != "redis: nil" {
+			return nil, err
+		}
+		if locked == "" {
+			return gs.GetEndSummary()
+		}
+		if time.Now().After(deadline) {
+			return nil, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// ListRooms devuelve las partidas registradas en curso con su estado y
+// cantidad de jugadores. Por ahora el servidor maneja solo DefaultRoom.
+func (gs *GameStateService) ListRooms() ([]models.RoomInfo, error) {
+	roomIDs, err := gs.redisClient.GetSetMembers(roomsKey)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo partidas: %w", err)
+	}
+
+	rooms := []models.RoomInfo{}
+	for _, roomID := range roomIDs {
+		if roomID != DefaultRoom {
+			continue
+		}
+
+		gameState, err := gs.GetGameState()
+		if err != nil {
+			return nil, err
+		}
+
+		playerCount := 0
+		if gs.sessionService != nil {
+			if playerCount, err = gs.sessionService.CountActiveSessions(); err != nil {
+				return nil, err
+			}
+		}
+
+		rooms = append(rooms, models.RoomInfo{
+			ID:              roomID,
+			IsActive:        gameState.IsActive,
+			Message:         gameState.Message,
+			PlayerCount:     playerCount,
+			CurrentQuestion: gameState.CurrentQuestion,
+		})
+	}
+
+	return rooms, nil
+}
+
+// StartQuestion inicia el temporizador de la pregunta indicada, con una
+// duración según su dificultad, y lo guarda en el estado del juego
+func (gs *GameStateService) StartQuestion(number, difficulty int) (*models.GameState, error) {
+	currentState, err := gs.GetGameState()
+	if err != nil {
+		return nil, err
+	}
+
+	gs.settingsMutex.RLock()
+	duration := gs.timer.DurationFor(difficulty)
+	gs.settingsMutex.RUnlock()
+	now := time.Now().UTC()
+	deadline := now.Add(duration)
+	currentState.QuestionNumber = number
+	currentState.QuestionDuration = int(duration / time.Second)
+	currentState.QuestionStartedAt = &now
+	currentState.QuestionDeadline = &deadline
+	currentState.AnswersOpen = true
+
+	data, err := json.Marshal(currentState)
+	if err != nil {
+		return nil, fmt.Errorf("error serializando estado del juego: %w", err)
+	}
+
+	if err := gs.saveGameState(string(data)); err != nil {
+		return nil, fmt.Errorf("error guardando estado del juego: %w", err)
+	}
+
+	gs.markQuestionServed(number)
+	gs.startTimerTicks(number, deadline)
+	return currentState, nil
+}
+
+// ShowQuestion muestra la pregunta indicada sin abrir las respuestas ni iniciar
+// el temporizador (primera fase del modo en dos fases)
+func (gs *GameStateService) ShowQuestion(number int) (*models.GameState, error) {
+	gs.StopTimerTicks()
+
+	currentState, err := gs.GetGameState()
+	if err != nil {
+		return nil, err
+	}
+
+	currentState.QuestionNumber = number
+	currentState.QuestionDuration = 0
+	currentState.QuestionStartedAt = nil
+	currentState.QuestionDeadline = nil
+	currentState.AnswersOpen = false
+
+	data, err := json.Marshal(currentState)
+	if err != nil {
+		return nil, fmt.Errorf("error serializando estado del juego: %w", err)
+	}
+
+	if err := gs.saveGameState(string(data)); err != nil {
+		return nil, fmt.Errorf("error guardando estado del juego: %w", err)
+	}
+
+	gs.markQuestionServed(number)
+	return currentState, nil
+}
+
+// markQuestionServed registra la pregunta número N del plan como servida en la
+// partida, para que la selección aleatoria no la repita, y suma su jugada
+func (gs *GameStateService) markQuestionServed(number int) {
+	plan, err := gs.redisClient.GetQuestionPlan()
+	if err != nil {
+		log.Printf("⚠️ Error obteniendo plan de preguntas: %v", err)
+		return
+	}
+	if number < 1 || number > len(plan) {
+		return
+	}
+
+	id := plan[number-1]
+	added, err := gs.redisClient.AddToSetIfAbsent(servedQuestionsKey(DefaultRoom), strconv.Itoa(id))
+	if err != nil {
+		log.Printf("⚠️ Error marcando pregunta %d como servida: %v", id, err)
+		return
+	}
+	if added {
+		recordPlay(gs.redisClient, id)
+	}
+}
+
+// OpenAnswers abre las respuestas de la pregunta mostrada e inicia su
+// temporizador (segunda fase del modo en dos fases)
+func (gs *GameStateService) OpenAnswers(difficulty int) (*models.GameState, error) {
+	currentState, err := gs.GetGameState()
+	if err != nil {
+		return nil, err
+	}
+
+	if currentState.QuestionNumber == 0 {
+		return nil, ErrNoQuestionShown
+	}
+	if currentState.AnswersOpen {
+		return nil, ErrAnswersAlreadyOpen
+	}
+
+	return gs.StartQuestion(currentState.QuestionNumber, difficulty)
+}
+
+// startTimerTicks difunde timerTick con los segundos restantes cada
+// tickInterval hasta el plazo, reemplazando la cuenta de la pregunta anterior
+func (gs *GameStateService) startTimerTicks(number int, deadline time.Time) {
+	gs.StopTimerTicks()
+	gs.settingsMutex.RLock()
+	interval := gs.tickInterval
+	gs.settingsMutex.RUnlock()
+	if gs.broadcast == nil || interval <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	gs.tickMutex.Lock()
+	gs.stopTick = stop
+	gs.tickMutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				remaining := int((time.Until(deadline) + time.Second - 1) / time.Second)
+				if remaining < 0 {
+					remaining = 0
+				}
+				gs.broadcast("timerTick", map[string]interface{}{
+					"questionNumber": number,
+					"remaining":      remaining,
+					"deadline":       models.FormatTime(deadline),
+				})
+				if remaining == 0 {
+					gs.clearTimerTicks(stop)
+					return
+				}
+			}
+		}
+	}()
+}
+
+// StopTimerTicks detiene la cuenta regresiva en curso (al revelar la respuesta,
+// avanzar de pregunta o terminar la partida)
+func (gs *GameStateService) StopTimerTicks() {
+	gs.tickMutex.Lock()
+	defer gs.tickMutex.Unlock()
+	if gs.stopTick != nil {
+		close(gs.stopTick)
+		gs.stopTick = nil
+	}
+}
+
+// clearTimerTicks olvida la cuenta que terminó sola, si sigue siendo la actual
+func (gs *GameStateService) clearTimerTicks(stop chan struct{}) {
+	gs.tickMutex.Lock()
+	defer gs.tickMutex.Unlock()
+	if gs.stopTick == stop {
+		gs.stopTick = nil
+	}
+}
+
+// SetMessage actualiza el mensaje/anuncio del juego sin alterar su estado
+func (gs *GameStateService) SetMessage(message string) (*models.GameState, error) {
+	currentState, err := gs.GetGameState()
+	if err != nil {
+		return nil, err
+	}
+
+	currentState.Message = message
+
+	data, err := json.Marshal(currentState)
+	if err != nil {
+		return nil, fmt.Errorf("error serializando estado del juego: %w", err)
+	}
+
+	if err := gs.saveGameState(string(data)); err != nil {
+		return nil, fmt.Errorf("error guardando estado del juego: %w", err)
+	}
+
+	return currentState, nil
+}
+
+// saveGameState guarda el estado del juego e invalida la caché
+func (gs *GameStateService) saveGameState(data string) error {
+	defer gs.InvalidateCache()
+	return gs.redisClient.Set(gameStateKey, data, 0)
 }
 
 func (gs *GameStateService) IsGameActive() (bool, error) {
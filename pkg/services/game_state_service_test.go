@@ -0,0 +1,273 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/backsoul/quiz/pkg/redis"
+)
+
+// newTestGameStateService crea un GameStateService sobre un MemoryStore con 8
+// preguntas cargadas y la caché desactivada
+func newTestGameStateService(t *testing.T) (*GameStateService, *redis.MemoryStore) {
+	t.Helper()
+	_, store := newTestQuestionService(t, testQuestions(8))
+	gs := NewGameStateService(store)
+	gs.SetCacheTTL(0)
+	t.Cleanup(gs.StopTimerTicks)
+	return gs, store
+}
+
+func TestStartQuestionUsesDurationByDifficulty(t *testing.T) {
+	gs, _ := newTestGameStateService(t)
+	gs.SetQuestionTimer(QuestionTimer{
+		Default:      20 * time.Second,
+		ByDifficulty: map[int]time.Duration{5: 45 * time.Second},
+	})
+	if err := gs.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+
+	for _, c := range []struct {
+		difficulty int
+		seconds    int
+	}{{5, 45}, {2, 20}} {
+		state, err := gs.StartQuestion(1, c.difficulty)
+		if err != nil {
+			t.Fatalf("error iniciando pregunta: %v", err)
+		}
+		if state.QuestionDuration != c.seconds {
+			t.Fatalf("dificultad %d: duración %d, esperaba %d", c.difficulty, state.QuestionDuration, c.seconds)
+		}
+		if got := state.QuestionDeadline.Sub(*state.QuestionStartedAt); got != time.Duration(c.seconds)*time.Second {
+			t.Fatalf("dificultad %d: plazo a %v del inicio", c.difficulty, got)
+		}
+		if !state.AnswersOpen {
+			t.Fatalf("iniciar la pregunta abre las respuestas")
+		}
+	}
+}
+
+func TestGetGameStateCache(t *testing.T) {
+	gs, store := newTestGameStateService(t)
+	gs.SetCacheTTL(time.Hour)
+
+	state, err := gs.GetGameState()
+	if err != nil || state.IsActive {
+		t.Fatalf("estado inicial inesperado: %+v (%v)", state, err)
+	}
+	// Cada llamada recibe su copia: modificarla no altera la caché
+	state.Message = "modificado"
+
+	// Un cambio directo en Redis no se ve mientras la caché siga vigente
+	if err := store.Set(gameStateKey, `{"isActive":true,"message":"En curso"}`, 0); err != nil {
+		t.Fatalf("error escribiendo estado: %v", err)
+	}
+	state, err = gs.GetGameState()
+	if err != nil || state.IsActive || state.Message == "modificado" {
+		t.Fatalf("dentro del TTL se esperaba el estado en caché: %+v (%v)", state, err)
+	}
+
+	gs.InvalidateCache()
+	state, err = gs.GetGameState()
+	if err != nil || !state.IsActive || state.Message != "En curso" {
+		t.Fatalf("tras invalidar se esperaba el estado nuevo: %+v (%v)", state, err)
+	}
+
+	// Las transiciones del juego invalidan la caché por sí mismas
+	if _, err := gs.SetMessage("Pausa"); err != nil {
+		t.Fatalf("error cambiando el mensaje: %v", err)
+	}
+	if state, _ = gs.GetGameState(); state.Message != "Pausa" {
+		t.Fatalf("tras cambiar el mensaje la caché debe refrescarse: %+v", state)
+	}
+}
+
+func TestGetGameStateCacheExpires(t *testing.T) {
+	gs, store := newTestGameStateService(t)
+	gs.SetCacheTTL(20 * time.Millisecond)
+
+	if _, err := gs.GetGameState(); err != nil {
+		t.Fatalf("error obteniendo estado: %v", err)
+	}
+	if err := store.Set(gameStateKey, `{"isActive":true}`, 0); err != nil {
+		t.Fatalf("error escribiendo estado: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if state, _ := gs.GetGameState(); !state.IsActive {
+		t.Fatalf("vencido el TTL se esperaba releer Redis")
+	}
+}
+
+func TestConcurrentStartGameOnlyOneWins(t *testing.T) {
+	gs, _ := newTestGameStateService(t)
+
+	const callers = 50
+	errs := make(chan error, callers)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs <- gs.StartGame()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrGameAlreadyActive):
+			t.Fatalf("error inesperado: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("esperaba exactamente un inicio exitoso, hubo %d", succeeded)
+	}
+	if active, err := gs.IsGameActive(); err != nil || !active {
+		t.Fatalf("la partida debe quedar activa (%v)", err)
+	}
+}
+
+func TestConcurrentEndGameOnlyOneWins(t *testing.T) {
+	gs, _ := newTestGameStateService(t)
+	if err := gs.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+
+	const callers = 50
+	var succeeded, rejected atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := gs.EndGame()
+			if err == nil {
+				succeeded.Add(1)
+			} else if errors.Is(err, ErrGameNotActive) {
+				rejected.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded.Load() != 1 || rejected.Load() != callers-1 {
+		t.Fatalf("esperaba 1 fin exitoso y %d rechazados, hubo %d y %d", callers-1, succeeded.Load(), rejected.Load())
+	}
+	if err := gs.StartGame(); err != nil {
+		t.Fatalf("terminada la partida se puede iniciar otra: %v", err)
+	}
+}
+
+// tickRecorder configura el difusor del servicio y entrega cada timerTick por un canal
+func tickRecorder(gs *GameStateService) <-chan map[string]interface{} {
+	ticks := make(chan map[string]interface{}, 100)
+	gs.SetBroadcaster(func(msgType string, data interface{}) {
+		if msgType == "timerTick" {
+			ticks <- data.(map[string]interface{})
+		}
+	})
+	return ticks
+}
+
+func TestTimerTicksCountDownAndStopAtDeadline(t *testing.T) {
+	gs, _ := newTestGameStateService(t)
+	gs.SetQuestionTimer(QuestionTimer{Default: time.Second})
+	gs.SetTickInterval(150 * time.Millisecond)
+	ticks := tickRecorder(gs)
+
+	if err := gs.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+	if _, err := gs.StartQuestion(1, 1); err != nil {
+		t.Fatalf("error iniciando pregunta: %v", err)
+	}
+
+	previous := 2
+	count := 0
+	for previous > 0 {
+		select {
+		case tick := <-ticks:
+			count++
+			remaining := tick["remaining"].(int)
+			if remaining > previous {
+				t.Fatalf("la cuenta no debe subir: %d tras %d", remaining, previous)
+			}
+			if tick["questionNumber"] != 1 {
+				t.Fatalf("tick de otra pregunta: %v", tick)
+			}
+			previous = remaining
+		case <-time.After(2 * time.Second):
+			t.Fatalf("la cuenta no llegó a cero (último %d)", previous)
+		}
+	}
+	if count < 2 {
+		t.Fatalf("esperaba varios ticks antes del plazo, hubo %d", count)
+	}
+
+	// Llegado el plazo no se envían más ticks
+	select {
+	case tick := <-ticks:
+		t.Fatalf("tick después del plazo: %v", tick)
+	case <-time.After(400 * time.Millisecond):
+	}
+}
+
+func TestStopTimerTicksOnShowAndEnd(t *testing.T) {
+	gs, _ := newTestGameStateService(t)
+	gs.SetQuestionTimer(QuestionTimer{Default: 30 * time.Second})
+	gs.SetTickInterval(50 * time.Millisecond)
+	ticks := tickRecorder(gs)
+
+	if err := gs.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+
+	// expectTickThenSilence espera un tick y, tras stop, comprueba que cesan
+	expectTickThenSilence := func(stop func()) {
+		t.Helper()
+		select {
+		case <-ticks:
+		case <-time.After(time.Second):
+			t.Fatalf("no llegó ningún tick")
+		}
+		stop()
+		time.Sleep(20 * time.Millisecond)
+		for len(ticks) > 0 {
+			<-ticks
+		}
+		select {
+		case tick := <-ticks:
+			t.Fatalf("tick después de detener la cuenta: %v", tick)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+
+	if _, err := gs.StartQuestion(1, 1); err != nil {
+		t.Fatalf("error iniciando pregunta: %v", err)
+	}
+	expectTickThenSilence(func() {
+		if _, err := gs.ShowQuestion(2); err != nil {
+			t.Fatalf("error mostrando pregunta: %v", err)
+		}
+	})
+
+	if _, err := gs.StartQuestion(2, 1); err != nil {
+		t.Fatalf("error iniciando pregunta: %v", err)
+	}
+	expectTickThenSilence(func() {
+		if err := gs.EndGame(); err != nil {
+			t.Fatalf("error terminando partida: %v", err)
+		}
+	})
+}
@@ -1,28 +1,86 @@
 package services
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math"
 	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/backsoul/quiz/pkg/models"
 	"github.com/backsoul/quiz/pkg/redis"
 )
 
+// DefaultRoom identifica la partida única que maneja actualmente el servidor
+const DefaultRoom = "main"
+
+// ErrQuestionOutOfPlan indica un número de pregunta fuera del plan de la partida
+var ErrQuestionOutOfPlan = errors.New("número de pregunta fuera del plan")
+
+// ErrQuestionIDCollision indica que varios archivos de preguntas usan el mismo ID
+var ErrQuestionIDCollision = errors.New("IDs de pregunta repetidos entre archivos")
+
+// ErrNoUnseenQuestions indica que ya se sirvieron todas las preguntas de la partida
+var ErrNoUnseenQuestions = errors.New("no quedan preguntas sin mostrar en esta partida")
+
+// ErrUnknownRoom indica que la partida solicitada no existe
+var ErrUnknownRoom = errors.New("partida desconocida")
+
+// ErrDifficultyOutOfRange indica una dificultad fuera del rango permitido
+var ErrDifficultyOutOfRange = errors.New("dificultad fuera del rango permitido")
+
+// ErrInvalidQuestion indica que la pregunta resultante de un cambio no es válida
+var ErrInvalidQuestion = errors.New("pregunta inválida")
+
+// Rango de dificultad por defecto
+const (
+	DefaultMinDifficulty = 1
+	DefaultMaxDifficulty = 5
+)
+
 // QuestionService maneja la lógica de negocio para las preguntas
 type QuestionService struct {
-	redisClient *redis.RedisClient
+	redisClient    redis.RedisStore
+	matchingMutex  sync.RWMutex  // protege answerMatching, ajustable en caliente
+	answerMatching string        // AnswerMatchExact (por defecto), AnswerMatchNFC o AnswerMatchFold
+	statsRetention time.Duration // cada cuánto se reinicia el conteo de jugadas (0 = nunca)
+	minDifficulty  int
+	maxDifficulty  int
+	clampRange     bool // al cargar, ajusta las dificultades fuera de rango en lugar de rechazar el archivo
 }
 
 // NewQuestionService crea una nueva instancia del servicio
-func NewQuestionService(redisClient *redis.RedisClient) *QuestionService {
+func NewQuestionService(redisClient redis.RedisStore) *QuestionService {
 	return &QuestionService{
-		redisClient: redisClient,
+		redisClient:   redisClient,
+		minDifficulty: DefaultMinDifficulty,
+		maxDifficulty: DefaultMaxDifficulty,
+		clampRange:    true,
 	}
 }
 
+// SetStatsRetention define cada cuánto se reinicia el conteo de jugadas por
+// pregunta que usa la selección ponderada (0 = se conserva siempre)
+func (s *QuestionService) SetStatsRetention(retention time.Duration) {
+	s.statsRetention = retention
+}
+
+// SetDifficultyRange define el rango de dificultad válido. Al cargar preguntas
+// fuera de rango se ajustan al extremo más cercano con clamp o, si no, se
+// rechaza la carga completa.
+func (s *QuestionService) SetDifficultyRange(min, max int, clamp bool) {
+	s.minDifficulty = min
+	s.maxDifficulty = max
+	s.clampRange = clamp
+}
+
 // LoadQuestionsFromFile carga las preguntas desde el archivo JSON a Redis
 func (s *QuestionService) LoadQuestionsFromFile(filePath string) error {
 	log.Printf("📂 Cargando preguntas desde: %s", filePath)
@@ -33,6 +91,11 @@ func (s *QuestionService) LoadQuestionsFromFile(filePath string) error {
 		return fmt.Errorf("error leyendo archivo JSON: %v", err)
 	}
 
+	jsonData, err = s.checkDifficulties(jsonData)
+	if err != nil {
+		return err
+	}
+
 	// Cargar a Redis usando el cliente
 	if err := s.redisClient.LoadQuestionsFromJSON(jsonData); err != nil {
 		return fmt.Errorf("error cargando preguntas a Redis: %v", err)
@@ -42,7 +105,121 @@ func (s *QuestionService) LoadQuestionsFromFile(filePath string) error {
 	return nil
 }
 
-// GetAllQuestions obtiene todas las preguntas
+// LoadQuestionsFromFiles carga y combina varios archivos de preguntas (p. ej.
+// general + ronda temática). El plan sigue el orden de los archivos. Si dos
+// archivos repiten un ID no se carga nada y se devuelve ErrQuestionIDCollision.
+func (s *QuestionService) LoadQuestionsFromFiles(filePaths []string) error {
+	if len(filePaths) == 1 {
+		return s.LoadQuestionsFromFile(filePaths[0])
+	}
+
+	log.Printf("📂 Cargando preguntas desde: %s", strings.Join(filePaths, ", "))
+
+	jsonData, err := MergeQuestionFiles(filePaths)
+	if err != nil {
+		return err
+	}
+	jsonData, err = s.checkDifficulties(jsonData)
+	if err != nil {
+		return err
+	}
+
+	if err := s.redisClient.LoadQuestionsFromJSON(jsonData); err != nil {
+		return fmt.Errorf("error cargando preguntas a Redis: %v", err)
+	}
+
+	log.Printf("✅ Preguntas de %d archivos cargadas exitosamente", len(filePaths))
+	return nil
+}
+
+// checkDifficulties revisa que todas las preguntas tengan una dificultad dentro
+// del rango. Las que no, se listan en el log y, según la configuración, se
+// ajustan o hacen fallar la carga con ErrDifficultyOutOfRange.
+func (s *QuestionService) checkDifficulties(jsonData []byte) ([]byte, error) {
+	var data redis.QuestionsData
+	if err := json.Unmarshal(jsonData, &data); err != nil {
+		return nil, fmt.Errorf("error parsing JSON: %v", err)
+	}
+
+	var report []string
+	for i := range data.Questions {
+		question := &data.Questions[i]
+		if question.Difficulty >= s.minDifficulty && question.Difficulty <= s.maxDifficulty {
+			continue
+		}
+		entry := fmt.Sprintf("ID %d: %d", question.ID, question.Difficulty)
+		if s.clampRange {
+			question.Difficulty = s.clampDifficulty(question.Difficulty)
+			entry += fmt.Sprintf(" -> %d", question.Difficulty)
+		}
+		report = append(report, entry)
+	}
+	if len(report) == 0 {
+		return jsonData, nil
+	}
+
+	if !s.clampRange {
+		return nil, fmt.Errorf("%w (%d-%d): %s", ErrDifficultyOutOfRange, s.minDifficulty, s.maxDifficulty, strings.Join(report, "; "))
+	}
+	log.Printf("⚠️ %d preguntas con dificultad fuera de %d-%d, ajustadas: %s", len(report), s.minDifficulty, s.maxDifficulty, strings.Join(report, "; "))
+	return json.Marshal(data)
+}
+
+// clampDifficulty lleva una dificultad al extremo más cercano del rango
+func (s *QuestionService) clampDifficulty(difficulty int) int {
+	if difficulty < s.minDifficulty {
+		return s.minDifficulty
+	}
+	if difficulty > s.maxDifficulty {
+		return s.maxDifficulty
+	}
+	return difficulty
+}
+
+// MergeQuestionFiles combina varios archivos de preguntas en un solo JSON con
+// el formato de answers.json; los metadatos se toman del primer archivo
+func MergeQuestionFiles(filePaths []string) ([]byte, error) {
+	if len(filePaths) == 0 {
+		return nil, fmt.Errorf("no se indicaron archivos de preguntas")
+	}
+
+	var merged redis.QuestionsData
+	sources := make(map[int]string)
+	var collisions []string
+
+	for i, filePath := range filePaths {
+		jsonData, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("error leyendo archivo JSON %s: %v", filePath, err)
+		}
+
+		var data redis.QuestionsData
+		if err := json.Unmarshal(jsonData, &data); err != nil {
+			return nil, fmt.Errorf("error parsing JSON %s: %v", filePath, err)
+		}
+		if i == 0 {
+			merged.Metadata = data.Metadata
+		}
+
+		for _, question := range data.Questions {
+			if source, ok := sources[question.ID]; ok {
+				collisions = append(collisions, fmt.Sprintf("ID %d en %s y %s", question.ID, source, filePath))
+				continue
+			}
+			sources[question.ID] = filePath
+			merged.Questions = append(merged.Questions, question)
+		}
+	}
+
+	if len(collisions) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrQuestionIDCollision, strings.Join(collisions, "; "))
+	}
+
+	merged.Metadata.Total = len(merged.Questions)
+	return json.Marshal(merged)
+}
+
+// GetAllQuestions obtiene todas las preguntas ordenadas por ID
 func (s *QuestionService) GetAllQuestions() ([]models.Question, error) {
 	redisQuestions, err := s.redisClient.GetAllQuestions()
 	if err != nil {
@@ -62,10 +239,16 @@ func (s *QuestionService) GetAllQuestions() ([]models.Question, error) {
 			Correct:     rq.Correct,
 			Explanation: rq.Explanation,
 			Difficulty:  rq.Difficulty,
+			Category:    rq.Category,
+			OptionOrder: rq.OptionOrder,
 		}
 		questionIDs[i] = rq.ID
 	}
 
+	// Redis devuelve el set en orden arbitrario; ordenar para que el listado sea estable
+	sortQuestionsByID(questions)
+	sort.Ints(questionIDs)
+
 	log.Printf("📋 IDs de preguntas disponibles: %v", questionIDs)
 
 	return questions, nil
@@ -85,28 +268,270 @@ func (s *QuestionService) GetQuestion(id int) (*models.Question, error) {
 		Correct:     redisQuestion.Correct,
 		Explanation: redisQuestion.Explanation,
 		Difficulty:  redisQuestion.Difficulty,
+		Category:    redisQuestion.Category,
+		OptionOrder: redisQuestion.OptionOrder,
 	}
 
 	return question, nil
 }
 
-// GetRandomQuestion obtiene una pregunta aleatoria
+// GetQuestionPlan obtiene los IDs de preguntas en orden de juego
+func (s *QuestionService) GetQuestionPlan() ([]int, error) {
+	plan, err := s.redisClient.GetQuestionPlan()
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo plan de preguntas: %v", err)
+	}
+	return plan, nil
+}
+
+// GetQuestionByNumber resuelve el número de pregunta (1..N) contra el plan
+func (s *QuestionService) GetQuestionByNumber(number int) (*models.Question, error) {
+	plan, err := s.GetQuestionPlan()
+	if err != nil {
+		return nil, err
+	}
+
+	if number < 1 || number > len(plan) {
+		return nil, ErrQuestionOutOfPlan
+	}
+
+	return s.GetQuestion(plan[number-1])
+}
+
+// GetRandomQuestion obtiene una pregunta aleatoria que no se haya servido aún
+// en la partida actual
 func (s *QuestionService) GetRandomQuestion() (*models.Question, error) {
-	redisQuestion, err := s.redisClient.GetRandomQuestion()
+	return s.GetRandomUnseenQuestion(DefaultRoom)
+}
+
+// GetRandomUnseenQuestion obtiene una pregunta aleatoria que no se haya servido
+// aún en la partida y la marca como servida; agotadas, devuelve ErrNoUnseenQuestions
+func (s *QuestionService) GetRandomUnseenQuestion(room string) (*models.Question, error) {
+	allIDs, err := s.redisClient.GetSetMembers("question_ids")
 	if err != nil {
-		return nil, fmt.Errorf("error obteniendo pregunta aleatoria: %v", err)
+		return nil, fmt.Errorf("error obteniendo IDs de preguntas: %v", err)
 	}
 
-	question := &models.Question{
-		ID:          redisQuestion.ID,
-		Question:    redisQuestion.Question,
-		Options:     redisQuestion.Options,
-		Correct:     redisQuestion.Correct,
-		Explanation: redisQuestion.Explanation,
-		Difficulty:  redisQuestion.Difficulty,
+	ids := make([]int, 0, len(allIDs))
+	for _, idStr := range allIDs {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			log.Printf("⚠️ ID de pregunta inválido: %s", idStr)
+			continue
+		}
+		ids = append(ids, id)
 	}
 
-	return question, nil
+	return s.claimRandomQuestion(room, ids)
+}
+
+// claimRandomQuestion elige una de ids que no se haya servido en la partida,
+// favoreciendo las menos jugadas, y la marca como servida. La marca (SADD) es
+// atómica: si otra petición concurrente la tomó primero, se elige otra.
+func (s *QuestionService) claimRandomQuestion(room string, ids []int) (*models.Question, error) {
+	served, err := s.servedQuestions(room)
+	if err != nil {
+		return nil, err
+	}
+
+	unseen := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !served[strconv.Itoa(id)] {
+			unseen = append(unseen, id)
+		}
+	}
+
+	playCounts, err := s.playCounts()
+	if err != nil {
+		return nil, err
+	}
+
+	for len(unseen) > 0 {
+		weights, totalWeight := playWeights(unseen, playCounts)
+		i := pickWeighted(weights, totalWeight, rand.Float64())
+		id := unseen[i]
+
+		claimed, err := s.redisClient.AddToSetIfAbsent(servedQuestionsKey(room), strconv.Itoa(id))
+		if err != nil {
+			return nil, fmt.Errorf("error marcando pregunta %d como servida: %v", id, err)
+		}
+		if !claimed {
+			unseen = append(unseen[:i], unseen[i+1:]...)
+			continue
+		}
+
+		recordPlay(s.redisClient, id)
+		return s.GetQuestion(id)
+	}
+
+	return nil, ErrNoUnseenQuestions
+}
+
+// GetRandomWeightedQuestion obtiene una pregunta aleatoria de todo el banco,
+// sin excluir las ya servidas, favoreciendo las menos jugadas
+func (s *QuestionService) GetRandomWeightedQuestion() (*models.Question, error) {
+	idStrs, err := s.redisClient.GetSetMembers("question_ids")
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo IDs de preguntas: %v", err)
+	}
+
+	ids := make([]int, 0, len(idStrs))
+	for _, idStr := range idStrs {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			log.Printf("⚠️ ID de pregunta inválido: %s", idStr)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no hay preguntas disponibles")
+	}
+
+	playCounts, err := s.playCounts()
+	if err != nil {
+		return nil, err
+	}
+	weights, totalWeight := playWeights(ids, playCounts)
+	id := ids[pickWeighted(weights, totalWeight, rand.Float64())]
+
+	recordPlay(s.redisClient, id)
+	return s.GetQuestion(id)
+}
+
+// playCounts devuelve las veces que se ha servido cada pregunta, tras aplicar
+// la retención configurada
+func (s *QuestionService) playCounts() (map[string]string, error) {
+	s.prunePlayCounts()
+	playCounts, err := s.redisClient.GetHashAll(questionPlaysKey)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo conteo de jugadas: %v", err)
+	}
+	return playCounts, nil
+}
+
+// playWeights pesa cada pregunta con 1/(veces servida + 1); sin jugadas
+// registradas todas pesan lo mismo
+func playWeights(ids []int, playCounts map[string]string) ([]float64, float64) {
+	weights := make([]float64, len(ids))
+	totalWeight := 0.0
+	for i, id := range ids {
+		plays, _ := strconv.Atoi(playCounts[strconv.Itoa(id)])
+		weights[i] = 1.0 / float64(plays+1)
+		totalWeight += weights[i]
+	}
+	return weights, totalWeight
+}
+
+// recordPlay suma una jugada al contador de la pregunta
+func recordPlay(store redis.RedisStore, id int) {
+	if _, err := store.IncrementHashField(questionPlaysKey, strconv.Itoa(id), 1); err != nil {
+		log.Printf("⚠️ Error registrando jugada de pregunta %d: %v", id, err)
+	}
+}
+
+// pickWeighted elige un índice según su peso usando r en [0, 1)
+func pickWeighted(weights []float64, totalWeight, r float64) int {
+	target := r * totalWeight
+	for i, weight := range weights {
+		if target < weight {
+			return i
+		}
+		target -= weight
+	}
+	return len(weights) - 1
+}
+
+// GetUnusedQuestions devuelve las preguntas que aún no se sirvieron en la
+// partida (mostradas con el plan o al azar), ordenadas por dificultad y luego
+// por ID. Por ahora la única partida es DefaultRoom.
+func (s *QuestionService) GetUnusedQuestions(room string) ([]models.Question, error) {
+	if room != DefaultRoom {
+		return nil, ErrUnknownRoom
+	}
+
+	questions, err := s.GetAllQuestions()
+	if err != nil {
+		return nil, err
+	}
+	served, err := s.servedQuestions(room)
+	if err != nil {
+		return nil, err
+	}
+
+	unused := make([]models.Question, 0, len(questions))
+	for _, question := range questions {
+		if !served[strconv.Itoa(question.ID)] {
+			unused = append(unused, question)
+		}
+	}
+	sort.SliceStable(unused, func(i, j int) bool {
+		return unused[i].Difficulty < unused[j].Difficulty
+	})
+
+	return unused, nil
+}
+
+// servedQuestions IDs de las preguntas ya servidas en la partida
+func (s *QuestionService) servedQuestions(room string) (map[string]bool, error) {
+	servedIDs, err := s.redisClient.GetSetMembers(servedQuestionsKey(room))
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo preguntas servidas: %v", err)
+	}
+
+	served := make(map[string]bool, len(servedIDs))
+	for _, id := range servedIDs {
+		served[id] = true
+	}
+	return served, nil
+}
+
+// ResetServedQuestions limpia el registro de preguntas servidas de una partida
+func (s *QuestionService) ResetServedQuestions(room string) error {
+	return s.redisClient.Delete(servedQuestionsKey(room))
+}
+
+// questionPlaysKey hash con las veces que se ha servido cada pregunta
+const questionPlaysKey = "question_plays"
+
+// questionPlaysSinceKey momento desde el que se cuentan las jugadas
+const questionPlaysSinceKey = "question_plays_since"
+
+// ResetPlayCounts borra el conteo de jugadas por pregunta; la selección
+// ponderada vuelve a ser uniforme
+func (s *QuestionService) ResetPlayCounts() error {
+	return s.redisClient.Delete(questionPlaysKey, questionPlaysSinceKey)
+}
+
+// prunePlayCounts reinicia el conteo de jugadas cuando supera la retención
+func (s *QuestionService) prunePlayCounts() {
+	if s.statsRetention <= 0 {
+		return
+	}
+
+	now := time.Now()
+	if _, err := s.redisClient.SetIfAbsent(questionPlaysSinceKey, models.FormatTime(now), 0); err != nil {
+		log.Printf("⚠️ Error registrando inicio del conteo de jugadas: %v", err)
+		return
+	}
+	value, err := s.redisClient.Get(questionPlaysSinceKey)
+	if err != nil {
+		return
+	}
+	since, err := time.Parse(time.RFC3339, value)
+	if err == nil && now.Sub(since) < s.statsRetention {
+		return
+	}
+
+	if err := s.ResetPlayCounts(); err != nil {
+		log.Printf("⚠️ Error reiniciando conteo de jugadas: %v", err)
+		return
+	}
+	log.Printf("🧹 Conteo de jugadas por pregunta reiniciado (retención %v)", s.statsRetention)
+}
+
+func servedQuestionsKey(room string) string {
+	return fmt.Sprintf("served:%s", room)
 }
 
 // GetQuestionsByDifficulty obtiene preguntas filtradas por dificultad
@@ -125,13 +550,63 @@ func (s *QuestionService) GetQuestionsByDifficulty(minDifficulty, maxDifficulty
 			Correct:     rq.Correct,
 			Explanation: rq.Explanation,
 			Difficulty:  rq.Difficulty,
+			Category:    rq.Category,
+			OptionOrder: rq.OptionOrder,
 		}
 	}
 
+	sortQuestionsByID(questions)
 	return questions, nil
 }
 
-// GetRandomQuestionByDifficulty obtiene una pregunta aleatoria de cierta dificultad
+// sortQuestionsByID ordena las preguntas por ID ascendente
+func sortQuestionsByID(questions []models.Question) {
+	sort.Slice(questions, func(i, j int) bool {
+		return questions[i].ID < questions[j].ID
+	})
+}
+
+// SearchQuestions filtra preguntas combinando dificultad, categoría y texto
+// (sin distinguir mayúsculas en pregunta y explicación). Devuelve la página
+// solicitada, ordenada por ID (como GetAllQuestions), y el total de coincidencias.
+func (s *QuestionService) SearchQuestions(criteria models.QuestionSearchCriteria) ([]models.Question, int, error) {
+	questions, err := s.GetAllQuestions()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	text := strings.ToLower(strings.TrimSpace(criteria.Text))
+	matches := make([]models.Question, 0)
+	for _, question := range questions {
+		if criteria.Difficulty > 0 && question.Difficulty != criteria.Difficulty {
+			continue
+		}
+		if criteria.Category != "" && !strings.EqualFold(question.Category, criteria.Category) {
+			continue
+		}
+		if text != "" &&
+			!strings.Contains(strings.ToLower(question.Question), text) &&
+			!strings.Contains(strings.ToLower(question.Explanation), text) {
+			continue
+		}
+		matches = append(matches, question)
+	}
+
+	total := len(matches)
+	start := criteria.Offset
+	if start > total {
+		start = total
+	}
+	end := total
+	if criteria.Limit > 0 && start+criteria.Limit < total {
+		end = start + criteria.Limit
+	}
+
+	return matches[start:end], total, nil
+}
+
+// GetRandomQuestionByDifficulty obtiene una pregunta aleatoria de cierta
+// dificultad que no se haya servido aún en la partida actual
 func (s *QuestionService) GetRandomQuestionByDifficulty(minDifficulty, maxDifficulty int) (*models.Question, error) {
 	questions, err := s.GetQuestionsByDifficulty(minDifficulty, maxDifficulty)
 	if err != nil {
@@ -142,20 +617,244 @@ func (s *QuestionService) GetRandomQuestionByDifficulty(minDifficulty, maxDiffic
 		return nil, fmt.Errorf("no hay preguntas disponibles en el rango de dificultad %d-%d", minDifficulty, maxDifficulty)
 	}
 
-	// Semilla aleatoria
-	rand.Seed(time.Now().UnixNano())
-	randomIndex := rand.Intn(len(questions))
+	ids := make([]int, len(questions))
+	for i, question := range questions {
+		ids[i] = question.ID
+	}
+
+	return s.claimRandomQuestion(DefaultRoom, ids)
+}
+
+// UpdateDifficulty cambia la dificultad de una pregunta existente; fuera del
+// rango permitido devuelve ErrDifficultyOutOfRange
+func (s *QuestionService) UpdateDifficulty(id, difficulty int) error {
+	if difficulty < s.minDifficulty || difficulty > s.maxDifficulty {
+		return fmt.Errorf("%w (%d-%d): %d", ErrDifficultyOutOfRange, s.minDifficulty, s.maxDifficulty, difficulty)
+	}
+
+	redisQuestion, err := s.redisClient.GetQuestion(id)
+	if err != nil {
+		return fmt.Errorf("error obteniendo pregunta %d: %v", id, err)
+	}
+
+	redisQuestion.Difficulty = difficulty
+	if err := s.redisClient.SaveQuestion(*redisQuestion); err != nil {
+		return fmt.Errorf("error guardando pregunta %d: %v", id, err)
+	}
+
+	return nil
+}
+
+// PatchQuestion aplica a una pregunta existente solo los campos presentes en
+// patch, valida el resultado y lo guarda
+func (s *QuestionService) PatchQuestion(id int, patch models.QuestionPatch) (*models.Question, error) {
+	redisQuestion, err := s.redisClient.GetQuestion(id)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo pregunta %d: %v", id, err)
+	}
+
+	if patch.Question != nil {
+		redisQuestion.Question = *patch.Question
+	}
+	if len(patch.Options) > 0 {
+		options := make(map[string]string, len(redisQuestion.Options)+len(patch.Options))
+		for key, text := range redisQuestion.Options {
+			options[key] = text
+		}
+		// Las opciones nuevas van al final del orden de presentación
+		newKeys := make([]string, 0, len(patch.Options))
+		for key, text := range patch.Options {
+			if _, exists := options[key]; !exists {
+				newKeys = append(newKeys, key)
+			}
+			options[key] = text
+		}
+		if len(redisQuestion.OptionOrder) > 0 {
+			sort.Strings(newKeys)
+			redisQuestion.OptionOrder = append(redisQuestion.OptionOrder, newKeys...)
+		}
+		redisQuestion.Options = options
+	}
+	if patch.Correct != nil {
+		redisQuestion.Correct = *patch.Correct
+	}
+	if patch.Explanation != nil {
+		redisQuestion.Explanation = *patch.Explanation
+	}
+	if patch.Category != nil {
+		redisQuestion.Category = *patch.Category
+	}
+	if patch.Difficulty != nil {
+		difficulty := *patch.Difficulty
+		if difficulty < s.minDifficulty || difficulty > s.maxDifficulty {
+			return nil, fmt.Errorf("%w (%d-%d): %d", ErrDifficultyOutOfRange, s.minDifficulty, s.maxDifficulty, difficulty)
+		}
+		redisQuestion.Difficulty = difficulty
+	}
+
+	if strings.TrimSpace(redisQuestion.Question) == "" {
+		return nil, fmt.Errorf("%w: el enunciado no puede quedar vacío", ErrInvalidQuestion)
+	}
+	for key, text := range redisQuestion.Options {
+		if strings.TrimSpace(text) == "" {
+			return nil, fmt.Errorf("%w: la opción %s no puede quedar vacía", ErrInvalidQuestion, key)
+		}
+	}
+	if _, ok := redisQuestion.Options[redisQuestion.Correct]; !ok {
+		return nil, fmt.Errorf("%w: la respuesta correcta %q no es una de las opciones", ErrInvalidQuestion, redisQuestion.Correct)
+	}
+
+	if err := s.redisClient.SaveQuestion(*redisQuestion); err != nil {
+		return nil, fmt.Errorf("error guardando pregunta %d: %v", id, err)
+	}
+
+	return s.GetQuestion(id)
+}
+
+// SuggestDifficulty traduce una tasa de acierto (0-1) a una dificultad 1-5:
+// a menor tasa de acierto, mayor dificultad
+func SuggestDifficulty(correctRate float64) int {
+	switch {
+	case correctRate >= 0.8:
+		return 1
+	case correctRate >= 0.6:
+		return 2
+	case correctRate >= 0.4:
+		return 3
+	case correctRate >= 0.2:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// CalibrateDifficulties sugiere dificultades a partir de las estadísticas reales.
+// Solo se consideran preguntas con al menos minAttempts respuestas; con apply
+// se guardan las dificultades sugeridas que difieran de las actuales.
+func (s *QuestionService) CalibrateDifficulties(stats map[int]*models.QuestionStats, minAttempts int, apply bool) ([]models.DifficultySuggestion, error) {
+	questions, err := s.GetAllQuestions()
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]models.DifficultySuggestion, 0, len(questions))
+	for _, question := range questions {
+		stat, ok := stats[question.ID]
+		if !ok || stat.Attempts < minAttempts {
+			continue
+		}
+
+		suggestion := models.DifficultySuggestion{
+			QuestionID:          question.ID,
+			CurrentDifficulty:   question.Difficulty,
+			SuggestedDifficulty: s.clampDifficulty(SuggestDifficulty(stat.CorrectRate)),
+			Attempts:            stat.Attempts,
+			CorrectRate:         stat.CorrectRate,
+		}
+
+		if apply && suggestion.SuggestedDifficulty != question.Difficulty {
+			if err := s.UpdateDifficulty(question.ID, suggestion.SuggestedDifficulty); err != nil {
+				log.Printf("⚠️ Error aplicando dificultad a pregunta %d: %v", question.ID, err)
+			} else {
+				suggestion.Applied = true
+			}
+		}
+
+		suggestions = append(suggestions, suggestion)
+	}
+
+	return suggestions, nil
+}
+
+// GetAnswerKeyDistribution cuenta cuántas veces cada opción es la correcta, en
+// todo el banco y por dificultad, para detectar sesgos como "siempre la B"
+func (s *QuestionService) GetAnswerKeyDistribution() (*models.AnswerKeyDistribution, error) {
+	questions, err := s.GetAllQuestions()
+	if err != nil {
+		return nil, err
+	}
+
+	// Todas las opciones del banco, para reportar también las que nunca son correctas
+	optionSet := make(map[string]bool)
+	for _, question := range questions {
+		for option := range question.Options {
+			optionSet[option] = true
+		}
+	}
+	options := make([]string, 0, len(optionSet))
+	for option := range optionSet {
+		options = append(options, option)
+	}
+	sort.Strings(options)
+
+	overall := make(map[string]int)
+	byDifficulty := make(map[int]map[string]int)
+	for _, question := range questions {
+		overall[question.Correct]++
+		if byDifficulty[question.Difficulty] == nil {
+			byDifficulty[question.Difficulty] = make(map[string]int)
+		}
+		byDifficulty[question.Difficulty][question.Correct]++
+	}
 
-	return &questions[randomIndex], nil
+	distribution := &models.AnswerKeyDistribution{
+		Overall:      answerKeyGroup(0, overall, options),
+		ByDifficulty: make([]models.AnswerKeyGroup, 0, len(byDifficulty)),
+	}
+	for difficulty, counts := range byDifficulty {
+		distribution.ByDifficulty = append(distribution.ByDifficulty, answerKeyGroup(difficulty, counts, options))
+	}
+	sort.Slice(distribution.ByDifficulty, func(i, j int) bool {
+		return distribution.ByDifficulty[i].Difficulty < distribution.ByDifficulty[j].Difficulty
+	})
+
+	return distribution, nil
+}
+
+// answerKeyGroup arma el reparto de un grupo con porcentajes redondeados a un decimal
+func answerKeyGroup(difficulty int, counts map[string]int, options []string) models.AnswerKeyGroup {
+	group := models.AnswerKeyGroup{Difficulty: difficulty}
+	for _, count := range counts {
+		group.Total += count
+	}
+
+	for _, option := range options {
+		entry := models.AnswerKeyCount{Option: option, Count: counts[option]}
+		if group.Total > 0 {
+			entry.Percent = math.Round(float64(entry.Count)*1000/float64(group.Total)) / 10
+		}
+		group.Options = append(group.Options, entry)
+	}
+	return group
 }
 
-// GetQuestionMetadata obtiene los metadatos del quiz
+// GetQuestionMetadata obtiene los metadatos del quiz; si el total declarado no
+// coincide con las preguntas cargadas agrega el aviso countMismatch
 func (s *QuestionService) GetQuestionMetadata() (interface{}, error) {
 	metadata, err := s.redisClient.GetMetadata()
 	if err != nil {
 		return nil, fmt.Errorf("error obteniendo metadatos: %v", err)
 	}
 
+	// Si la carga fue parcial, totalQuestions no coincide con lo que hay en Redis.
+	// Un archivo sin metadatos guarda 0: no declara total y no hay qué comparar.
+	expected, ok := metadata["totalQuestions"].(float64)
+	if !ok || expected <= 0 {
+		return metadata, nil
+	}
+	loaded, err := s.GetQuestionCount()
+	if err != nil {
+		return nil, err
+	}
+	if int(expected) != loaded {
+		log.Printf("⚠️ Los metadatos indican %d preguntas pero hay %d cargadas", int(expected), loaded)
+		metadata["countMismatch"] = map[string]interface{}{
+			"expected": int(expected),
+			"loaded":   loaded,
+			"message":  fmt.Sprintf("Se esperaban %d preguntas pero hay %d cargadas", int(expected), loaded),
+		}
+	}
+
 	return metadata, nil
 }
 
@@ -179,10 +878,10 @@ func (s *QuestionService) HealthCheck() error {
 }
 
 // ReloadQuestions recarga las preguntas desde el archivo JSON
-func (s *QuestionService) ReloadQuestions(filePath string) error {
+func (s *QuestionService) ReloadQuestions(filePaths ...string) error {
 	log.Println("🔄 Recargando preguntas...")
 
-	if err := s.LoadQuestionsFromFile(filePath); err != nil {
+	if err := s.LoadQuestionsFromFiles(filePaths); err != nil {
 		return fmt.Errorf("error recargando preguntas: %v", err)
 	}
 
@@ -0,0 +1,636 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/backsoul/quiz/pkg/models"
+	"github.com/backsoul/quiz/pkg/redis"
+)
+
+// testQuestions genera n preguntas con IDs 1..n, dificultad rotando de 1 a 5 y
+// "A" como respuesta correcta
+func testQuestions(n int) []models.Question {
+	questions := make([]models.Question, 0, n)
+	for i := 1; i <= n; i++ {
+		questions = append(questions, models.Question{
+			ID:       i,
+			Question: fmt.Sprintf("Pregunta %d", i),
+			Options: map[string]string{
+				"A": "Uno", "B": "Dos", "C": "Tres", "D": "Cuatro",
+			},
+			Correct:     "A",
+			Explanation: fmt.Sprintf("Explicación %d", i),
+			Difficulty:  (i-1)%5 + 1,
+		})
+	}
+	return questions
+}
+
+// writeQuestionsFile escribe las preguntas en un archivo JSON temporal con el
+// mismo formato que questions.json
+func writeQuestionsFile(t *testing.T, name string, questions []models.Question) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{"questions": questions})
+	if err != nil {
+		t.Fatalf("error serializando preguntas: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("error escribiendo %s: %v", path, err)
+	}
+	return path
+}
+
+// newTestQuestionService crea un QuestionService sobre un MemoryStore con las
+// preguntas ya cargadas
+func newTestQuestionService(t *testing.T, questions []models.Question) (*QuestionService, *redis.MemoryStore) {
+	t.Helper()
+	store := redis.NewMemoryStore()
+	s := NewQuestionService(store)
+	if err := s.LoadQuestionsFromFile(writeQuestionsFile(t, "questions.json", questions)); err != nil {
+		t.Fatalf("error cargando preguntas: %v", err)
+	}
+	return s, store
+}
+
+func TestGetRandomUnseenQuestionDrainsBank(t *testing.T) {
+	s, _ := newTestQuestionService(t, testQuestions(10))
+
+	seen := make(map[int]bool)
+	for i := 0; i < 10; i++ {
+		question, err := s.GetRandomUnseenQuestion(DefaultRoom)
+		if err != nil {
+			t.Fatalf("pregunta %d: error inesperado: %v", i+1, err)
+		}
+		if seen[question.ID] {
+			t.Fatalf("la pregunta %d se sirvió dos veces", question.ID)
+		}
+		seen[question.ID] = true
+	}
+
+	if _, err := s.GetRandomUnseenQuestion(DefaultRoom); !errors.Is(err, ErrNoUnseenQuestions) {
+		t.Fatalf("esperaba ErrNoUnseenQuestions con el banco agotado, obtuve %v", err)
+	}
+
+	if err := s.ResetServedQuestions(DefaultRoom); err != nil {
+		t.Fatalf("error reiniciando preguntas servidas: %v", err)
+	}
+	if _, err := s.GetRandomQuestion(); err != nil {
+		t.Fatalf("tras reiniciar debería volver a servir preguntas: %v", err)
+	}
+}
+
+func TestGetRandomQuestionByDifficultyDrainsRange(t *testing.T) {
+	s, _ := newTestQuestionService(t, testQuestions(10))
+
+	// Las dificultades 1 y 2 corresponden a las preguntas 1, 2, 6 y 7
+	for i := 0; i < 4; i++ {
+		question, err := s.GetRandomQuestionByDifficulty(1, 2)
+		if err != nil {
+			t.Fatalf("pregunta %d: error inesperado: %v", i+1, err)
+		}
+		if question.Difficulty > 2 {
+			t.Fatalf("dificultad %d fuera del rango 1-2", question.Difficulty)
+		}
+	}
+	if _, err := s.GetRandomQuestionByDifficulty(1, 2); !errors.Is(err, ErrNoUnseenQuestions) {
+		t.Fatalf("esperaba ErrNoUnseenQuestions con el rango agotado, obtuve %v", err)
+	}
+
+	// Las del resto del banco siguen disponibles
+	if _, err := s.GetRandomQuestion(); err != nil {
+		t.Fatalf("esperaba preguntas de otras dificultades: %v", err)
+	}
+}
+
+func TestGetRandomUnseenQuestionConcurrentClaims(t *testing.T) {
+	s, _ := newTestQuestionService(t, testQuestions(20))
+
+	results := make(chan int, 40)
+	done := make(chan struct{})
+	for w := 0; w < 4; w++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for {
+				question, err := s.GetRandomUnseenQuestion(DefaultRoom)
+				if err != nil {
+					return
+				}
+				results <- question.ID
+			}
+		}()
+	}
+	for w := 0; w < 4; w++ {
+		<-done
+	}
+	close(results)
+
+	seen := make(map[int]bool)
+	for id := range results {
+		if seen[id] {
+			t.Fatalf("la pregunta %d se sirvió a dos peticiones concurrentes", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != 20 {
+		t.Fatalf("esperaba servir las 20 preguntas, se sirvieron %d", len(seen))
+	}
+}
+
+func TestSuggestDifficultyBuckets(t *testing.T) {
+	cases := []struct {
+		rate float64
+		want int
+	}{
+		{1.0, 1}, {0.8, 1},
+		{0.79, 2}, {0.6, 2},
+		{0.59, 3}, {0.4, 3},
+		{0.39, 4}, {0.2, 4},
+		{0.19, 5}, {0, 5},
+	}
+	for _, c := range cases {
+		if got := SuggestDifficulty(c.rate); got != c.want {
+			t.Errorf("SuggestDifficulty(%.2f) = %d, esperaba %d", c.rate, got, c.want)
+		}
+	}
+}
+
+func TestCalibrateDifficulties(t *testing.T) {
+	s, _ := newTestQuestionService(t, testQuestions(3))
+
+	stats := map[int]*models.QuestionStats{
+		1: {QuestionID: 1, Attempts: 10, Correct: 1, CorrectRate: 0.1}, // dificultad 1 -> 5
+		2: {QuestionID: 2, Attempts: 10, Correct: 7, CorrectRate: 0.7}, // dificultad 2 -> 2
+		3: {QuestionID: 3, Attempts: 2, Correct: 2, CorrectRate: 1.0},  // pocas respuestas
+	}
+
+	suggestions, err := s.CalibrateDifficulties(stats, 5, false)
+	if err != nil {
+		t.Fatalf("error calibrando: %v", err)
+	}
+	if len(suggestions) != 2 {
+		t.Fatalf("esperaba 2 sugerencias (la 3 tiene pocas respuestas), obtuve %d", len(suggestions))
+	}
+	for _, suggestion := range suggestions {
+		if suggestion.Applied {
+			t.Fatalf("sin apply no se debe aplicar nada: %+v", suggestion)
+		}
+	}
+	if question, _ := s.GetQuestion(1); question.Difficulty != 1 {
+		t.Fatalf("sin apply la dificultad no debe cambiar, es %d", question.Difficulty)
+	}
+
+	suggestions, err = s.CalibrateDifficulties(stats, 5, true)
+	if err != nil {
+		t.Fatalf("error calibrando con apply: %v", err)
+	}
+	applied := make(map[int]bool)
+	for _, suggestion := range suggestions {
+		applied[suggestion.QuestionID] = suggestion.Applied
+	}
+	if !applied[1] || applied[2] {
+		t.Fatalf("solo la pregunta 1 cambia de dificultad: %v", applied)
+	}
+	if question, _ := s.GetQuestion(1); question.Difficulty != 5 {
+		t.Fatalf("esperaba dificultad 5 aplicada, es %d", question.Difficulty)
+	}
+}
+
+func TestSearchQuestionsCombinedFilters(t *testing.T) {
+	questions := testQuestions(20)
+	for i := range questions {
+		if questions[i].ID%2 == 0 {
+			questions[i].Category = "Historia"
+		} else {
+			questions[i].Category = "Ciencia"
+		}
+	}
+	questions[3].Explanation = "Trata sobre la Revolución Francesa" // ID 4, dificultad 4, Historia
+	questions[13].Question = "¿En qué año fue la revolución rusa?"  // ID 14, dificultad 4, Historia
+	questions[8].Question = "¿Qué revolución cambió la industria?"  // ID 9, dificultad 4, Ciencia
+	s, _ := newTestQuestionService(t, questions)
+
+	ids := func(questions []models.Question) []int {
+		result := make([]int, 0, len(questions))
+		for _, question := range questions {
+			result = append(result, question.ID)
+		}
+		return result
+	}
+
+	found, total, err := s.SearchQuestions(models.QuestionSearchCriteria{
+		Difficulty: 4,
+		Category:   "historia",
+		Text:       "REVOLUCIÓN",
+	})
+	if err != nil {
+		t.Fatalf("error buscando: %v", err)
+	}
+	if total != 2 || fmt.Sprint(ids(found)) != "[4 14]" {
+		t.Fatalf("esperaba [4 14] (total 2), obtuve %v (total %d)", ids(found), total)
+	}
+
+	// Paginación sobre el total filtrado
+	found, total, _ = s.SearchQuestions(models.QuestionSearchCriteria{Category: "Ciencia", Limit: 3, Offset: 2})
+	if total != 10 || fmt.Sprint(ids(found)) != "[5 7 9]" {
+		t.Fatalf("esperaba [5 7 9] (total 10), obtuve %v (total %d)", ids(found), total)
+	}
+
+	// Sin coincidencias: lista vacía y total cero
+	found, total, _ = s.SearchQuestions(models.QuestionSearchCriteria{Difficulty: 1, Text: "revolución"})
+	if total != 0 || found == nil || len(found) != 0 {
+		t.Fatalf("esperaba una lista vacía, obtuve %v (total %d)", found, total)
+	}
+
+	// Un offset más allá del total no falla
+	if found, total, _ = s.SearchQuestions(models.QuestionSearchCriteria{Offset: 50}); total != 20 || len(found) != 0 {
+		t.Fatalf("offset fuera de rango: %d resultados, total %d", len(found), total)
+	}
+}
+
+func TestPlayWeightsFavorLeastPlayed(t *testing.T) {
+	ids := []int{1, 2, 3}
+	weights, total := playWeights(ids, map[string]string{"1": "9", "2": "1"})
+	if weights[0] != 0.1 || weights[1] != 0.5 || weights[2] != 1 || total != 1.6 {
+		t.Fatalf("pesos inesperados: %v (total %v)", weights, total)
+	}
+
+	// r recorre [0, 1): cada índice se elige en proporción a su peso
+	picks := make([]int, len(ids))
+	for i := 0; i < 1600; i++ {
+		picks[pickWeighted(weights, total, float64(i)/1600)]++
+	}
+	if picks[0] != 100 || picks[1] != 500 || picks[2] != 1000 {
+		t.Fatalf("reparto inesperado: %v", picks)
+	}
+
+	// Sin jugadas todas pesan lo mismo
+	weights, total = playWeights(ids, map[string]string{})
+	if weights[0] != weights[1] || weights[1] != weights[2] || total != 3 {
+		t.Fatalf("sin jugadas los pesos deben ser iguales: %v", weights)
+	}
+}
+
+func TestRandomSelectionPrefersLeastPlayed(t *testing.T) {
+	s, store := newTestQuestionService(t, testQuestions(2))
+	if _, err := store.IncrementHashField(questionPlaysKey, "1", 1000000); err != nil {
+		t.Fatalf("error sesgando jugadas: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		if err := s.ResetServedQuestions(DefaultRoom); err != nil {
+			t.Fatalf("error reiniciando: %v", err)
+		}
+		question, err := s.GetRandomUnseenQuestion(DefaultRoom)
+		if err != nil {
+			t.Fatalf("error eligiendo pregunta: %v", err)
+		}
+		if question.ID != 2 {
+			t.Fatalf("intento %d: se eligió la pregunta más jugada", i+1)
+		}
+	}
+
+	// Cada pregunta servida suma una jugada
+	plays, err := store.GetHashField(questionPlaysKey, "2")
+	if err != nil || plays != "20" {
+		t.Fatalf("esperaba 20 jugadas de la pregunta 2, hay %q (%v)", plays, err)
+	}
+	if _, err := s.GetRandomWeightedQuestion(); err != nil {
+		t.Fatalf("error en la selección ponderada: %v", err)
+	}
+	counts, _ := store.GetHashAll(questionPlaysKey)
+	if counts["1"] != "1000000" && counts["2"] != "21" {
+		t.Fatalf("la selección ponderada no registró la jugada: %v", counts)
+	}
+}
+
+func TestGetAllQuestionsSortedByID(t *testing.T) {
+	questions := testQuestions(12)
+	// El archivo trae las preguntas desordenadas
+	rand.New(rand.NewSource(3)).Shuffle(len(questions), func(i, j int) {
+		questions[i], questions[j] = questions[j], questions[i]
+	})
+	s, _ := newTestQuestionService(t, questions)
+
+	for attempt := 1; attempt <= 5; attempt++ {
+		all, err := s.GetAllQuestions()
+		if err != nil {
+			t.Fatalf("error obteniendo preguntas: %v", err)
+		}
+		if len(all) != 12 {
+			t.Fatalf("esperaba 12 preguntas, hay %d", len(all))
+		}
+		for i, question := range all {
+			if question.ID != i+1 {
+				t.Fatalf("intento %d: posición %d tiene la pregunta %d", attempt, i, question.ID)
+			}
+		}
+	}
+}
+
+func TestLoadQuestionsFromFilesMerges(t *testing.T) {
+	questions := testQuestions(5)
+	general := writeQuestionsFile(t, "general.json", questions[:3])
+	themed := writeQuestionsFile(t, "tematica.json", questions[3:])
+
+	s := NewQuestionService(redis.NewMemoryStore())
+	if err := s.LoadQuestionsFromFiles([]string{general, themed}); err != nil {
+		t.Fatalf("error combinando archivos: %v", err)
+	}
+
+	if count, err := s.GetQuestionCount(); err != nil || count != 5 {
+		t.Fatalf("esperaba 5 preguntas, hay %d (%v)", count, err)
+	}
+	plan, err := s.GetQuestionPlan()
+	if err != nil {
+		t.Fatalf("error obteniendo plan: %v", err)
+	}
+	if fmt.Sprint(plan) != "[1 2 3 4 5]" {
+		t.Fatalf("el plan debe seguir el orden de los archivos: %v", plan)
+	}
+}
+
+func TestLoadQuestionsFromFilesRejectsCollisions(t *testing.T) {
+	questions := testQuestions(5)
+	general := writeQuestionsFile(t, "general.json", questions[:3])
+	colliding := writeQuestionsFile(t, "repetidas.json", questions[2:])
+
+	s, _ := newTestQuestionService(t, testQuestions(2))
+	err := s.LoadQuestionsFromFiles([]string{general, colliding})
+	if !errors.Is(err, ErrQuestionIDCollision) {
+		t.Fatalf("esperaba ErrQuestionIDCollision, obtuve %v", err)
+	}
+	if !strings.Contains(err.Error(), "ID 3") || !strings.Contains(err.Error(), "repetidas.json") {
+		t.Fatalf("el error debe indicar el ID y los archivos: %v", err)
+	}
+
+	// Con colisiones no se carga nada: queda el banco anterior
+	if count, _ := s.GetQuestionCount(); count != 2 {
+		t.Fatalf("no debía cambiar el banco cargado, hay %d preguntas", count)
+	}
+}
+
+func TestLoadQuestionsFromFilesSingleFile(t *testing.T) {
+	s := NewQuestionService(redis.NewMemoryStore())
+	if err := s.LoadQuestionsFromFiles([]string{writeQuestionsFile(t, "questions.json", testQuestions(4))}); err != nil {
+		t.Fatalf("error cargando un solo archivo: %v", err)
+	}
+	if count, _ := s.GetQuestionCount(); count != 4 {
+		t.Fatalf("esperaba 4 preguntas, hay %d", count)
+	}
+}
+
+func TestGetAnswerKeyDistribution(t *testing.T) {
+	questions := testQuestions(6)
+	for i, key := range []struct {
+		correct    string
+		difficulty int
+	}{{"A", 1}, {"A", 1}, {"B", 1}, {"B", 3}, {"B", 3}, {"D", 3}} {
+		questions[i].Correct = key.correct
+		questions[i].Difficulty = key.difficulty
+	}
+	s, _ := newTestQuestionService(t, questions)
+
+	distribution, err := s.GetAnswerKeyDistribution()
+	if err != nil {
+		t.Fatalf("error calculando distribución: %v", err)
+	}
+
+	// formatGroup resume un grupo como "total: opción=cantidad(porcentaje) ..."
+	formatGroup := func(group models.AnswerKeyGroup) string {
+		parts := []string{fmt.Sprint(group.Total) + ":"}
+		for _, option := range group.Options {
+			parts = append(parts, fmt.Sprintf("%s=%d(%.1f)", option.Option, option.Count, option.Percent))
+		}
+		return strings.Join(parts, " ")
+	}
+
+	// Las opciones que nunca son correctas también aparecen, con 0
+	if got := formatGroup(distribution.Overall); got != "6: A=2(33.3) B=3(50.0) C=0(0.0) D=1(16.7)" {
+		t.Fatalf("distribución general inesperada: %s", got)
+	}
+	if len(distribution.ByDifficulty) != 2 || distribution.ByDifficulty[0].Difficulty != 1 || distribution.ByDifficulty[1].Difficulty != 3 {
+		t.Fatalf("esperaba las dificultades 1 y 3 en orden: %+v", distribution.ByDifficulty)
+	}
+	if got := formatGroup(distribution.ByDifficulty[0]); got != "3: A=2(66.7) B=1(33.3) C=0(0.0) D=0(0.0)" {
+		t.Fatalf("dificultad 1 inesperada: %s", got)
+	}
+	if got := formatGroup(distribution.ByDifficulty[1]); got != "3: A=0(0.0) B=2(66.7) C=0(0.0) D=1(33.3)" {
+		t.Fatalf("dificultad 3 inesperada: %s", got)
+	}
+}
+
+func TestResetPlayCountsRestoresUniformSelection(t *testing.T) {
+	s, store := newTestQuestionService(t, testQuestions(4))
+	for id, plays := range map[string]int64{"1": 50, "2": 3, "3": 7} {
+		if _, err := store.IncrementHashField(questionPlaysKey, id, plays); err != nil {
+			t.Fatalf("error sesgando jugadas: %v", err)
+		}
+	}
+
+	if err := s.ResetPlayCounts(); err != nil {
+		t.Fatalf("error reiniciando conteo: %v", err)
+	}
+	counts, err := s.playCounts()
+	if err != nil || len(counts) != 0 {
+		t.Fatalf("tras reiniciar no debe quedar ninguna jugada: %v (%v)", counts, err)
+	}
+
+	// Con todos los contadores en cero cada pregunta pesa lo mismo
+	ids := []int{1, 2, 3, 4}
+	weights, total := playWeights(ids, counts)
+	picks := make([]int, len(ids))
+	for i := 0; i < 400; i++ {
+		picks[pickWeighted(weights, total, float64(i)/400)]++
+	}
+	for i, n := range picks {
+		if n != 100 {
+			t.Fatalf("pregunta %d elegida %d veces, esperaba reparto uniforme: %v", ids[i], n, picks)
+		}
+	}
+}
+
+func TestPlayCountsPrunedAfterRetention(t *testing.T) {
+	s, store := newTestQuestionService(t, testQuestions(2))
+	s.SetStatsRetention(time.Hour)
+	if _, err := store.IncrementHashField(questionPlaysKey, "1", 10); err != nil {
+		t.Fatalf("error sesgando jugadas: %v", err)
+	}
+
+	// Dentro de la retención el conteo se conserva
+	if err := store.Set(questionPlaysSinceKey, models.FormatTime(time.Now().Add(-30*time.Minute)), 0); err != nil {
+		t.Fatalf("error fijando inicio del conteo: %v", err)
+	}
+	if counts, _ := s.playCounts(); counts["1"] != "10" {
+		t.Fatalf("dentro de la retención se esperaba conservar el conteo: %v", counts)
+	}
+
+	// Vencida la retención se reinicia
+	if err := store.Set(questionPlaysSinceKey, models.FormatTime(time.Now().Add(-2*time.Hour)), 0); err != nil {
+		t.Fatalf("error fijando inicio del conteo: %v", err)
+	}
+	if counts, _ := s.playCounts(); len(counts) != 0 {
+		t.Fatalf("vencida la retención se esperaba un conteo vacío: %v", counts)
+	}
+}
+
+// outOfRangeQuestions 4 preguntas de prueba con las dificultades 0 (ID 2) y 99 (ID 3)
+func outOfRangeQuestions() []models.Question {
+	questions := testQuestions(4)
+	questions[1].Difficulty = 0
+	questions[2].Difficulty = 99
+	return questions
+}
+
+func TestLoadRejectsOutOfRangeDifficulties(t *testing.T) {
+	store := redis.NewMemoryStore()
+	s := NewQuestionService(store)
+	s.SetDifficultyRange(1, 5, false)
+
+	err := s.LoadQuestionsFromFile(writeQuestionsFile(t, "questions.json", outOfRangeQuestions()))
+	if !errors.Is(err, ErrDifficultyOutOfRange) {
+		t.Fatalf("esperaba ErrDifficultyOutOfRange, obtuve %v", err)
+	}
+	// El error informa cada pregunta fuera de rango
+	for _, want := range []string{"(1-5)", "ID 2: 0", "ID 3: 99"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("el reporte debe incluir %q: %v", want, err)
+		}
+	}
+	if ids, _ := store.GetSetMembers("question_ids"); len(ids) != 0 {
+		t.Fatalf("una carga rechazada no debe guardar preguntas: %v", ids)
+	}
+}
+
+func TestLoadClampsOutOfRangeDifficulties(t *testing.T) {
+	// Por defecto las dificultades fuera de rango se ajustan
+	s, _ := newTestQuestionService(t, outOfRangeQuestions())
+
+	for id, want := range map[int]int{1: 1, 2: 1, 3: 5, 4: 4} {
+		question, err := s.GetQuestion(id)
+		if err != nil {
+			t.Fatalf("error obteniendo pregunta %d: %v", id, err)
+		}
+		if question.Difficulty != want {
+			t.Fatalf("pregunta %d: dificultad %d, esperaba %d", id, question.Difficulty, want)
+		}
+	}
+
+	// Con un rango configurado se ajusta a sus extremos
+	store := redis.NewMemoryStore()
+	narrow := NewQuestionService(store)
+	narrow.SetDifficultyRange(2, 3, true)
+	if err := narrow.LoadQuestionsFromFile(writeQuestionsFile(t, "questions.json", outOfRangeQuestions())); err != nil {
+		t.Fatalf("error cargando preguntas: %v", err)
+	}
+	if questions, _ := narrow.GetQuestionsByDifficulty(2, 3); len(questions) != 4 {
+		t.Fatalf("todas las preguntas deben quedar dentro de 2-3: %+v", questions)
+	}
+}
+
+func TestSaveRejectsOutOfRangeDifficulties(t *testing.T) {
+	s, _ := newTestQuestionService(t, testQuestions(2))
+
+	for _, difficulty := range []int{0, 6, 99} {
+		if err := s.UpdateDifficulty(1, difficulty); !errors.Is(err, ErrDifficultyOutOfRange) {
+			t.Fatalf("UpdateDifficulty(%d): esperaba ErrDifficultyOutOfRange, obtuve %v", difficulty, err)
+		}
+		if _, err := s.PatchQuestion(1, models.QuestionPatch{Difficulty: &difficulty}); !errors.Is(err, ErrDifficultyOutOfRange) {
+			t.Fatalf("PatchQuestion(%d): esperaba ErrDifficultyOutOfRange, obtuve %v", difficulty, err)
+		}
+	}
+	if question, _ := s.GetQuestion(1); question.Difficulty != 1 {
+		t.Fatalf("una dificultad rechazada no debe guardarse: %d", question.Difficulty)
+	}
+	if err := s.UpdateDifficulty(1, 5); err != nil {
+		t.Fatalf("una dificultad dentro del rango debe aceptarse: %v", err)
+	}
+}
+
+// partialLoadStore simula una carga parcial: las preguntas de failIDs no se
+// guardan, como cuando falla su escritura en Redis, pero los metadatos sí
+type partialLoadStore struct {
+	*redis.MemoryStore
+	failIDs map[int]bool
+}
+
+func (s *partialLoadStore) LoadQuestionsFromJSON(jsonData []byte) error {
+	var data redis.QuestionsData
+	if err := json.Unmarshal(jsonData, &data); err != nil {
+		return err
+	}
+	saved := data.Questions[:0]
+	for _, question := range data.Questions {
+		if !s.failIDs[question.ID] {
+			saved = append(saved, question)
+		}
+	}
+	data.Questions = saved
+	filtered, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return s.MemoryStore.LoadQuestionsFromJSON(filtered)
+}
+
+// writeQuestionsFileWithTotal escribe las preguntas declarando total en los metadatos
+func writeQuestionsFileWithTotal(t *testing.T, questions []models.Question, total int) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"questions": questions,
+		"metadata":  map[string]interface{}{"totalQuestions": total, "version": "1.0"},
+	})
+	if err != nil {
+		t.Fatalf("error serializando preguntas: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "questions.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("error escribiendo %s: %v", path, err)
+	}
+	return path
+}
+
+func TestQuestionMetadataReportsCountMismatch(t *testing.T) {
+	store := &partialLoadStore{MemoryStore: redis.NewMemoryStore(), failIDs: map[int]bool{2: true, 5: true}}
+	s := NewQuestionService(store)
+	if err := s.LoadQuestionsFromFile(writeQuestionsFileWithTotal(t, testQuestions(6), 6)); err != nil {
+		t.Fatalf("error cargando preguntas: %v", err)
+	}
+
+	metadata, err := s.GetQuestionMetadata()
+	if err != nil {
+		t.Fatalf("error obteniendo metadatos: %v", err)
+	}
+	mismatch, ok := metadata.(map[string]interface{})["countMismatch"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("una carga parcial debe reportar countMismatch: %v", metadata)
+	}
+	if mismatch["expected"] != 6 || mismatch["loaded"] != 4 || mismatch["message"] == "" {
+		t.Fatalf("countMismatch inesperado: %v", mismatch)
+	}
+}
+
+func TestQuestionMetadataWithoutMismatch(t *testing.T) {
+	// Carga completa
+	s := NewQuestionService(redis.NewMemoryStore())
+	if err := s.LoadQuestionsFromFile(writeQuestionsFileWithTotal(t, testQuestions(6), 6)); err != nil {
+		t.Fatalf("error cargando preguntas: %v", err)
+	}
+	if metadata, _ := s.GetQuestionMetadata(); metadata.(map[string]interface{})["countMismatch"] != nil {
+		t.Fatalf("una carga completa no debe reportar diferencias: %v", metadata)
+	}
+
+	// Un archivo sin total declarado no tiene con qué compararse
+	s, _ = newTestQuestionService(t, testQuestions(6))
+	if metadata, _ := s.GetQuestionMetadata(); metadata.(map[string]interface{})["countMismatch"] != nil {
+		t.Fatalf("sin total declarado no debe reportar diferencias: %v", metadata)
+	}
+}
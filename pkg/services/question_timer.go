@@ -0,0 +1,23 @@
+package services
+
+import "time"
+
+// QuestionTimer calcula el tiempo para responder cada pregunta: un valor fijo
+// o, si hay un mapeo configurado, uno según la dificultad de la pregunta
+type QuestionTimer struct {
+	Default      time.Duration
+	ByDifficulty map[int]time.Duration
+}
+
+// DefaultQuestionTimer tiempo fijo de 30 segundos para todas las preguntas
+func DefaultQuestionTimer() QuestionTimer {
+	return QuestionTimer{Default: 30 * time.Second}
+}
+
+// DurationFor devuelve el tiempo para una pregunta de la dificultad indicada
+func (t QuestionTimer) DurationFor(difficulty int) time.Duration {
+	if d, ok := t.ByDifficulty[difficulty]; ok {
+		return d
+	}
+	return t.Default
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuestionTimerDurationFor(t *testing.T) {
+	fixed := DefaultQuestionTimer()
+	for difficulty := 1; difficulty <= 5; difficulty++ {
+		if got := fixed.DurationFor(difficulty); got != 30*time.Second {
+			t.Errorf("modo fijo, dificultad %d: %v, esperaba 30s", difficulty, got)
+		}
+	}
+
+	byDifficulty := QuestionTimer{
+		Default: 20 * time.Second,
+		ByDifficulty: map[int]time.Duration{
+			1: 10 * time.Second,
+			5: 60 * time.Second,
+		},
+	}
+	cases := map[int]time.Duration{
+		1: 10 * time.Second,
+		3: 20 * time.Second, // sin mapeo: tiempo por defecto
+		5: 60 * time.Second,
+	}
+	for difficulty, want := range cases {
+		if got := byDifficulty.DurationFor(difficulty); got != want {
+			t.Errorf("dificultad %d: %v, esperaba %v", difficulty, got, want)
+		}
+	}
+}
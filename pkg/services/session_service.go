@@ -1,9 +1,18 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/backsoul/quiz/pkg/models"
@@ -11,29 +20,152 @@ import (
 	"github.com/google/uuid"
 )
 
+// ErrAnswerNotFound indica que la sesión no tiene respuesta para esa pregunta
+var ErrAnswerNotFound = errors.New("respuesta no encontrada")
+
+// ErrSessionCorrupt indica que el JSON almacenado de una sesión no se puede interpretar
+var ErrSessionCorrupt = errors.New("sesión corrupta")
+
+// ErrGameFull indica que se alcanzó el máximo de jugadores simultáneos
+var ErrGameFull = errors.New("la partida está llena")
+
+// ErrPlayerNameTaken indica que el nombre ya tiene una sesión activa de otra persona
+var ErrPlayerNameTaken = errors.New("el nombre de jugador ya está en uso")
+
+// ErrNameReserved indica que el nombre está pre-registrado y el código no coincide
+var ErrNameReserved = errors.New("el nombre está reservado")
+
+// ErrInvalidSessionToken indica que falta el token de la sesión o no coincide
+var ErrInvalidSessionToken = errors.New("token de sesión inválido")
+
+// ErrInvalidAdjustment indica una corrección de premio mal formada
+var ErrInvalidAdjustment = errors.New("corrección de premio inválida")
+
+// ErrNegativePrize indica que la corrección dejaría el premio en negativo
+var ErrNegativePrize = errors.New("el premio no puede quedar en negativo")
+
+// ErrSessionNotPlaying indica que la sesión ya no puede responder (eliminada o terminada)
+var ErrSessionNotPlaying = errors.New("la sesión no está en juego")
+
+// ErrRejoinNotAllowed indica que no se permite reingresar (eliminación estricta o sesión no eliminada)
+var ErrRejoinNotAllowed = errors.New("no se permite volver a entrar a la partida")
+
+// ErrRejoinWindowExpired indica que pasó el tiempo permitido para reingresar tras la eliminación
+var ErrRejoinWindowExpired = errors.New("pasó el tiempo para volver a entrar a la partida")
+
 // SessionService maneja las sesiones de los jugadores
 type SessionService struct {
-	redisClient *redis.RedisClient
+	redisClient  redis.RedisStore
+	maxPlayers   int
+	maxQuestions int
+	maxPrize     int64
+	autoContinue bool
+	sessionTTL   time.Duration
+	rejoinWindow time.Duration // 0 = eliminación estricta, sin reingreso
+
+	// Vidas de las sesiones nuevas, ajustables en caliente
+	livesMutex sync.RWMutex
+	lives      int
+
+	// Tabla de posiciones precalculada por RunLeaderboardWorker
+	leaderboardMutex sync.RWMutex
+	leaderboard      *models.LeaderboardResponse
+	leaderboardDirty chan struct{}
 }
 
 // NewSessionService crea una nueva instancia del servicio de sesiones
-func NewSessionService(redisClient *redis.RedisClient) *SessionService {
+func NewSessionService(redisClient redis.RedisStore) *SessionService {
 	return &SessionService{
-		redisClient: redisClient,
+		redisClient:  redisClient,
+		maxQuestions: 8,
+		maxPrize:     models.MaxSafePrize,
+		autoContinue: true,
+		sessionTTL:   24 * time.Hour,
+		lives:        1,
+
+		leaderboardDirty: make(chan struct{}, 1),
 	}
 }
 
-// CreateSession crea una nueva sesión para un jugador
-func (s *SessionService) CreateSession(playerName string) (*models.GameSession, error) {
-	// Verificar si ya existe una sesión activa para este jugador
-	existingSession, err := s.GetActiveSessionByPlayer(playerName)
+// SetMaxPlayers configura el máximo de jugadores activos simultáneos (0 = sin límite)
+func (s *SessionService) SetMaxPlayers(maxPlayers int) {
+	s.maxPlayers = maxPlayers
+}
+
+// SetMaxQuestions configura cuántas preguntas hay que superar para terminar la partida
+func (s *SessionService) SetMaxQuestions(maxQuestions int) {
+	s.maxQuestions = maxQuestions
+}
+
+// SetMaxPrize configura el tope de los premios (nunca mayor que models.MaxSafePrize)
+func (s *SessionService) SetMaxPrize(maxPrize int64) {
+	s.maxPrize = maxPrize
+}
+
+// SetLives configura cuántas respuestas incorrectas elimina a un jugador (1 = eliminación inmediata)
+func (s *SessionService) SetLives(lives int) {
+	s.livesMutex.Lock()
+	s.lives = lives
+	s.livesMutex.Unlock()
+}
+
+// startingLives devuelve las vidas vigentes para una sesión nueva
+func (s *SessionService) startingLives() int {
+	s.livesMutex.RLock()
+	defer s.livesMutex.RUnlock()
+	return s.lives
+}
+
+// SetRejoinWindow permite que un jugador eliminado vuelva a entrar durante ese
+// tiempo desde su eliminación (0 = eliminación estricta)
+func (s *SessionService) SetRejoinWindow(window time.Duration) {
+	s.rejoinWindow = window
+}
+
+// SetSessionTTL define cuánto tiempo se conservan las sesiones en Redis
+func (s *SessionService) SetSessionTTL(ttl time.Duration) {
+	s.sessionTTL = ttl
+}
+
+// SetAutoContinue define si un nombre repetido continúa la sesión activa
+// existente (por defecto) o si exige el ID de esa sesión para reconectarse
+func (s *SessionService) SetAutoContinue(autoContinue bool) {
+	s.autoContinue = autoContinue
+}
+
+// CreateSession crea una nueva sesión para un jugador en el modo indicado
+// ("live", "practice" o "wager"). Las sesiones de práctica no ocupan cupo ni entran
+// al set de sesiones activas. reconnectID es el ID de la sesión previa del
+// cliente, necesario para continuarla cuando el auto-continuar está desactivado.
+// El bool indica si la sesión es nueva (false al continuar una existente).
+func (s *SessionService) CreateSession(playerName, mode, reconnectID, claimCode string) (*models.GameSession, bool, error) {
+	practice := mode == models.SessionModePractice
+
+	// Los nombres pre-registrados solo se pueden usar con su código
+	reserved, err := s.checkReservation(playerName, claimCode)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Verificar si ya existe una sesión activa para este jugador en el mismo modo
+	existingSession, err := s.getActiveSessionByPlayer(playerName, practice)
 	if err == nil && existingSession != nil {
+		if !s.autoContinue && existingSession.ID != reconnectID {
+			return nil, false, ErrPlayerNameTaken
+		}
 		log.Printf("🔄 Jugador %s ya tiene una sesión activa, continuando...", playerName)
-		return existingSession, nil
+		return existingSession, false, nil
 	}
 
-	// Crear nueva sesión
+	// Reservar el lugar en las sesiones activas (las de práctica no cuentan)
 	sessionID := uuid.New().String()
+	if !practice {
+		if err := s.reserveActiveSlot(sessionID, reserved); err != nil {
+			return nil, false, err
+		}
+	}
+
+	// Crear nueva sesión
 	session := &models.GameSession{
 		ID:                sessionID,
 		PlayerName:        playerName,
@@ -42,19 +174,22 @@ func (s *SessionService) CreateSession(playerName string) (*models.GameSession,
 		LifelinesUsed:     models.LifelinesState{},
 		AnswersGiven:      []models.PlayerAnswer{},
 		GameStatus:        "active",
-		StartTime:         time.Now(),
-		LastActivity:      time.Now(),
-		CurrentQuestionID: 0,
+		StartTime:         time.Now().UTC(),
+		LastActivity:      time.Now().UTC(),
+		CurrentQuestionID: s.questionIDForNumber(1),
+		Mode:              models.SessionModeLive,
+		LivesRemaining:    s.startingLives(),
+	}
+	if mode == models.SessionModePractice || mode == models.SessionModeWager {
+		session.Mode = mode
 	}
 
 	// Guardar en Redis
 	if err := s.saveSession(session); err != nil {
-		return nil, fmt.Errorf("error guardando sesión: %v", err)
-	}
-
-	// Agregar a la lista de sesiones activas
-	if err := s.addToActiveSessions(sessionID); err != nil {
-		log.Printf("⚠️ Error agregando a sesiones activas: %v", err)
+		if !practice {
+			s.removeFromActiveSessions(sessionID)
+		}
+		return nil, false, fmt.Errorf("error guardando sesión: %v", err)
 	}
 
 	// Agregar a las sesiones del jugador
@@ -63,52 +198,293 @@ func (s *SessionService) CreateSession(playerName string) (*models.GameSession,
 	}
 
 	log.Printf("✅ Nueva sesión creada para %s (ID: %s)", playerName, sessionID)
-	return session, nil
+	return session, true, nil
+}
+
+// PreregisterPlayers reserva nombres de jugadores y genera el código con el
+// que cada participante reclamará su nombre. Reservar un nombre ya reservado
+// no cambia su código.
+func (s *SessionService) PreregisterPlayers(names []string) ([]models.PlayerReservation, error) {
+	reservations := make([]models.PlayerReservation, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		code := strings.ToUpper(uuid.New().String()[:8])
+		created, err := s.redisClient.SetHashFieldIfAbsent("reserved_players", name, code)
+		if err != nil {
+			return nil, fmt.Errorf("error reservando %s: %v", name, err)
+		}
+
+		reservation := models.PlayerReservation{PlayerName: name, Created: created}
+		if created {
+			reservation.ClaimCode = code
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	log.Printf("📋 %d nombres pre-registrados", len(reservations))
+	return reservations, nil
+}
+
+// checkReservation indica si el nombre está reservado y valida su código
+func (s *SessionService) checkReservation(playerName, claimCode string) (bool, error) {
+	code, err := s.redisClient.GetHashField("reserved_players", playerName)
+	if err != nil {
+		if err.Error() == "redis: nil" {
+			return false, nil
+		}
+		return false, fmt.Errorf("error verificando reserva: %v", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.ToUpper(claimCode)), []byte(code)) != 1 {
+		return true, ErrNameReserved
+	}
+	return true, nil
+}
+
+// IssueSessionToken genera un token secreto para la sesión y guarda solo su
+// hash. Emitir un token nuevo invalida el anterior.
+func (s *SessionService) IssueSessionToken(sessionID string) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("error generando token: %v", err)
+	}
+	token := hex.EncodeToString(secret)
+
+	if err := s.redisClient.Set(sessionTokenKey(sessionID), hashToken(token), s.sessionTTL); err != nil {
+		return "", fmt.Errorf("error guardando token: %v", err)
+	}
+
+	return token, nil
+}
+
+// VerifySessionToken comprueba el token enviado contra el hash guardado
+func (s *SessionService) VerifySessionToken(sessionID, token string) error {
+	if token == "" {
+		return ErrInvalidSessionToken
+	}
+
+	storedHash, err := s.redisClient.Get(sessionTokenKey(sessionID))
+	if err != nil {
+		if err.Error() == "redis: nil" {
+			return ErrInvalidSessionToken
+		}
+		return fmt.Errorf("error verificando token: %v", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(storedHash)) != 1 {
+		return ErrInvalidSessionToken
+	}
+	return nil
+}
+
+func sessionTokenKey(sessionID string) string {
+	return fmt.Sprintf("session_token:%s", sessionID)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 // GetSession obtiene una sesión por ID
 func (s *SessionService) GetSession(sessionID string) (*models.GameSession, error) {
-	sessionJSON, err := s.redisClient.Get(fmt.Sprintf("quiz:session:%s", sessionID))
+	sessionJSON, err := s.redisClient.Get(fmt.Sprintf("session:%s", sessionID))
 	if err != nil {
 		return nil, fmt.Errorf("sesión no encontrada: %v", err)
 	}
 
 	var session models.GameSession
 	if err := json.Unmarshal([]byte(sessionJSON), &session); err != nil {
-		return nil, fmt.Errorf("error parsing sesión: %v", err)
+		return nil, fmt.Errorf("%w: error parsing sesión: %v", ErrSessionCorrupt, err)
 	}
 
+	// El indicador de conexión vive fuera del JSON (ver SetConnected)
+	_, err = s.redisClient.Get(sessionConnectedKey(sessionID))
+	session.Connected = err == nil
+
 	return &session, nil
 }
 
-// GetActiveSessionByPlayer obtiene la sesión activa de un jugador
+// GetActiveSessionByPlayer obtiene la sesión activa (en vivo) de un jugador
 func (s *SessionService) GetActiveSessionByPlayer(playerName string) (*models.GameSession, error) {
+	return s.getActiveSessionByPlayer(playerName, false)
+}
+
+// getActiveSessionByPlayer devuelve la sesión activa más reciente del jugador.
+// Si quedaron varias activas (p. ej. por una limpieza incompleta), las más
+// antiguas se dan por terminadas para reparar la inconsistencia.
+func (s *SessionService) getActiveSessionByPlayer(playerName string, practice bool) (*models.GameSession, error) {
 	// Obtener las sesiones del jugador
 	sessionIDs, err := s.getPlayerSessions(playerName)
 	if err != nil {
 		return nil, err
 	}
 
-	// Buscar una sesión activa
+	// Buscar las sesiones activas
+	var active []*models.GameSession
 	for _, sessionID := range sessionIDs {
 		session, err := s.GetSession(sessionID)
 		if err != nil {
 			continue
 		}
-		if session.GameStatus == "active" {
-			return session, nil
+		if session.GameStatus == "active" && session.IsPractice() == practice {
+			active = append(active, session)
 		}
 	}
 
-	return nil, fmt.Errorf("no se encontró sesión activa para %s", playerName)
+	if len(active) == 0 {
+		return nil, fmt.Errorf("no se encontró sesión activa para %s", playerName)
+	}
+
+	sort.SliceStable(active, func(i, j int) bool {
+		if !active[i].LastActivity.Equal(active[j].LastActivity) {
+			return active[i].LastActivity.After(active[j].LastActivity)
+		}
+		if !active[i].StartTime.Equal(active[j].StartTime) {
+			return active[i].StartTime.After(active[j].StartTime)
+		}
+		return active[i].ID < active[j].ID
+	})
+
+	for _, duplicate := range active[1:] {
+		log.Printf("⚠️ %s tenía varias sesiones activas, terminando la más antigua (ID: %s)", playerName, duplicate.ID)
+		if err := s.FinishSession(duplicate.ID); err != nil {
+			log.Printf("⚠️ Error terminando sesión duplicada %s: %v", duplicate.ID, err)
+		}
+	}
+
+	return active[0], nil
 }
 
 // UpdateSession actualiza una sesión existente
 func (s *SessionService) UpdateSession(session *models.GameSession) error {
-	session.LastActivity = time.Now()
+	session.LastActivity = time.Now().UTC()
 	return s.saveSession(session)
 }
 
+// SetCurrentQuestionID fija la pregunta servida a la sesión, contra la que se
+// valida la siguiente respuesta
+func (s *SessionService) SetCurrentQuestionID(sessionID string, questionID int) (*models.GameSession, error) {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	session.CurrentQuestionID = questionID
+	if err := s.UpdateSession(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// SelectOption guarda la opción que el jugador está considerando para su
+// pregunta actual, sin puntuarla; una nueva selección reemplaza la anterior
+func (s *SessionService) SelectOption(sessionID string, questionID int, option string) (*models.GameSession, error) {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.GameStatus != "active" {
+		return nil, ErrSessionNotPlaying
+	}
+
+	session.TentativeSelection = &models.TentativeSelection{
+		QuestionID:     questionID,
+		QuestionNumber: session.CurrentQuestion,
+		Option:         option,
+		SelectedAt:     time.Now().UTC(),
+	}
+	if err := s.UpdateSession(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// SetConnected marca si el jugador tiene su WebSocket abierto; no cuenta como
+// actividad ni cambia el estado de juego de la sesión. El indicador se guarda
+// en su propia clave y no en el JSON de la sesión, para que abrir o cerrar un
+// WebSocket no reescriba la sesión y pise una respuesta guardada a la vez.
+func (s *SessionService) SetConnected(sessionID string, connected bool) (*models.GameSession, error) {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	key := sessionConnectedKey(sessionID)
+	if connected {
+		err = s.redisClient.Set(key, "1", s.sessionTTL)
+	} else {
+		err = s.redisClient.Delete(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error guardando conexión de la sesión %s: %v", sessionID, err)
+	}
+	session.Connected = connected
+	return session, nil
+}
+
+func sessionConnectedKey(sessionID string) string {
+	return fmt.Sprintf("session_connected:%s", sessionID)
+}
+
+// RejoinSession devuelve a juego una sesión eliminada, con una vida, en la
+// pregunta currentQuestion de la partida (o la siguiente a su última respuesta)
+func (s *SessionService) RejoinSession(sessionID string, currentQuestion int) (*models.GameSession, error) {
+	if s.rejoinWindow <= 0 {
+		return nil, ErrRejoinNotAllowed
+	}
+
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.GameStatus != "eliminated" || session.IsPractice() {
+		return nil, fmt.Errorf("%w: la sesión no está eliminada", ErrRejoinNotAllowed)
+	}
+
+	eliminatedAt := session.LastActivity
+	lastAnswered := 0
+	for _, answer := range session.AnswersGiven {
+		if answer.QuestionNumber > lastAnswered {
+			lastAnswered = answer.QuestionNumber
+		}
+		if !answer.IsCorrect && !answer.Voided {
+			eliminatedAt = answer.Timestamp
+		}
+	}
+	if time.Since(eliminatedAt) > s.rejoinWindow {
+		return nil, ErrRejoinWindowExpired
+	}
+
+	if currentQuestion <= lastAnswered {
+		currentQuestion = lastAnswered + 1
+	}
+	session.GameStatus = "active"
+	session.LivesRemaining = 1
+	session.CurrentQuestion = currentQuestion
+	session.CurrentQuestionID = s.questionIDForNumber(currentQuestion)
+	session.TentativeSelection = nil
+	session.Rejoins = append(session.Rejoins, models.Rejoin{
+		QuestionNumber: currentQuestion,
+		Timestamp:      time.Now().UTC(),
+	})
+
+	if err := s.UpdateSession(session); err != nil {
+		return nil, err
+	}
+	if err := s.addToActiveSessions(session.ID); err != nil {
+		return nil, err
+	}
+	s.reopenRound()
+
+	log.Printf("🔁 %s volvió a entrar en la pregunta %d", session.PlayerName, currentQuestion)
+	return session, nil
+}
+
 // AddAnswer agrega una respuesta a la sesión
 func (s *SessionService) AddAnswer(sessionID string, answer models.PlayerAnswer) error {
 	session, err := s.GetSession(sessionID)
@@ -116,24 +492,400 @@ func (s *SessionService) AddAnswer(sessionID string, answer models.PlayerAnswer)
 		return err
 	}
 
-	// Agregar la respuesta
+	// Las respuestas a una pregunta anulada no cuentan
+	voided, err := s.IsQuestionVoided(answer.QuestionNumber)
+	if err != nil {
+		return err
+	}
+	if voided {
+		answer.Voided = true
+		answer.PrizeWon = 0
+		answer.Wager = 0
+	} else if session.IsWager() {
+		applyWager(session, &answer, s.maxPrize)
+	} else {
+		answer.Wager = 0
+	}
+
+	// Agregar la respuesta; la selección provisional queda confirmada
 	session.AnswersGiven = append(session.AnswersGiven, answer)
+	session.TentativeSelection = nil
 
-	// Actualizar pregunta actual si es correcta
-	if answer.IsCorrect {
+	if answer.Voided {
+		session.CurrentQuestion++
+		session.CurrentQuestionID = s.questionIDForNumber(session.CurrentQuestion)
+	} else if session.IsWager() {
+		// En modo apuesta un error descuenta lo apostado en lugar de eliminar
 		session.CurrentQuestion++
-		session.TotalPrize = answer.PrizeWon
+		session.CurrentQuestionID = s.questionIDForNumber(session.CurrentQuestion)
+	} else if answer.IsCorrect {
+		// Actualizar pregunta actual si es correcta
+		session.CurrentQuestion++
+		session.CurrentQuestionID = s.questionIDForNumber(session.CurrentQuestion)
+		session.TotalPrize = models.ClampPrize(answer.PrizeWon, s.maxPrize)
 	} else {
-		// Marcar como eliminado pero manteniendo en modo espectador
-		session.GameStatus = "eliminated"
+		// Descontar una vida; al quedarse sin vidas se marca como eliminado
+		// pero manteniendo en modo espectador
+		session.LivesRemaining--
+		if session.LivesRemaining > 0 {
+			session.CurrentQuestion++
+			session.CurrentQuestionID = s.questionIDForNumber(session.CurrentQuestion)
+		} else {
+			session.LivesRemaining = 0
+			session.GameStatus = "eliminated"
+		}
 	}
 
 	// Verificar si ganó el juego
-	if session.CurrentQuestion > 15 {
+	if session.CurrentQuestion > s.maxQuestions {
 		session.GameStatus = "finished"
 	}
 
-	return s.UpdateSession(session)
+	if err := s.UpdateSession(session); err != nil {
+		return err
+	}
+
+	// Sacar del set activo en el mismo paso para que no quede una sesión terminada listada como activa
+	if session.GameStatus == "finished" {
+		return s.removeFromActiveSessions(sessionID)
+	}
+
+	return nil
+}
+
+// ReverseAnswer anula la respuesta de la sesión a la pregunta número
+// questionNumber (p. ej. una pregunta impugnada) y reconstruye el premio, la
+// pregunta actual y el estado a partir de las respuestas restantes
+func (s *SessionService) ReverseAnswer(sessionID string, questionNumber int) (*models.GameSession, *models.PlayerAnswer, error) {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	index := -1
+	for i := len(session.AnswersGiven) - 1; i >= 0; i-- {
+		if session.AnswersGiven[i].QuestionNumber == questionNumber {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, nil, ErrAnswerNotFound
+	}
+
+	lives := initialLives(session)
+	reversed := session.AnswersGiven[index]
+	session.AnswersGiven = append(session.AnswersGiven[:index:index], session.AnswersGiven[index+1:]...)
+
+	if err := s.rebuildSession(session, lives); err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("↩️ Respuesta a la pregunta %d de %s anulada, premio recalculado: $%d", questionNumber, session.PlayerName, session.TotalPrize)
+	return session, &reversed, nil
+}
+
+// voidedQuestionsKey números de pregunta anulados en la partida en curso
+const voidedQuestionsKey = "voided_questions"
+
+// IsQuestionVoided indica si la pregunta con ese número fue anulada
+func (s *SessionService) IsQuestionVoided(questionNumber int) (bool, error) {
+	numbers, err := s.redisClient.GetSetMembers(voidedQuestionsKey)
+	if err != nil {
+		return false, err
+	}
+	for _, number := range numbers {
+		if number == strconv.Itoa(questionNumber) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// VoidQuestion anula una pregunta: las respuestas ya dadas a ella dejan de
+// sumar premio o eliminar (se recalcula cada sesión afectada) y las que lleguen
+// después se registran anuladas. Devuelve las sesiones afectadas.
+func (s *SessionService) VoidQuestion(questionNumber int) ([]models.GameSession, error) {
+	if err := s.redisClient.AddToSet(voidedQuestionsKey, strconv.Itoa(questionNumber)); err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.GetAllSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	affected := make([]models.GameSession, 0)
+	for i := range sessions {
+		session := &sessions[i]
+
+		// Las vidas iniciales se deducen antes de anular, cuando el error aún cuenta
+		lives := initialLives(session)
+		changed := false
+		for j := range session.AnswersGiven {
+			answer := &session.AnswersGiven[j]
+			if answer.QuestionNumber == questionNumber && !answer.Voided {
+				answer.Voided = true
+				answer.PrizeWon = 0
+				answer.Wager = 0
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		if err := s.rebuildSession(session, lives); err != nil {
+			return nil, err
+		}
+		affected = append(affected, *session)
+	}
+
+	log.Printf("🚫 Pregunta %d anulada: %d sesiones recalculadas", questionNumber, len(affected))
+	return affected, nil
+}
+
+// RestartSession devuelve la sesión al inicio (pregunta 1, sin respuestas,
+// premio, comodines ni correcciones) conservando su ID, jugador y modo
+func (s *SessionService) RestartSession(sessionID string) (*models.GameSession, error) {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	restarted := &models.GameSession{
+		ID:                session.ID,
+		PlayerName:        session.PlayerName,
+		CurrentQuestion:   1,
+		LifelinesUsed:     models.LifelinesState{},
+		AnswersGiven:      []models.PlayerAnswer{},
+		GameStatus:        "active",
+		StartTime:         now,
+		LastActivity:      now,
+		CurrentQuestionID: s.questionIDForNumber(1),
+		Mode:              session.Mode,
+		LivesRemaining:    s.startingLives(),
+		Connected:         session.Connected,
+	}
+	if err := s.saveSession(restarted); err != nil {
+		return nil, err
+	}
+
+	if err := s.redisClient.Delete(fmt.Sprintf("player:%s:answers", session.PlayerName)); err != nil {
+		log.Printf("⚠️ Error eliminando respuestas del jugador %s: %v", session.PlayerName, err)
+	}
+	if !restarted.IsPractice() {
+		if err := s.addToActiveSessions(restarted.ID); err != nil {
+			return nil, err
+		}
+		s.reopenRound()
+	}
+
+	log.Printf("🔄 Sesión de %s reiniciada desde la pregunta 1", session.PlayerName)
+	return restarted, nil
+}
+
+// RecomputeSession reconstruye los campos derivados de la sesión (premio,
+// pregunta actual, vidas y estado) solo a partir de sus respuestas, para
+// reparar desvíos por escrituras parciales. Indica si algo cambió.
+func (s *SessionService) RecomputeSession(sessionID string) (*models.GameSession, bool, error) {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	before := *session
+	if err := s.rebuildSession(session, initialLives(session)); err != nil {
+		return nil, false, err
+	}
+
+	changed := before.TotalPrize != session.TotalPrize ||
+		before.CurrentQuestion != session.CurrentQuestion ||
+		before.CurrentQuestionID != session.CurrentQuestionID ||
+		before.LivesRemaining != session.LivesRemaining ||
+		before.GameStatus != session.GameStatus
+	if changed {
+		log.Printf("🔧 Sesión %s de %s recalculada: premio $%d → $%d, pregunta %d → %d, estado %s → %s",
+			session.ID, session.PlayerName, before.TotalPrize, session.TotalPrize,
+			before.CurrentQuestion, session.CurrentQuestion, before.GameStatus, session.GameStatus)
+	}
+
+	return session, changed, nil
+}
+
+// rebuildSession recalcula los campos derivados de la sesión a partir de sus
+// respuestas, la guarda y ajusta su pertenencia al set de sesiones activas
+func (s *SessionService) rebuildSession(session *models.GameSession, lives int) error {
+	wasFinished := session.GameStatus == "finished"
+	manuallyFinished := wasFinished && session.CurrentQuestion <= s.maxQuestions
+
+	rebuildFromAnswers(session, lives, s.maxQuestions, s.maxPrize)
+	if manuallyFinished {
+		// Terminada con FinishSession, no por sus respuestas: se mantiene terminada
+		session.GameStatus = "finished"
+	}
+	session.CurrentQuestionID = s.questionIDForNumber(session.CurrentQuestion)
+
+	if err := s.UpdateSession(session); err != nil {
+		return err
+	}
+
+	if session.GameStatus == "finished" {
+		return s.removeFromActiveSessions(session.ID)
+	}
+	if wasFinished && !session.IsPractice() {
+		return s.addToActiveSessions(session.ID)
+	}
+	return nil
+}
+
+// initialLives deduce las vidas con que empezó la sesión: las que le quedan más
+// los errores que le costaron una (mínimo 1, como las sesiones anteriores a las vidas)
+func initialLives(session *models.GameSession) int {
+	// Cada reingreso dio una vida que no era de las iniciales
+	lives := session.LivesRemaining - len(session.Rejoins)
+	if !session.IsWager() {
+		for _, answer := range session.AnswersGiven {
+			if !answer.IsCorrect && !answer.Voided {
+				lives++
+			}
+		}
+	}
+	if lives < 1 {
+		lives = 1
+	}
+	return lives
+}
+
+// rebuildFromAnswers recalcula premio, pregunta actual, vidas y estado desde
+// cero aplicando las respuestas en orden, con las mismas reglas que AddAnswer
+func rebuildFromAnswers(session *models.GameSession, lives, maxQuestions int, maxPrize int64) {
+	session.CurrentQuestion = 1
+	session.TotalPrize = 0
+	session.LivesRemaining = lives
+	session.GameStatus = "active"
+	rejoins := session.Rejoins
+
+	// Cada reingreso devuelve a juego, con una vida, tras una eliminación
+	rejoin := func() {
+		if session.GameStatus == "eliminated" && len(rejoins) > 0 {
+			session.GameStatus = "active"
+			session.LivesRemaining = 1
+			session.CurrentQuestion = rejoins[0].QuestionNumber
+			rejoins = rejoins[1:]
+		}
+	}
+
+	for _, answer := range session.AnswersGiven {
+		rejoin()
+		if session.GameStatus != "active" {
+			break
+		}
+
+		if answer.Voided {
+			// Pregunta anulada: se pasa a la siguiente sin premio ni penalización
+			session.CurrentQuestion++
+		} else if session.IsWager() {
+			if answer.IsCorrect {
+				session.TotalPrize = models.ClampPrize(session.TotalPrize+answer.PrizeWon+answer.Wager, maxPrize)
+			} else {
+				session.TotalPrize -= answer.Wager
+			}
+			session.CurrentQuestion++
+		} else if answer.IsCorrect {
+			session.CurrentQuestion++
+			session.TotalPrize = models.ClampPrize(answer.PrizeWon, maxPrize)
+		} else {
+			session.LivesRemaining--
+			if session.LivesRemaining > 0 {
+				session.CurrentQuestion++
+			} else {
+				session.LivesRemaining = 0
+				session.GameStatus = "eliminated"
+			}
+		}
+
+		if session.CurrentQuestion > maxQuestions {
+			session.GameStatus = "finished"
+		}
+	}
+	rejoin()
+
+	// Las correcciones manuales del presentador se mantienen
+	for _, adjustment := range session.PrizeAdjustments {
+		session.TotalPrize += adjustment.NewPrize - adjustment.PreviousPrize
+	}
+	if session.TotalPrize < 0 {
+		session.TotalPrize = 0
+	}
+	session.TotalPrize = models.ClampPrize(session.TotalPrize, maxPrize)
+}
+
+// applyWager limita la apuesta al premio acumulado y lo actualiza: una respuesta
+// correcta suma el premio de la pregunta más lo apostado (hasta maxPrize), una incorrecta lo resta
+func applyWager(session *models.GameSession, answer *models.PlayerAnswer, maxPrize int64) {
+	if answer.Wager < 0 {
+		answer.Wager = 0
+	}
+	if answer.Wager > session.TotalPrize {
+		answer.Wager = session.TotalPrize
+	}
+
+	// Los montos no superan MaxSafePrize, así que la suma no desborda int64
+	if answer.IsCorrect {
+		session.TotalPrize = models.ClampPrize(session.TotalPrize+answer.PrizeWon+answer.Wager, maxPrize)
+	} else {
+		session.TotalPrize -= answer.Wager
+	}
+}
+
+// AdjustPrize corrige manualmente el premio de una sesión (disputas en vivo) y
+// deja registro de la corrección en la propia sesión
+func (s *SessionService) AdjustPrize(sessionID string, request models.AdjustPrizeRequest, admin string) (*models.GameSession, error) {
+	if (request.Delta == nil) == (request.NewValue == nil) {
+		return nil, fmt.Errorf("%w: use delta o newValue", ErrInvalidAdjustment)
+	}
+	if strings.TrimSpace(request.Reason) == "" {
+		return nil, fmt.Errorf("%w: el motivo es requerido", ErrInvalidAdjustment)
+	}
+
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	newPrize := session.TotalPrize
+	if request.Delta != nil {
+		if *request.Delta > s.maxPrize || *request.Delta < -s.maxPrize {
+			return nil, fmt.Errorf("%w: la corrección supera el premio máximo", ErrInvalidAdjustment)
+		}
+		newPrize = models.ClampPrize(newPrize+*request.Delta, s.maxPrize)
+	} else {
+		if *request.NewValue > s.maxPrize {
+			return nil, fmt.Errorf("%w: el premio supera el máximo (%s)", ErrInvalidAdjustment, models.FormatPrize(s.maxPrize))
+		}
+		newPrize = *request.NewValue
+	}
+	if newPrize < 0 {
+		return nil, ErrNegativePrize
+	}
+
+	session.PrizeAdjustments = append(session.PrizeAdjustments, models.PrizeAdjustment{
+		PreviousPrize: session.TotalPrize,
+		NewPrize:      newPrize,
+		Reason:        strings.TrimSpace(request.Reason),
+		Admin:         admin,
+		Timestamp:     time.Now().UTC(),
+	})
+	session.TotalPrize = newPrize
+
+	if err := s.UpdateSession(session); err != nil {
+		return nil, err
+	}
+
+	log.Printf("💰 Premio de %s corregido a $%d por %s", session.PlayerName, newPrize, admin)
+	return session, nil
 }
 
 // UseLifeline marca un comodín como usado
@@ -173,7 +925,7 @@ func (s *SessionService) GetPlayerHistory(playerName string) ([]models.GameSessi
 		return nil, err
 	}
 
-	var sessions []models.GameSession
+	sessions := make([]models.GameSession, 0, len(sessionIDs))
 	for _, sessionID := range sessionIDs {
 		session, err := s.GetSession(sessionID)
 		if err != nil {
@@ -188,15 +940,19 @@ func (s *SessionService) GetPlayerHistory(playerName string) ([]models.GameSessi
 
 // GetActiveSessions obtiene todas las sesiones activas
 func (s *SessionService) GetActiveSessions() ([]models.GameSession, error) {
-	sessionIDs, err := s.redisClient.GetSetMembers("quiz:active_sessions")
+	sessionIDs, err := s.redisClient.GetSetMembers("active_sessions")
 	if err != nil {
 		return nil, fmt.Errorf("error obteniendo sesiones activas: %v", err)
 	}
 
-	var sessions []models.GameSession
+	sessions := make([]models.GameSession, 0, len(sessionIDs))
 	for _, sessionID := range sessionIDs {
 		session, err := s.GetSession(sessionID)
 		if err != nil {
+			if errors.Is(err, ErrSessionCorrupt) {
+				s.quarantineSession(sessionID)
+				continue
+			}
 			log.Printf("⚠️ Error obteniendo sesión activa %s: %v", sessionID, err)
 			continue
 		}
@@ -215,6 +971,70 @@ func (s *SessionService) GetActiveSessions() ([]models.GameSession, error) {
 	return sessions, nil
 }
 
+// LookupPlayers devuelve el estado de cada nombre en el orden pedido. Las
+// sesiones activas se leen de una sola vez; solo para los nombres sin sesión
+// activa se consulta su historial, para distinguir terminados de desconocidos.
+func (s *SessionService) LookupPlayers(names []string) ([]models.PlayerLookupStatus, error) {
+	activeSessions, err := s.GetActiveSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	activeByName := make(map[string]models.GameSession, len(activeSessions))
+	for _, session := range activeSessions {
+		if session.IsPractice() {
+			continue
+		}
+		if current, ok := activeByName[session.PlayerName]; !ok || session.LastActivity.After(current.LastActivity) {
+			activeByName[session.PlayerName] = session
+		}
+	}
+
+	statuses := make([]models.PlayerLookupStatus, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if session, ok := activeByName[name]; ok {
+			statuses = append(statuses, playerLookupStatus(name, &session))
+			continue
+		}
+
+		history, err := s.GetPlayerHistory(name)
+		if err != nil {
+			return nil, err
+		}
+		var latest *models.GameSession
+		for i := range history {
+			if history[i].IsPractice() {
+				continue
+			}
+			if latest == nil || history[i].LastActivity.After(latest.LastActivity) {
+				latest = &history[i]
+			}
+		}
+		if latest == nil {
+			statuses = append(statuses, models.PlayerLookupStatus{PlayerName: name, GameStatus: "not_found"})
+			continue
+		}
+		statuses = append(statuses, playerLookupStatus(name, latest))
+	}
+
+	return statuses, nil
+}
+
+func playerLookupStatus(name string, session *models.GameSession) models.PlayerLookupStatus {
+	lastActivity := session.LastActivity
+	return models.PlayerLookupStatus{
+		PlayerName:      name,
+		GameStatus:      session.GameStatus,
+		SessionID:       session.ID,
+		CurrentQuestion: session.CurrentQuestion,
+		TotalPrize:      session.TotalPrize,
+		LivesRemaining:  session.LivesRemaining,
+		Connected:       session.Connected,
+		LastActivity:    &lastActivity,
+	}
+}
+
 // FinishSession termina una sesión
 func (s *SessionService) FinishSession(sessionID string) error {
 	session, err := s.GetSession(sessionID)
@@ -222,51 +1042,149 @@ func (s *SessionService) FinishSession(sessionID string) error {
 		return err
 	}
 
-	session.GameStatus = "finished"
-	session.LastActivity = time.Now()
-
-	// Actualizar sesión
-	if err := s.saveSession(session); err != nil {
+	session.GameStatus = "finished"
+	session.LastActivity = time.Now().UTC()
+
+	// Actualizar sesión
+	if err := s.saveSession(session); err != nil {
+		return err
+	}
+
+	// Remover de sesiones activas
+	return s.removeFromActiveSessions(sessionID)
+}
+
+// FinishAllActive termina todas las sesiones activas conservando su premio,
+// sin borrar ningún dato (a diferencia de ClearAllSessions). Devuelve las
+// sesiones terminadas.
+func (s *SessionService) FinishAllActive() ([]models.GameSession, error) {
+	sessions, err := s.GetActiveSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	finished := make([]models.GameSession, 0, len(sessions))
+	now := time.Now().UTC()
+	for _, session := range sessions {
+		session.GameStatus = "finished"
+		session.LastActivity = now
+		if err := s.saveSession(&session); err != nil {
+			log.Printf("⚠️ Error terminando sesión %s: %v", session.ID, err)
+			continue
+		}
+		if err := s.removeFromActiveSessions(session.ID); err != nil {
+			log.Printf("⚠️ Error removiendo sesión terminada %s: %v", session.ID, err)
+		}
+		finished = append(finished, session)
+	}
+
+	// Con el worker en marcha, la tabla en caché debe reflejar ya el cierre
+	s.leaderboardMutex.RLock()
+	cached := s.leaderboard != nil
+	s.leaderboardMutex.RUnlock()
+	if cached {
+		s.refreshLeaderboard()
+	}
+
+	return finished, nil
+}
+
+// Métodos privados auxiliares
+
+// questionIDForNumber resuelve el ID de la pregunta número N según el plan.
+// Devuelve 0 solo si el número queda fuera del plan (p. ej. juego completado).
+func (s *SessionService) questionIDForNumber(number int) int {
+	plan, err := s.redisClient.GetQuestionPlan()
+	if err != nil {
+		log.Printf("⚠️ Error obteniendo plan de preguntas: %v", err)
+		return 0
+	}
+	if number < 1 || number > len(plan) {
+		return 0
+	}
+	return plan[number-1]
+}
+
+func (s *SessionService) saveSession(session *models.GameSession) error {
+	sessionJSON, err := session.StorageJSON()
+	if err != nil {
+		return fmt.Errorf("error serializando sesión: %v", err)
+	}
+
+	key := fmt.Sprintf("session:%s", session.ID)
+	if err := s.redisClient.Set(key, string(sessionJSON), s.sessionTTL); err != nil {
 		return err
 	}
+	s.InvalidateLeaderboard()
+	return nil
+}
 
-	// Remover de sesiones activas
-	return s.removeFromActiveSessions(sessionID)
+// CountActiveSessions devuelve la cantidad de sesiones activas (sin práctica)
+func (s *SessionService) CountActiveSessions() (int, error) {
+	count, err := s.redisClient.GetSetSize("active_sessions")
+	if err != nil {
+		return 0, fmt.Errorf("error contando sesiones activas: %v", err)
+	}
+	return int(count), nil
 }
 
-// Métodos privados auxiliares
+// reserveActiveSlot agrega la sesión al set activo respetando el cupo de
+// jugadores simultáneos (los pre-registrados tienen cupo garantizado). Contar y
+// agregar es una sola operación atómica: varios ingresos a la vez con el cupo
+// casi lleno no pueden superarlo.
+func (s *SessionService) reserveActiveSlot(sessionID string, reserved bool) error {
+	if s.maxPlayers <= 0 || reserved {
+		if err := s.addToActiveSessions(sessionID); err != nil {
+			return fmt.Errorf("error agregando a sesiones activas: %v", err)
+		}
+		return nil
+	}
 
-func (s *SessionService) saveSession(session *models.GameSession) error {
-	sessionJSON, err := json.Marshal(session)
+	added, err := s.redisClient.AddToSetIfBelow("active_sessions", sessionID, int64(s.maxPlayers))
 	if err != nil {
-		return fmt.Errorf("error serializando sesión: %v", err)
+		return fmt.Errorf("error agregando a sesiones activas: %v", err)
 	}
-
-	key := fmt.Sprintf("quiz:session:%s", session.ID)
-	return s.redisClient.Set(key, string(sessionJSON), 24*time.Hour) // TTL de 24 horas
+	if !added {
+		return ErrGameFull
+	}
+	return nil
 }
 
 func (s *SessionService) addToActiveSessions(sessionID string) error {
-	return s.redisClient.AddToSet("quiz:active_sessions", sessionID)
+	return s.redisClient.AddToSet("active_sessions", sessionID)
 }
 
 func (s *SessionService) removeFromActiveSessions(sessionID string) error {
-	return s.redisClient.RemoveFromSet("quiz:active_sessions", sessionID)
+	return s.redisClient.RemoveFromSet("active_sessions", sessionID)
+}
+
+// quarantineSession saca una sesión ilegible del set activo y la deja en
+// corrupt_sessions para poder inspeccionarla
+func (s *SessionService) quarantineSession(sessionID string) {
+	if err := s.redisClient.AddToSet("corrupt_sessions", sessionID); err != nil {
+		log.Printf("⚠️ Error registrando sesión corrupta %s: %v", sessionID, err)
+		return
+	}
+	if err := s.removeFromActiveSessions(sessionID); err != nil {
+		log.Printf("⚠️ Error removiendo sesión corrupta %s: %v", sessionID, err)
+		return
+	}
+	log.Printf("🧪 Sesión corrupta %s movida a cuarentena", sessionID)
 }
 
 func (s *SessionService) addToPlayerSessions(playerName, sessionID string) error {
-	key := fmt.Sprintf("quiz:player_sessions:%s", playerName)
+	key := fmt.Sprintf("player_sessions:%s", playerName)
 	return s.redisClient.AddToSet(key, sessionID)
 }
 
 func (s *SessionService) getPlayerSessions(playerName string) ([]string, error) {
-	key := fmt.Sprintf("quiz:player_sessions:%s", playerName)
+	key := fmt.Sprintf("player_sessions:%s", playerName)
 	return s.redisClient.GetSetMembers(key)
 }
 
 // GetPlayerNames obtiene todos los nombres de jugadores registrados
 func (s *SessionService) GetPlayerNames() ([]string, error) {
-	pattern := "quiz:player_sessions:*"
+	pattern := "player_sessions:*"
 	keys, err := s.redisClient.GetKeysByPattern(pattern)
 	if err != nil {
 		return nil, fmt.Errorf("error obteniendo nombres de jugadores: %v", err)
@@ -275,23 +1193,84 @@ func (s *SessionService) GetPlayerNames() ([]string, error) {
 	var playerNames []string
 	for _, key := range keys {
 		// Extraer el nombre del jugador de la clave
-		playerName := key[len("quiz:player_sessions:"):]
+		playerName := key[len("player_sessions:"):]
 		playerNames = append(playerNames, playerName)
 	}
 
 	return playerNames, nil
 }
 
-// GetLeaderboard obtiene la tabla de posiciones
+// GetLeaderboard obtiene la tabla de posiciones; con RunLeaderboardWorker en
+// marcha devuelve la precalculada, que no debe modificarse
 func (s *SessionService) GetLeaderboard() (*models.LeaderboardResponse, error) {
+	s.leaderboardMutex.RLock()
+	cached := s.leaderboard
+	s.leaderboardMutex.RUnlock()
+	if cached != nil {
+		return cached, nil
+	}
+	return s.computeLeaderboard()
+}
+
+// leaderboardMaxAge cada cuánto el worker recalcula la tabla aunque no haya
+// cambios avisados (p. ej. sesiones que expiran o escritas por otra instancia)
+const leaderboardMaxAge = 5 * time.Second
+
+// InvalidateLeaderboard avisa al worker que la tabla de posiciones cambió; no bloquea
+func (s *SessionService) InvalidateLeaderboard() {
+	select {
+	case s.leaderboardDirty <- struct{}{}:
+	default:
+	}
+}
+
+// RunLeaderboardWorker mantiene precalculada la tabla de posiciones que
+// devuelve GetLeaderboard. Tras un cambio espera debounce para agrupar ráfagas
+// de respuestas en un solo recálculo. No retorna; se lanza con go.
+func (s *SessionService) RunLeaderboardWorker(debounce time.Duration) {
+	ticker := time.NewTicker(leaderboardMaxAge)
+	defer ticker.Stop()
+
+	for {
+		s.refreshLeaderboard()
+
+		select {
+		case <-s.leaderboardDirty:
+			time.Sleep(debounce)
+			// Los avisos llegados durante la espera quedan cubiertos por este recálculo
+			select {
+			case <-s.leaderboardDirty:
+			default:
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshLeaderboard recalcula la tabla de posiciones en caché; si falla se
+// descarta la caché para que GetLeaderboard calcule en cada petición
+func (s *SessionService) refreshLeaderboard() {
+	leaderboard, err := s.computeLeaderboard()
+	if err != nil {
+		log.Printf("⚠️ Error precalculando tabla de posiciones: %v", err)
+	}
+
+	s.leaderboardMutex.Lock()
+	s.leaderboard = leaderboard
+	s.leaderboardMutex.Unlock()
+}
+
+// computeLeaderboard calcula la tabla de posiciones desde Redis
+func (s *SessionService) computeLeaderboard() (*models.LeaderboardResponse, error) {
 	// Obtener todas las sesiones activas y terminadas recientes
 	allSessions, err := s.getAllRecentSessions()
 	if err != nil {
 		return nil, fmt.Errorf("error obteniendo sesiones: %v", err)
 	}
 
-	// Crear entradas de la tabla de posiciones
-	var leaderboard []models.LeaderboardEntry
+	// Crear entradas de la tabla de posiciones (vacía pero no nil, para que se
+	// serialice como [] y no como null)
+	leaderboard := make([]models.LeaderboardEntry, 0, len(allSessions))
 	avatars := []string{"🎯", "⭐", "🔥", "💎", "🌟", "🎪", "🚀", "👤", "🎨", "🎵", "🌊", "⚡", "🎭", "🦄", "🔮"}
 
 	activePlayers := 0
@@ -304,12 +1283,13 @@ func (s *SessionService) GetLeaderboard() (*models.LeaderboardResponse, error) {
 		avatar := avatars[i%len(avatars)]
 
 		entry := models.LeaderboardEntry{
-			Position:     i + 1,
-			PlayerName:   session.PlayerName,
-			CurrentPrize: session.TotalPrize,
-			Status:       session.GameStatus,
-			Avatar:       avatar,
-			Question:     session.CurrentQuestion,
+			Position:       i + 1,
+			PlayerName:     session.PlayerName,
+			CurrentPrize:   session.TotalPrize,
+			FormattedPrize: models.FormatPrize(session.TotalPrize),
+			Status:         session.GameStatus,
+			Avatar:         avatar,
+			Question:       session.CurrentQuestion,
 		}
 
 		leaderboard = append(leaderboard, entry)
@@ -342,14 +1322,10 @@ func (s *SessionService) getAllRecentSessions() ([]models.GameSession, error) {
 	// Combinar y ordenar por premio (mayor a menor)
 	allSessions := append(activeSessions, finishedSessions...)
 
-	// Ordenar por premio total (descendente)
-	for i := 0; i < len(allSessions)-1; i++ {
-		for j := i + 1; j < len(allSessions); j++ {
-			if allSessions[i].TotalPrize < allSessions[j].TotalPrize {
-				allSessions[i], allSessions[j] = allSessions[j], allSessions[i]
-			}
-		}
-	}
+	// Ordenar por premio total (descendente) con desempate determinista
+	sort.SliceStable(allSessions, func(i, j int) bool {
+		return rankBefore(&allSessions[i], &allSessions[j])
+	})
 
 	// Limitar a los primeros 20 para no sobrecargar
 	if len(allSessions) > 20 {
@@ -359,10 +1335,44 @@ func (s *SessionService) getAllRecentSessions() ([]models.GameSession, error) {
 	return allSessions, nil
 }
 
+// rankBefore indica si la sesión a va antes que b en la tabla de posiciones:
+// mayor premio primero; a igual premio, quien lo alcanzó antes; luego la
+// última actividad más temprana y, por último, nombre e ID.
+func rankBefore(a, b *models.GameSession) bool {
+	if a.TotalPrize != b.TotalPrize {
+		return a.TotalPrize > b.TotalPrize
+	}
+
+	achievedA, achievedB := prizeAchievedAt(a), prizeAchievedAt(b)
+	if !achievedA.IsZero() && !achievedB.IsZero() && !achievedA.Equal(achievedB) {
+		return achievedA.Before(achievedB)
+	}
+
+	if !a.LastActivity.Equal(b.LastActivity) {
+		return a.LastActivity.Before(b.LastActivity)
+	}
+
+	if a.PlayerName != b.PlayerName {
+		return a.PlayerName < b.PlayerName
+	}
+	return a.ID < b.ID
+}
+
+// prizeAchievedAt devuelve el momento de la respuesta que otorgó el premio actual
+func prizeAchievedAt(session *models.GameSession) time.Time {
+	for i := len(session.AnswersGiven) - 1; i >= 0; i-- {
+		answer := session.AnswersGiven[i]
+		if answer.IsCorrect && answer.PrizeWon == session.TotalPrize {
+			return answer.Timestamp
+		}
+	}
+	return time.Time{}
+}
+
 // getRecentFinishedSessions obtiene sesiones terminadas recientes
 func (s *SessionService) getRecentFinishedSessions() ([]models.GameSession, error) {
 	// Obtener todas las claves de sesiones
-	pattern := "quiz:session:*"
+	pattern := "session:*"
 	keys, err := s.redisClient.GetKeysByPattern(pattern)
 	if err != nil {
 		return nil, err
@@ -372,13 +1382,13 @@ func (s *SessionService) getRecentFinishedSessions() ([]models.GameSession, erro
 
 	// Revisar cada sesión para encontrar las terminadas
 	for _, key := range keys {
-		sessionID := key[len("quiz:session:"):]
+		sessionID := key[len("session:"):]
 		session, err := s.GetSession(sessionID)
 		if err != nil {
 			continue
 		}
 
-		if session.GameStatus == "finished" {
+		if session.GameStatus == "finished" && !session.IsPractice() {
 			finishedSessions = append(finishedSessions, *session)
 		}
 	}
@@ -391,9 +1401,362 @@ func (s *SessionService) getRecentFinishedSessions() ([]models.GameSession, erro
 	return finishedSessions, nil
 }
 
+// GetAllSessions obtiene todas las sesiones almacenadas, sin importar su estado
+func (s *SessionService) GetAllSessions() ([]models.GameSession, error) {
+	keys, err := s.redisClient.GetKeysByPattern("session:*")
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]models.GameSession, 0, len(keys))
+	for _, key := range keys {
+		session, err := s.GetSession(key[len("session:"):])
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, *session)
+	}
+
+	return sessions, nil
+}
+
+// GetAnswerProgress cuenta en una sola pasada quiénes respondieron la pregunta
+// número questionNumber, quiénes siguen pendientes y qué opción eligieron
+func (s *SessionService) GetAnswerProgress(questionNumber int) (*models.AnswerProgress, error) {
+	sessions, err := s.GetAllSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &models.AnswerProgress{Distribution: make(map[string]int)}
+	for _, session := range sessions {
+		if session.IsPractice() {
+			continue
+		}
+
+		answered := false
+		for _, answer := range session.AnswersGiven {
+			if answer.QuestionNumber == questionNumber {
+				if !answer.Voided {
+					progress.Distribution[answer.SelectedOption]++
+				}
+				progress.Answered++
+				answered = true
+				break
+			}
+		}
+		if !answered && session.GameStatus == "active" && session.CurrentQuestion == questionNumber {
+			progress.Pending++
+		}
+	}
+
+	return progress, nil
+}
+
+// GetAnswerDistribution cuenta cuántos jugadores en vivo eligieron cada opción
+// en la pregunta número questionNumber (solo quienes ya la respondieron)
+func (s *SessionService) GetAnswerDistribution(questionNumber int) (map[string]int, int, error) {
+	sessions, err := s.GetAllSessions()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	distribution := make(map[string]int)
+	total := 0
+	for _, session := range sessions {
+		if session.IsPractice() {
+			continue
+		}
+		for _, answer := range session.AnswersGiven {
+			if answer.QuestionNumber == questionNumber && !answer.Voided {
+				distribution[answer.SelectedOption]++
+				total++
+				break
+			}
+		}
+	}
+
+	return distribution, total, nil
+}
+
+// timingBucketSeconds ancho de cada rango del histograma de tiempos
+const timingBucketSeconds = 5
+
+// GetAnswerTiming agrupa el tiempo que tardaron en responder la pregunta los
+// jugadores, medido entre startedAt y la hora en que el servidor registró cada
+// respuesta. Los jugadores activos que siguen en esa pregunta cuentan como
+// pendientes. duration define el número de rangos; el último no tiene límite.
+func (s *SessionService) GetAnswerTiming(questionNumber int, startedAt time.Time, duration time.Duration) (*models.QuestionTiming, error) {
+	sessions, err := s.GetAllSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	bucketCount := int((duration + timingBucketSeconds*time.Second - 1) / (timingBucketSeconds * time.Second))
+	if bucketCount < 1 {
+		bucketCount = 6
+	}
+	buckets := make([]models.TimingBucket, bucketCount+1)
+	for i := range buckets {
+		buckets[i].FromSeconds = i * timingBucketSeconds
+		if i < bucketCount {
+			buckets[i].ToSeconds = (i + 1) * timingBucketSeconds
+		}
+	}
+
+	timing := &models.QuestionTiming{
+		QuestionNumber: questionNumber,
+		StartedAt:      startedAt,
+		ElapsedSeconds: int(time.Since(startedAt) / time.Second),
+	}
+
+	var totalElapsed time.Duration
+	for _, session := range sessions {
+		if session.IsPractice() {
+			continue
+		}
+
+		answered := false
+		for _, answer := range session.AnswersGiven {
+			if answer.QuestionNumber != questionNumber {
+				continue
+			}
+			elapsed := answer.Timestamp.Sub(startedAt)
+			if elapsed < 0 {
+				elapsed = 0
+			}
+			index := int(elapsed / (timingBucketSeconds * time.Second))
+			if index > bucketCount {
+				index = bucketCount
+			}
+			buckets[index].Count++
+			totalElapsed += elapsed
+			timing.Answered++
+			answered = true
+			break
+		}
+
+		if !answered && session.GameStatus == "active" && session.CurrentQuestion == questionNumber {
+			timing.Pending++
+		}
+	}
+
+	if timing.Answered > 0 {
+		timing.AverageSeconds = totalElapsed.Seconds() / float64(timing.Answered)
+	}
+	timing.Buckets = buckets
+
+	return timing, nil
+}
+
+// GetNextPrize calcula cuánto gana la sesión si acierta su pregunta actual y
+// cuánto conserva si falla. Al fallar se conserva el premio acumulado, salvo en
+// modo apuesta donde se arriesga lo apostado (no incluido aquí).
+func (s *SessionService) GetNextPrize(sessionID string) (*models.NextPrize, error) {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	next := &models.NextPrize{
+		SessionID:      session.ID,
+		QuestionNumber: session.CurrentQuestion,
+		PrizeIfWrong:   session.TotalPrize,
+	}
+
+	number := session.CurrentQuestion
+	if number < 1 || number > len(models.PrizeLevels) || session.GameStatus == "finished" {
+		next.Complete = true
+		next.Prize = session.TotalPrize
+	} else if session.IsWager() {
+		next.Prize = models.ClampPrize(session.TotalPrize+models.PrizeLevels[number-1], s.maxPrize)
+	} else {
+		next.Prize = models.PrizeLevels[number-1]
+	}
+
+	next.FormattedPrize = models.FormatPrize(next.Prize)
+	next.FormattedPrizeIfWrong = models.FormatPrize(next.PrizeIfWrong)
+	return next, nil
+}
+
+// GetCertificate arma los datos del certificado de una sesión. Quienes
+// terminaron el juego se clasifican entre los que terminaron; el resto, entre
+// todos los jugadores en vivo.
+func (s *SessionService) GetCertificate(sessionID string) (*models.Certificate, error) {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	conquered := 0
+	for _, answer := range session.AnswersGiven {
+		if answer.IsCorrect && !answer.Voided {
+			conquered++
+		}
+	}
+
+	certificate := &models.Certificate{
+		SessionID:          session.ID,
+		PlayerName:         session.PlayerName,
+		FinalPrize:         session.TotalPrize,
+		QuestionsConquered: conquered,
+		Status:             session.GameStatus,
+		Date:               session.LastActivity,
+	}
+
+	if session.IsPractice() {
+		return certificate, nil
+	}
+
+	allSessions, err := s.GetAllSessions()
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo sesiones: %v", err)
+	}
+
+	finisher := session.GameStatus == "finished"
+	certificate.RankedAmong = "allPlayers"
+	if finisher {
+		certificate.RankedAmong = "finishers"
+	}
+
+	certificate.Rank = 1
+	for i := range allSessions {
+		other := &allSessions[i]
+		if other.IsPractice() || (finisher && other.GameStatus != "finished") {
+			continue
+		}
+		certificate.TotalRanked++
+		if other.ID != session.ID && rankBefore(other, session) {
+			certificate.Rank++
+		}
+	}
+
+	return certificate, nil
+}
+
+// GetQuestionStats agrega los resultados de todas las sesiones por pregunta
+func (s *SessionService) GetQuestionStats() (map[int]*models.QuestionStats, error) {
+	sessions, err := s.GetAllSessions()
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo sesiones: %v", err)
+	}
+
+	stats := make(map[int]*models.QuestionStats)
+	totalTimes := make(map[int]int)
+	for _, session := range sessions {
+		if session.IsPractice() {
+			continue
+		}
+		for _, answer := range session.AnswersGiven {
+			if answer.Voided {
+				continue
+			}
+			stat, ok := stats[answer.QuestionID]
+			if !ok {
+				stat = &models.QuestionStats{QuestionID: answer.QuestionID}
+				stats[answer.QuestionID] = stat
+			}
+			stat.Attempts++
+			if answer.IsCorrect {
+				stat.Correct++
+			} else {
+				stat.Incorrect++
+			}
+			totalTimes[answer.QuestionID] += answer.TimeToAnswer
+		}
+	}
+
+	for id, stat := range stats {
+		stat.CorrectRate = float64(stat.Correct) / float64(stat.Attempts)
+		stat.AvgTime = float64(totalTimes[id]) / float64(stat.Attempts)
+	}
+
+	return stats, nil
+}
+
+// GetQuestionResult resultados de una sola pregunta en todas las sesiones (sin
+// práctica ni respuestas anuladas); sin intentos devuelve todo en cero
+func (s *SessionService) GetQuestionResult(questionID int) (*models.QuestionStats, error) {
+	sessions, err := s.GetAllSessions()
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo sesiones: %v", err)
+	}
+
+	result := &models.QuestionStats{QuestionID: questionID}
+	totalTime := 0
+	for _, session := range sessions {
+		if session.IsPractice() {
+			continue
+		}
+		for _, answer := range session.AnswersGiven {
+			if answer.QuestionID != questionID || answer.Voided {
+				continue
+			}
+			result.Attempts++
+			if answer.IsCorrect {
+				result.Correct++
+			} else {
+				result.Incorrect++
+			}
+			totalTime += answer.TimeToAnswer
+		}
+	}
+
+	if result.Attempts > 0 {
+		result.CorrectRate = float64(result.Correct) / float64(result.Attempts)
+		result.AvgTime = float64(totalTime) / float64(result.Attempts)
+	}
+
+	return result, nil
+}
+
+// GetAnswerTimeline respuestas a una pregunta de todas las sesiones (sin
+// práctica ni respuestas anuladas), ordenadas por hora de llegada al servidor
+func (s *SessionService) GetAnswerTimeline(questionID int) (*models.AnswerTimeline, error) {
+	sessions, err := s.GetAllSessions()
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo sesiones: %v", err)
+	}
+
+	timeline := &models.AnswerTimeline{QuestionID: questionID, Answers: []models.AnswerTimelineEntry{}}
+	for _, session := range sessions {
+		if session.IsPractice() {
+			continue
+		}
+		for _, answer := range session.AnswersGiven {
+			if answer.QuestionID != questionID || answer.Voided {
+				continue
+			}
+			timeline.Answers = append(timeline.Answers, models.AnswerTimelineEntry{
+				SessionID:      session.ID,
+				PlayerName:     session.PlayerName,
+				SelectedOption: answer.SelectedOption,
+				IsCorrect:      answer.IsCorrect,
+				Suspicious:     answer.Suspicious,
+				ReceivedAt:     answer.Timestamp,
+			})
+		}
+	}
+
+	sort.SliceStable(timeline.Answers, func(i, j int) bool {
+		return timeline.Answers[i].ReceivedAt.Before(timeline.Answers[j].ReceivedAt)
+	})
+	for i := range timeline.Answers {
+		entry := &timeline.Answers[i]
+		entry.Position = i + 1
+		if i > 0 {
+			entry.SinceFirstMs = entry.ReceivedAt.Sub(timeline.Answers[0].ReceivedAt).Milliseconds()
+			entry.SincePreviousMs = entry.ReceivedAt.Sub(timeline.Answers[i-1].ReceivedAt).Milliseconds()
+		}
+	}
+
+	return timeline, nil
+}
+
 // ClearAllSessions elimina todas las sesiones y datos relacionados
 func (s *SessionService) ClearAllSessions() error {
 	log.Println("🧹 Iniciando limpieza completa de todas las sesiones y datos de la partida...")
+	defer s.InvalidateLeaderboard()
 
 	// Obtener todas las sesiones activas para limpiarlas individualmente
 	activeSessions, err := s.GetActiveSessions()
@@ -413,21 +1776,21 @@ func (s *SessionService) ClearAllSessions() error {
 	// Limpiar sesiones individuales
 	for _, session := range allSessions {
 		// Eliminar la sesión individual
-		sessionKey := fmt.Sprintf("quiz:session:%s", session.ID)
+		sessionKey := fmt.Sprintf("session:%s", session.ID)
 		err := s.redisClient.Delete(sessionKey)
 		if err != nil {
 			log.Printf("⚠️ Error eliminando sesión %s: %v", session.ID, err)
 		}
 
 		// Eliminar historial del jugador
-		playerSessionsKey := fmt.Sprintf("quiz:player:%s:sessions", session.PlayerName)
+		playerSessionsKey := fmt.Sprintf("player:%s:sessions", session.PlayerName)
 		err = s.redisClient.Delete(playerSessionsKey)
 		if err != nil {
 			log.Printf("⚠️ Error eliminando historial del jugador %s: %v", session.PlayerName, err)
 		}
 
 		// Eliminar datos de respuestas del jugador
-		playerAnswersKey := fmt.Sprintf("quiz:player:%s:answers", session.PlayerName)
+		playerAnswersKey := fmt.Sprintf("player:%s:answers", session.PlayerName)
 		err = s.redisClient.Delete(playerAnswersKey)
 		if err != nil {
 			log.Printf("⚠️ Error eliminando respuestas del jugador %s: %v", session.PlayerName, err)
@@ -436,12 +1799,16 @@ func (s *SessionService) ClearAllSessions() error {
 
 	// Limpiar listas centrales
 	keysToDelete := []string{
-		"quiz:active_sessions",
-		"quiz:finished_sessions", 
-		"quiz:player_names",
-		"quiz:game_stats",
-		"quiz:current_players",
-		"quiz:eliminated_players",
+		"active_sessions",
+		"corrupt_sessions",
+		voidedQuestionsKey,
+		allAnsweredKey,
+		roundCompleteKey,
+		"finished_sessions", 
+		"player_names",
+		"game_stats",
+		"current_players",
+		"eliminated_players",
 	}
 
 	for _, key := range keysToDelete {
@@ -453,10 +1820,12 @@ func (s *SessionService) ClearAllSessions() error {
 
 	// Limpiar cualquier clave relacionada con el juego que pueda existir
 	patterns := []string{
-		"quiz:session:*",
-		"quiz:player:*",
-		"quiz:game:*",
-		"quiz:question:*:responses",
+		"session:*",
+		"session_token:*",
+		"session_connected:*",
+		"player:*",
+		"game:*",
+		"question:*:responses",
 	}
 
 	for _, pattern := range patterns {
@@ -478,6 +1847,122 @@ func (s *SessionService) ClearAllSessions() error {
 	return nil
 }
 
+// GetSpectators devuelve los jugadores eliminados (sin contar práctica), con
+// la pregunta en la que fallaron y su premio final; los que llegaron más lejos primero
+func (s *SessionService) GetSpectators() ([]models.Spectator, error) {
+	sessions, err := s.GetAllSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	spectators := make([]models.Spectator, 0)
+	for _, session := range sessions {
+		if session.GameStatus != "eliminated" || session.IsPractice() {
+			continue
+		}
+
+		spectator := models.Spectator{
+			SessionID:      session.ID,
+			PlayerName:     session.PlayerName,
+			EliminatedAt:   session.CurrentQuestion,
+			FinalPrize:     session.TotalPrize,
+			FormattedPrize: models.FormatPrize(session.TotalPrize),
+			EliminatedTime: session.LastActivity,
+		}
+		for i := len(session.AnswersGiven) - 1; i >= 0; i-- {
+			if answer := session.AnswersGiven[i]; !answer.IsCorrect {
+				spectator.EliminatedAt = answer.QuestionNumber
+				spectator.EliminatedTime = answer.Timestamp
+				break
+			}
+		}
+		spectators = append(spectators, spectator)
+	}
+
+	sort.Slice(spectators, func(i, j int) bool {
+		if spectators[i].EliminatedAt != spectators[j].EliminatedAt {
+			return spectators[i].EliminatedAt > spectators[j].EliminatedAt
+		}
+		return spectators[i].FinalPrize > spectators[j].FinalPrize
+	})
+
+	return spectators, nil
+}
+
+// allAnsweredKey preguntas para las que ya se avisó que todos respondieron
+const allAnsweredKey = "all_answered"
+
+// MarkAllAnswered indica si todos los jugadores activos ya respondieron la
+// pregunta questionNumber (los que van más adelante cuentan como respondidos).
+// Devuelve true una sola vez por pregunta, aunque varias respuestas lleguen juntas.
+func (s *SessionService) MarkAllAnswered(questionNumber int) (bool, error) {
+	activeSessions, err := s.GetActiveSessions()
+	if err != nil {
+		return false, err
+	}
+
+	for _, session := range activeSessions {
+		if session.IsPractice() || session.CurrentQuestion > questionNumber {
+			continue
+		}
+		answered := false
+		for _, answer := range session.AnswersGiven {
+			if answer.QuestionNumber == questionNumber {
+				answered = true
+				break
+			}
+		}
+		if !answered {
+			return false, nil
+		}
+	}
+
+	return s.redisClient.SetHashFieldIfAbsent(allAnsweredKey, strconv.Itoa(questionNumber), models.Timestamp())
+}
+
+// roundCompleteKey marca que ya se avisó que no quedan jugadores activos
+const roundCompleteKey = "round_complete"
+
+// MarkRoundComplete indica si la ronda terminó: hubo jugadores (sin contar
+// práctica) y ninguno sigue activo. Devuelve true una sola vez hasta que
+// alguien vuelva a jugar (reingreso o reinicio) o se limpie la partida.
+func (s *SessionService) MarkRoundComplete() (bool, error) {
+	activeSessions, err := s.GetActiveSessions()
+	if err != nil {
+		return false, err
+	}
+	for _, session := range activeSessions {
+		if !session.IsPractice() {
+			return false, nil
+		}
+	}
+
+	sessions, err := s.GetAllSessions()
+	if err != nil {
+		return false, err
+	}
+	played := false
+	for _, session := range sessions {
+		if !session.IsPractice() {
+			played = true
+			break
+		}
+	}
+	if !played {
+		return false, nil
+	}
+
+	return s.redisClient.SetIfAbsent(roundCompleteKey, models.Timestamp(), 0)
+}
+
+// reopenRound permite volver a avisar el fin de la ronda cuando un jugador
+// vuelve a estar activo
+func (s *SessionService) reopenRound() {
+	if err := s.redisClient.Delete(roundCompleteKey); err != nil {
+		log.Printf("⚠️ Error reabriendo la ronda: %v", err)
+	}
+}
+
 // GetPlayersStatus obtiene el estado de respuestas de todos los jugadores
 func (s *SessionService) GetPlayersStatus() (*models.PlayersStatusResponse, error) {
 	// Obtener todas las sesiones activas
@@ -0,0 +1,1444 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/backsoul/quiz/pkg/models"
+	"github.com/backsoul/quiz/pkg/redis"
+)
+
+// newTestSessionService crea un SessionService sobre un MemoryStore vacío
+func newTestSessionService(t *testing.T) (*SessionService, *redis.MemoryStore) {
+	t.Helper()
+	store := redis.NewMemoryStore()
+	return NewSessionService(store), store
+}
+
+// createTestSession crea una sesión "live" nueva para el jugador
+func createTestSession(t *testing.T, s *SessionService, playerName string) *models.GameSession {
+	t.Helper()
+	session, created, err := s.CreateSession(playerName, models.SessionModeLive, "", "")
+	if err != nil {
+		t.Fatalf("error creando sesión de %s: %v", playerName, err)
+	}
+	if !created {
+		t.Fatalf("esperaba una sesión nueva para %s", playerName)
+	}
+	return session
+}
+
+// mustGetSession lee la sesión guardada
+func mustGetSession(t *testing.T, s *SessionService, sessionID string) *models.GameSession {
+	t.Helper()
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		t.Fatalf("error leyendo sesión %s: %v", sessionID, err)
+	}
+	return session
+}
+
+// testAnswer arma la respuesta a la pregunta número number
+func testAnswer(number int, correct bool, prize int64) models.PlayerAnswer {
+	selected := "A"
+	if !correct {
+		selected = "B"
+	}
+	return models.PlayerAnswer{
+		QuestionID:     number,
+		QuestionNumber: number,
+		SelectedOption: selected,
+		CorrectOption:  "A",
+		IsCorrect:      correct,
+		Timestamp:      time.Now().UTC(),
+		PrizeWon:       prize,
+	}
+}
+
+// addTestAnswer agrega la respuesta y devuelve la sesión actualizada
+func addTestAnswer(t *testing.T, s *SessionService, sessionID string, answer models.PlayerAnswer) *models.GameSession {
+	t.Helper()
+	if err := s.AddAnswer(sessionID, answer); err != nil {
+		t.Fatalf("error agregando respuesta %d: %v", answer.QuestionNumber, err)
+	}
+	return mustGetSession(t, s, sessionID)
+}
+
+// isActiveSession indica si la sesión está en el set de sesiones activas
+func isActiveSession(t *testing.T, s *SessionService, sessionID string) bool {
+	t.Helper()
+	sessions, err := s.GetActiveSessions()
+	if err != nil {
+		t.Fatalf("error leyendo sesiones activas: %v", err)
+	}
+	for _, session := range sessions {
+		if session.ID == sessionID {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSessionLifecycleWithMemoryStore(t *testing.T) {
+	s, _ := newTestSessionService(t)
+
+	session := createTestSession(t, s, "Ana")
+	if session.GameStatus != "active" || session.CurrentQuestion != 1 || session.TotalPrize != 0 {
+		t.Fatalf("sesión inicial inesperada: %+v", session)
+	}
+	if !isActiveSession(t, s, session.ID) {
+		t.Fatalf("la sesión nueva debería estar activa")
+	}
+
+	// Respuesta correcta: avanza y gana el premio de la pregunta
+	session = addTestAnswer(t, s, session.ID, testAnswer(1, true, 500))
+	if session.CurrentQuestion != 2 || session.TotalPrize != 500 || session.GameStatus != "active" {
+		t.Fatalf("tras acertar: pregunta %d, premio %d, estado %s", session.CurrentQuestion, session.TotalPrize, session.GameStatus)
+	}
+
+	// El mismo nombre vuelve a la sesión existente mientras sigue activa
+	again, created, err := s.CreateSession("Ana", models.SessionModeLive, "", "")
+	if err != nil {
+		t.Fatalf("error reingresando: %v", err)
+	}
+	if created || again.ID != session.ID {
+		t.Fatalf("esperaba continuar la sesión %s, obtuve %s (nueva: %v)", session.ID, again.ID, created)
+	}
+
+	// Con una sola vida, un error elimina al jugador y conserva el premio
+	session = addTestAnswer(t, s, session.ID, testAnswer(2, false, 0))
+	if session.GameStatus != "eliminated" {
+		t.Fatalf("esperaba eliminado tras fallar, estado %s", session.GameStatus)
+	}
+	if session.TotalPrize != 500 || session.LivesRemaining != 0 {
+		t.Fatalf("eliminado con premio %d y %d vidas", session.TotalPrize, session.LivesRemaining)
+	}
+	if len(session.AnswersGiven) != 2 {
+		t.Fatalf("esperaba 2 respuestas guardadas, hay %d", len(session.AnswersGiven))
+	}
+}
+
+func TestSessionFinishesAfterMaxQuestions(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	s.SetMaxQuestions(3)
+
+	session := createTestSession(t, s, "Beto")
+	for number := 1; number <= 3; number++ {
+		session = addTestAnswer(t, s, session.ID, testAnswer(number, true, int64(number)*1000))
+	}
+
+	if session.GameStatus != "finished" {
+		t.Fatalf("esperaba finished tras 3 aciertos, estado %s", session.GameStatus)
+	}
+	if session.TotalPrize != 3000 {
+		t.Fatalf("esperaba premio 3000, obtuve %d", session.TotalPrize)
+	}
+}
+
+func TestLeaderboardTieBreakByPrizeAchievedTime(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	base := time.Now().UTC().Add(-time.Minute)
+
+	// Ana responde primero en el reloj del servidor, pero su respuesta ganadora
+	// tiene una marca posterior a la de Beto: a igual premio, Beto va primero
+	ana := createTestSession(t, s, "Ana")
+	answer := testAnswer(1, true, 1000)
+	answer.Timestamp = base.Add(10 * time.Second)
+	addTestAnswer(t, s, ana.ID, answer)
+
+	beto := createTestSession(t, s, "Beto")
+	answer = testAnswer(1, true, 1000)
+	answer.Timestamp = base.Add(5 * time.Second)
+	addTestAnswer(t, s, beto.ID, answer)
+
+	leaderboard, err := s.GetLeaderboard()
+	if err != nil {
+		t.Fatalf("error obteniendo tabla: %v", err)
+	}
+	if len(leaderboard.Leaderboard) != 2 {
+		t.Fatalf("esperaba 2 jugadores, hay %d", len(leaderboard.Leaderboard))
+	}
+	first, second := leaderboard.Leaderboard[0], leaderboard.Leaderboard[1]
+	if first.PlayerName != "Beto" || second.PlayerName != "Ana" {
+		t.Fatalf("orden inesperado: %s, %s", first.PlayerName, second.PlayerName)
+	}
+	if first.Position != 1 || second.Position != 2 {
+		t.Fatalf("posiciones inesperadas: %d, %d", first.Position, second.Position)
+	}
+}
+
+func TestRankBeforeFallsBackToLastActivity(t *testing.T) {
+	base := time.Now().UTC()
+	a := models.GameSession{ID: "a", PlayerName: "Ana", TotalPrize: 0, LastActivity: base.Add(time.Second)}
+	b := models.GameSession{ID: "b", PlayerName: "Beto", TotalPrize: 0, LastActivity: base}
+
+	// Sin premio no hay momento de logro: decide la última actividad
+	if !rankBefore(&b, &a) || rankBefore(&a, &b) {
+		t.Fatalf("con premio igual y sin logro, la actividad más temprana debe ir primero")
+	}
+
+	b.LastActivity = a.LastActivity
+	if !rankBefore(&a, &b) {
+		t.Fatalf("con todo igual, el orden debe ser por nombre")
+	}
+}
+
+// setMembers devuelve los miembros de un set como mapa
+func setMembers(t *testing.T, store redis.RedisStore, key string) map[string]bool {
+	t.Helper()
+	members, err := store.GetSetMembers(key)
+	if err != nil {
+		t.Fatalf("error leyendo %s: %v", key, err)
+	}
+	set := make(map[string]bool, len(members))
+	for _, member := range members {
+		set[member] = true
+	}
+	return set
+}
+
+func TestGetActiveSessionsQuarantinesCorruptSession(t *testing.T) {
+	s, store := newTestSessionService(t)
+	good := createTestSession(t, s, "Ana")
+	corrupt := createTestSession(t, s, "Beto")
+
+	if err := store.Set("session:"+corrupt.ID, `{"id": "roto",`, 0); err != nil {
+		t.Fatalf("error corrompiendo sesión: %v", err)
+	}
+
+	sessions, err := s.GetActiveSessions()
+	if err != nil {
+		t.Fatalf("una sesión corrupta no debe hacer fallar la consulta: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != good.ID {
+		t.Fatalf("esperaba solo la sesión válida, obtuve %d sesiones", len(sessions))
+	}
+
+	if setMembers(t, store, "active_sessions")[corrupt.ID] {
+		t.Fatalf("la sesión corrupta sigue en el set activo")
+	}
+	if !setMembers(t, store, "corrupt_sessions")[corrupt.ID] {
+		t.Fatalf("la sesión corrupta no quedó en cuarentena")
+	}
+	if _, err := store.Get("session:" + corrupt.ID); err != nil {
+		t.Fatalf("el valor corrupto debe conservarse para inspeccionarlo: %v", err)
+	}
+
+	// Las siguientes consultas ya no la encuentran
+	if sessions, _ := s.GetActiveSessions(); len(sessions) != 1 {
+		t.Fatalf("esperaba 1 sesión activa en la segunda consulta, hay %d", len(sessions))
+	}
+}
+
+func TestCreateSessionMaxPlayers(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	s.SetMaxPlayers(2)
+
+	createTestSession(t, s, "Ana")
+	beto := createTestSession(t, s, "Beto")
+
+	if _, _, err := s.CreateSession("Carla", models.SessionModeLive, "", ""); !errors.Is(err, ErrGameFull) {
+		t.Fatalf("esperaba ErrGameFull con el cupo lleno, obtuve %v", err)
+	}
+
+	// Quien ya tiene sesión puede continuarla aunque el cupo esté lleno
+	if session, created, err := s.CreateSession("Beto", models.SessionModeLive, "", ""); err != nil || created || session.ID != beto.ID {
+		t.Fatalf("Beto debería continuar su sesión: %v", err)
+	}
+
+	// La práctica no ocupa cupo
+	if _, _, err := s.CreateSession("Carla", models.SessionModePractice, "", ""); err != nil {
+		t.Fatalf("la práctica no debe estar limitada por el cupo: %v", err)
+	}
+
+	// Al terminar una sesión se libera su lugar
+	if err := s.FinishSession(beto.ID); err != nil {
+		t.Fatalf("error terminando sesión: %v", err)
+	}
+	createTestSession(t, s, "Carla")
+}
+
+func TestConcurrentJoinsRespectMaxPlayers(t *testing.T) {
+	s, store := newTestSessionService(t)
+	s.SetMaxPlayers(5)
+
+	const joiners = 30
+	var wg sync.WaitGroup
+	var joined, full atomic.Int32
+	errs := make(chan error, joiners)
+	for i := 0; i < joiners; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, err := s.CreateSession(fmt.Sprintf("Jugador %d", i), models.SessionModeLive, "", "")
+			switch {
+			case err == nil:
+				joined.Add(1)
+			case errors.Is(err, ErrGameFull):
+				full.Add(1)
+			default:
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("error inesperado creando sesión: %v", err)
+	}
+	if joined.Load() != 5 || full.Load() != joiners-5 {
+		t.Fatalf("esperaba 5 ingresos y %d rechazos, obtuve %d y %d", joiners-5, joined.Load(), full.Load())
+	}
+	if size, err := store.GetSetSize("active_sessions"); err != nil || size != 5 {
+		t.Fatalf("esperaba 5 sesiones activas, obtuve %d (%v)", size, err)
+	}
+}
+
+func TestPracticeSessionsStayOffLeaderboard(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	s.SetMaxQuestions(2)
+
+	live := createTestSession(t, s, "Ana")
+	practice, created, err := s.CreateSession("Ana", models.SessionModePractice, "", "")
+	if err != nil || !created {
+		t.Fatalf("la práctica debe crear su propia sesión aunque haya una en vivo: %v", err)
+	}
+	if practice.ID == live.ID || !practice.IsPractice() {
+		t.Fatalf("esperaba una sesión de práctica distinta: %+v", practice)
+	}
+
+	// La práctica funciona igual: acierta, avanza y termina
+	practice = addTestAnswer(t, s, practice.ID, testAnswer(1, true, 1000000))
+	if practice.TotalPrize != 1000000 || practice.CurrentQuestion != 2 {
+		t.Fatalf("la práctica no avanzó: premio %d, pregunta %d", practice.TotalPrize, practice.CurrentQuestion)
+	}
+	practice = addTestAnswer(t, s, practice.ID, testAnswer(2, true, 2000000))
+	if practice.GameStatus != "finished" {
+		t.Fatalf("la práctica debería terminar, estado %s", practice.GameStatus)
+	}
+
+	if isActiveSession(t, s, practice.ID) {
+		t.Fatalf("la práctica no debe estar entre las sesiones activas")
+	}
+	leaderboard, err := s.GetLeaderboard()
+	if err != nil {
+		t.Fatalf("error obteniendo tabla: %v", err)
+	}
+	if len(leaderboard.Leaderboard) != 1 || leaderboard.Leaderboard[0].CurrentPrize != 0 {
+		t.Fatalf("la tabla solo debe mostrar la sesión en vivo: %+v", leaderboard.Leaderboard)
+	}
+}
+
+func TestNewSessionStartsOnFirstPlannedQuestion(t *testing.T) {
+	// El plan sigue el orden del archivo: 3, 1, 2
+	questions := testQuestions(3)
+	questions[0], questions[1], questions[2] = questions[2], questions[0], questions[1]
+	_, store := newTestQuestionService(t, questions)
+	s := NewSessionService(store)
+
+	gameState := NewGameStateService(store)
+	if err := gameState.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+
+	session := createTestSession(t, s, "Ana")
+	if session.CurrentQuestionID != 3 {
+		t.Fatalf("esperaba CurrentQuestionID 3 (primera del plan), obtuve %d", session.CurrentQuestionID)
+	}
+	if stored := mustGetSession(t, s, session.ID); stored.CurrentQuestionID != 3 {
+		t.Fatalf("la sesión guardada tiene CurrentQuestionID %d", stored.CurrentQuestionID)
+	}
+
+	session = addTestAnswer(t, s, session.ID, testAnswer(1, true, 100))
+	if session.CurrentQuestionID != 1 {
+		t.Fatalf("tras acertar esperaba la pregunta 1 del banco, obtuve %d", session.CurrentQuestionID)
+	}
+}
+
+func TestCreateSessionAutoContinue(t *testing.T) {
+	t.Run("activado", func(t *testing.T) {
+		s, _ := newTestSessionService(t)
+		first := createTestSession(t, s, "Ana")
+
+		again, created, err := s.CreateSession("Ana", models.SessionModeLive, "", "")
+		if err != nil || created || again.ID != first.ID {
+			t.Fatalf("con auto-continuar, el mismo nombre retoma la sesión: %v", err)
+		}
+	})
+
+	t.Run("desactivado", func(t *testing.T) {
+		s, _ := newTestSessionService(t)
+		s.SetAutoContinue(false)
+		first := createTestSession(t, s, "Ana")
+
+		if _, _, err := s.CreateSession("Ana", models.SessionModeLive, "", ""); !errors.Is(err, ErrPlayerNameTaken) {
+			t.Fatalf("sin el ID de la sesión esperaba ErrPlayerNameTaken, obtuve %v", err)
+		}
+		if _, _, err := s.CreateSession("Ana", models.SessionModeLive, "otra-sesion", ""); !errors.Is(err, ErrPlayerNameTaken) {
+			t.Fatalf("con un ID ajeno esperaba ErrPlayerNameTaken, obtuve %v", err)
+		}
+
+		again, created, err := s.CreateSession("Ana", models.SessionModeLive, first.ID, "")
+		if err != nil || created || again.ID != first.ID {
+			t.Fatalf("con su propio ID debe reconectarse: %v", err)
+		}
+	})
+}
+
+func TestPreregisteredNameIsReserved(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	s.SetMaxPlayers(1)
+
+	reservations, err := s.PreregisterPlayers([]string{" Ana ", "", "Beto"})
+	if err != nil {
+		t.Fatalf("error pre-registrando: %v", err)
+	}
+	if len(reservations) != 2 || reservations[0].PlayerName != "Ana" || reservations[0].ClaimCode == "" {
+		t.Fatalf("reservas inesperadas: %+v", reservations)
+	}
+	code := reservations[0].ClaimCode
+
+	// Reservar de nuevo no cambia ni revela el código
+	again, _ := s.PreregisterPlayers([]string{"Ana"})
+	if again[0].Created || again[0].ClaimCode != "" {
+		t.Fatalf("una reserva repetida no debe generar código nuevo: %+v", again[0])
+	}
+
+	if _, _, err := s.CreateSession("Ana", models.SessionModeLive, "", ""); !errors.Is(err, ErrNameReserved) {
+		t.Fatalf("sin código esperaba ErrNameReserved, obtuve %v", err)
+	}
+	if _, _, err := s.CreateSession("Ana", models.SessionModeLive, "", "INCORRECTO"); !errors.Is(err, ErrNameReserved) {
+		t.Fatalf("con código incorrecto esperaba ErrNameReserved, obtuve %v", err)
+	}
+
+	// Llenar el cupo: los pre-registrados tienen lugar garantizado
+	createTestSession(t, s, "Carla")
+	session, created, err := s.CreateSession("Ana", models.SessionModeLive, "", strings.ToLower(code))
+	if err != nil || !created || session.PlayerName != "Ana" {
+		t.Fatalf("con su código Ana debe entrar aunque el cupo esté lleno: %v", err)
+	}
+}
+
+func TestFinishedSessionLeavesActiveSet(t *testing.T) {
+	s, store := newTestSessionService(t)
+	s.SetMaxQuestions(1)
+
+	ana := createTestSession(t, s, "Ana")
+	beto := createTestSession(t, s, "Beto")
+	addTestAnswer(t, s, ana.ID, testAnswer(1, true, 500))
+
+	// AddAnswer la saca del set en el mismo paso, sin esperar a GetActiveSessions
+	if setMembers(t, store, "active_sessions")[ana.ID] {
+		t.Fatalf("la sesión terminada sigue en el set activo")
+	}
+	if isActiveSession(t, s, ana.ID) || !isActiveSession(t, s, beto.ID) {
+		t.Fatalf("solo Beto debería seguir activo")
+	}
+	if count, _ := s.CountActiveSessions(); count != 1 {
+		t.Fatalf("esperaba 1 sesión activa, hay %d", count)
+	}
+
+	// Una sesión terminada que quedó en el set (p. ej. escrita por una versión
+	// anterior) se limpia al listar
+	finished := mustGetSession(t, s, ana.ID)
+	store.AddToSet("active_sessions", finished.ID)
+	if isActiveSession(t, s, finished.ID) {
+		t.Fatalf("una sesión terminada nunca se lista como activa")
+	}
+	if setMembers(t, store, "active_sessions")[finished.ID] {
+		t.Fatalf("la sesión terminada debió quitarse del set al listar")
+	}
+}
+
+func TestCertificateReflectsFinalStateAndRank(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	s.SetMaxQuestions(2)
+
+	// Ana y Beto terminan; Carla queda eliminada con el mayor premio parcial
+	ana := createTestSession(t, s, "Ana")
+	addTestAnswer(t, s, ana.ID, testAnswer(1, true, 1000))
+	addTestAnswer(t, s, ana.ID, testAnswer(2, true, 2000))
+
+	beto := createTestSession(t, s, "Beto")
+	addTestAnswer(t, s, beto.ID, testAnswer(1, true, 1000))
+	addTestAnswer(t, s, beto.ID, testAnswer(2, true, 5000))
+
+	carla := createTestSession(t, s, "Carla")
+	addTestAnswer(t, s, carla.ID, testAnswer(1, true, 3000))
+	addTestAnswer(t, s, carla.ID, testAnswer(2, false, 0))
+
+	certificate, err := s.GetCertificate(ana.ID)
+	if err != nil {
+		t.Fatalf("error obteniendo certificado: %v", err)
+	}
+	if certificate.PlayerName != "Ana" || certificate.FinalPrize != 2000 || certificate.QuestionsConquered != 2 {
+		t.Fatalf("certificado inesperado: %+v", certificate)
+	}
+	if certificate.Status != "finished" || certificate.RankedAmong != "finishers" {
+		t.Fatalf("Ana terminó y se clasifica entre quienes terminaron: %+v", certificate)
+	}
+	if certificate.Rank != 2 || certificate.TotalRanked != 2 {
+		t.Fatalf("Ana es segunda de 2 que terminaron, obtuve %d de %d", certificate.Rank, certificate.TotalRanked)
+	}
+
+	certificate, _ = s.GetCertificate(carla.ID)
+	if certificate.Status != "eliminated" || certificate.RankedAmong != "allPlayers" || certificate.QuestionsConquered != 1 {
+		t.Fatalf("certificado de eliminada inesperado: %+v", certificate)
+	}
+	if certificate.Rank != 2 || certificate.TotalRanked != 3 {
+		t.Fatalf("Carla es segunda de 3 jugadores, obtuve %d de %d", certificate.Rank, certificate.TotalRanked)
+	}
+
+	if _, err := s.GetCertificate("no-existe"); err == nil {
+		t.Fatalf("una sesión inexistente no tiene certificado")
+	}
+}
+
+func TestWagerAnswers(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	session, _, err := s.CreateSession("Apostador", models.SessionModeWager, "", "")
+	if err != nil {
+		t.Fatalf("error creando sesión: %v", err)
+	}
+
+	wagered := func(number int, correct bool, prize, wager int64) models.PlayerAnswer {
+		answer := testAnswer(number, correct, prize)
+		answer.Wager = wager
+		return answer
+	}
+	steps := []struct {
+		answer    models.PlayerAnswer
+		total     int64
+		wagerUsed int64
+	}{
+		{wagered(1, true, 100, 500), 100, 0},    // sin premio acumulado la apuesta queda en 0
+		{wagered(2, true, 200, 50), 350, 50},    // gana: premio + apuesta
+		{wagered(3, false, 300, 100), 250, 100}, // pierde: descuenta la apuesta
+		{wagered(4, false, 400, 1000), 0, 250},  // la apuesta se limita a lo acumulado
+	}
+	for _, step := range steps {
+		session = addTestAnswer(t, s, session.ID, step.answer)
+		if session.TotalPrize != step.total {
+			t.Fatalf("pregunta %d: premio %d, esperaba %d", step.answer.QuestionNumber, session.TotalPrize, step.total)
+		}
+		recorded := session.AnswersGiven[len(session.AnswersGiven)-1]
+		if recorded.Wager != step.wagerUsed {
+			t.Fatalf("pregunta %d: apuesta registrada %d, esperaba %d", step.answer.QuestionNumber, recorded.Wager, step.wagerUsed)
+		}
+	}
+
+	// Un error en modo apuesta no elimina: se sigue jugando
+	if session.GameStatus != "active" || session.CurrentQuestion != 5 {
+		t.Fatalf("esperaba sesión activa en la pregunta 5, está %s en la %d", session.GameStatus, session.CurrentQuestion)
+	}
+}
+
+func TestWagerIgnoredOutsideWagerMode(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	session := createTestSession(t, s, "Ana")
+	answer := testAnswer(1, true, 100)
+	answer.Wager = 50
+
+	session = addTestAnswer(t, s, session.ID, answer)
+	if session.TotalPrize != 100 || session.AnswersGiven[0].Wager != 0 {
+		t.Fatalf("fuera del modo apuesta la apuesta no cuenta: premio %d, apuesta %d", session.TotalPrize, session.AnswersGiven[0].Wager)
+	}
+}
+
+func TestApplyWagerClampsToMaxPrize(t *testing.T) {
+	const maxPrize = models.MaxSafePrize
+	session := &models.GameSession{Mode: models.SessionModeWager, TotalPrize: maxPrize - 10}
+	answer := models.PlayerAnswer{IsCorrect: true, PrizeWon: maxPrize, Wager: maxPrize}
+
+	applyWager(session, &answer, maxPrize)
+	if answer.Wager != maxPrize-10 {
+		t.Fatalf("la apuesta debe limitarse al premio acumulado, quedó %d", answer.Wager)
+	}
+	if session.TotalPrize != maxPrize {
+		t.Fatalf("el premio debe limitarse al tope, quedó %d", session.TotalPrize)
+	}
+
+	answer = models.PlayerAnswer{Wager: -5}
+	applyWager(session, &answer, maxPrize)
+	if answer.Wager != 0 || session.TotalPrize != maxPrize {
+		t.Fatalf("una apuesta negativa cuenta como 0: apuesta %d, premio %d", answer.Wager, session.TotalPrize)
+	}
+}
+
+func TestSeedDemoSessions(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	seeded, err := s.SeedDemoSessions(20, 7)
+	if err != nil {
+		t.Fatalf("error sembrando sesiones: %v", err)
+	}
+	if len(seeded) != 20 {
+		t.Fatalf("esperaba 20 sesiones, se crearon %d", len(seeded))
+	}
+
+	all, err := s.GetAllSessions()
+	if err != nil || len(all) != 20 {
+		t.Fatalf("esperaba 20 sesiones guardadas, hay %d (%v)", len(all), err)
+	}
+	active := 0
+	for _, session := range seeded {
+		if session.GameStatus == "active" {
+			active++
+		}
+	}
+	if count, err := s.CountActiveSessions(); err != nil || count != active {
+		t.Fatalf("esperaba %d sesiones activas, hay %d (%v)", active, count, err)
+	}
+
+	// La misma semilla produce los mismos datos
+	other, _ := newTestSessionService(t)
+	again, err := other.SeedDemoSessions(20, 7)
+	if err != nil {
+		t.Fatalf("error sembrando sesiones: %v", err)
+	}
+	for i := range seeded {
+		if again[i].ID != seeded[i].ID || again[i].TotalPrize != seeded[i].TotalPrize || again[i].GameStatus != seeded[i].GameStatus {
+			t.Fatalf("sesión %d distinta con la misma semilla: %+v vs %+v", i, again[i], seeded[i])
+		}
+	}
+}
+
+func TestAdjustPrize(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	session := createTestSession(t, s, "Ana")
+	addTestAnswer(t, s, session.ID, testAnswer(1, true, 1000))
+	amount := func(v int64) *int64 { return &v }
+
+	adjusted, err := s.AdjustPrize(session.ID, models.AdjustPrizeRequest{Delta: amount(-300), Reason: " Respuesta en disputa "}, "presentador")
+	if err != nil {
+		t.Fatalf("error con corrección relativa: %v", err)
+	}
+	if adjusted.TotalPrize != 700 {
+		t.Fatalf("esperaba premio 700, quedó %d", adjusted.TotalPrize)
+	}
+
+	adjusted, err = s.AdjustPrize(session.ID, models.AdjustPrizeRequest{NewValue: amount(5000), Reason: "Pregunta repetida"}, "productor")
+	if err != nil {
+		t.Fatalf("error con corrección absoluta: %v", err)
+	}
+	if adjusted.TotalPrize != 5000 {
+		t.Fatalf("esperaba premio 5000, quedó %d", adjusted.TotalPrize)
+	}
+
+	// Las correcciones quedan registradas en la sesión guardada
+	stored := mustGetSession(t, s, session.ID)
+	if stored.TotalPrize != 5000 || len(stored.PrizeAdjustments) != 2 {
+		t.Fatalf("sesión guardada inesperada: premio %d, %d correcciones", stored.TotalPrize, len(stored.PrizeAdjustments))
+	}
+	first := stored.PrizeAdjustments[0]
+	if first.PreviousPrize != 1000 || first.NewPrize != 700 || first.Reason != "Respuesta en disputa" || first.Admin != "presentador" || first.Timestamp.IsZero() {
+		t.Fatalf("registro de corrección inesperado: %+v", first)
+	}
+	if second := stored.PrizeAdjustments[1]; second.PreviousPrize != 700 || second.NewPrize != 5000 || second.Admin != "productor" {
+		t.Fatalf("registro de corrección inesperado: %+v", second)
+	}
+}
+
+func TestAdjustPrizeValidation(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	session := createTestSession(t, s, "Ana")
+	amount := func(v int64) *int64 { return &v }
+
+	cases := []struct {
+		name    string
+		request models.AdjustPrizeRequest
+		want    error
+	}{
+		{"sin monto", models.AdjustPrizeRequest{Reason: "x"}, ErrInvalidAdjustment},
+		{"ambos montos", models.AdjustPrizeRequest{Delta: amount(1), NewValue: amount(1), Reason: "x"}, ErrInvalidAdjustment},
+		{"sin motivo", models.AdjustPrizeRequest{Delta: amount(1), Reason: "  "}, ErrInvalidAdjustment},
+		{"sobre el máximo", models.AdjustPrizeRequest{NewValue: amount(models.MaxSafePrize + 1), Reason: "x"}, ErrInvalidAdjustment},
+		{"premio negativo", models.AdjustPrizeRequest{Delta: amount(-1), Reason: "x"}, ErrNegativePrize},
+		{"valor negativo", models.AdjustPrizeRequest{NewValue: amount(-5), Reason: "x"}, ErrNegativePrize},
+	}
+	for _, c := range cases {
+		if _, err := s.AdjustPrize(session.ID, c.request, "presentador"); !errors.Is(err, c.want) {
+			t.Fatalf("%s: esperaba %v, obtuve %v", c.name, c.want, err)
+		}
+	}
+	if stored := mustGetSession(t, s, session.ID); stored.TotalPrize != 0 || len(stored.PrizeAdjustments) != 0 {
+		t.Fatalf("una corrección rechazada no debe modificar la sesión: %+v", stored)
+	}
+}
+
+func TestGetActiveSessionByPlayerReconcilesDuplicates(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	older := createTestSession(t, s, "Ana")
+
+	// Un fallo de limpieza dejó una segunda sesión activa con el mismo nombre
+	newer := *older
+	newer.ID = "sesion-duplicada"
+	newer.LastActivity = older.LastActivity.Add(time.Minute)
+	if err := s.saveSession(&newer); err != nil {
+		t.Fatalf("error guardando sesión duplicada: %v", err)
+	}
+	if err := s.addToActiveSessions(newer.ID); err != nil {
+		t.Fatalf("error agregando a sesiones activas: %v", err)
+	}
+	if err := s.addToPlayerSessions(newer.PlayerName, newer.ID); err != nil {
+		t.Fatalf("error agregando a sesiones del jugador: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		session, err := s.GetActiveSessionByPlayer(older.PlayerName)
+		if err != nil {
+			t.Fatalf("error buscando sesión activa: %v", err)
+		}
+		if session.ID != newer.ID {
+			t.Fatalf("intento %d: esperaba la sesión más reciente %s, obtuve %s", i+1, newer.ID, session.ID)
+		}
+	}
+
+	if reconciled := mustGetSession(t, s, older.ID); reconciled.GameStatus != "finished" {
+		t.Fatalf("la sesión más antigua debe terminarse, está %s", reconciled.GameStatus)
+	}
+	if isActiveSession(t, s, older.ID) || !isActiveSession(t, s, newer.ID) {
+		t.Fatalf("solo la sesión más reciente debe seguir activa")
+	}
+}
+
+func TestMultipleLivesElimination(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	s.SetLives(3)
+	session := createTestSession(t, s, "Ana")
+	if session.LivesRemaining != 3 {
+		t.Fatalf("esperaba 3 vidas al empezar, tiene %d", session.LivesRemaining)
+	}
+
+	steps := []struct {
+		answer   models.PlayerAnswer
+		lives    int
+		status   string
+		question int
+		prize    int64
+	}{
+		{testAnswer(1, false, 0), 2, "active", 2, 0},
+		{testAnswer(2, true, 200), 2, "active", 3, 200},
+		{testAnswer(3, false, 0), 1, "active", 4, 200},
+		{testAnswer(4, false, 0), 0, "eliminated", 4, 200},
+	}
+	for _, step := range steps {
+		session = addTestAnswer(t, s, session.ID, step.answer)
+		if session.LivesRemaining != step.lives || session.GameStatus != step.status ||
+			session.CurrentQuestion != step.question || session.TotalPrize != step.prize {
+			t.Fatalf("tras la pregunta %d: vidas %d, estado %s, pregunta %d, premio %d",
+				step.answer.QuestionNumber, session.LivesRemaining, session.GameStatus, session.CurrentQuestion, session.TotalPrize)
+		}
+	}
+
+	// Cambiar la configuración solo afecta a las sesiones nuevas
+	s.SetLives(1)
+	if other := createTestSession(t, s, "Luis"); other.LivesRemaining != 1 {
+		t.Fatalf("esperaba 1 vida en la sesión nueva, tiene %d", other.LivesRemaining)
+	}
+}
+
+func TestGetNextPrize(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	last := len(models.PrizeLevels)
+
+	cases := []struct {
+		name         string
+		mode         string
+		question     int
+		totalPrize   int64
+		status       string
+		prize        int64
+		prizeIfWrong int64
+		complete     bool
+	}{
+		{"primera", models.SessionModeLive, 1, 0, "active", 100, 0, false},
+		{"intermedia", models.SessionModeLive, 5, 500, "active", 1000, 500, false},
+		{"última", models.SessionModeLive, last, 500000, "active", 1000000, 500000, false},
+		{"fuera de la escalera", models.SessionModeLive, last + 1, 1000000, "active", 1000000, 1000000, true},
+		{"número inválido", models.SessionModeLive, 0, 0, "active", 0, 0, true},
+		{"terminada", models.SessionModeLive, 3, 200, "finished", 200, 200, true},
+		{"apuesta", models.SessionModeWager, 2, 700, "active", 900, 700, false},
+	}
+	for i, c := range cases {
+		session, _, err := s.CreateSession(fmt.Sprintf("Jugador %d", i), c.mode, "", "")
+		if err != nil {
+			t.Fatalf("%s: error creando sesión: %v", c.name, err)
+		}
+		session.CurrentQuestion = c.question
+		session.TotalPrize = c.totalPrize
+		session.GameStatus = c.status
+		if err := s.UpdateSession(session); err != nil {
+			t.Fatalf("%s: error actualizando sesión: %v", c.name, err)
+		}
+
+		next, err := s.GetNextPrize(session.ID)
+		if err != nil {
+			t.Fatalf("%s: error obteniendo premio: %v", c.name, err)
+		}
+		if next.Prize != c.prize || next.PrizeIfWrong != c.prizeIfWrong || next.Complete != c.complete || next.QuestionNumber != c.question {
+			t.Fatalf("%s: premio inesperado %+v", c.name, next)
+		}
+		if next.FormattedPrize != models.FormatPrize(c.prize) || next.FormattedPrizeIfWrong != models.FormatPrize(c.prizeIfWrong) {
+			t.Fatalf("%s: formato inesperado %+v", c.name, next)
+		}
+	}
+
+	if _, err := s.GetNextPrize("no-existe"); err == nil {
+		t.Fatalf("una sesión inexistente debe devolver error")
+	}
+}
+
+func TestReverseCorrectAnswer(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	session := createTestSession(t, s, "Ana")
+	addTestAnswer(t, s, session.ID, testAnswer(1, true, 100))
+	addTestAnswer(t, s, session.ID, testAnswer(2, true, 200))
+
+	reversedSession, reversed, err := s.ReverseAnswer(session.ID, 2)
+	if err != nil {
+		t.Fatalf("error anulando respuesta: %v", err)
+	}
+	if reversed.QuestionNumber != 2 || !reversed.IsCorrect {
+		t.Fatalf("respuesta anulada inesperada: %+v", reversed)
+	}
+	stored := mustGetSession(t, s, session.ID)
+	for _, got := range []*models.GameSession{reversedSession, stored} {
+		if got.TotalPrize != 100 || got.CurrentQuestion != 2 || got.GameStatus != "active" || len(got.AnswersGiven) != 1 {
+			t.Fatalf("estado recalculado inesperado: premio %d, pregunta %d, estado %s, %d respuestas",
+				got.TotalPrize, got.CurrentQuestion, got.GameStatus, len(got.AnswersGiven))
+		}
+	}
+}
+
+func TestReverseWrongAnswerRevivesPlayer(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	session := createTestSession(t, s, "Ana")
+	addTestAnswer(t, s, session.ID, testAnswer(1, true, 100))
+	if eliminated := addTestAnswer(t, s, session.ID, testAnswer(2, false, 0)); eliminated.GameStatus != "eliminated" {
+		t.Fatalf("esperaba al jugador eliminado, está %s", eliminated.GameStatus)
+	}
+
+	if _, _, err := s.ReverseAnswer(session.ID, 2); err != nil {
+		t.Fatalf("error anulando respuesta: %v", err)
+	}
+	stored := mustGetSession(t, s, session.ID)
+	if stored.GameStatus != "active" || stored.LivesRemaining != 1 || stored.CurrentQuestion != 2 || stored.TotalPrize != 100 {
+		t.Fatalf("el jugador debe volver a la pregunta 2: estado %s, vidas %d, pregunta %d, premio %d",
+			stored.GameStatus, stored.LivesRemaining, stored.CurrentQuestion, stored.TotalPrize)
+	}
+	if !isActiveSession(t, s, session.ID) {
+		t.Fatalf("la sesión revivida debe estar en el set de activas")
+	}
+
+	if _, _, err := s.ReverseAnswer(session.ID, 5); !errors.Is(err, ErrAnswerNotFound) {
+		t.Fatalf("esperaba ErrAnswerNotFound, obtuve %v", err)
+	}
+}
+
+func TestRecomputeMatchesIncrementalState(t *testing.T) {
+	wagered := func(number int, correct bool, prize, wager int64) models.PlayerAnswer {
+		answer := testAnswer(number, correct, prize)
+		answer.Wager = wager
+		return answer
+	}
+	scenarios := []struct {
+		name    string
+		mode    string
+		lives   int
+		answers []models.PlayerAnswer
+	}{
+		{"eliminado", models.SessionModeLive, 1, []models.PlayerAnswer{testAnswer(1, true, 100), testAnswer(2, false, 0)}},
+		{"con vidas", models.SessionModeLive, 3, []models.PlayerAnswer{
+			testAnswer(1, false, 0), testAnswer(2, true, 200), testAnswer(3, false, 0), testAnswer(4, true, 500),
+		}},
+		{"terminado", models.SessionModeLive, 1, []models.PlayerAnswer{
+			testAnswer(1, true, 100), testAnswer(2, true, 200), testAnswer(3, true, 300), testAnswer(4, true, 500),
+		}},
+		{"apuesta", models.SessionModeWager, 1, []models.PlayerAnswer{
+			wagered(1, true, 100, 0), wagered(2, true, 200, 50), wagered(3, false, 300, 100),
+		}},
+	}
+
+	for i, scenario := range scenarios {
+		s, _ := newTestSessionService(t)
+		s.SetMaxQuestions(4)
+		s.SetLives(scenario.lives)
+		session, _, err := s.CreateSession(fmt.Sprintf("Jugador %d", i), scenario.mode, "", "")
+		if err != nil {
+			t.Fatalf("%s: error creando sesión: %v", scenario.name, err)
+		}
+		var incremental *models.GameSession
+		for _, answer := range scenario.answers {
+			incremental = addTestAnswer(t, s, session.ID, answer)
+		}
+
+		recomputed, changed, err := s.RecomputeSession(session.ID)
+		if err != nil {
+			t.Fatalf("%s: error recalculando: %v", scenario.name, err)
+		}
+		if changed || recomputed.TotalPrize != incremental.TotalPrize || recomputed.CurrentQuestion != incremental.CurrentQuestion ||
+			recomputed.LivesRemaining != incremental.LivesRemaining || recomputed.GameStatus != incremental.GameStatus {
+			t.Fatalf("%s: recalculado %+v distinto del incremental %+v", scenario.name, recomputed, incremental)
+		}
+
+		// Un campo derivado desincronizado se repara
+		drifted := mustGetSession(t, s, session.ID)
+		drifted.TotalPrize = 999999
+		drifted.CurrentQuestion = 7
+		if err := s.UpdateSession(drifted); err != nil {
+			t.Fatalf("%s: error guardando sesión: %v", scenario.name, err)
+		}
+		if _, changed, err := s.RecomputeSession(session.ID); err != nil || !changed {
+			t.Fatalf("%s: esperaba reparar la sesión (%v)", scenario.name, err)
+		}
+		repaired := mustGetSession(t, s, session.ID)
+		if repaired.TotalPrize != incremental.TotalPrize || repaired.CurrentQuestion != incremental.CurrentQuestion {
+			t.Fatalf("%s: reparación inesperada: premio %d, pregunta %d", scenario.name, repaired.TotalPrize, repaired.CurrentQuestion)
+		}
+	}
+}
+
+func TestGetAnswerTimingBuckets(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	startedAt := time.Now().UTC().Add(-30 * time.Second)
+
+	for i, offset := range []time.Duration{2 * time.Second, 7 * time.Second, 8 * time.Second, 25 * time.Second} {
+		session := createTestSession(t, s, fmt.Sprintf("Rápido%d", i))
+		answer := testAnswer(1, true, 1000)
+		answer.Timestamp = startedAt.Add(offset)
+		addTestAnswer(t, s, session.ID, answer)
+	}
+	createTestSession(t, s, "Pensando1")
+	createTestSession(t, s, "Pensando2")
+
+	timing, err := s.GetAnswerTiming(1, startedAt, 20*time.Second)
+	if err != nil {
+		t.Fatalf("error calculando tiempos: %v", err)
+	}
+	if timing.Answered != 4 || timing.Pending != 2 {
+		t.Fatalf("esperaba 4 respondidas y 2 pendientes: %+v", timing)
+	}
+	if timing.ElapsedSeconds < 30 {
+		t.Fatalf("tiempo transcurrido %d, esperaba al menos 30", timing.ElapsedSeconds)
+	}
+
+	// 20 segundos en rangos de 5 más un último rango sin límite
+	want := []models.TimingBucket{
+		{FromSeconds: 0, ToSeconds: 5, Count: 1},
+		{FromSeconds: 5, ToSeconds: 10, Count: 2},
+		{FromSeconds: 10, ToSeconds: 15, Count: 0},
+		{FromSeconds: 15, ToSeconds: 20, Count: 0},
+		{FromSeconds: 20, ToSeconds: 0, Count: 1},
+	}
+	if len(timing.Buckets) != len(want) {
+		t.Fatalf("esperaba %d rangos, hubo %+v", len(want), timing.Buckets)
+	}
+	for i, bucket := range want {
+		if timing.Buckets[i] != bucket {
+			t.Fatalf("rango %d: esperaba %+v, obtuve %+v", i, bucket, timing.Buckets[i])
+		}
+	}
+	if timing.AverageSeconds != 10.5 {
+		t.Fatalf("promedio %v, esperaba 10.5", timing.AverageSeconds)
+	}
+}
+
+func TestGetSpectatorsOnlyEliminated(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	s.SetMaxQuestions(2)
+
+	createTestSession(t, s, "Activa")
+	early := createTestSession(t, s, "Temprano")
+	addTestAnswer(t, s, early.ID, testAnswer(1, false, 0))
+	late := createTestSession(t, s, "Tarde")
+	addTestAnswer(t, s, late.ID, testAnswer(1, true, 1000))
+	addTestAnswer(t, s, late.ID, testAnswer(2, false, 0))
+	winner := createTestSession(t, s, "Ganador")
+	addTestAnswer(t, s, winner.ID, testAnswer(1, true, 1000))
+	addTestAnswer(t, s, winner.ID, testAnswer(2, true, 2000))
+	practice, _, err := s.CreateSession("Practica", models.SessionModePractice, "", "")
+	if err != nil {
+		t.Fatalf("error creando práctica: %v", err)
+	}
+	addTestAnswer(t, s, practice.ID, testAnswer(1, false, 0))
+
+	spectators, err := s.GetSpectators()
+	if err != nil {
+		t.Fatalf("error obteniendo espectadores: %v", err)
+	}
+	if len(spectators) != 2 {
+		t.Fatalf("esperaba solo los 2 eliminados en vivo, obtuve %+v", spectators)
+	}
+	// Primero quien llegó más lejos
+	if spectators[0].SessionID != late.ID || spectators[0].EliminatedAt != 2 || spectators[0].FinalPrize != 1000 || spectators[0].FormattedPrize != "$1,000" {
+		t.Fatalf("primer espectador inesperado: %+v", spectators[0])
+	}
+	if spectators[1].SessionID != early.ID || spectators[1].EliminatedAt != 1 || spectators[1].FinalPrize != 0 {
+		t.Fatalf("segundo espectador inesperado: %+v", spectators[1])
+	}
+
+	// Sin eliminados la lista está vacía, no es nil
+	empty, _ := newTestSessionService(t)
+	createTestSession(t, empty, "Activa")
+	if spectators, err := empty.GetSpectators(); err != nil || spectators == nil || len(spectators) != 0 {
+		t.Fatalf("esperaba una lista vacía: %v (%v)", spectators, err)
+	}
+}
+
+func TestVoidQuestionRevertsEliminations(t *testing.T) {
+	s, _ := newTestSessionService(t)
+
+	eliminated := createTestSession(t, s, "Ana")
+	addTestAnswer(t, s, eliminated.ID, testAnswer(1, true, 1000))
+	if session := addTestAnswer(t, s, eliminated.ID, testAnswer(2, false, 0)); session.GameStatus != "eliminated" {
+		t.Fatalf("fallar la pregunta 2 debe eliminar, estado %s", session.GameStatus)
+	}
+	correct := createTestSession(t, s, "Beto")
+	addTestAnswer(t, s, correct.ID, testAnswer(1, true, 1000))
+	addTestAnswer(t, s, correct.ID, testAnswer(2, true, 2000))
+	untouched := createTestSession(t, s, "Carla")
+	addTestAnswer(t, s, untouched.ID, testAnswer(1, true, 1000))
+
+	affected, err := s.VoidQuestion(2)
+	if err != nil {
+		t.Fatalf("error anulando pregunta: %v", err)
+	}
+	if len(affected) != 2 {
+		t.Fatalf("esperaba 2 sesiones afectadas, hubo %d", len(affected))
+	}
+
+	// La eliminación se revierte: vuelve a jugar con su premio previo
+	session := mustGetSession(t, s, eliminated.ID)
+	if session.GameStatus != "active" || session.TotalPrize != 1000 || session.CurrentQuestion != 3 || session.LivesRemaining != 1 {
+		t.Fatalf("la anulación debe revertir la eliminación: %+v", session)
+	}
+	if !isActiveSession(t, s, eliminated.ID) {
+		t.Fatalf("la sesión revivida debe volver a las activas")
+	}
+	if !session.AnswersGiven[1].Voided || session.AnswersGiven[1].PrizeWon != 0 {
+		t.Fatalf("la respuesta anulada queda marcada y sin premio: %+v", session.AnswersGiven[1])
+	}
+
+	// Quien acertó tampoco gana por la pregunta anulada
+	if session := mustGetSession(t, s, correct.ID); session.TotalPrize != 1000 || session.CurrentQuestion != 3 {
+		t.Fatalf("el acierto anulado no suma premio: %+v", session)
+	}
+	if session := mustGetSession(t, s, untouched.ID); session.TotalPrize != 1000 || session.CurrentQuestion != 2 {
+		t.Fatalf("quien no respondió no cambia: %+v", session)
+	}
+
+	// Responder después la pregunta anulada no elimina
+	session = addTestAnswer(t, s, untouched.ID, testAnswer(2, false, 0))
+	if session.GameStatus != "active" || session.CurrentQuestion != 3 || !session.AnswersGiven[1].Voided {
+		t.Fatalf("una respuesta tardía a la pregunta anulada no cuenta: %+v", session)
+	}
+}
+
+func TestLookupPlayers(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	s.SetMaxQuestions(1)
+
+	active := createTestSession(t, s, "Ana")
+	finished := createTestSession(t, s, "Beto")
+	addTestAnswer(t, s, finished.ID, testAnswer(1, true, 1000))
+	eliminated := createTestSession(t, s, "Carla")
+	addTestAnswer(t, s, eliminated.ID, testAnswer(1, false, 0))
+
+	statuses, err := s.LookupPlayers([]string{"Carla", " Ana ", "Nadie", "Beto"})
+	if err != nil {
+		t.Fatalf("error consultando jugadores: %v", err)
+	}
+	want := []struct {
+		name, status, sessionID string
+	}{
+		{"Carla", "eliminated", eliminated.ID},
+		{"Ana", "active", active.ID},
+		{"Nadie", "not_found", ""},
+		{"Beto", "finished", finished.ID},
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("esperaba %d estados en el orden pedido, obtuve %+v", len(want), statuses)
+	}
+	for i, w := range want {
+		got := statuses[i]
+		if got.PlayerName != w.name || got.GameStatus != w.status || got.SessionID != w.sessionID {
+			t.Fatalf("estado %d: esperaba %s/%s/%s, obtuve %+v", i, w.name, w.status, w.sessionID, got)
+		}
+	}
+	if statuses[3].TotalPrize != 1000 || statuses[2].LastActivity != nil {
+		t.Fatalf("datos de la sesión inesperados: %+v %+v", statuses[3], statuses[2])
+	}
+}
+
+func TestRejoinSession(t *testing.T) {
+	s, _ := newTestSessionService(t)
+
+	eliminate := func(name string, at time.Time) *models.GameSession {
+		t.Helper()
+		session := createTestSession(t, s, name)
+		addTestAnswer(t, s, session.ID, testAnswer(1, true, 1000))
+		answer := testAnswer(2, false, 0)
+		answer.Timestamp = at
+		return addTestAnswer(t, s, session.ID, answer)
+	}
+	recent := eliminate("Ana", time.Now().UTC())
+	old := eliminate("Beto", time.Now().UTC().Add(-2*time.Minute))
+	playing := createTestSession(t, s, "Carla")
+
+	// Eliminación estricta: sin ventana no se puede volver
+	if _, err := s.RejoinSession(recent.ID, 3); !errors.Is(err, ErrRejoinNotAllowed) {
+		t.Fatalf("en modo estricto esperaba ErrRejoinNotAllowed, obtuve %v", err)
+	}
+
+	s.SetRejoinWindow(time.Minute)
+	if _, err := s.RejoinSession(old.ID, 3); !errors.Is(err, ErrRejoinWindowExpired) {
+		t.Fatalf("fuera de la ventana esperaba ErrRejoinWindowExpired, obtuve %v", err)
+	}
+	if _, err := s.RejoinSession(playing.ID, 3); !errors.Is(err, ErrRejoinNotAllowed) {
+		t.Fatalf("una sesión activa no puede reingresar, obtuve %v", err)
+	}
+
+	// Dentro de la ventana vuelve con una vida en la pregunta en curso
+	session, err := s.RejoinSession(recent.ID, 4)
+	if err != nil {
+		t.Fatalf("error reingresando: %v", err)
+	}
+	if session.GameStatus != "active" || session.LivesRemaining != 1 || session.CurrentQuestion != 4 || session.TotalPrize != 1000 {
+		t.Fatalf("reingreso inesperado: %+v", session)
+	}
+	if len(session.Rejoins) != 1 || session.Rejoins[0].QuestionNumber != 4 {
+		t.Fatalf("el reingreso debe quedar registrado: %+v", session.Rejoins)
+	}
+	if !isActiveSession(t, s, recent.ID) {
+		t.Fatalf("la sesión debe volver a las activas")
+	}
+
+	// Sin pregunta en curso retoma después de la última que respondió
+	again := eliminate("Dani", time.Now().UTC())
+	if session, err := s.RejoinSession(again.ID, 0); err != nil || session.CurrentQuestion != 3 {
+		t.Fatalf("esperaba retomar en la pregunta 3: %+v (%v)", session, err)
+	}
+}
+
+func TestPrizesClampedNearCap(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	s.SetMaxPrize(1000000)
+
+	// Un premio por pregunta sobre el tope se limita
+	live := createTestSession(t, s, "Ana")
+	if session := addTestAnswer(t, s, live.ID, testAnswer(1, true, 5000000)); session.TotalPrize != 1000000 {
+		t.Fatalf("esperaba el premio limitado a 1000000, obtuve %d", session.TotalPrize)
+	}
+
+	// En modo apuesta el acumulado tampoco supera el tope
+	wager, _, err := s.CreateSession("Beto", models.SessionModeWager, "", "")
+	if err != nil {
+		t.Fatalf("error creando sesión de apuesta: %v", err)
+	}
+	addTestAnswer(t, s, wager.ID, testAnswer(1, true, 900000))
+	answer := testAnswer(2, true, 900000)
+	answer.Wager = 900000
+	if session := addTestAnswer(t, s, wager.ID, answer); session.TotalPrize != 1000000 {
+		t.Fatalf("esperaba el acumulado limitado a 1000000, obtuve %d", session.TotalPrize)
+	}
+
+	// Correcciones enormes se rechazan en lugar de desbordar
+	huge := int64(math.MaxInt64)
+	if _, err := s.AdjustPrize(live.ID, models.AdjustPrizeRequest{Delta: &huge, Reason: "prueba"}, "admin"); !errors.Is(err, ErrInvalidAdjustment) {
+		t.Fatalf("esperaba ErrInvalidAdjustment, obtuve %v", err)
+	}
+	negative := int64(math.MinInt64)
+	if _, err := s.AdjustPrize(live.ID, models.AdjustPrizeRequest{Delta: &negative, Reason: "prueba"}, "admin"); !errors.Is(err, ErrInvalidAdjustment) {
+		t.Fatalf("esperaba ErrInvalidAdjustment, obtuve %v", err)
+	}
+	delta := int64(1)
+	if session, err := s.AdjustPrize(live.ID, models.AdjustPrizeRequest{Delta: &delta, Reason: "bono"}, "admin"); err != nil || session.TotalPrize != 1000000 {
+		t.Fatalf("sumar sobre el tope debe limitarse: %+v (%v)", session, err)
+	}
+}
+
+func TestRestartSessionIsBrandNew(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	s.SetLives(2)
+
+	session := createTestSession(t, s, "Ana")
+	fresh := mustGetSession(t, s, createTestSession(t, s, "Referencia").ID)
+	if err := s.UseLifeline(session.ID, "fiftyFifty"); err != nil {
+		t.Fatalf("error usando comodín: %v", err)
+	}
+	addTestAnswer(t, s, session.ID, testAnswer(1, true, 1000))
+	bonus := int64(500)
+	if _, err := s.AdjustPrize(session.ID, models.AdjustPrizeRequest{Delta: &bonus, Reason: "bono"}, "admin"); err != nil {
+		t.Fatalf("error corrigiendo premio: %v", err)
+	}
+	addTestAnswer(t, s, session.ID, testAnswer(2, false, 0))
+	if played := addTestAnswer(t, s, session.ID, testAnswer(2, false, 0)); played.GameStatus != "eliminated" {
+		t.Fatalf("esperaba la sesión eliminada antes de reiniciar, estado %s", played.GameStatus)
+	}
+
+	if _, err := s.RestartSession(session.ID); err != nil {
+		t.Fatalf("error reiniciando sesión: %v", err)
+	}
+	restarted := mustGetSession(t, s, session.ID)
+	if restarted.ID != session.ID || restarted.PlayerName != "Ana" || restarted.Mode != session.Mode {
+		t.Fatalf("el reinicio conserva ID, nombre y modo: %+v", restarted)
+	}
+	if restarted.GameStatus != "active" || restarted.CurrentQuestion != fresh.CurrentQuestion ||
+		restarted.CurrentQuestionID != fresh.CurrentQuestionID || restarted.TotalPrize != 0 ||
+		restarted.LivesRemaining != fresh.LivesRemaining || len(restarted.AnswersGiven) != 0 ||
+		restarted.LifelinesUsed != (models.LifelinesState{}) || len(restarted.PrizeAdjustments) != 0 {
+		t.Fatalf("la sesión reiniciada debe quedar como nueva:\n%+v\n%+v", restarted, fresh)
+	}
+	if !isActiveSession(t, s, session.ID) {
+		t.Fatalf("la sesión reiniciada vuelve a las activas")
+	}
+
+	// Se juega igual que una sesión nueva
+	played := addTestAnswer(t, s, session.ID, testAnswer(1, true, 1000))
+	if played.TotalPrize != 1000 || played.CurrentQuestion != 2 || played.GameStatus != "active" {
+		t.Fatalf("la sesión reiniciada no avanzó como nueva: %+v", played)
+	}
+}
+
+func TestGetQuestionResult(t *testing.T) {
+	s, _ := newTestSessionService(t)
+
+	answer := func(playerName, mode string, questionID int, correct bool, seconds int) {
+		t.Helper()
+		session, _, err := s.CreateSession(playerName, mode, "", "")
+		if err != nil {
+			t.Fatalf("error creando sesión de %s: %v", playerName, err)
+		}
+		a := testAnswer(questionID, correct, 0)
+		a.TimeToAnswer = seconds
+		addTestAnswer(t, s, session.ID, a)
+	}
+	answer("Ana", models.SessionModeLive, 3, true, 4)
+	answer("Luis", models.SessionModeLive, 3, false, 9)
+	answer("Marta", models.SessionModeLive, 3, true, 5)
+	answer("Pedro", models.SessionModeLive, 5, false, 2)
+	// La práctica no cuenta
+	answer("Práctica", models.SessionModePractice, 3, false, 1)
+
+	result, err := s.GetQuestionResult(3)
+	if err != nil {
+		t.Fatalf("error obteniendo resultados: %v", err)
+	}
+	if result.QuestionID != 3 || result.Attempts != 3 || result.Correct != 2 || result.Incorrect != 1 {
+		t.Fatalf("conteos inesperados: %+v", result)
+	}
+	if math.Abs(result.CorrectRate-2.0/3) > 1e-9 || result.AvgTime != 6 {
+		t.Fatalf("tasa o tiempo promedio inesperados: %+v", result)
+	}
+
+	// Sin intentos todo queda en cero
+	result, err = s.GetQuestionResult(7)
+	if err != nil {
+		t.Fatalf("error obteniendo resultados: %v", err)
+	}
+	if *result != (models.QuestionStats{QuestionID: 7}) {
+		t.Fatalf("sin intentos se esperaba todo en cero: %+v", result)
+	}
+}
+
+func TestGetAnswerTimelineOrderedByReceiveTime(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	base := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+
+	// Las sesiones se crean en un orden distinto al de llegada de las respuestas
+	for _, c := range []struct {
+		playerName string
+		offset     time.Duration
+		correct    bool
+	}{
+		{"Ana", 2500 * time.Millisecond, true},
+		{"Luis", 1000 * time.Millisecond, false},
+		{"Marta", 4000 * time.Millisecond, true},
+		{"Pedro", 1020 * time.Millisecond, true},
+	} {
+		session := createTestSession(t, s, c.playerName)
+		answer := testAnswer(3, c.correct, 0)
+		answer.Timestamp = base.Add(c.offset)
+		addTestAnswer(t, s, session.ID, answer)
+	}
+
+	timeline, err := s.GetAnswerTimeline(3)
+	if err != nil {
+		t.Fatalf("error obteniendo línea de tiempo: %v", err)
+	}
+	want := []struct {
+		playerName      string
+		sinceFirstMs    int64
+		sincePreviousMs int64
+	}{
+		{"Luis", 0, 0},
+		{"Pedro", 20, 20},
+		{"Ana", 1500, 1480},
+		{"Marta", 3000, 1500},
+	}
+	if timeline.QuestionID != 3 || len(timeline.Answers) != len(want) {
+		t.Fatalf("línea de tiempo inesperada: %+v", timeline)
+	}
+	for i, w := range want {
+		entry := timeline.Answers[i]
+		if entry.Position != i+1 || entry.PlayerName != w.playerName || entry.SinceFirstMs != w.sinceFirstMs || entry.SincePreviousMs != w.sincePreviousMs {
+			t.Fatalf("posición %d: esperaba %+v, obtuve %+v", i+1, w, entry)
+		}
+		if i > 0 && entry.ReceivedAt.Before(timeline.Answers[i-1].ReceivedAt) {
+			t.Fatalf("la línea de tiempo no está ordenada por llegada: %+v", timeline.Answers)
+		}
+	}
+
+	// Una pregunta sin respuestas devuelve una lista vacía
+	if timeline, err = s.GetAnswerTimeline(7); err != nil || timeline.Answers == nil || len(timeline.Answers) != 0 {
+		t.Fatalf("sin respuestas se esperaba una lista vacía: %+v (%v)", timeline, err)
+	}
+}
+
+func TestLeaderboardWorkerUpdatesWithinDebounce(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	const debounce = 200 * time.Millisecond
+	session := createTestSession(t, s, "Ana")
+
+	// cachedPrize premio de Ana en la tabla precalculada; -1 si aún no hay tabla
+	cachedPrize := func() int64 {
+		s.leaderboardMutex.RLock()
+		defer s.leaderboardMutex.RUnlock()
+		if s.leaderboard == nil || len(s.leaderboard.Leaderboard) != 1 {
+			return -1
+		}
+		return s.leaderboard.Leaderboard[0].CurrentPrize
+	}
+	waitForPrize := func(prize int64, within time.Duration) {
+		t.Helper()
+		deadline := time.Now().Add(within)
+		for cachedPrize() != prize {
+			if time.Now().After(deadline) {
+				t.Fatalf("tiempo agotado esperando el premio %d en caché (hay %d)", prize, cachedPrize())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// Se descarta el aviso de la creación para que el worker arranque en reposo
+	<-s.leaderboardDirty
+	go s.RunLeaderboardWorker(debounce)
+	waitForPrize(0, time.Second)
+
+	addTestAnswer(t, s, session.ID, testAnswer(1, true, 100))
+
+	// GetLeaderboard devuelve la tabla precalculada sin recalcular...
+	if leaderboard, err := s.GetLeaderboard(); err != nil || leaderboard.Leaderboard[0].CurrentPrize != 0 {
+		t.Fatalf("antes de la espera se esperaba la tabla en caché: %+v (%v)", leaderboard, err)
+	}
+	// ...y la respuesta se refleja dentro de la ventana de espera
+	waitForPrize(100, debounce+300*time.Millisecond)
+	if leaderboard, _ := s.GetLeaderboard(); leaderboard.Leaderboard[0].CurrentPrize != 100 {
+		t.Fatalf("GetLeaderboard debe devolver la tabla actualizada: %+v", leaderboard)
+	}
+}
+
+func TestFinishAllActiveKeepsSessionsQueryable(t *testing.T) {
+	s, _ := newTestSessionService(t)
+	ana := createTestSession(t, s, "Ana")
+	addTestAnswer(t, s, ana.ID, testAnswer(1, true, 500))
+	addTestAnswer(t, s, ana.ID, testAnswer(2, true, 1000))
+	luis := createTestSession(t, s, "Luis")
+	pedro := createTestSession(t, s, "Pedro")
+	addTestAnswer(t, s, pedro.ID, testAnswer(1, false, 0))
+
+	finished, err := s.FinishAllActive()
+	if err != nil {
+		t.Fatalf("error terminando sesiones activas: %v", err)
+	}
+	if len(finished) != 2 {
+		t.Fatalf("esperaba 2 sesiones terminadas, hubo %d", len(finished))
+	}
+	if active, _ := s.GetActiveSessions(); len(active) != 0 {
+		t.Fatalf("no deben quedar sesiones activas: %+v", active)
+	}
+
+	// Las sesiones siguen consultables, con su premio intacto; la eliminada no cambia
+	for id, want := range map[string]struct {
+		status string
+		prize  int64
+	}{
+		ana.ID:   {"finished", 1000},
+		luis.ID:  {"finished", 0},
+		pedro.ID: {"eliminated", 0},
+	} {
+		session := mustGetSession(t, s, id)
+		if session.GameStatus != want.status || session.TotalPrize != want.prize {
+			t.Fatalf("%s: esperaba %s con %d, obtuve %s con %d", session.PlayerName, want.status, want.prize, session.GameStatus, session.TotalPrize)
+		}
+	}
+	if history, err := s.GetPlayerHistory("Ana"); err != nil || len(history) != 1 || history[0].GameStatus != "finished" {
+		t.Fatalf("el historial de Ana debe incluir la sesión terminada: %+v (%v)", history, err)
+	}
+	leaderboard, err := s.GetLeaderboard()
+	if err != nil || leaderboard.TotalPlayers != 2 || leaderboard.ActivePlayers != 0 || leaderboard.Leaderboard[0].PlayerName != "Ana" {
+		t.Fatalf("la tabla debe conservar a los jugadores terminados: %+v (%v)", leaderboard, err)
+	}
+
+	// Sin sesiones activas no hay nada que terminar
+	if finished, err := s.FinishAllActive(); err != nil || len(finished) != 0 {
+		t.Fatalf("una segunda llamada no debe terminar nada: %d (%v)", len(finished), err)
+	}
+}
+
+// interleavingStore ejecuta afterGet una vez, justo después de leer key: sirve
+// para meter una escritura entre la lectura y la escritura de otra operación
+type interleavingStore struct {
+	*redis.MemoryStore
+	key      string
+	afterGet func()
+}
+
+func (s *interleavingStore) Get(key string) (string, error) {
+	value, err := s.MemoryStore.Get(key)
+	if key == s.key && s.afterGet != nil {
+		afterGet := s.afterGet
+		s.afterGet = nil
+		afterGet()
+	}
+	return value, err
+}
+
+func TestSetConnectedKeepsConcurrentAnswers(t *testing.T) {
+	store := &interleavingStore{MemoryStore: redis.NewMemoryStore()}
+	s := NewSessionService(store)
+	session := createTestSession(t, s, "Ana")
+
+	// Una respuesta guardada mientras se marca la conexión no debe perderse
+	store.key = "session:" + session.ID
+	store.afterGet = func() {
+		if err := s.AddAnswer(session.ID, testAnswer(1, true, 100)); err != nil {
+			t.Errorf("error agregando respuesta: %v", err)
+		}
+	}
+	if _, err := s.SetConnected(session.ID, true); err != nil {
+		t.Fatalf("error marcando conexión: %v", err)
+	}
+
+	stored := mustGetSession(t, s, session.ID)
+	if len(stored.AnswersGiven) != 1 || stored.TotalPrize != 100 {
+		t.Fatalf("se perdió la respuesta: %d respuestas, premio %d", len(stored.AnswersGiven), stored.TotalPrize)
+	}
+	if !stored.Connected {
+		t.Fatalf("la sesión debe leerse como conectada")
+	}
+
+	if _, err := s.SetConnected(session.ID, false); err != nil {
+		t.Fatalf("error marcando desconexión: %v", err)
+	}
+	if mustGetSession(t, s, session.ID).Connected {
+		t.Fatalf("la sesión debe leerse como desconectada")
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/backsoul/quiz/pkg/models"
+	"github.com/backsoul/quiz/pkg/redis"
+)
+
+// ErrInvalidSettings indica un valor fuera de rango en la configuración
+var ErrInvalidSettings = errors.New("configuración inválida")
+
+// settingsKey configuración ajustada en caliente (sobre los valores de entorno)
+const settingsKey = "settings"
+
+// SettingsService guarda la configuración ajustable del juego en Redis y avisa
+// a los servicios que la usan cada vez que cambia
+type SettingsService struct {
+	redisClient redis.RedisStore
+	mutex       sync.RWMutex
+	current     models.GameSettings
+	listeners   []func(models.GameSettings)
+}
+
+// NewSettingsService crea el servicio con los valores por defecto (los de entorno)
+func NewSettingsService(redisClient redis.RedisStore, defaults models.GameSettings) *SettingsService {
+	return &SettingsService{
+		redisClient: redisClient,
+		current:     defaults,
+	}
+}
+
+// OnChange registra una función que recibe la configuración al cargarla y en cada cambio
+func (s *SettingsService) OnChange(listener func(models.GameSettings)) {
+	s.listeners = append(s.listeners, listener)
+}
+
+// Current devuelve la configuración vigente
+func (s *SettingsService) Current() models.GameSettings {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.current
+}
+
+// Load aplica la configuración guardada en Redis, si la hay, y avisa a los
+// servicios. Lo guardado pasa por la misma validación que Update y solo se
+// aplica si es válido: un valor corrupto deja intacta la configuración vigente.
+func (s *SettingsService) Load() (models.GameSettings, error) {
+	data, err := s.redisClient.Get(settingsKey)
+	if err != nil && err.Error() != "redis: nil" {
+		return s.Current(), err
+	}
+
+	var stored models.SettingsUpdate
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &stored); err != nil {
+			return s.Current(), fmt.Errorf("error deserializando configuración: %v", err)
+		}
+		if fieldErr := stored.Validate(); fieldErr != nil {
+			return s.Current(), fmt.Errorf("%w: configuración guardada: %v", ErrInvalidSettings, fieldErr)
+		}
+	}
+
+	s.mutex.Lock()
+	stored.Apply(&s.current)
+	settings := s.current
+	s.mutex.Unlock()
+
+	s.notify(settings)
+	return settings, nil
+}
+
+// Update valida y aplica cambios parciales, los guarda y avisa a los servicios
+func (s *SettingsService) Update(update models.SettingsUpdate) (models.GameSettings, error) {
+	if fieldErr := update.Validate(); fieldErr != nil {
+		return s.Current(), fmt.Errorf("%w: %v", ErrInvalidSettings, fieldErr)
+	}
+
+	s.mutex.Lock()
+	settings := s.current
+	update.Apply(&settings)
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		s.mutex.Unlock()
+		return s.Current(), fmt.Errorf("error serializando configuración: %v", err)
+	}
+	if err := s.redisClient.Set(settingsKey, string(data), 0); err != nil {
+		s.mutex.Unlock()
+		return s.Current(), err
+	}
+	s.current = settings
+	s.mutex.Unlock()
+
+	log.Printf("⚙️ Configuración actualizada: %+v", settings)
+	s.notify(settings)
+	return settings, nil
+}
+
+func (s *SettingsService) notify(settings models.GameSettings) {
+	for _, listener := range s.listeners {
+		listener(settings)
+	}
+}
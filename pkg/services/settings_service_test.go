@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/backsoul/quiz/pkg/models"
+)
+
+// testSettings valores iniciales de la configuración, como los de entorno
+func testSettings() models.GameSettings {
+	return models.GameSettings{
+		QuestionTimeLimit: 30,
+		BroadcastInterval: 5,
+		PlayerLives:       1,
+		AnswerMatching:    AnswerMatchExact,
+	}
+}
+
+func TestSettingsUpdateChangesBehavior(t *testing.T) {
+	gs, store := newTestGameStateService(t)
+	sessions := NewSessionService(store)
+	settings := NewSettingsService(store, testSettings())
+	// Conectado a los servicios como en main
+	settings.OnChange(func(current models.GameSettings) {
+		gs.SetQuestionTimer(QuestionTimer{Default: time.Duration(current.QuestionTimeLimit) * time.Second})
+		gs.SetTwoPhaseQuestions(current.TwoPhaseQuestions)
+		sessions.SetLives(current.PlayerLives)
+	})
+	if _, err := settings.Load(); err != nil {
+		t.Fatalf("error cargando configuración: %v", err)
+	}
+	if err := gs.StartGame(); err != nil {
+		t.Fatalf("error iniciando partida: %v", err)
+	}
+
+	state, err := gs.StartQuestion(1, 1)
+	if err != nil || state.QuestionDuration != 30 {
+		t.Fatalf("esperaba 30 segundos por pregunta: %+v (%v)", state, err)
+	}
+
+	shorter, lives, twoPhase := 10, 3, true
+	if _, err := settings.Update(models.SettingsUpdate{QuestionTimeLimit: &shorter, PlayerLives: &lives, TwoPhaseQuestions: &twoPhase}); err != nil {
+		t.Fatalf("error actualizando configuración: %v", err)
+	}
+
+	// El temporizador más corto aplica desde la siguiente pregunta
+	state, err = gs.StartQuestion(2, 1)
+	if err != nil || state.QuestionDuration != 10 {
+		t.Fatalf("esperaba 10 segundos tras el cambio: %+v (%v)", state, err)
+	}
+	if !gs.TwoPhaseQuestions() {
+		t.Fatalf("el modo en dos fases debe activarse")
+	}
+	if session := createTestSession(t, sessions, "Ana"); session.LivesRemaining != 3 {
+		t.Fatalf("las sesiones nuevas deben tener 3 vidas, tienen %d", session.LivesRemaining)
+	}
+}
+
+func TestSettingsUpdateValidatesAndPersists(t *testing.T) {
+	_, store := newTestQuestionService(t, testQuestions(1))
+	settings := NewSettingsService(store, testSettings())
+
+	notified := 0
+	settings.OnChange(func(models.GameSettings) { notified++ })
+
+	tooShort := 2
+	_, err := settings.Update(models.SettingsUpdate{QuestionTimeLimit: &tooShort})
+	if !errors.Is(err, ErrInvalidSettings) {
+		t.Fatalf("esperaba ErrInvalidSettings, obtuve %v", err)
+	}
+	if settings.Current().QuestionTimeLimit != 30 || notified != 0 {
+		t.Fatalf("un valor inválido no cambia nada: %+v (%d avisos)", settings.Current(), notified)
+	}
+
+	// Los campos omitidos se conservan
+	matching := AnswerMatchFold
+	updated, err := settings.Update(models.SettingsUpdate{AnswerMatching: &matching})
+	if err != nil || updated.AnswerMatching != AnswerMatchFold || updated.QuestionTimeLimit != 30 || notified != 1 {
+		t.Fatalf("actualización parcial inesperada: %+v (%v)", updated, err)
+	}
+
+	// Un servidor nuevo carga lo guardado sobre sus valores de entorno
+	reloaded := NewSettingsService(store, testSettings())
+	current, err := reloaded.Load()
+	if err != nil || current.AnswerMatching != AnswerMatchFold {
+		t.Fatalf("la configuración debe persistir en Redis: %+v (%v)", current, err)
+	}
+}
+
+func TestSettingsLoadRejectsInvalidStoredValues(t *testing.T) {
+	_, store := newTestQuestionService(t, testQuestions(1))
+
+	for name, stored := range map[string]string{
+		"JSON truncado":   `{"questionTimeLimit":12,"playerLives":`,
+		"tipo incorrecto": `{"questionTimeLimit":12,"playerLives":"tres"}`,
+		"fuera de rango":  `{"questionTimeLimit":12,"playerLives":99}`,
+		"modo inválido":   `{"questionTimeLimit":12,"answerMatching":"regex"}`,
+	} {
+		store.Set(settingsKey, stored, 0)
+		settings := NewSettingsService(store, testSettings())
+		notified := 0
+		settings.OnChange(func(models.GameSettings) { notified++ })
+
+		// Nada de lo guardado se aplica, ni siquiera los campos válidos
+		current, err := settings.Load()
+		if err == nil {
+			t.Fatalf("%s: esperaba un error al cargar", name)
+		}
+		if current != testSettings() || settings.Current() != testSettings() || notified != 0 {
+			t.Fatalf("%s: la configuración vigente debe quedar intacta: %+v (%d avisos)", name, settings.Current(), notified)
+		}
+	}
+	store.Set(settingsKey, `{"playerLives":99}`, 0)
+	if _, err := NewSettingsService(store, testSettings()).Load(); !errors.Is(err, ErrInvalidSettings) {
+		t.Fatalf("un valor fuera de rango debe dar ErrInvalidSettings, obtuve %v", err)
+	}
+
+	// Lo guardado de forma parcial se aplica sobre los valores de entorno
+	store.Set(settingsKey, `{"questionTimeLimit":12}`, 0)
+	current, err := NewSettingsService(store, testSettings()).Load()
+	if err != nil || current.QuestionTimeLimit != 12 || current.PlayerLives != 1 || current.AnswerMatching != AnswerMatchExact {
+		t.Fatalf("carga parcial inesperada: %+v (%v)", current, err)
+	}
+}
@@ -0,0 +1,54 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+)
+
+// EventBatcher agrupa eventos que llegan en ráfaga y los difunde como un
+// único mensaje al cerrar la ventana de agrupación
+type EventBatcher struct {
+	hub     *Hub
+	msgType string
+	window  time.Duration
+	mutex   sync.Mutex
+	pending []interface{}
+	timer   *time.Timer
+}
+
+func NewEventBatcher(hub *Hub, msgType string, window time.Duration) *EventBatcher {
+	return &EventBatcher{
+		hub:     hub,
+		msgType: msgType,
+		window:  window,
+	}
+}
+
+// Add encola un evento; el primero de la ventana programa el envío del lote
+func (b *EventBatcher) Add(event interface{}) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.pending = append(b.pending, event)
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.window, b.flush)
+	}
+}
+
+func (b *EventBatcher) flush() {
+	b.mutex.Lock()
+	events := b.pending
+	b.pending = nil
+	b.timer = nil
+	b.mutex.Unlock()
+
+	if len(events) == 0 {
+		return
+	}
+
+	b.hub.BroadcastMessage(b.msgType, map[string]interface{}{
+		"count":     len(events),
+		"events":    events,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,62 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// nextOutbound espera el siguiente mensaje encolado en el hub (sin Run en marcha)
+func nextOutbound(t *testing.T, h *Hub, wait time.Duration) (outbound, bool) {
+	t.Helper()
+	select {
+	case message := <-h.broadcast:
+		return message, true
+	case <-time.After(wait):
+		return outbound{}, false
+	}
+}
+
+func TestEventBatcherGroupsBurst(t *testing.T) {
+	h := NewHub()
+	batcher := NewEventBatcher(h, "answersBatch", 50*time.Millisecond)
+
+	for i := 0; i < 100; i++ {
+		batcher.Add(map[string]interface{}{"n": i})
+	}
+
+	message, ok := nextOutbound(t, h, time.Second)
+	if !ok {
+		t.Fatalf("no se difundió el lote")
+	}
+	if message.msgType != "answersBatch" {
+		t.Fatalf("tipo inesperado: %s", message.msgType)
+	}
+
+	var decoded struct {
+		Type string `json:"type"`
+		Data struct {
+			Count  int                      `json:"count"`
+			Events []map[string]interface{} `json:"events"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(message.data, &decoded); err != nil {
+		t.Fatalf("mensaje inválido: %v", err)
+	}
+	if decoded.Data.Count != 100 || len(decoded.Data.Events) != 100 {
+		t.Fatalf("esperaba 100 eventos en un solo lote, hay %d (count %d)", len(decoded.Data.Events), decoded.Data.Count)
+	}
+	if decoded.Data.Events[0]["n"] != float64(0) || decoded.Data.Events[99]["n"] != float64(99) {
+		t.Fatalf("los eventos deben conservar su orden")
+	}
+
+	if _, ok := nextOutbound(t, h, 150*time.Millisecond); ok {
+		t.Fatalf("la ráfaga debe producir un único mensaje")
+	}
+
+	// Un evento posterior abre una ventana nueva
+	batcher.Add("otro")
+	if _, ok := nextOutbound(t, h, time.Second); !ok {
+		t.Fatalf("el evento tras la ventana debe difundirse en otro lote")
+	}
+}
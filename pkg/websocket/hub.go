@@ -2,18 +2,44 @@ package websocket
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"runtime/debug"
 	"sync"
+	"time"
 
 	"github.com/fasthttp/websocket"
 )
 
+// ProtocolVersion versión del protocolo de mensajes WebSocket
+const ProtocolVersion = 1
+
+// broadcastBuffer mensajes que pueden quedar en cola mientras Run los envía
+const broadcastBuffer = 256
+
+// broadcastTimeout tiempo máximo que un emisor espera a que haya lugar en la
+// cola; pasado ese tiempo el mensaje se descarta para no colgar la petición HTTP
+const broadcastTimeout = 2 * time.Second
+
+// DefaultReadLimit tamaño máximo por defecto (en bytes) de un mensaje entrante
+const DefaultReadLimit = 4096
+
+// Límite por defecto de mensajes entrantes por conexión: por segundo y ráfaga
+const (
+	DefaultRateLimit = 20
+	DefaultRateBurst = 40
+)
+
 type Hub struct {
 	clients    map[*websocket.Conn]bool
-	broadcast  chan []byte
+	filters    map[*websocket.Conn]map[string]bool // tipos suscritos por conexión; sin filtro = todos
+	broadcast  chan outbound
 	register   chan *websocket.Conn
 	unregister chan *websocket.Conn
 	mutex      sync.RWMutex
+	readLimit  int64
+	rateLimit  int // mensajes entrantes por segundo y conexión (0 = sin límite)
+	rateBurst  int
 }
 
 type Message struct {
@@ -21,6 +47,35 @@ type Message struct {
 	Data interface{} `json:"data"`
 }
 
+// outbound mensaje serializado junto con su tipo, para aplicar los filtros.
+// Si done no es nil, Run informa por ahí el resultado del envío.
+type outbound struct {
+	msgType string
+	data    []byte
+	done    chan Delivery
+}
+
+// Delivery resultado de difundir un mensaje: a cuántos clientes se escribió,
+// cuántos fallaron y cuántos lo omitieron por su filtro de suscripción
+type Delivery struct {
+	Delivered int `json:"delivered"`
+	Failed    int `json:"failed"`
+	Filtered  int `json:"filtered"`
+}
+
+// ErrDeliveryTimeout indica que el mensaje no se envió a tiempo (cola llena o hub detenido)
+var ErrDeliveryTimeout = errors.New("el mensaje WebSocket no se entregó a tiempo")
+
+// subscribeCommand comando con el que un cliente elige los tipos de evento que
+// quiere recibir: {"type":"subscribe","data":{"types":["nextQuestion"]}}.
+// Una lista vacía vuelve a recibir todos los eventos.
+type subscribeCommand struct {
+	Type string `json:"type"`
+	Data struct {
+		Types []string `json:"types"`
+	} `json:"data"`
+}
+
 type GameStateMessage struct {
 	IsActive  bool   `json:"isActive"`
 	Message   string `json:"message"`
@@ -30,43 +85,183 @@ type GameStateMessage struct {
 func NewHub() *Hub {
 	return &Hub{
 		clients:    make(map[*websocket.Conn]bool),
-		broadcast:  make(chan []byte),
+		filters:    make(map[*websocket.Conn]map[string]bool),
+		broadcast:  make(chan outbound, broadcastBuffer),
 		register:   make(chan *websocket.Conn),
 		unregister: make(chan *websocket.Conn),
+		readLimit:  DefaultReadLimit,
+		rateLimit:  DefaultRateLimit,
+		rateBurst:  DefaultRateBurst,
 	}
 }
 
+// SetReadLimit configura el tamaño máximo de los mensajes entrantes; un mensaje
+// mayor cierra la conexión
+func (h *Hub) SetReadLimit(limit int64) {
+	h.readLimit = limit
+}
+
+// SetRateLimit configura cuántos mensajes por segundo (con una ráfaga de
+// burst) acepta cada conexión; los que exceden se descartan y, tras burst
+// descartes seguidos, se cierra la conexión. perSecond 0 desactiva el límite.
+func (h *Hub) SetRateLimit(perSecond, burst int) {
+	h.rateLimit = perSecond
+	h.rateBurst = burst
+}
+
+// CommandHandler procesa un mensaje entrante de un cliente WebSocket
+type CommandHandler func(conn *websocket.Conn, data []byte)
+
 func (h *Hub) Run() {
 	for {
-		select {
-		case client := <-h.register:
-			h.mutex.Lock()
-			h.clients[client] = true
-			h.mutex.Unlock()
-			log.Printf("Cliente WebSocket conectado. Total: %d", len(h.clients))
-
-		case client := <-h.unregister:
-			h.mutex.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				client.Close()
+		h.runOnce()
+	}
+}
+
+// runOnce atiende un evento del hub; un panic se registra sin detener el hub
+func (h *Hub) runOnce() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("🔥 Panic en el hub WebSocket: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	// Cada sección crítica libera el lock con defer: un panic dentro (p. ej. al
+	// escribir o cerrar una conexión) no debe dejar el hub bloqueado
+	select {
+	case client := <-h.register:
+		log.Printf("Cliente WebSocket conectado. Total: %d", h.addClient(client))
+
+	case client := <-h.unregister:
+		log.Printf("Cliente WebSocket desconectado. Total: %d", h.removeClients(client))
+
+	case message := <-h.broadcast:
+		delivery, failed := h.writeToClients(message)
+		if message.done != nil {
+			message.done <- delivery
+		}
+		if len(failed) > 0 {
+			h.removeClients(failed...)
+		}
+	}
+}
+
+// addClient registra la conexión y devuelve el total de clientes
+func (h *Hub) addClient(client *websocket.Conn) int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.clients[client] = true
+	return len(h.clients)
+}
+
+// removeClients quita y cierra las conexiones registradas; devuelve el total
+// de clientes que quedan
+func (h *Hub) removeClients(clients ...*websocket.Conn) int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for _, client := range clients {
+		if _, ok := h.clients[client]; ok {
+			delete(h.clients, client)
+			delete(h.filters, client)
+			client.Close()
+		}
+	}
+	return len(h.clients)
+}
+
+// writeToClients escribe el mensaje a cada cliente suscrito a su tipo. Los que
+// fallan se devuelven para quitarlos después, con el lock de escritura.
+func (h *Hub) writeToClients(message outbound) (Delivery, []*websocket.Conn) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	var failed []*websocket.Conn
+	var delivery Delivery
+	for client := range h.clients {
+		if filter, ok := h.filters[client]; ok && !filter[message.msgType] {
+			delivery.Filtered++
+			continue
+		}
+		err := client.WriteMessage(websocket.TextMessage, message.data)
+		if err != nil {
+			log.Printf("Error enviando mensaje WebSocket: %v", err)
+			failed = append(failed, client)
+			continue
+		}
+		delivery.Delivered++
+	}
+	delivery.Failed = len(failed)
+	return delivery, failed
+}
+
+// ServeConn registra la conexión, atiende sus mensajes entrantes hasta que se
+// cierre y la desregistra. Un panic al procesar un mensaje, un mensaje que
+// excede el límite de lectura o una inundación de mensajes cierran la conexión
+// limpiamente.
+func (h *Hub) ServeConn(conn *websocket.Conn, handle CommandHandler) {
+	if h.readLimit > 0 {
+		conn.SetReadLimit(h.readLimit)
+	}
+	h.Register(conn)
+	defer h.Unregister(conn)
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("🔥 Panic atendiendo cliente WebSocket %s: %v\n%s", conn.RemoteAddr(), r, debug.Stack())
+		}
+	}()
+
+	var limiter *rateLimiter
+	if h.rateLimit > 0 {
+		limiter = newRateLimiter(float64(h.rateLimit), h.rateBurst)
+	}
+	dropped := 0
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if err == websocket.ErrReadLimit {
+				log.Printf("⚠️ Mensaje WebSocket de %s excede %d bytes, cerrando conexión", conn.RemoteAddr(), h.readLimit)
 			}
-			h.mutex.Unlock()
-			log.Printf("Cliente WebSocket desconectado. Total: %d", len(h.clients))
-
-		case message := <-h.broadcast:
-			h.mutex.RLock()
-			for client := range h.clients {
-				err := client.WriteMessage(websocket.TextMessage, message)
-				if err != nil {
-					log.Printf("Error enviando mensaje WebSocket: %v", err)
-					delete(h.clients, client)
-					client.Close()
-				}
+			break
+		}
+		if limiter != nil && !limiter.allow(time.Now()) {
+			dropped++
+			if dropped >= h.rateBurst {
+				log.Printf("🚫 Cliente WebSocket %s supera %d mensajes/s, cerrando conexión", conn.RemoteAddr(), h.rateLimit)
+				break
 			}
-			h.mutex.RUnlock()
+			continue
 		}
+		dropped = 0
+		if h.handleSubscribe(conn, data) {
+			continue
+		}
+		if handle != nil {
+			handle(conn, data)
+		}
+	}
+}
+
+// handleSubscribe aplica un comando subscribe; devuelve false si el mensaje es otro comando
+func (h *Hub) handleSubscribe(conn *websocket.Conn, data []byte) bool {
+	var cmd subscribeCommand
+	if err := json.Unmarshal(data, &cmd); err != nil || cmd.Type != "subscribe" {
+		return false
 	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if len(cmd.Data.Types) == 0 {
+		delete(h.filters, conn)
+		return true
+	}
+	filter := make(map[string]bool, len(cmd.Data.Types))
+	for _, msgType := range cmd.Data.Types {
+		filter[msgType] = true
+	}
+	h.filters[conn] = filter
+	return true
 }
 
 func (h *Hub) Register(conn *websocket.Conn) {
@@ -81,7 +276,7 @@ func (h *Hub) BroadcastGameState(isActive bool, message string) {
 	gameState := GameStateMessage{
 		IsActive:  isActive,
 		Message:   message,
-		Timestamp: "2025-07-31T" + "12:00:00Z", // Usar time.Now() en producción
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	}
 
 	msg := Message{
@@ -95,7 +290,7 @@ func (h *Hub) BroadcastGameState(isActive bool, message string) {
 		return
 	}
 
-	h.broadcast <- data
+	h.enqueue(outbound{msgType: msg.Type, data: data})
 }
 
 func (h *Hub) BroadcastMessage(msgType string, data interface{}) {
@@ -110,5 +305,64 @@ func (h *Hub) BroadcastMessage(msgType string, data interface{}) {
 		return
 	}
 
-	h.broadcast <- msgData
+	h.enqueue(outbound{msgType: msgType, data: msgData})
+}
+
+// SendTo envía un mensaje a un solo cliente (p. ej. la respuesta a un comando).
+// Toma el lock de escritura para no escribir a la vez que Run difunde.
+func (h *Hub) SendTo(conn *websocket.Conn, msgType string, data interface{}) error {
+	msgData, err := json.Marshal(Message{Type: msgType, Data: data})
+	if err != nil {
+		return err
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return conn.WriteMessage(websocket.TextMessage, msgData)
+}
+
+// BroadcastAndWait difunde un mensaje y espera a que Run lo haya escrito en
+// todas las conexiones (y, por el orden de la cola, también los anteriores).
+// Devuelve ErrDeliveryTimeout si no termina antes de timeout.
+func (h *Hub) BroadcastAndWait(msgType string, data interface{}, timeout time.Duration) (Delivery, error) {
+	msgData, err := json.Marshal(Message{Type: msgType, Data: data})
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	done := make(chan Delivery, 1)
+	if !h.enqueue(outbound{msgType: msgType, data: msgData, done: done}) {
+		return Delivery{}, ErrDeliveryTimeout
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case delivery := <-done:
+		return delivery, nil
+	case <-timer.C:
+		return Delivery{}, ErrDeliveryTimeout
+	}
+}
+
+// enqueue encola un mensaje para difundir sin bloquear indefinidamente si el
+// hub no está atendiendo la cola; devuelve false si se descartó
+func (h *Hub) enqueue(message outbound) bool {
+	select {
+	case h.broadcast <- message:
+		return true
+	default:
+	}
+
+	timer := time.NewTimer(broadcastTimeout)
+	defer timer.Stop()
+
+	select {
+	case h.broadcast <- message:
+		return true
+	case <-timer.C:
+		log.Printf("⚠️ Cola de difusión WebSocket llena, mensaje descartado")
+		return false
+	}
 }
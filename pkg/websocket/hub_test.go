@@ -0,0 +1,473 @@
+package websocket
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// startHub crea un hub con Run en marcha
+func startHub() *Hub {
+	h := NewHub()
+	go h.Run()
+	return h
+}
+
+// testServer atiende WebSockets con hub.ServeConn sobre un listener en memoria
+type testServer struct {
+	ln *fasthttputil.InmemoryListener
+}
+
+func newTestServer(t *testing.T, h *Hub, handle CommandHandler) *testServer {
+	t.Helper()
+	upgrader := websocket.FastHTTPUpgrader{
+		CheckOrigin: func(ctx *fasthttp.RequestCtx) bool { return true },
+	}
+	ln := fasthttputil.NewInmemoryListener()
+	server := &fasthttp.Server{Handler: func(ctx *fasthttp.RequestCtx) {
+		upgrader.Upgrade(ctx, func(conn *websocket.Conn) {
+			h.ServeConn(conn, handle)
+		})
+	}}
+	go server.Serve(ln)
+	t.Cleanup(func() { ln.Close() })
+	return &testServer{ln: ln}
+}
+
+// dial abre un cliente y espera a que el hub lo registre
+func (s *testServer) dial(t *testing.T, h *Hub) *websocket.Conn {
+	t.Helper()
+	before := clientCount(h)
+	dialer := websocket.Dialer{
+		NetDial: func(network, addr string) (net.Conn, error) {
+			return s.ln.Dial()
+		},
+	}
+	conn, _, err := dialer.Dial("ws://quiz.test/ws", nil)
+	if err != nil {
+		t.Fatalf("error conectando WebSocket: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	waitFor(t, "registro del cliente", func() bool { return clientCount(h) > before })
+	return conn
+}
+
+// clientCount clientes registrados en el hub
+func clientCount(h *Hub) int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return len(h.clients)
+}
+
+// waitFor espera hasta 2 segundos a que se cumpla la condición
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("tiempo agotado esperando %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// readType lee el siguiente mensaje y devuelve su tipo
+func readType(t *testing.T, conn *websocket.Conn) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var message Message
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatalf("error leyendo mensaje: %v", err)
+	}
+	return message.Type
+}
+
+// expectClosed comprueba que el servidor cerró la conexión
+func expectClosed(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				t.Fatalf("la conexión debía cerrarse")
+			}
+			return
+		}
+	}
+}
+
+func TestServeConnRecoversFromHandlerPanic(t *testing.T) {
+	h := startHub()
+	server := newTestServer(t, h, func(conn *websocket.Conn, data []byte) {
+		if string(data) == "boom" {
+			var question map[string]string
+			question["id"] = "nil map"
+		}
+	})
+
+	conn := server.dial(t, h)
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("boom")); err != nil {
+		t.Fatalf("error enviando mensaje: %v", err)
+	}
+
+	expectClosed(t, conn)
+	waitFor(t, "que el cliente se desregistre", func() bool { return clientCount(h) == 0 })
+
+	// El hub sigue atendiendo a otros clientes
+	other := server.dial(t, h)
+	h.BroadcastMessage("gameState", map[string]interface{}{"isActive": true})
+	if msgType := readType(t, other); msgType != "gameState" {
+		t.Fatalf("esperaba gameState, obtuve %s", msgType)
+	}
+}
+
+func TestServeConnClosesOnOversizedMessage(t *testing.T) {
+	h := startHub()
+	h.SetReadLimit(64)
+	received := make(chan int, 10)
+	server := newTestServer(t, h, func(conn *websocket.Conn, data []byte) {
+		received <- len(data)
+	})
+
+	conn := server.dial(t, h)
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("ping")); err != nil {
+		t.Fatalf("error enviando mensaje: %v", err)
+	}
+	select {
+	case size := <-received:
+		if size != 4 {
+			t.Fatalf("tamaño recibido inesperado: %d", size)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("el mensaje dentro del límite no llegó al handler")
+	}
+
+	if err := conn.WriteMessage(websocket.TextMessage, make([]byte, 1024)); err != nil {
+		t.Fatalf("error enviando mensaje: %v", err)
+	}
+	expectClosed(t, conn)
+	waitFor(t, "que el cliente se desregistre", func() bool { return clientCount(h) == 0 })
+	select {
+	case size := <-received:
+		t.Fatalf("el mensaje excesivo no debe llegar al handler (%d bytes)", size)
+	default:
+	}
+
+	// El servidor sigue aceptando conexiones
+	other := server.dial(t, h)
+	h.BroadcastMessage("gameState", map[string]interface{}{"isActive": true})
+	if msgType := readType(t, other); msgType != "gameState" {
+		t.Fatalf("esperaba gameState, obtuve %s", msgType)
+	}
+}
+
+func TestBroadcastDoesNotBlockWithoutRun(t *testing.T) {
+	h := NewHub() // sin Run: nadie vacía la cola
+
+	// Mientras haya lugar en la cola el envío es inmediato
+	start := time.Now()
+	for i := 0; i < broadcastBuffer; i++ {
+		h.BroadcastMessage("gameState", map[string]interface{}{"i": i})
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("encolar con lugar disponible tardó %v", elapsed)
+	}
+
+	// Con la cola llena el mensaje se descarta tras broadcastTimeout
+	done := make(chan struct{})
+	go func() {
+		h.BroadcastGameState(true, "Partida activa")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(broadcastTimeout + 2*time.Second):
+		t.Fatalf("BroadcastGameState se bloqueó con el hub detenido")
+	}
+	if len(h.broadcast) != broadcastBuffer {
+		t.Fatalf("el mensaje descartado no debe ocupar la cola: %d", len(h.broadcast))
+	}
+
+	if _, err := h.BroadcastAndWait("gameState", nil, time.Second); err != ErrDeliveryTimeout {
+		t.Fatalf("esperaba ErrDeliveryTimeout, obtuve %v", err)
+	}
+}
+
+// filterCount conexiones con filtro de suscripción
+func filterCount(h *Hub) int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return len(h.filters)
+}
+
+func TestSubscribeFiltersEventTypes(t *testing.T) {
+	h := startHub()
+	server := newTestServer(t, h, nil)
+	subscriber := server.dial(t, h)
+	other := server.dial(t, h)
+
+	if err := subscriber.WriteJSON(map[string]interface{}{
+		"type": "subscribe",
+		"data": map[string]interface{}{"types": []string{"announcement"}},
+	}); err != nil {
+		t.Fatalf("error enviando subscribe: %v", err)
+	}
+	waitFor(t, "el filtro de suscripción", func() bool { return filterCount(h) == 1 })
+
+	delivery, err := h.BroadcastAndWait("gameState", map[string]interface{}{"isActive": true}, 2*time.Second)
+	if err != nil {
+		t.Fatalf("error difundiendo: %v", err)
+	}
+	if delivery.Delivered != 1 || delivery.Filtered != 1 {
+		t.Fatalf("esperaba 1 entrega y 1 filtrado, obtuve %+v", delivery)
+	}
+	h.BroadcastMessage("announcement", map[string]interface{}{"message": "Hola"})
+
+	// El suscriptor solo recibe el tipo elegido; el resto recibe todo
+	if msgType := readType(t, subscriber); msgType != "announcement" {
+		t.Fatalf("el suscriptor no debía recibir %s", msgType)
+	}
+	if msgType := readType(t, other); msgType != "gameState" {
+		t.Fatalf("esperaba gameState, obtuve %s", msgType)
+	}
+	if msgType := readType(t, other); msgType != "announcement" {
+		t.Fatalf("esperaba announcement, obtuve %s", msgType)
+	}
+
+	// Una suscripción vacía vuelve a recibir todo
+	if err := subscriber.WriteJSON(map[string]interface{}{"type": "subscribe", "data": map[string]interface{}{}}); err != nil {
+		t.Fatalf("error enviando subscribe: %v", err)
+	}
+	waitFor(t, "quitar el filtro", func() bool { return filterCount(h) == 0 })
+	h.BroadcastMessage("gameState", map[string]interface{}{"isActive": false})
+	if msgType := readType(t, subscriber); msgType != "gameState" {
+		t.Fatalf("sin filtro esperaba gameState, obtuve %s", msgType)
+	}
+}
+
+func TestBroadcastWithWriteFailures(t *testing.T) {
+	h := startHub()
+	server := newTestServer(t, h, nil)
+
+	const total = 10
+	conns := make([]*websocket.Conn, total)
+	for i := range conns {
+		conns[i] = server.dial(t, h)
+	}
+
+	// Los clientes sanos leen hasta recibir el mensaje final
+	done := make(chan string, total)
+	for _, conn := range conns[total/2:] {
+		go func(conn *websocket.Conn) {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			for {
+				var message Message
+				if err := conn.ReadJSON(&message); err != nil {
+					done <- err.Error()
+					return
+				}
+				if message.Type == "final" {
+					done <- ""
+					return
+				}
+			}
+		}(conn)
+	}
+
+	// Cerrar la mitad de las conexiones mientras se difunde: las escrituras
+	// fallan a la vez que ServeConn las desregistra
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				h.BroadcastMessage("gameState", map[string]interface{}{"sender": i, "n": j})
+			}
+		}(i)
+	}
+	for _, conn := range conns[:total/2] {
+		conn.Close()
+	}
+	wg.Wait()
+
+	waitFor(t, "que se quiten los clientes fallidos", func() bool { return clientCount(h) == total/2 })
+	h.BroadcastMessage("final", nil)
+	for range conns[total/2:] {
+		if err := <-done; err != "" {
+			t.Fatalf("un cliente sano dejó de recibir mensajes: %s", err)
+		}
+	}
+}
+
+func TestBroadcastAndWaitReportsDelivery(t *testing.T) {
+	h := startHub()
+	server := newTestServer(t, h, nil)
+	conns := []*websocket.Conn{server.dial(t, h), server.dial(t, h), server.dial(t, h)}
+
+	// Un cliente suscrito solo a otros eventos cuenta como filtrado
+	if err := conns[2].WriteJSON(map[string]interface{}{"type": "subscribe", "data": map[string]interface{}{"types": []string{"nextQuestion"}}}); err != nil {
+		t.Fatalf("error suscribiendo: %v", err)
+	}
+	waitFor(t, "que se registre el filtro", func() bool { return filterCount(h) == 1 })
+
+	h.BroadcastMessage("previous", nil)
+	delivery, err := h.BroadcastAndWait("gameEnded", nil, time.Second)
+	if err != nil {
+		t.Fatalf("error difundiendo: %v", err)
+	}
+	if delivery.Delivered != 2 || delivery.Filtered != 1 || delivery.Failed != 0 {
+		t.Fatalf("entrega inesperada: %+v", delivery)
+	}
+
+	// Al volver, el mensaje y los anteriores ya están escritos en cada conexión
+	for _, conn := range conns[:2] {
+		if got := readType(t, conn); got != "previous" {
+			t.Fatalf("esperaba previous primero, llegó %s", got)
+		}
+		if got := readType(t, conn); got != "gameEnded" {
+			t.Fatalf("esperaba gameEnded, llegó %s", got)
+		}
+	}
+}
+
+func TestBroadcastAndWaitTimesOutWithoutRun(t *testing.T) {
+	h := NewHub()
+	start := time.Now()
+	if _, err := h.BroadcastAndWait("gameEnded", nil, 50*time.Millisecond); !errors.Is(err, ErrDeliveryTimeout) {
+		t.Fatalf("sin Run esperaba ErrDeliveryTimeout, obtuve %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("la espera debe respetar el plazo, tardó %v", elapsed)
+	}
+}
+
+func TestServeConnThrottlesThenClosesFlood(t *testing.T) {
+	h := startHub()
+	h.SetRateLimit(1, 5)
+	var mutex sync.Mutex
+	handled := 0
+	server := newTestServer(t, h, func(conn *websocket.Conn, data []byte) {
+		mutex.Lock()
+		handled++
+		mutex.Unlock()
+	})
+
+	conn := server.dial(t, h)
+	other := server.dial(t, h)
+
+	// Pasa la ráfaga; los siguientes se descartan y, tras 5 descartes seguidos,
+	// se cierra la conexión. Escribir tras el cierre puede fallar.
+	for i := 0; i < 50; i++ {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"submitAnswer"}`)); err != nil {
+			break
+		}
+	}
+	expectClosed(t, conn)
+	waitFor(t, "que el cliente se desregistre", func() bool { return clientCount(h) == 1 })
+
+	mutex.Lock()
+	got := handled
+	mutex.Unlock()
+	if got != 5 {
+		t.Fatalf("esperaba que solo la ráfaga (5) llegara al handler, llegaron %d", got)
+	}
+
+	// La inundación de un cliente no afecta a los demás
+	h.BroadcastMessage("gameState", map[string]interface{}{"isActive": true})
+	if msgType := readType(t, other); msgType != "gameState" {
+		t.Fatalf("esperaba gameState, obtuve %s", msgType)
+	}
+}
+
+func TestServeConnWithinRateLimitStaysOpen(t *testing.T) {
+	h := startHub()
+	h.SetRateLimit(100, 2)
+	received := make(chan struct{}, 10)
+	server := newTestServer(t, h, func(conn *websocket.Conn, data []byte) {
+		received <- struct{}{}
+	})
+
+	conn := server.dial(t, h)
+	for i := 0; i < 6; i++ {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("ping")); err != nil {
+			t.Fatalf("error enviando mensaje: %v", err)
+		}
+		select {
+		case <-received:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("el mensaje %d dentro del límite no llegó al handler", i+1)
+		}
+		time.Sleep(15 * time.Millisecond)
+	}
+	if clientCount(h) != 1 {
+		t.Fatalf("un cliente dentro del límite no debe desconectarse")
+	}
+}
+
+// panicConn conexión que hace panic la primera vez que se cierra con panicOnClose
+type panicConn struct {
+	net.Conn
+	panicOnClose atomic.Bool
+}
+
+func (c *panicConn) Close() error {
+	if c.panicOnClose.CompareAndSwap(true, false) {
+		panic("cierre fallido")
+	}
+	return c.Conn.Close()
+}
+
+// hubUnlocked indica si el lock del hub está libre, sin bloquearse si no lo está
+func hubUnlocked(h *Hub) bool {
+	if !h.mutex.TryLock() {
+		return false
+	}
+	h.mutex.Unlock()
+	return true
+}
+
+func TestHubKeepsWorkingAfterPanicHoldingLock(t *testing.T) {
+	h := startHub()
+	server := newTestServer(t, h, nil)
+
+	// Un cliente cuyo cierre hace panic mientras el hub tiene el lock tomado
+	var broken *panicConn
+	dialer := websocket.Dialer{
+		NetDial: func(network, addr string) (net.Conn, error) {
+			conn, err := server.ln.Dial()
+			broken = &panicConn{Conn: conn}
+			return broken, err
+		},
+	}
+	conn, _, err := dialer.Dial("ws://quiz.test/ws", nil)
+	if err != nil {
+		t.Fatalf("error conectando WebSocket: %v", err)
+	}
+	waitFor(t, "registro del servidor", func() bool { return clientCount(h) == 1 })
+	h.Register(conn)
+	waitFor(t, "registro del cliente", func() bool { return clientCount(h) == 2 })
+
+	broken.panicOnClose.Store(true)
+	h.Unregister(conn)
+	waitFor(t, "el lock del hub libre tras el panic", func() bool { return hubUnlocked(h) && clientCount(h) == 1 })
+
+	other := server.dial(t, h)
+	delivery, err := h.BroadcastAndWait("ping", nil, time.Second)
+	if err != nil {
+		t.Fatalf("el hub debe seguir difundiendo tras el panic: %v", err)
+	}
+	if delivery.Delivered != 2 || delivery.Failed != 0 {
+		t.Fatalf("entrega inesperada: %+v", delivery)
+	}
+	if msgType := readType(t, other); msgType != "ping" {
+		t.Fatalf("esperaba ping, obtuve %s", msgType)
+	}
+}
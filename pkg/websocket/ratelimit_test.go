@@ -0,0 +1,37 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterThrottlesAndRefills(t *testing.T) {
+	l := newRateLimiter(2, 3)
+	now := l.last
+
+	// La ráfaga inicial pasa completa y el siguiente mensaje se descarta
+	for i := 0; i < 3; i++ {
+		if !l.allow(now) {
+			t.Fatalf("mensaje %d de la ráfaga descartado", i+1)
+		}
+	}
+	if l.allow(now) {
+		t.Fatalf("agotada la ráfaga el mensaje debe descartarse")
+	}
+
+	// A 2 fichas por segundo, medio segundo recarga una sola
+	now = now.Add(500 * time.Millisecond)
+	if !l.allow(now) || l.allow(now) {
+		t.Fatalf("medio segundo debe recargar exactamente una ficha")
+	}
+
+	// La recarga nunca supera la ráfaga
+	now = now.Add(time.Minute)
+	allowed := 0
+	for l.allow(now) {
+		allowed++
+	}
+	if allowed != 3 {
+		t.Fatalf("tras una pausa larga esperaba 3 mensajes, pasaron %d", allowed)
+	}
+}